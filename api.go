@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"smplr/player"
+	"smplr/stream"
+	"smplr/wavfile"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// apiTimeout bounds how long a request waits for the UI to handle it
+const apiTimeout = 5 * time.Second
+
+// apiSample is the JSON form of a sample
+type apiSample struct {
+	Index      int     `json:"index"`
+	Name       string  `json:"name"`
+	Channel    int     `json:"channel"`
+	Note       int     `json:"note"`
+	NoteName   string  `json:"noteName"`
+	Pitch      int     `json:"pitch"`
+	GainDB     float64 `json:"gainDb"`
+	StartFrame int     `json:"startFrame"`
+	EndFrame   int     `json:"endFrame"`
+	Playing    bool    `json:"playing"`
+	Loading    bool    `json:"loading"`
+	Corrupted  bool    `json:"corrupted"`
+}
+
+// apiSampleUpdate holds the mapping fields a PATCH may change; missing
+// fields are left alone
+type apiSampleUpdate struct {
+	Channel *int     `json:"channel"`
+	Note    *int     `json:"note"`
+	Pitch   *int     `json:"pitch"`
+	GainDB  *float64 `json:"gainDb"`
+}
+
+// apiRequestMsg hands an HTTP request to the model, so handlers never touch
+// model state outside the UI goroutine
+type apiRequestMsg struct {
+	action string // "list", "get", "update", "trigger", "stop", "stopall", "record" or "stoprecord"
+	index  int
+	update apiSampleUpdate
+	reply  chan apiResponse
+}
+
+// apiResponse is the status and JSON body of a reply
+type apiResponse struct {
+	status int
+	body   any
+}
+
+// apiError is the JSON body of a failed request
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// startAPIServer serves the HTTP control API on addr, handing every
+// request to the program with send, and streams hub's events over a
+// WebSocket
+func startAPIServer(addr string, send func(tea.Msg), hub *stream.Hub) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/samples", apiHandler(send, "list"))
+	mux.HandleFunc("GET /api/samples/{index}", apiHandler(send, "get"))
+	mux.HandleFunc("PATCH /api/samples/{index}", apiHandler(send, "update"))
+	mux.HandleFunc("POST /api/samples/{index}/trigger", apiHandler(send, "trigger"))
+	mux.HandleFunc("POST /api/samples/{index}/stop", apiHandler(send, "stop"))
+	mux.HandleFunc("POST /api/stop", apiHandler(send, "stopall"))
+	mux.HandleFunc("POST /api/record/start", apiHandler(send, "record"))
+	mux.HandleFunc("POST /api/record/stop", apiHandler(send, "stoprecord"))
+	mux.Handle("GET /api/stream", hub)
+
+	// Reject state-changing requests a browser sends on behalf of another
+	// site, so a web page can't trigger samples or start recording
+	go http.Serve(listener, http.NewCrossOriginProtection().Handler(mux))
+	return nil
+}
+
+// apiHandler parses a request for an action and writes the model's reply
+func apiHandler(send func(tea.Msg), action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		msg := apiRequestMsg{action: action, index: -1, reply: make(chan apiResponse, 1)}
+
+		if value := r.PathValue("index"); value != "" {
+			index, err := strconv.Atoi(value)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid sample index"})
+				return
+			}
+			msg.index = index
+		}
+		if action == "update" {
+			if err := json.NewDecoder(r.Body).Decode(&msg.update); err != nil {
+				writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid JSON: " + err.Error()})
+				return
+			}
+		}
+
+		send(msg)
+		select {
+		case response := <-msg.reply:
+			writeJSON(w, response.status, response.body)
+		case <-time.After(apiTimeout):
+			writeJSON(w, http.StatusServiceUnavailable, apiError{Error: "timed out waiting for smplr"})
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// handleAPIRequest carries out an API request and replies to it. A
+// recording it started gets the timer that stops it at its limit.
+func (m *model) handleAPIRequest(msg apiRequestMsg) tea.Cmd {
+	wasRecording := m.recording
+	msg.reply <- m.apiResponse(msg)
+	if m.recording && !wasRecording {
+		return m.recordingTimer()
+	}
+	return nil
+}
+
+func (m *model) apiResponse(msg apiRequestMsg) apiResponse {
+	switch msg.action {
+	case "list":
+		samples := make([]apiSample, 0, len(*m.files))
+		for i := range *m.files {
+			samples = append(samples, m.apiSample(i))
+		}
+		return apiResponse{http.StatusOK, samples}
+	case "stopall":
+		m.roller.Stop()
+		if err := m.audio.StopAll(); err != nil {
+			return apiResponse{http.StatusInternalServerError, apiError{Error: err.Error()}}
+		}
+		for i := range *m.files {
+			(*m.files)[i].PlayingCount = 0
+		}
+		return apiResponse{http.StatusOK, struct{}{}}
+	case "record":
+		if m.recording {
+			return apiResponse{http.StatusConflict, apiError{Error: "already recording"}}
+		}
+		if m.editing {
+			return apiResponse{http.StatusConflict, apiError{Error: "busy editing"}}
+		}
+		if err := m.startRecording(); err != nil {
+			return apiResponse{http.StatusInternalServerError, apiError{Error: err.Error()}}
+		}
+		return apiResponse{http.StatusOK, map[string]string{"filename": m.recordingFilename}}
+	case "stoprecord":
+		if !m.recording {
+			return apiResponse{http.StatusConflict, apiError{Error: "not recording"}}
+		}
+		// Stop it the way the record key does, so the take is finished
+		// and named in the UI
+		filename := m.recordingFilename
+		m.stopRecording()
+		return apiResponse{http.StatusOK, map[string]string{"filename": filename}}
+	}
+
+	// The rest act on one sample
+	if msg.index < 0 || msg.index >= len(*m.files) {
+		return apiResponse{http.StatusNotFound, apiError{Error: "no sample at that index"}}
+	}
+	file := &(*m.files)[msg.index]
+
+	switch msg.action {
+	case "update":
+		if err := m.applyAPIUpdate(file, msg.update); err != nil {
+			return apiResponse{http.StatusBadRequest, apiError{Error: err.Error()}}
+		}
+	case "trigger":
+		if m.recording {
+			return apiResponse{http.StatusConflict, apiError{Error: "recording"}}
+		}
+		if file.Metadata == nil || file.Corrupted {
+			return apiResponse{http.StatusConflict, apiError{Error: "sample isn't loaded"}}
+		}
+		if err := m.playSample(msg.index, player.SourceAPI); err != nil {
+			return apiResponse{http.StatusInternalServerError, apiError{Error: err.Error()}}
+		}
+	case "stop":
+		for _, index := range wavfile.ChainIndices(*m.files, msg.index) {
+			m.audio.StopPlayer((*m.files)[index].PlayerId)
+			(*m.files)[index].PlayingCount = 0
+		}
+	}
+	return apiResponse{http.StatusOK, m.apiSample(msg.index)}
+}
+
+// applyAPIUpdate validates every field of an update before changing any
+func (m *model) applyAPIUpdate(file *wavfile.WavFile, update apiSampleUpdate) error {
+	if update.Channel != nil && (*update.Channel < 1 || *update.Channel > 16) {
+		return fmt.Errorf("channel must be 1-16")
+	}
+	if update.Note != nil && (*update.Note < 0 || *update.Note > 127) {
+		return fmt.Errorf("note must be 0-127")
+	}
+	if update.Pitch != nil && (*update.Pitch < -12 || *update.Pitch > 12) {
+		return fmt.Errorf("pitch must be -12 to 12")
+	}
+	if update.GainDB != nil && (*update.GainDB < -60 || *update.GainDB > 0) {
+		return fmt.Errorf("gainDb must be -60 to 0")
+	}
+
+	if update.Channel != nil {
+		file.MidiChannel = *update.Channel
+	}
+	if update.Note != nil {
+		file.MidiNote = *update.Note
+	}
+	if update.Pitch != nil {
+		file.Pitch = *update.Pitch
+	}
+	if update.GainDB != nil {
+		file.GainDB = *update.GainDB
+		if file.PlayerId != 0 {
+			return m.audio.SetGain(file.PlayerId, float32(wavfile.DBToAmplitude(file.GainDB)))
+		}
+	}
+	return nil
+}
+
+func (m *model) apiSample(index int) apiSample {
+	file := (*m.files)[index]
+	return apiSample{
+		Index:      index,
+		Name:       file.Name,
+		Channel:    file.MidiChannel,
+		Note:       file.MidiNote,
+		NoteName:   wavfile.NoteName(file.MidiNote),
+		Pitch:      file.Pitch,
+		GainDB:     file.GainDB,
+		StartFrame: file.StartFrame,
+		EndFrame:   file.EndFrame,
+		Playing:    file.PlayingCount > 0,
+		Loading:    file.Loading,
+		Corrupted:  file.Corrupted,
+	}
+}
@@ -5,15 +5,76 @@ import ScreenCaptureKit
 
 // Global state for audio recorder and player
 private var gSystemAudioRecorder: SystemAudioRecorder?
+private var gInputRecorder: InputRecorder?
 private var gAudioEngineManager: AudioEngineManager?
 private var gCompletionCallback: (@convention(c) (Int32) -> Void)?
-private var gDecibelCallback: (@convention(c) (Float) -> Void)?
+private var gDecibelCallback: (@convention(c) (Float, Float) -> Void)?
+
+// Amplitude envelope applied to every trigger of a player
+struct Envelope {
+    var attackMs: Float
+    var decayMs: Float
+    var sustain: Float  // Level held after the decay, 0...1
+    var releaseMs: Float
+}
+
+// One playback of a player's buffer. Players keep a pool of voices so
+// retriggers overlap instead of cutting each other off.
+private class Voice {
+    let node = AVAudioPlayerNode()
+    let playerID: Int32
+    // Bumped whenever the voice stops or restarts so stale streams, ramps and
+    // completion handlers give up
+    var generation = 0
+    var active = false
+    // Trigger order, used to steal the oldest voice
+    var startedAt = 0
+
+    init(playerID: Int32) {
+        self.playerID = playerID
+    }
+}
 
 // Audio Engine Manager class
 class AudioEngineManager {
     private let engine: AVAudioEngine
-    private var players: [Int32: AVAudioPlayerNode] = [:]
+    // Voice pool per player
+    private var players: [Int32: [Voice]] = [:]
+    private var playerFormats: [Int32: AVAudioFormat] = [:]
+    private let maxVoicesPerPlayer = 8
+    // Longest crossfade between chained links, matching wavfile.MaxChainCrossfade
+    private let maxCrossfadeMs: Int32 = 2000
+    // Voices allowed to sound at once across all players
+    private var maxVoices = 32
     private var playerBuffers: [Int32: AVAudioPCMBuffer] = [:]
+    // Ratio of buffer frames to source file frames for resampled players
+    private var playerFrameRatios: [Int32: Double] = [:]
+    // Stereo width per player, 0 = mono sum, 1 = original
+    private var playerWidths: [Int32: Float] = [:]
+    private var playerGains: [Int32: Float] = [:]
+    // Pan per player, -1 = left, 1 = right
+    private var playerPans: [Int32: Float] = [:]
+    private var playerEnvelopes: [Int32: Envelope] = [:]
+    private var playerLoops: [Int32: Bool] = [:]
+    private var playerReversed: [Int32: Bool] = [:]
+    // Full buffers of players with more than two channels, which play a
+    // stereo pair taken from them
+    private var playerSourceBuffers: [Int32: AVAudioPCMBuffer] = [:]
+    // Channel pair per multichannel player, 0 = mix of all, 1 = channels 1-2, ...
+    private var playerChannelPairs: [Int32: Int] = [:]
+    // Runs release ramps
+    private let releaseQueue = DispatchQueue(label: "smplr.release")
+    // Guards voice state, which completion handlers and ramps touch off the main thread
+    private let voiceLock = NSLock()
+    private var voiceCounter = 0
+    // Master output being bounced to a file, nil when not bouncing
+    private var bounceFile: AVAudioFile?
+    // Peak limiter between the main mixer and the output, installed on start
+    private var limiter: AVAudioUnitEffect?
+    private var limiterEnabled = true
+    // Renders real-time pitch shifts ahead of playback
+    private let pitchQueue = DispatchQueue(label: "smplr.pitch")
+    private let pitchBlockSize = 1024
     private var nextPlayerID: Int32 = 1
     private var deviceID: AudioDeviceID?
 
@@ -22,7 +83,7 @@ class AudioEngineManager {
     }
 
     // Helper function to find audio device by name
-    private static func findDeviceByName(_ name: String) -> AudioDeviceID? {
+    static func findDeviceByName(_ name: String) -> AudioDeviceID? {
         var propertySize: UInt32 = 0
         var propertyAddress = AudioObjectPropertyAddress(
             mSelector: kAudioHardwarePropertyDevices,
@@ -111,17 +172,66 @@ class AudioEngineManager {
             }
         }
 
+        installLimiter()
+
         if !engine.isRunning {
             try engine.start()
         }
     }
 
+    // Route the main mixer through a peak limiter so stacked samples don't clip
+    private func installLimiter() {
+        guard limiter == nil else { return }
+
+        let description = AudioComponentDescription(
+            componentType: kAudioUnitType_Effect,
+            componentSubType: kAudioUnitSubType_PeakLimiter,
+            componentManufacturer: kAudioUnitManufacturer_Apple,
+            componentFlags: 0,
+            componentFlagsMask: 0
+        )
+        let effect = AVAudioUnitEffect(audioComponentDescription: description)
+        let format = engine.mainMixerNode.outputFormat(forBus: 0)
+
+        engine.attach(effect)
+        engine.disconnectNodeOutput(engine.mainMixerNode)
+        engine.connect(engine.mainMixerNode, to: effect, format: format)
+        engine.connect(effect, to: engine.outputNode, format: format)
+        effect.bypass = !limiterEnabled
+        limiter = effect
+    }
+
+    func setLimiter(_ enabled: Bool) {
+        limiterEnabled = enabled
+        limiter?.bypass = !enabled
+    }
+
+    // The last node before the output: what the listener hears
+    private var masterNode: AVAudioNode {
+        return limiter ?? engine.mainMixerNode
+    }
+
+    // Sample rate the engine renders at
+    private var engineSampleRate: Double {
+        return engine.outputNode.outputFormat(forBus: 0).sampleRate
+    }
+
     func createPlayer(_ fileURL: URL) throws -> Int32 {
         let playerID = nextPlayerID
         nextPlayerID += 1
 
+        // Use a resampled copy when the file doesn't match the engine sample rate
+        var loadURL = fileURL
+        var frameRatio = 1.0
+        let sourceRate = try AVAudioFile(forReading: fileURL).processingFormat.sampleRate
+        let targetRate = engineSampleRate
+        if targetRate > 0 && sourceRate != targetRate {
+            loadURL = try resampledCopy(of: fileURL, to: targetRate)
+            frameRatio = targetRate / sourceRate
+        }
+
         // Load the audio file and buffer first to get the format
-        let audioFile = try AVAudioFile(forReading: fileURL)
+        let audioFile = try AVAudioFile(forReading: loadURL)
         let frameCount = AVAudioFrameCount(audioFile.length)
         let format = audioFile.processingFormat
 
@@ -138,120 +248,851 @@ class AudioEngineManager {
 
         try audioFile.read(into: buffer)
 
-        // Create and connect the player node directly to mixer (no real-time pitch shifting)
-        let playerNode = AVAudioPlayerNode()
+        // Multichannel files play as stereo, mixed down until a pair is chosen
+        var playBuffer = buffer
+        if format.channelCount > 2 {
+            playerSourceBuffers[playerID] = buffer
+            playBuffer = try channelPairBuffer(buffer, pair: 0)
+        }
+
+        playerFormats[playerID] = playBuffer.format
+        let voice = makeVoice(playerID, format: playBuffer.format)
+        voiceLock.lock()
+        players[playerID] = [voice]
+        voiceLock.unlock()
+        playerBuffers[playerID] = playBuffer
+        playerFrameRatios[playerID] = frameRatio
 
-        engine.attach(playerNode)
+        return playerID
+    }
 
+    // Create a voice and connect it directly to the mixer (no real-time pitch shifting)
+    private func makeVoice(_ playerID: Int32, format: AVAudioFormat) -> Voice {
+        let voice = Voice(playerID: playerID)
+        engine.attach(voice.node)
         // Connect: player -> mixer (direct, low latency)
-        engine.connect(playerNode, to: engine.mainMixerNode, format: format)
+        engine.connect(voice.node, to: engine.mainMixerNode, format: format)
+        voice.node.volume = playerGains[playerID] ?? 1.0
+        voice.node.pan = playerPans[playerID] ?? 0
+        return voice
+    }
 
-        players[playerID] = playerNode
-        playerBuffers[playerID] = buffer
+    // Returns a cached copy of the file resampled to the target rate, rendering it if needed
+    private func resampledCopy(of fileURL: URL, to targetRate: Double) throws -> URL {
+        let cacheDir = URL(fileURLWithPath: FileManager.default.currentDirectoryPath)
+            .appendingPathComponent(".smplr/resampled")
+        try FileManager.default.createDirectory(at: cacheDir, withIntermediateDirectories: true)
+
+        // Name the copy after the file's whole path, extension included, so
+        // kick.wav and kick.aif, or kicks in other directories, don't share one
+        let workingDir = FileManager.default.currentDirectoryPath + "/"
+        var name = fileURL.standardizedFileURL.path
+        if name.hasPrefix(workingDir) {
+            name.removeFirst(workingDir.count)
+        }
+        // Escape the escape character first, so no two paths share a name
+        name = name.replacingOccurrences(of: "%", with: "%25")
+            .replacingOccurrences(of: "/", with: "%2F")
+        let cacheURL = cacheDir.appendingPathComponent("\(name)_\(Int(targetRate)).wav")
 
-        return playerID
+        // Reuse the cached copy unless the source is newer
+        let fileManager = FileManager.default
+        if let cacheAttrs = try? fileManager.attributesOfItem(atPath: cacheURL.path),
+            let sourceAttrs = try? fileManager.attributesOfItem(atPath: fileURL.path),
+            let cacheDate = cacheAttrs[.modificationDate] as? Date,
+            let sourceDate = sourceAttrs[.modificationDate] as? Date,
+            cacheDate >= sourceDate
+        {
+            return cacheURL
+        }
+
+        let sourceFile = try AVAudioFile(forReading: fileURL)
+        guard
+            let sourceBuffer = AVAudioPCMBuffer(
+                pcmFormat: sourceFile.processingFormat,
+                frameCapacity: AVAudioFrameCount(sourceFile.length)
+            )
+        else {
+            throw NSError(
+                domain: "AudioEngineManager", code: -2,
+                userInfo: [NSLocalizedDescriptionKey: "Failed to create audio buffer"])
+        }
+        try sourceFile.read(into: sourceBuffer)
+
+        let resampled = try resampleBuffer(sourceBuffer, to: targetRate)
+
+        if fileManager.fileExists(atPath: cacheURL.path) {
+            try fileManager.removeItem(at: cacheURL)
+        }
+        let outputFile = try AVAudioFile(
+            forWriting: cacheURL,
+            settings: containerSettings(
+                for: cacheURL, sampleRate: targetRate, channels: resampled.format.channelCount),
+            commonFormat: .pcmFormatFloat32,
+            interleaved: false
+        )
+        try outputFile.write(from: resampled)
+
+        return cacheURL
     }
 
     func destroyPlayer(_ playerID: Int32) {
-        guard let playerNode = players[playerID] else {
+        guard let voices = players[playerID] else {
             print("Warning: Player ID \(playerID) not found")
             return
         }
 
-        playerNode.stop()
-        engine.disconnectNodeOutput(playerNode)
-        engine.detach(playerNode)
+        for voice in voices {
+            stopVoice(voice)
+            engine.disconnectNodeOutput(voice.node)
+            engine.detach(voice.node)
+        }
 
+        voiceLock.lock()
         players.removeValue(forKey: playerID)
+        voiceLock.unlock()
+        playerFormats.removeValue(forKey: playerID)
         playerBuffers.removeValue(forKey: playerID)
+        playerFrameRatios.removeValue(forKey: playerID)
+        playerWidths.removeValue(forKey: playerID)
+        playerGains.removeValue(forKey: playerID)
+        playerPans.removeValue(forKey: playerID)
+        playerEnvelopes.removeValue(forKey: playerID)
+        playerLoops.removeValue(forKey: playerID)
+        playerReversed.removeValue(forKey: playerID)
+        playerSourceBuffers.removeValue(forKey: playerID)
+        playerChannelPairs.removeValue(forKey: playerID)
+    }
+
+    func setStereoWidth(_ playerID: Int32, width: Float) throws {
+        guard players[playerID] != nil else {
+            throw NSError(
+                domain: "AudioEngineManager", code: -1,
+                userInfo: [NSLocalizedDescriptionKey: "Player ID \(playerID) not found"])
+        }
+
+        playerWidths[playerID] = max(0, min(1, width))
+    }
+
+    func setGain(_ playerID: Int32, gain: Float) throws {
+        guard let voices = players[playerID] else {
+            throw NSError(
+                domain: "AudioEngineManager", code: -1,
+                userInfo: [NSLocalizedDescriptionKey: "Player ID \(playerID) not found"])
+        }
+
+        // Player node volume only attenuates
+        playerGains[playerID] = max(0, min(1, gain))
+        for voice in voices {
+            voice.node.volume = playerGains[playerID]!
+        }
+    }
+
+    func setPan(_ playerID: Int32, pan: Float) throws {
+        guard let voices = players[playerID] else {
+            throw NSError(
+                domain: "AudioEngineManager", code: -1,
+                userInfo: [NSLocalizedDescriptionKey: "Player ID \(playerID) not found"])
+        }
+
+        playerPans[playerID] = max(-1, min(1, pan))
+        for voice in voices {
+            voice.node.pan = playerPans[playerID]!
+        }
+    }
+
+    func setEnvelope(_ playerID: Int32, _ envelope: Envelope) throws {
+        guard players[playerID] != nil else {
+            throw NSError(
+                domain: "AudioEngineManager", code: -1,
+                userInfo: [NSLocalizedDescriptionKey: "Player ID \(playerID) not found"])
+        }
+
+        playerEnvelopes[playerID] = envelope
     }
 
+    func setLoop(_ playerID: Int32, loop: Bool) throws {
+        guard players[playerID] != nil else {
+            throw NSError(
+                domain: "AudioEngineManager", code: -1,
+                userInfo: [NSLocalizedDescriptionKey: "Player ID \(playerID) not found"])
+        }
+
+        playerLoops[playerID] = loop
+    }
+
+    func setReverse(_ playerID: Int32, reverse: Bool) throws {
+        guard players[playerID] != nil else {
+            throw NSError(
+                domain: "AudioEngineManager", code: -1,
+                userInfo: [NSLocalizedDescriptionKey: "Player ID \(playerID) not found"])
+        }
+
+        playerReversed[playerID] = reverse
+    }
+
+    // Choose which stereo pair of a multichannel player plays. Players of one
+    // or two channels ignore it.
+    func setChannelPair(_ playerID: Int32, pair: Int) throws {
+        guard players[playerID] != nil else {
+            throw NSError(
+                domain: "AudioEngineManager", code: -1,
+                userInfo: [NSLocalizedDescriptionKey: "Player ID \(playerID) not found"])
+        }
+
+        playerChannelPairs[playerID] = pair
+        if let source = playerSourceBuffers[playerID] {
+            playerBuffers[playerID] = try channelPairBuffer(source, pair: pair)
+        }
+    }
+
+    // Reduce a multichannel buffer to stereo: pair 0 mixes odd-numbered
+    // channels to the left and even-numbered ones to the right, pair n takes
+    // channels 2n-1 and 2n as they are
+    private func channelPairBuffer(_ source: AVAudioPCMBuffer, pair: Int) throws
+        -> AVAudioPCMBuffer
+    {
+        let channels = Int(source.format.channelCount)
+        let frames = Int(source.frameLength)
+        guard
+            let format = AVAudioFormat(
+                standardFormatWithSampleRate: source.format.sampleRate, channels: 2),
+            let stereo = AVAudioPCMBuffer(
+                pcmFormat: format, frameCapacity: AVAudioFrameCount(frames))
+        else {
+            throw NSError(
+                domain: "AudioEngineManager", code: -2,
+                userInfo: [NSLocalizedDescriptionKey: "Failed to create channel pair buffer"])
+        }
+        stereo.frameLength = AVAudioFrameCount(frames)
+
+        for side in 0..<2 {
+            let dest = stereo.floatChannelData![side]
+            if pair > 0 {
+                // A lone last channel plays on both sides
+                let channel = min((pair - 1) * 2 + side, channels - 1)
+                memcpy(dest, source.floatChannelData![channel], frames * MemoryLayout<Float>.stride)
+                continue
+            }
+
+            let sideChannels = Array(stride(from: side, to: channels, by: 2))
+            let scale = 1 / Float(sideChannels.count)
+            for frame in 0..<frames {
+                var sum: Float = 0
+                for channel in sideChannels {
+                    sum += source.floatChannelData![channel][frame]
+                }
+                dest[frame] = sum * scale
+            }
+        }
+
+        return stereo
+    }
+
+    // Stop every voice of a player
     func stopPlayer(_ playerID: Int32) {
-        guard let playerNode = players[playerID] else {
+        guard let voices = players[playerID] else {
             print("Warning: Player ID \(playerID) not found")
             return
         }
 
-        playerNode.stop()
+        for voice in voices {
+            releaseVoice(voice, playerID: playerID)
+        }
+    }
+
+    // Cut every voice of every player without release ramps
+    func stopAll() {
+        for voice in players.values.joined() {
+            stopVoice(voice)
+            voice.node.volume = playerGains[voice.playerID] ?? 1.0
+        }
+    }
+
+    // Fade a voice out over the release time, unless it is retriggered first
+    private func releaseVoice(_ voice: Voice, playerID: Int32) {
+        let generation = currentGeneration(voice)
+        guard let release = playerEnvelopes[playerID]?.releaseMs, release > 0,
+            generation.active
+        else {
+            stopVoice(voice)
+            return
+        }
+
+        let gain = playerGains[playerID] ?? 1.0
+        let stepMs = 5
+        let steps = max(1, Int(release) / stepMs)
+
+        func rampStep(_ step: Int) {
+            guard isCurrentGeneration(voice, generation.value) else { return }
+            if step >= steps {
+                stopVoice(voice)
+                voice.node.volume = gain
+                return
+            }
+            voice.node.volume = gain * (1 - Float(step) / Float(steps))
+            releaseQueue.asyncAfter(deadline: .now() + .milliseconds(stepMs)) {
+                rampStep(step + 1)
+            }
+        }
+        releaseQueue.async { rampStep(0) }
     }
 
     func playFile(_ playerID: Int32, _ fileURL: URL, cents: Float) throws {
-        guard let playerNode = players[playerID] else {
+        guard players[playerID] != nil else {
             print("Error: Player ID \(playerID) not found")
             throw NSError(
                 domain: "AudioEngineManager", code: -1,
                 userInfo: [NSLocalizedDescriptionKey: "Player ID \(playerID) not found"])
         }
 
-        // No real-time pitch shifting - files are pre-rendered at the correct pitch
-        // cents parameter is ignored (should be 0)
-
         // If buffer is loaded, use it; otherwise fall back to file
-        if let buffer = playerBuffers[playerID] {
-            playerNode.stop()
-            playerNode.scheduleBuffer(buffer, at: nil) {
-                // Call completion callback when playback finishes
-                if let callback = gCompletionCallback {
-                    callback(playerID)
+        if var buffer = playerBuffers[playerID] {
+            // Shape a copy so the shared buffer keeps the original audio
+            let reversed = playerReversed[playerID] ?? false
+            if playerWidths[playerID] != nil || playerEnvelopes[playerID] != nil || reversed {
+                buffer = try copyFrames(buffer, start: 0, frameCount: Int(buffer.frameLength))
+                if let width = playerWidths[playerID] {
+                    applyStereoWidth(buffer, width: width)
+                }
+                if reversed {
+                    reverseFrames(buffer)
                 }
             }
-            playerNode.play()
+
+            try play(buffer, playerID: playerID, cents: cents)
         }
     }
 
     func playRegion(
         _ playerID: Int32, _ fileURL: URL, startFrame: Int32, endFrame: Int32, cents: Float
     ) throws {
-        guard let playerNode = players[playerID] else {
+        guard players[playerID] != nil else {
             throw NSError(
                 domain: "AudioEngineManager", code: -1,
                 userInfo: [NSLocalizedDescriptionKey: "Player ID \(playerID) not found"])
         }
 
-        // No real-time pitch shifting - files are pre-rendered at the correct pitch
-        // cents parameter is ignored (should be 0)
-
         // If buffer is loaded, create a segment buffer; otherwise use file
-        if let sourceBuffer = playerBuffers[playerID] {
-            let start = Int(startFrame)
-            let end = Int(endFrame)
-            let frameCount = end - start
+        if playerBuffers[playerID] != nil {
+            let segmentBuffer = try regionBuffer(
+                playerID, startFrame: startFrame, endFrame: endFrame)
 
-            guard start >= 0 && end <= Int(sourceBuffer.frameLength) && frameCount > 0 else {
-                throw NSError(
-                    domain: "AudioEngineManager", code: -3,
-                    userInfo: [NSLocalizedDescriptionKey: "Invalid frame range"])
+            try play(segmentBuffer, playerID: playerID, cents: cents)
+        }
+    }
+
+    // Play a buffer on a free voice of a player, applying its envelope and
+    // loop mode and pitch-shifting it in real time when cents is nonzero. The
+    // buffer is modified when the player has an envelope, so it must not be
+    // shared then.
+    private func play(_ buffer: AVAudioPCMBuffer, playerID: Int32, cents: Float) throws {
+        let (voice, generation) = try acquireVoice(playerID)
+
+        if playerLoops[playerID] ?? false {
+            try playLooped(
+                buffer, on: voice, playerID: playerID, cents: cents, generation: generation)
+            return
+        }
+
+        if let envelope = playerEnvelopes[playerID] {
+            applyEnvelope(buffer, envelope)
+        }
+
+        if cents == 0 {
+            voice.node.scheduleBuffer(
+                buffer, at: nil,
+                completionHandler: finished(voice, playerID: playerID, generation: generation))
+            voice.node.play()
+            return
+        }
+
+        pitchQueue.async { [weak self] in
+            self?.streamPitched(
+                buffer, on: voice, playerID: playerID, cents: cents, generation: generation)
+        }
+    }
+
+    // Write everything the master output renders to a WAV file until stopBounce
+    func startBounce(to url: URL) throws {
+        guard bounceFile == nil else {
+            throw NSError(
+                domain: "AudioEngineManager", code: -6,
+                userInfo: [NSLocalizedDescriptionKey: "Already bouncing"])
+        }
+
+        let master = masterNode
+        let format = master.outputFormat(forBus: 0)
+        let file = try AVAudioFile(
+            forWriting: url,
+            settings: containerSettings(
+                for: url, sampleRate: format.sampleRate, channels: format.channelCount),
+            commonFormat: .pcmFormatFloat32,
+            interleaved: false
+        )
+        bounceFile = file
+
+        master.installTap(onBus: 0, bufferSize: 4096, format: format) { buffer, _ in
+            do {
+                try file.write(from: buffer)
+            } catch {
+                print("Error writing bounce: \(error)")
             }
+        }
+    }
 
-            guard
-                let segmentBuffer = AVAudioPCMBuffer(
-                    pcmFormat: sourceBuffer.format,
-                    frameCapacity: AVAudioFrameCount(frameCount)
-                )
-            else {
-                throw NSError(
-                    domain: "AudioEngineManager", code: -2,
-                    userInfo: [NSLocalizedDescriptionKey: "Failed to create segment buffer"])
+    func stopBounce() {
+        guard bounceFile != nil else { return }
+        masterNode.removeTap(onBus: 0)
+        // Releasing the file flushes and closes it
+        bounceFile = nil
+    }
+
+    func setMasterVolume(_ volume: Float) {
+        engine.mainMixerNode.outputVolume = max(0, min(1, volume))
+    }
+
+    func setMaxVoices(_ count: Int) {
+        maxVoices = max(1, count)
+    }
+
+    // Number of voices currently sounding across all players
+    func activeVoiceCount() -> Int {
+        voiceLock.lock()
+        defer { voiceLock.unlock() }
+        return players.values.reduce(0) { count, voices in
+            count + voices.filter { $0.active }.count
+        }
+    }
+
+    // Take an idle voice from the player's pool, growing the pool up to
+    // maxVoicesPerPlayer and then stealing the oldest voice. When maxVoices
+    // are already sounding, the oldest voice of any player is stolen to make
+    // room. The voice is stopped, restored to the player's gain and marked
+    // active.
+    private func acquireVoice(_ playerID: Int32) throws -> (Voice, Int) {
+        guard let voices = players[playerID], let format = playerFormats[playerID] else {
+            throw NSError(
+                domain: "AudioEngineManager", code: -1,
+                userInfo: [NSLocalizedDescriptionKey: "Player ID \(playerID) not found"])
+        }
+
+        voiceLock.lock()
+        let idle = voices.first { !$0.active }
+        let oldest = voices.min { $0.startedAt < $1.startedAt }!
+        voiceLock.unlock()
+
+        var voice: Voice
+        if let idle = idle {
+            voice = idle
+        } else if voices.count < maxVoicesPerPlayer {
+            voice = makeVoice(playerID, format: format)
+            // The voice counts iterate players from other threads
+            voiceLock.lock()
+            players[playerID]!.append(voice)
+            voiceLock.unlock()
+        } else {
+            voice = oldest
+            steal(voice)
+        }
+
+        // Keep within the global polyphony limit
+        if activeVoiceCount() >= maxVoices, let victim = oldestActiveVoice() {
+            steal(victim)
+        }
+
+        voiceLock.lock()
+        voice.generation += 1
+        voice.active = true
+        voiceCounter += 1
+        voice.startedAt = voiceCounter
+        let generation = voice.generation
+        voiceLock.unlock()
+
+        voice.node.stop()
+        // Undo any release ramp that was cut short
+        voice.node.volume = playerGains[playerID] ?? 1.0
+        return (voice, generation)
+    }
+
+    private func oldestActiveVoice() -> Voice? {
+        voiceLock.lock()
+        defer { voiceLock.unlock() }
+        return players.values.joined().filter { $0.active }.min { $0.startedAt < $1.startedAt }
+    }
+
+    // Cut off a sounding voice. A stolen playback never completes, so report
+    // it finished now.
+    private func steal(_ voice: Voice) {
+        stopVoice(voice)
+        if let callback = gCompletionCallback {
+            callback(voice.playerID)
+        }
+    }
+
+    // Completion handler for the last buffer of a playback. It frees the voice
+    // and reports the playback finished unless the voice was stopped or
+    // retriggered since, in which case the stop already accounted for it.
+    private func finished(_ voice: Voice, playerID: Int32, generation: Int)
+        -> AVAudioNodeCompletionHandler
+    {
+        return { [weak self] in
+            guard let self = self else { return }
+            self.voiceLock.lock()
+            let current = voice.generation == generation
+            if current {
+                voice.active = false
+            }
+            self.voiceLock.unlock()
+
+            if current, let callback = gCompletionCallback {
+                callback(playerID)
+            }
+        }
+    }
+
+    // Repeat a buffer until the player is stopped. The first pass carries the
+    // attack and decay; the repeats play at the sustain level. Looped regions
+    // are pitch-shifted up front since the loop replays the same buffer.
+    private func playLooped(
+        _ buffer: AVAudioPCMBuffer, on voice: Voice, playerID: Int32, cents: Float,
+        generation: Int
+    ) throws {
+        var loopBuffer = buffer
+        if cents != 0 {
+            loopBuffer = try pitchShiftBuffer(buffer, cents: cents)
+        }
+
+        if let envelope = playerEnvelopes[playerID] {
+            let head = try copyFrames(loopBuffer, start: 0, frameCount: Int(loopBuffer.frameLength))
+            applyEnvelope(head, envelope)
+            if cents == 0 {
+                // Don't scale a buffer the caller may share
+                loopBuffer = try copyFrames(
+                    loopBuffer, start: 0, frameCount: Int(loopBuffer.frameLength))
+            }
+            applyEnvelope(
+                loopBuffer, Envelope(attackMs: 0, decayMs: 0, sustain: envelope.sustain, releaseMs: 0))
+            voice.node.scheduleBuffer(head, at: nil)
+        }
+
+        // Looping buffers only end by being stopped, which accounts for them
+        voice.node.scheduleBuffer(loopBuffer, at: nil, options: .loops)
+        voice.node.play()
+    }
+
+    // Feed a buffer through a real-time Rubberband stretcher block by block,
+    // scheduling each block of output as soon as it is ready. Playback starts
+    // after the first block instead of after the whole region is rendered.
+    private func streamPitched(
+        _ buffer: AVAudioPCMBuffer, on voice: Voice, playerID: Int32,
+        cents: Float, generation: Int
+    ) {
+        let channels = Int(buffer.format.channelCount)
+        let options: RubberBandOptions = Int32(
+            RubberBandOptionProcessRealTime.rawValue
+                | RubberBandOptionPitchHighConsistency.rawValue
+                | RubberBandOptionChannelsApart.rawValue
+        )
+        guard
+            let rb = rubberband_new(
+                UInt32(buffer.format.sampleRate), UInt32(channels), options, 1.0,
+                pow(2.0, Double(cents) / 1200.0))
+        else {
+            print("Error: Failed to create Rubberband stretcher")
+            return
+        }
+        defer { rubberband_delete(rb) }
+        rubberband_set_max_process_size(rb, UInt32(pitchBlockSize))
+
+        // The stretcher delays its output; drop the delay so the attack stays on time
+        var latency = Int(rubberband_get_latency(rb))
+        let length = Int(buffer.frameLength)
+        var offset = 0
+        var started = false
+        // The newest block is held back so the completion callback goes on the last one
+        var pending: AVAudioPCMBuffer?
+
+        while offset < length {
+            guard isCurrentGeneration(voice, generation) else { return }
+
+            let count = min(pitchBlockSize, length - offset)
+            let final: Int32 = offset + count >= length ? 1 : 0
+            let inputPtrs: [UnsafePointer<Float>?] = (0..<channels).map {
+                UnsafePointer(buffer.floatChannelData![$0].advanced(by: offset))
+            }
+            inputPtrs.withUnsafeBufferPointer { ptrsBuffer in
+                rubberband_process(rb, ptrsBuffer.baseAddress, UInt32(count), final)
+            }
+            offset += count
+
+            var available = Int(rubberband_available(rb))
+            while available > 0 {
+                guard
+                    let block = AVAudioPCMBuffer(
+                        pcmFormat: buffer.format, frameCapacity: AVAudioFrameCount(available))
+                else { return }
+                var outputPtrs: [UnsafeMutablePointer<Float>?] = (0..<channels).map {
+                    block.floatChannelData![$0]
+                }
+                let retrieved = outputPtrs.withUnsafeMutableBufferPointer { ptrsBuffer in
+                    Int(rubberband_retrieve(rb, ptrsBuffer.baseAddress, UInt32(available)))
+                }
+                block.frameLength = AVAudioFrameCount(retrieved)
+                available = Int(rubberband_available(rb))
+
+                var output = block
+                if latency > 0 {
+                    let skip = min(latency, retrieved)
+                    latency -= skip
+                    if skip == retrieved { continue }
+                    guard
+                        let trimmed = try? copyFrames(
+                            block, start: skip, frameCount: retrieved - skip)
+                    else { return }
+                    output = trimmed
+                }
+
+                if let previous = pending {
+                    guard isCurrentGeneration(voice, generation) else { return }
+                    voice.node.scheduleBuffer(previous, at: nil)
+                    if !started {
+                        voice.node.play()
+                        started = true
+                    }
+                }
+                pending = output
+            }
+        }
+
+        guard let last = pending, isCurrentGeneration(voice, generation) else { return }
+        voice.node.scheduleBuffer(
+            last, at: nil,
+            completionHandler: finished(voice, playerID: playerID, generation: generation))
+        if !started {
+            voice.node.play()
+        }
+    }
+
+    // Invalidate whatever the voice is playing and stop it
+    private func stopVoice(_ voice: Voice) {
+        voiceLock.lock()
+        voice.generation += 1
+        voice.active = false
+        voiceLock.unlock()
+        voice.node.stop()
+    }
+
+    private func currentGeneration(_ voice: Voice) -> (value: Int, active: Bool) {
+        voiceLock.lock()
+        defer { voiceLock.unlock() }
+        return (voice.generation, voice.active)
+    }
+
+    private func isCurrentGeneration(_ voice: Voice, _ generation: Int) -> Bool {
+        voiceLock.lock()
+        defer { voiceLock.unlock() }
+        return voice.generation == generation
+    }
+
+    // Copy a region of a player's buffer into a new buffer
+    private func regionBuffer(_ playerID: Int32, startFrame: Int32, endFrame: Int32) throws
+        -> AVAudioPCMBuffer
+    {
+        guard let sourceBuffer = playerBuffers[playerID] else {
+            throw NSError(
+                domain: "AudioEngineManager", code: -1,
+                userInfo: [NSLocalizedDescriptionKey: "Player ID \(playerID) not found"])
+        }
+
+        // Markers are in source file frames; scale them for resampled buffers
+        let frameRatio = playerFrameRatios[playerID] ?? 1.0
+        let start = Int(Double(startFrame) * frameRatio)
+        let end = min(Int(Double(endFrame) * frameRatio), Int(sourceBuffer.frameLength))
+        let frameCount = end - start
+
+        guard start >= 0 && end <= Int(sourceBuffer.frameLength) && frameCount > 0 else {
+            throw NSError(
+                domain: "AudioEngineManager", code: -3,
+                userInfo: [NSLocalizedDescriptionKey: "Invalid frame range"])
+        }
+
+        let segmentBuffer = try copyFrames(sourceBuffer, start: start, frameCount: frameCount)
+
+        if let width = playerWidths[playerID] {
+            applyStereoWidth(segmentBuffer, width: width)
+        }
+        if playerReversed[playerID] ?? false {
+            reverseFrames(segmentBuffer)
+        }
+
+        return segmentBuffer
+    }
+
+    // Copy frameCount frames starting at start into a new buffer
+    private func copyFrames(_ sourceBuffer: AVAudioPCMBuffer, start: Int, frameCount: Int) throws
+        -> AVAudioPCMBuffer
+    {
+        guard
+            let segmentBuffer = AVAudioPCMBuffer(
+                pcmFormat: sourceBuffer.format,
+                frameCapacity: AVAudioFrameCount(frameCount)
+            )
+        else {
+            throw NSError(
+                domain: "AudioEngineManager", code: -2,
+                userInfo: [NSLocalizedDescriptionKey: "Failed to create segment buffer"])
+        }
+
+        // Copy the region from source buffer to segment buffer
+        let channelCount = Int(sourceBuffer.format.channelCount)
+        for channel in 0..<channelCount {
+            let sourcePtr = sourceBuffer.floatChannelData![channel]
+            let destPtr = segmentBuffer.floatChannelData![channel]
+            memcpy(
+                destPtr, sourcePtr.advanced(by: start), frameCount * MemoryLayout<Float>.stride)
+        }
+        segmentBuffer.frameLength = AVAudioFrameCount(frameCount)
+
+        return segmentBuffer
+    }
+
+    // Apply the attack, decay and sustain stages of an envelope to a buffer.
+    // The release stage runs when the player is stopped.
+    private func applyEnvelope(_ buffer: AVAudioPCMBuffer, _ envelope: Envelope) {
+        let rate = Float(buffer.format.sampleRate)
+        let attackFrames = Int(envelope.attackMs / 1000 * rate)
+        let decayFrames = Int(envelope.decayMs / 1000 * rate)
+        let sustain = max(0, min(1, envelope.sustain))
+        guard attackFrames > 0 || decayFrames > 0 || sustain < 1 else { return }
+
+        for i in 0..<Int(buffer.frameLength) {
+            let level: Float
+            if i < attackFrames {
+                level = Float(i) / Float(attackFrames)
+            } else if i < attackFrames + decayFrames {
+                level = 1 - (1 - sustain) * Float(i - attackFrames) / Float(decayFrames)
+            } else {
+                level = sustain
+            }
+            for channel in 0..<Int(buffer.format.channelCount) {
+                buffer.floatChannelData![channel][i] *= level
             }
+        }
+    }
+
+    // Scale the side signal of a stereo buffer: 0 sums to mono, 1 leaves it unchanged
+    private func applyStereoWidth(_ buffer: AVAudioPCMBuffer, width: Float) {
+        guard width < 1, buffer.format.channelCount == 2 else { return }
+
+        let left = buffer.floatChannelData![0]
+        let right = buffer.floatChannelData![1]
+        for i in 0..<Int(buffer.frameLength) {
+            let mid = (left[i] + right[i]) / 2
+            let side = (left[i] - right[i]) / 2 * width
+            left[i] = mid + side
+            right[i] = mid - side
+        }
+    }
 
-            // Copy the region from source buffer to segment buffer
-            let channelCount = Int(sourceBuffer.format.channelCount)
-            for channel in 0..<channelCount {
-                let sourcePtr = sourceBuffer.floatChannelData![channel]
-                let destPtr = segmentBuffer.floatChannelData![channel]
-                memcpy(
-                    destPtr, sourcePtr.advanced(by: start), frameCount * MemoryLayout<Float>.stride)
+    // Reverse the order of the frames in a buffer, in place
+    private func reverseFrames(_ buffer: AVAudioPCMBuffer) {
+        let frameCount = Int(buffer.frameLength)
+        for channel in 0..<Int(buffer.format.channelCount) {
+            let samples = buffer.floatChannelData![channel]
+            var i = 0
+            var j = frameCount - 1
+            while i < j {
+                samples.swapAt(i, j)
+                i += 1
+                j -= 1
             }
-            segmentBuffer.frameLength = AVAudioFrameCount(frameCount)
+        }
+    }
+
+    // Play regions of several players back to back, scheduled on the output
+    // timeline so each link starts exactly where the previous one ends.
+    // crossfadeMs[i] overlaps link i with link i+1 using equal-power fades.
+    // Pitched links are shifted up front so the timeline stays sample-accurate.
+    func playChain(
+        playerIDs: [Int32], startFrames: [Int32], endFrames: [Int32], crossfadeMs: [Int32],
+        cents: [Float]
+    ) throws {
+        guard let renderTime = engine.outputNode.lastRenderTime, renderTime.isSampleTimeValid
+        else {
+            throw NSError(
+                domain: "AudioEngineManager", code: -4,
+                userInfo: [NSLocalizedDescriptionKey: "Audio engine is not rendering"])
+        }
+
+        let outputRate = engine.outputNode.outputFormat(forBus: 0).sampleRate
+        // Start slightly in the future so every link is scheduled before the first one plays
+        var startSample = renderTime.sampleTime + AVAudioFramePosition(outputRate * 0.01)
 
-            playerNode.stop()
-            playerNode.scheduleBuffer(segmentBuffer, at: nil) {
-                // Call completion callback when playback finishes
-                if let callback = gCompletionCallback {
-                    callback(playerID)
+        var segments: [AVAudioPCMBuffer] = []
+        for index in 0..<playerIDs.count {
+            var segment = try regionBuffer(
+                playerIDs[index], startFrame: startFrames[index], endFrame: endFrames[index])
+            if let envelope = playerEnvelopes[playerIDs[index]] {
+                applyEnvelope(segment, envelope)
+            }
+            if cents[index] != 0 {
+                segment = try pitchShiftBuffer(segment, cents: cents[index])
+            }
+            segments.append(segment)
+        }
+
+        // Overlap of the previous link with this one, which this link fades in over
+        var previousOverlap = 0
+        for index in 0..<playerIDs.count {
+            let playerID = playerIDs[index]
+            let segment = segments[index]
+
+            // Fade in from the previous link's crossfade, fade out into the next one
+            if previousOverlap > 0 {
+                applyFade(segment, frames: previousOverlap, fadeIn: true)
+            }
+            var overlap = 0
+            if index < playerIDs.count - 1 {
+                overlap = min(
+                    crossfadeFrames(crossfadeMs[index], outputRate), Int(segment.frameLength),
+                    Int(segments[index + 1].frameLength))
+                applyFade(segment, frames: overlap, fadeIn: false)
+            }
+
+            guard let acquired = try? acquireVoice(playerID) else { continue }
+            let (voice, generation) = acquired
+            voice.node.scheduleBuffer(
+                segment, at: nil,
+                completionHandler: finished(voice, playerID: playerID, generation: generation))
+            voice.node.play(at: AVAudioTime(sampleTime: startSample, atRate: outputRate))
+
+            startSample += AVAudioFramePosition(Int(segment.frameLength) - overlap)
+            previousOverlap = overlap
+        }
+    }
+
+    private func crossfadeFrames(_ milliseconds: Int32, _ sampleRate: Double) -> Int {
+        let clamped = max(0, min(milliseconds, maxCrossfadeMs))
+        return Int(Double(clamped) / 1000.0 * sampleRate)
+    }
+
+    // Apply an equal-power fade to the head (fadeIn) or tail of a buffer
+    private func applyFade(_ buffer: AVAudioPCMBuffer, frames: Int, fadeIn: Bool) {
+        let length = Int(buffer.frameLength)
+        let fadeFrames = min(frames, length)
+        guard fadeFrames > 0 else { return }
+
+        for channel in 0..<Int(buffer.format.channelCount) {
+            let data = buffer.floatChannelData![channel]
+            for i in 0..<fadeFrames {
+                let position = Float(i) / Float(fadeFrames)
+                if fadeIn {
+                    data[i] *= sin(position * Float.pi / 2)
+                } else {
+                    data[length - fadeFrames + i] *= cos(position * Float.pi / 2)
                 }
             }
-            playerNode.play()
         }
     }
 }
@@ -261,12 +1102,42 @@ class SystemAudioRecorder: NSObject, SCStreamDelegate, SCStreamOutput {
     private var stream: SCStream?
     private var assetWriter: AVAssetWriter?
     private var assetWriterInput: AVAssetWriterInput?
-    private let outputURL: URL
+    private let captureQueue = DispatchQueue(label: "audio.capture.queue")
+    // Both nil while armed, until record(to:) names the file
+    private var outputURL: URL?
+    // Capture always goes to WAV; other containers are encoded when recording stops
+    private var captureURL: URL?
     private var isRecording = false
-
-    init(outputPath: String) {
-        self.outputURL = URL(fileURLWithPath: outputPath)
+    // While armed, the last preRollSeconds of audio, written ahead of the
+    // recording once it starts
+    private let preRollSeconds: Double
+    private var preRoll: [CMSampleBuffer] = []
+
+    // A nil outputPath arms the recorder: it captures into the pre-roll
+    // until record(to:) is called
+    init(outputPath: String?, preRollSeconds: Double = 0) {
+        self.preRollSeconds = preRollSeconds
         super.init()
+        if let outputPath = outputPath {
+            setOutput(outputPath)
+        }
+    }
+
+    private func setOutput(_ outputPath: String) {
+        let url = URL(fileURLWithPath: outputPath)
+        outputURL = url
+        if url.pathExtension.lowercased() == "wav" {
+            captureURL = url
+        } else {
+            captureURL = url.deletingPathExtension().appendingPathExtension("capture.wav")
+        }
+    }
+
+    // Start writing an armed recorder's capture, pre-roll first, to outputPath
+    func record(to outputPath: String) {
+        captureQueue.sync {
+            setOutput(outputPath)
+        }
     }
 
     func startRecording() async throws {
@@ -313,8 +1184,7 @@ class SystemAudioRecorder: NSObject, SCStreamDelegate, SCStreamOutput {
         }
 
         // Add audio output
-        try stream.addStreamOutput(
-            self, type: .audio, sampleHandlerQueue: DispatchQueue(label: "audio.capture.queue"))
+        try stream.addStreamOutput(self, type: .audio, sampleHandlerQueue: captureQueue)
 
         // Start capture
         try await stream.startCapture()
@@ -339,13 +1209,23 @@ class SystemAudioRecorder: NSObject, SCStreamDelegate, SCStreamOutput {
         assetWriter = nil
         assetWriterInput = nil
 
+        // Encode the captured WAV into the requested container
+        if let captureURL = captureURL, let outputURL = outputURL, captureURL != outputURL {
+            do {
+                try convertAudioFile(from: captureURL, to: outputURL)
+                try FileManager.default.removeItem(at: captureURL)
+            } catch {
+                print("Error encoding recording: \(error)")
+            }
+        }
+
         isRecording = false
     }
 
-    // Calculate decibel level from audio buffer
-    private func calculateDecibels(from sampleBuffer: CMSampleBuffer) -> Float {
+    // Calculate left and right decibel levels from audio buffer
+    private func calculateDecibels(from sampleBuffer: CMSampleBuffer) -> (Float, Float) {
         guard let blockBuffer = CMSampleBufferGetDataBuffer(sampleBuffer) else {
-            return -160.0
+            return (-160.0, -160.0)
         }
 
         var length: Int = 0
@@ -357,27 +1237,38 @@ class SystemAudioRecorder: NSObject, SCStreamDelegate, SCStreamOutput {
                 totalLengthOut: &length, dataPointerOut: &dataPointer) == noErr,
             let data = dataPointer
         else {
-            return -160.0
+            return (-160.0, -160.0)
         }
 
         // Calculate samples (Float format from ScreenCaptureKit)
         let bytesPerSample = MemoryLayout<Float>.size
         let totalSamples = length / bytesPerSample
 
-        // Process as Float samples (interleaved)
         let floatData = data.withMemoryRebound(to: Float.self, capacity: totalSamples) { $0 }
 
-        // Calculate RMS across all channels
-        var sum: Float = 0.0
-        for i in 0..<totalSamples {
-            let sample = floatData[i]
-            sum += sample * sample
+        // ScreenCaptureKit usually delivers one channel after the other
+        // rather than interleaved; the format says which
+        var channels = 1
+        var interleaved = true
+        if let format = CMSampleBufferGetFormatDescription(sampleBuffer),
+            let description = CMAudioFormatDescriptionGetStreamBasicDescription(format)?.pointee
+        {
+            channels = max(1, Int(description.mChannelsPerFrame))
+            interleaved = description.mFormatFlags & kAudioFormatFlagIsNonInterleaved == 0
         }
+        let frames = max(1, totalSamples / channels)
 
-        let rms = sqrt(sum / Float(totalSamples))
-        let db = 20.0 * log10(max(rms, 0.00001))
+        // Sum squares per side
+        var sumSquares: [Float] = [0, 0]
+        var counts = [0, 0]
+        for i in 0..<totalSamples {
+            let channel = interleaved ? i % channels : i / frames
+            let side = channel % 2
+            sumSquares[side] += floatData[i] * floatData[i]
+            counts[side] += 1
+        }
 
-        return db
+        return stereoDecibels(sumSquares: sumSquares, counts: counts)
     }
 
     // SCStreamOutput protocol method
@@ -390,10 +1281,23 @@ class SystemAudioRecorder: NSObject, SCStreamDelegate, SCStreamOutput {
         let numSamples = CMSampleBufferGetNumSamples(sampleBuffer)
         guard numSamples > 0 else { return }
 
-        // Calculate and send decibel level
+        // Calculate and send decibel levels
         if let decibelCallback = gDecibelCallback {
-            let db = calculateDecibels(from: sampleBuffer)
-            decibelCallback(db)
+            let (left, right) = calculateDecibels(from: sampleBuffer)
+            decibelCallback(left, right)
+        }
+
+        guard let captureURL = captureURL else {
+            // Armed: keep just the last preRollSeconds
+            preRoll.append(sampleBuffer)
+            var duration = preRoll.reduce(0.0) { $0 + CMSampleBufferGetDuration($1).seconds }
+            while let first = preRoll.first,
+                duration - CMSampleBufferGetDuration(first).seconds >= preRollSeconds
+            {
+                duration -= CMSampleBufferGetDuration(first).seconds
+                preRoll.removeFirst()
+            }
+            return
         }
 
         // Initialize asset writer on first buffer
@@ -406,12 +1310,12 @@ class SystemAudioRecorder: NSObject, SCStreamDelegate, SCStreamOutput {
                 }
 
                 // Remove existing file if it exists
-                if FileManager.default.fileExists(atPath: outputURL.path) {
-                    try? FileManager.default.removeItem(at: outputURL)
+                if FileManager.default.fileExists(atPath: captureURL.path) {
+                    try? FileManager.default.removeItem(at: captureURL)
                 }
 
                 // Create asset writer
-                assetWriter = try AVAssetWriter(outputURL: outputURL, fileType: .wav)
+                assetWriter = try AVAssetWriter(outputURL: captureURL, fileType: .wav)
 
                 // Get audio format details
                 guard
@@ -454,8 +1358,14 @@ class SystemAudioRecorder: NSObject, SCStreamDelegate, SCStreamOutput {
                     return
                 }
 
+                // The pre-roll, if any, starts the file
                 writer.startSession(
-                    atSourceTime: CMSampleBufferGetPresentationTimeStamp(sampleBuffer))
+                    atSourceTime: CMSampleBufferGetPresentationTimeStamp(
+                        preRoll.first ?? sampleBuffer))
+                for buffer in preRoll where writerInput.isReadyForMoreMediaData {
+                    writerInput.append(buffer)
+                }
+                preRoll = []
             } catch {
                 print("Error creating asset writer: \(error)")
                 return
@@ -472,9 +1382,546 @@ class SystemAudioRecorder: NSObject, SCStreamDelegate, SCStreamOutput {
         }
     }
 
-    // SCStreamDelegate method
-    func stream(_ stream: SCStream, didStopWithError error: Error) {
-        print("ERROR: Stream stopped with error: \(error.localizedDescription)")
+    // SCStreamDelegate method
+    func stream(_ stream: SCStream, didStopWithError error: Error) {
+        print("ERROR: Stream stopped with error: \(error.localizedDescription)")
+    }
+}
+
+// Input device recorder, tapping the input node of its own engine so it
+// doesn't disturb playback
+class InputRecorder {
+    private let engine = AVAudioEngine()
+    private var captureFile: AVAudioFile?
+    private let outputURL: URL
+    // Capture always goes to WAV; other containers are encoded when recording stops
+    private let captureURL: URL
+
+    init(outputPath: String) {
+        let url = URL(fileURLWithPath: outputPath)
+        self.outputURL = url
+        if url.pathExtension.lowercased() == "wav" {
+            self.captureURL = url
+        } else {
+            self.captureURL = url.deletingPathExtension().appendingPathExtension("capture.wav")
+        }
+    }
+
+    func startRecording(deviceName: String) throws {
+        let inputNode = engine.inputNode
+        if !deviceName.isEmpty {
+            guard let deviceID = AudioEngineManager.findDeviceByName(deviceName) else {
+                throw NSError(
+                    domain: "InputRecorder", code: -1,
+                    userInfo: [NSLocalizedDescriptionKey: "No input device named '\(deviceName)'"])
+            }
+            var deviceIDCopy = deviceID
+            let status = AudioUnitSetProperty(
+                inputNode.audioUnit!,
+                kAudioOutputUnitProperty_CurrentDevice,
+                kAudioUnitScope_Global,
+                0,
+                &deviceIDCopy,
+                UInt32(MemoryLayout<AudioDeviceID>.size)
+            )
+            guard status == noErr else {
+                throw NSError(
+                    domain: "InputRecorder", code: Int(status),
+                    userInfo: [NSLocalizedDescriptionKey: "Failed to select input device"])
+            }
+        }
+
+        let format = inputNode.outputFormat(forBus: 0)
+        guard format.channelCount > 0 else {
+            throw NSError(
+                domain: "InputRecorder", code: -2,
+                userInfo: [NSLocalizedDescriptionKey: "Input device has no channels"])
+        }
+
+        let settings: [String: Any] = [
+            AVFormatIDKey: Int(kAudioFormatLinearPCM),
+            AVSampleRateKey: format.sampleRate,
+            AVNumberOfChannelsKey: Int(format.channelCount),
+            AVLinearPCMBitDepthKey: 16,
+            AVLinearPCMIsFloatKey: false,
+            AVLinearPCMIsBigEndianKey: false,
+        ]
+        let file = try AVAudioFile(forWriting: captureURL, settings: settings)
+        captureFile = file
+
+        inputNode.installTap(onBus: 0, bufferSize: 4096, format: format) { buffer, _ in
+            if let decibelCallback = gDecibelCallback {
+                let (left, right) = InputRecorder.decibels(of: buffer)
+                decibelCallback(left, right)
+            }
+            do {
+                try file.write(from: buffer)
+            } catch {
+                print("Error writing recording: \(error)")
+            }
+        }
+
+        engine.prepare()
+        try engine.start()
+    }
+
+    func stopRecording() {
+        engine.inputNode.removeTap(onBus: 0)
+        engine.stop()
+        // Closing the file flushes it
+        captureFile = nil
+
+        if captureURL != outputURL {
+            do {
+                try convertAudioFile(from: captureURL, to: outputURL)
+                try FileManager.default.removeItem(at: captureURL)
+            } catch {
+                print("Error encoding recording: \(error)")
+            }
+        }
+    }
+
+    // Left and right RMS levels of a buffer in dB
+    private static func decibels(of buffer: AVAudioPCMBuffer) -> (Float, Float) {
+        guard let data = buffer.floatChannelData, buffer.frameLength > 0 else {
+            return (-160.0, -160.0)
+        }
+        var sumSquares: [Float] = [0, 0]
+        var counts = [0, 0]
+        let channels = Int(buffer.format.channelCount)
+        let frames = Int(buffer.frameLength)
+        for channel in 0..<channels {
+            for i in 0..<frames {
+                sumSquares[channel % 2] += data[channel][i] * data[channel][i]
+            }
+            counts[channel % 2] += frames
+        }
+        return stereoDecibels(sumSquares: sumSquares, counts: counts)
+    }
+}
+
+// Left and right RMS levels in dB from the sums of squares of each side.
+// Odd channels count as left and even ones as right, as in the
+// multichannel mixdown; mono input shows the same level on both.
+func stereoDecibels(sumSquares: [Float], counts: [Int]) -> (Float, Float) {
+    func decibels(_ side: Int) -> Float {
+        guard counts[side] > 0 else { return -160.0 }
+        let rms = sqrt(sumSquares[side] / Float(counts[side]))
+        return rms > 0 ? 20 * log10(rms) : -160.0
+    }
+    let left = decibels(0)
+    return (left, counts[1] > 0 ? decibels(1) : left)
+}
+
+// Float format for a channel count, with a discrete layout beyond stereo
+// since AVAudioFormat has no standard one there
+func standardFormat(sampleRate: Double, channels: AVAudioChannelCount) -> AVAudioFormat? {
+    guard channels > 2 else {
+        return AVAudioFormat(standardFormatWithSampleRate: sampleRate, channels: channels)
+    }
+    guard
+        let layout = AVAudioChannelLayout(
+            layoutTag: kAudioChannelLayoutTag_DiscreteInOrder | UInt32(channels))
+    else {
+        return nil
+    }
+    return AVAudioFormat(standardFormatWithSampleRate: sampleRate, channelLayout: layout)
+}
+
+// Settings with a discrete channel layout added for more than two channels,
+// which AVAudioFile won't write without one
+func multichannelSettings(_ settings: [String: Any], channels: AVAudioChannelCount)
+    -> [String: Any]
+{
+    guard channels > 2, settings[AVChannelLayoutKey] == nil,
+        let layout = AVAudioChannelLayout(
+            layoutTag: kAudioChannelLayoutTag_DiscreteInOrder | UInt32(channels))
+    else {
+        return settings
+    }
+    var settings = settings
+    settings[AVChannelLayoutKey] = Data(
+        bytes: layout.layout, count: MemoryLayout<AudioChannelLayout>.size)
+    return settings
+}
+
+// Output settings for the container implied by the file extension
+func containerSettings(
+    for url: URL, sampleRate: Double, channels: AVAudioChannelCount, bitDepth: Int = 16
+)
+    -> [String: Any]
+{
+    let settings: [String: Any]
+    switch url.pathExtension.lowercased() {
+    case "flac":
+        settings = [
+            AVFormatIDKey: Int(kAudioFormatFLAC),
+            AVSampleRateKey: sampleRate,
+            AVNumberOfChannelsKey: Int(channels),
+            AVEncoderBitDepthHintKey: bitDepth,
+        ]
+    case "aif", "aiff":
+        settings = [
+            AVFormatIDKey: Int(kAudioFormatLinearPCM),
+            AVSampleRateKey: sampleRate,
+            AVNumberOfChannelsKey: Int(channels),
+            AVLinearPCMBitDepthKey: bitDepth,
+            AVLinearPCMIsFloatKey: false,
+            AVLinearPCMIsBigEndianKey: true,
+            AVLinearPCMIsNonInterleaved: false,
+        ]
+    default:
+        settings = [
+            AVFormatIDKey: Int(kAudioFormatLinearPCM),
+            AVSampleRateKey: sampleRate,
+            AVNumberOfChannelsKey: Int(channels),
+            AVLinearPCMBitDepthKey: bitDepth,
+            AVLinearPCMIsFloatKey: false,
+            AVLinearPCMIsBigEndianKey: false,
+            AVLinearPCMIsNonInterleaved: false,
+        ]
+    }
+    return multichannelSettings(settings, channels: channels)
+}
+
+// Resample a buffer to the target rate with libsamplerate
+func resampleBuffer(_ source: AVAudioPCMBuffer, to targetRate: Double) throws -> AVAudioPCMBuffer {
+    let ratio = targetRate / source.format.sampleRate
+    let channels = Int(source.format.channelCount)
+    let inputFrames = Int(source.frameLength)
+    let outputCapacity = Int(Double(inputFrames) * ratio) + 1
+
+    guard
+        let format = standardFormat(sampleRate: targetRate, channels: source.format.channelCount),
+        let output = AVAudioPCMBuffer(
+            pcmFormat: format, frameCapacity: AVAudioFrameCount(outputCapacity))
+    else {
+        throw NSError(
+            domain: "Resampler", code: -1,
+            userInfo: [NSLocalizedDescriptionKey: "Failed to create resample buffer"])
+    }
+
+    // libsamplerate works on interleaved samples
+    var input = [Float](repeating: 0, count: inputFrames * channels)
+    for channel in 0..<channels {
+        let channelData = source.floatChannelData![channel]
+        for frame in 0..<inputFrames {
+            input[frame * channels + channel] = channelData[frame]
+        }
+    }
+    var interleaved = [Float](repeating: 0, count: outputCapacity * channels)
+
+    var generated = 0
+    let status = input.withUnsafeBufferPointer { inputPtr in
+        interleaved.withUnsafeMutableBufferPointer { outputPtr -> Int32 in
+            var data = SRC_DATA()
+            data.data_in = inputPtr.baseAddress
+            data.data_out = outputPtr.baseAddress
+            data.input_frames = inputFrames
+            data.output_frames = outputCapacity
+            data.src_ratio = ratio
+            let result = src_simple(&data, Int32(SRC_SINC_BEST_QUALITY), Int32(channels))
+            generated = Int(data.output_frames_gen)
+            return result
+        }
+    }
+
+    guard status == 0 else {
+        throw NSError(
+            domain: "Resampler", code: Int(status),
+            userInfo: [
+                NSLocalizedDescriptionKey: String(cString: src_strerror(status))
+            ])
+    }
+
+    for channel in 0..<channels {
+        let channelData = output.floatChannelData![channel]
+        for frame in 0..<generated {
+            channelData[frame] = interleaved[frame * channels + channel]
+        }
+    }
+    output.frameLength = AVAudioFrameCount(generated)
+
+    return output
+}
+
+// Write the source file at a new sample rate and bit depth, resampling with
+// libsamplerate; a sampleRate of 0 keeps the source rate
+func resampleAudioFile(from sourceURL: URL, to targetURL: URL, sampleRate: Double, bitDepth: Int)
+    throws
+{
+    let sourceFile = try AVAudioFile(forReading: sourceURL)
+    guard
+        let sourceBuffer = AVAudioPCMBuffer(
+            pcmFormat: sourceFile.processingFormat,
+            frameCapacity: AVAudioFrameCount(sourceFile.length)
+        )
+    else {
+        throw NSError(
+            domain: "AudioConverter", code: -1,
+            userInfo: [NSLocalizedDescriptionKey: "Failed to create conversion buffer"])
+    }
+    try sourceFile.read(into: sourceBuffer)
+
+    var buffer = sourceBuffer
+    if sampleRate > 0 && sampleRate != sourceBuffer.format.sampleRate {
+        buffer = try resampleBuffer(sourceBuffer, to: sampleRate)
+    }
+
+    // Write to a temporary file so the source can be converted in place;
+    // the scope closes the output file before it is moved
+    let tempURL = targetURL.deletingPathExtension().appendingPathExtension(
+        "tmp.\(targetURL.pathExtension)")
+    if FileManager.default.fileExists(atPath: tempURL.path) {
+        try FileManager.default.removeItem(at: tempURL)
+    }
+    do {
+        let outputFile = try AVAudioFile(
+            forWriting: tempURL,
+            settings: containerSettings(
+                for: targetURL, sampleRate: buffer.format.sampleRate,
+                channels: buffer.format.channelCount, bitDepth: bitDepth),
+            commonFormat: .pcmFormatFloat32,
+            interleaved: false
+        )
+        try outputFile.write(from: buffer)
+    }
+
+    if FileManager.default.fileExists(atPath: targetURL.path) {
+        _ = try FileManager.default.replaceItemAt(targetURL, withItemAt: tempURL)
+    } else {
+        try FileManager.default.moveItem(at: tempURL, to: targetURL)
+    }
+}
+
+// Pitch-shift a whole buffer offline with Rubberband, keeping its length
+func pitchShiftBuffer(_ sourceBuffer: AVAudioPCMBuffer, cents: Float) throws -> AVAudioPCMBuffer {
+    let channels = sourceBuffer.format.channelCount
+    let sourceLength = sourceBuffer.frameLength
+
+    // Calculate pitch ratio from cents
+    let pitchRatio = pow(2.0, Double(cents) / 1200.0)
+
+    // Create Rubberband stretcher using C API
+    let options: RubberBandOptions = Int32(
+        RubberBandOptionProcessOffline.rawValue
+            | RubberBandOptionPitchHighQuality.rawValue
+            | RubberBandOptionChannelsApart.rawValue
+            | RubberBandOptionEngineFiner.rawValue
+    )
+
+    guard
+        let rb = rubberband_new(
+            UInt32(sourceBuffer.format.sampleRate),
+            UInt32(channels),
+            options,
+            1.0,  // Time ratio (no time stretching)
+            pitchRatio
+        )
+    else {
+        throw NSError(
+            domain: "AudioEngineManager", code: -5,
+            userInfo: [NSLocalizedDescriptionKey: "Failed to create Rubberband stretcher"])
+    }
+    defer { rubberband_delete(rb) }
+
+    // Set expected input duration and max process size
+    rubberband_set_expected_input_duration(rb, UInt32(sourceLength))
+    rubberband_set_max_process_size(rb, UInt32(sourceLength))
+
+    // Prepare input pointers
+    var inputPtrs = [UnsafePointer<Float>?](repeating: nil, count: Int(channels))
+    for i in 0..<Int(channels) {
+        inputPtrs[i] = UnsafePointer(sourceBuffer.floatChannelData![i])
+    }
+
+    // Study and process (offline mode)
+    inputPtrs.withUnsafeBufferPointer { ptrsBuffer in
+        rubberband_study(rb, ptrsBuffer.baseAddress, UInt32(sourceLength), 1)
+        rubberband_process(rb, ptrsBuffer.baseAddress, UInt32(sourceLength), 1)
+    }
+
+    // Get output frame count
+    let outputFrames = Int(rubberband_available(rb))
+
+    guard
+        let outputBuffer = AVAudioPCMBuffer(
+            pcmFormat: sourceBuffer.format,
+            frameCapacity: AVAudioFrameCount(outputFrames)
+        )
+    else {
+        throw NSError(
+            domain: "AudioEngineManager", code: -2,
+            userInfo: [NSLocalizedDescriptionKey: "Failed to create output buffer"])
+    }
+
+    // Retrieve processed audio
+    var outputPtrs = [UnsafeMutablePointer<Float>?](repeating: nil, count: Int(channels))
+    for i in 0..<Int(channels) {
+        outputPtrs[i] = outputBuffer.floatChannelData![i]
+    }
+
+    let retrieved = outputPtrs.withUnsafeMutableBufferPointer { ptrsBuffer in
+        rubberband_retrieve(rb, ptrsBuffer.baseAddress, UInt32(outputFrames))
+    }
+
+    outputBuffer.frameLength = AVAudioFrameCount(retrieved)
+    return outputBuffer
+}
+
+// Time-stretch a whole buffer offline with Rubberband, keeping its pitch
+func timeStretchBuffer(_ sourceBuffer: AVAudioPCMBuffer, timeRatio: Double) throws
+    -> AVAudioPCMBuffer
+{
+    let channels = Int(sourceBuffer.format.channelCount)
+    let sourceLength = sourceBuffer.frameLength
+
+    let options: RubberBandOptions = Int32(
+        RubberBandOptionProcessOffline.rawValue
+            | RubberBandOptionChannelsApart.rawValue
+            | RubberBandOptionEngineFiner.rawValue
+    )
+
+    guard
+        let rb = rubberband_new(
+            UInt32(sourceBuffer.format.sampleRate),
+            UInt32(channels),
+            options,
+            timeRatio,
+            1.0  // Pitch ratio (no pitch shifting)
+        )
+    else {
+        throw NSError(
+            domain: "AudioEngineManager", code: -5,
+            userInfo: [NSLocalizedDescriptionKey: "Failed to create Rubberband stretcher"])
+    }
+    defer { rubberband_delete(rb) }
+
+    rubberband_set_expected_input_duration(rb, UInt32(sourceLength))
+    rubberband_set_max_process_size(rb, UInt32(sourceLength))
+
+    var inputPtrs = [UnsafePointer<Float>?](repeating: nil, count: channels)
+    for i in 0..<channels {
+        inputPtrs[i] = UnsafePointer(sourceBuffer.floatChannelData![i])
+    }
+
+    inputPtrs.withUnsafeBufferPointer { ptrsBuffer in
+        rubberband_study(rb, ptrsBuffer.baseAddress, UInt32(sourceLength), 1)
+        rubberband_process(rb, ptrsBuffer.baseAddress, UInt32(sourceLength), 1)
+    }
+
+    // Leave room for the stretcher rounding up
+    let capacity = Int((Double(sourceLength) * timeRatio).rounded(.up)) + 4096
+    guard
+        let outputBuffer = AVAudioPCMBuffer(
+            pcmFormat: sourceBuffer.format,
+            frameCapacity: AVAudioFrameCount(capacity)
+        )
+    else {
+        throw NSError(
+            domain: "AudioEngineManager", code: -2,
+            userInfo: [NSLocalizedDescriptionKey: "Failed to create output buffer"])
+    }
+
+    // A stretched render can come out in several blocks
+    var written = 0
+    var available = Int(rubberband_available(rb))
+    while available > 0 && written < capacity {
+        var outputPtrs = [UnsafeMutablePointer<Float>?](repeating: nil, count: channels)
+        for i in 0..<channels {
+            outputPtrs[i] = outputBuffer.floatChannelData![i].advanced(by: written)
+        }
+        let count = min(available, capacity - written)
+        let retrieved = outputPtrs.withUnsafeMutableBufferPointer { ptrsBuffer in
+            rubberband_retrieve(rb, ptrsBuffer.baseAddress, UInt32(count))
+        }
+        written += Int(retrieved)
+        available = Int(rubberband_available(rb))
+    }
+
+    outputBuffer.frameLength = AVAudioFrameCount(written)
+    return outputBuffer
+}
+
+// Render the frames from startFrame to endFrame of a file, time-stretched
+// by timeRatio, to the target file
+func stretchAudioFile(
+    from sourceURL: URL, to targetURL: URL, startFrame: Int, endFrame: Int, timeRatio: Double
+) throws {
+    let sourceFile = try AVAudioFile(forReading: sourceURL)
+    let end = min(endFrame, Int(sourceFile.length))
+    guard startFrame >= 0 && end > startFrame && timeRatio > 0 else {
+        throw NSError(
+            domain: "AudioEngineManager", code: -3,
+            userInfo: [NSLocalizedDescriptionKey: "Invalid frame range"])
+    }
+
+    let frameCount = AVAudioFrameCount(end - startFrame)
+    guard
+        let buffer = AVAudioPCMBuffer(
+            pcmFormat: sourceFile.processingFormat, frameCapacity: frameCount)
+    else {
+        throw NSError(
+            domain: "AudioEngineManager", code: -2,
+            userInfo: [NSLocalizedDescriptionKey: "Failed to create audio buffer"])
+    }
+    sourceFile.framePosition = AVAudioFramePosition(startFrame)
+    try sourceFile.read(into: buffer, frameCount: frameCount)
+
+    let stretched = try timeStretchBuffer(buffer, timeRatio: timeRatio)
+
+    // Never overwrite another sample, least of all the source
+    if FileManager.default.fileExists(atPath: targetURL.path) {
+        throw NSError(
+            domain: "AudioEngineManager", code: -4,
+            userInfo: [NSLocalizedDescriptionKey: "\(targetURL.lastPathComponent) already exists"])
+    }
+    let outputFile = try AVAudioFile(
+        forWriting: targetURL,
+        settings: containerSettings(
+            for: targetURL, sampleRate: stretched.format.sampleRate,
+            channels: stretched.format.channelCount),
+        commonFormat: .pcmFormatFloat32,
+        interleaved: false
+    )
+    try outputFile.write(from: stretched)
+}
+
+// Decode any AVFoundation-readable file and write it in the container of the target extension
+// Compressed sources (MP3, AAC) only estimate their length, so read in chunks until EOF
+func convertAudioFile(from sourceURL: URL, to targetURL: URL) throws {
+    let sourceFile = try AVAudioFile(forReading: sourceURL)
+    let format = sourceFile.processingFormat
+
+    guard
+        let buffer = AVAudioPCMBuffer(
+            pcmFormat: format,
+            frameCapacity: 65536
+        )
+    else {
+        throw NSError(
+            domain: "AudioConverter", code: -1,
+            userInfo: [NSLocalizedDescriptionKey: "Failed to create conversion buffer"])
+    }
+
+    if FileManager.default.fileExists(atPath: targetURL.path) {
+        try FileManager.default.removeItem(at: targetURL)
+    }
+
+    let outputFile = try AVAudioFile(
+        forWriting: targetURL,
+        settings: containerSettings(
+            for: targetURL, sampleRate: format.sampleRate, channels: format.channelCount),
+        commonFormat: format.commonFormat,
+        interleaved: format.isInterleaved
+    )
+
+    while sourceFile.framePosition < sourceFile.length {
+        try sourceFile.read(into: buffer)
+        if buffer.frameLength == 0 {
+            break
+        }
+        try outputFile.write(from: buffer)
     }
 }
 
@@ -516,7 +1963,9 @@ public func SwiftAudio_setCompletionCallback(_ callback: @escaping @convention(c
 }
 
 @_cdecl("SwiftAudio_setDecibelCallback")
-public func SwiftAudio_setDecibelCallback(_ callback: @escaping @convention(c) (Float) -> Void) {
+public func SwiftAudio_setDecibelCallback(
+    _ callback: @escaping @convention(c) (Float, Float) -> Void
+) {
     gDecibelCallback = callback
 }
 
@@ -566,10 +2015,27 @@ public func SwiftAudio_stopPlayer(_ playerID: Int32) -> Int32 {
     return 0
 }
 
+@_cdecl("SwiftAudio_stopAll")
+public func SwiftAudio_stopAll() -> Int32 {
+    guard let manager = gAudioEngineManager else {
+        print("Error: Audio engine not initialized.")
+        return 1
+    }
+
+    manager.stopAll()
+    return 0
+}
+
 @_cdecl("SwiftAudio_record")
 public func SwiftAudio_record(_ filename: UnsafePointer<CChar>) -> Int32 {
     let filenameStr = String(cString: filename)
 
+    // An armed recorder is already capturing
+    if let recorder = gSystemAudioRecorder {
+        recorder.record(to: filenameStr)
+        return 0
+    }
+
     let recorder = SystemAudioRecorder(outputPath: filenameStr)
     gSystemAudioRecorder = recorder
 
@@ -584,8 +2050,55 @@ public func SwiftAudio_record(_ filename: UnsafePointer<CChar>) -> Int32 {
     return 0
 }
 
+@_cdecl("SwiftAudio_arm")
+public func SwiftAudio_arm(_ preRollSeconds: Double) -> Int32 {
+    guard gSystemAudioRecorder == nil else {
+        return 1
+    }
+
+    let recorder = SystemAudioRecorder(outputPath: nil, preRollSeconds: preRollSeconds)
+    gSystemAudioRecorder = recorder
+
+    Task {
+        do {
+            try await recorder.startRecording()
+        } catch {
+            print("Error arming system audio recording: \(error)")
+        }
+    }
+
+    return 0
+}
+
+@_cdecl("SwiftAudio_disarm")
+public func SwiftAudio_disarm() -> Int32 {
+    // Stopping before anything was written discards the pre-roll
+    return SwiftAudio_stopRecording()
+}
+
+@_cdecl("SwiftAudio_recordInput")
+public func SwiftAudio_recordInput(
+    _ filename: UnsafePointer<CChar>, _ deviceName: UnsafePointer<CChar>
+) -> Int32 {
+    let recorder = InputRecorder(outputPath: String(cString: filename))
+    do {
+        try recorder.startRecording(deviceName: String(cString: deviceName))
+    } catch {
+        print("Error starting input recording: \(error)")
+        return 1
+    }
+    gInputRecorder = recorder
+    return 0
+}
+
 @_cdecl("SwiftAudio_stopRecording")
 public func SwiftAudio_stopRecording() -> Int32 {
+    if let inputRecorder = gInputRecorder {
+        inputRecorder.stopRecording()
+        gInputRecorder = nil
+        return 0
+    }
+
     guard let recorder = gSystemAudioRecorder else {
         return 1
     }
@@ -652,6 +2165,216 @@ public func SwiftAudio_playRegion(
     }
 }
 
+@_cdecl("SwiftAudio_playChain")
+public func SwiftAudio_playChain(
+    _ playerIDs: UnsafePointer<Int32>, _ startFrames: UnsafePointer<Int32>,
+    _ endFrames: UnsafePointer<Int32>, _ crossfadeMs: UnsafePointer<Int32>,
+    _ cents: UnsafePointer<Float>, _ count: Int32
+) -> Int32 {
+    guard let manager = gAudioEngineManager else {
+        print("Error: Audio engine not initialized. Call Init() first.")
+        return 1
+    }
+
+    let n = Int(count)
+    do {
+        try manager.playChain(
+            playerIDs: Array(UnsafeBufferPointer(start: playerIDs, count: n)),
+            startFrames: Array(UnsafeBufferPointer(start: startFrames, count: n)),
+            endFrames: Array(UnsafeBufferPointer(start: endFrames, count: n)),
+            crossfadeMs: Array(UnsafeBufferPointer(start: crossfadeMs, count: n)),
+            cents: Array(UnsafeBufferPointer(start: cents, count: n)))
+        return 0
+    } catch {
+        print("Error playing chain: \(error)")
+        return 1
+    }
+}
+
+@_cdecl("SwiftAudio_setStereoWidth")
+public func SwiftAudio_setStereoWidth(_ playerID: Int32, _ width: Float) -> Int32 {
+    guard let manager = gAudioEngineManager else {
+        print("Error: Audio engine not initialized.")
+        return 1
+    }
+
+    do {
+        try manager.setStereoWidth(playerID, width: width)
+        return 0
+    } catch {
+        print("Error setting stereo width: \(error)")
+        return 1
+    }
+}
+
+@_cdecl("SwiftAudio_setGain")
+public func SwiftAudio_setGain(_ playerID: Int32, _ gain: Float) -> Int32 {
+    guard let manager = gAudioEngineManager else {
+        print("Error: Audio engine not initialized.")
+        return 1
+    }
+
+    do {
+        try manager.setGain(playerID, gain: gain)
+        return 0
+    } catch {
+        print("Error setting gain: \(error)")
+        return 1
+    }
+}
+
+@_cdecl("SwiftAudio_setPan")
+public func SwiftAudio_setPan(_ playerID: Int32, _ pan: Float) -> Int32 {
+    guard let manager = gAudioEngineManager else {
+        print("Error: Audio engine not initialized.")
+        return 1
+    }
+
+    do {
+        try manager.setPan(playerID, pan: pan)
+        return 0
+    } catch {
+        print("Error setting pan: \(error)")
+        return 1
+    }
+}
+
+@_cdecl("SwiftAudio_setEnvelope")
+public func SwiftAudio_setEnvelope(
+    _ playerID: Int32, _ attackMs: Float, _ decayMs: Float, _ sustain: Float, _ releaseMs: Float
+) -> Int32 {
+    guard let manager = gAudioEngineManager else {
+        print("Error: Audio engine not initialized.")
+        return 1
+    }
+
+    do {
+        try manager.setEnvelope(
+            playerID,
+            Envelope(attackMs: attackMs, decayMs: decayMs, sustain: sustain, releaseMs: releaseMs))
+        return 0
+    } catch {
+        print("Error setting envelope: \(error)")
+        return 1
+    }
+}
+
+@_cdecl("SwiftAudio_setLoop")
+public func SwiftAudio_setLoop(_ playerID: Int32, _ loop: Int32) -> Int32 {
+    guard let manager = gAudioEngineManager else {
+        print("Error: Audio engine not initialized.")
+        return 1
+    }
+
+    do {
+        try manager.setLoop(playerID, loop: loop != 0)
+        return 0
+    } catch {
+        print("Error setting loop: \(error)")
+        return 1
+    }
+}
+
+@_cdecl("SwiftAudio_setReverse")
+public func SwiftAudio_setReverse(_ playerID: Int32, _ reverse: Int32) -> Int32 {
+    guard let manager = gAudioEngineManager else {
+        print("Error: Audio engine not initialized.")
+        return 1
+    }
+
+    do {
+        try manager.setReverse(playerID, reverse: reverse != 0)
+        return 0
+    } catch {
+        print("Error setting reverse: \(error)")
+        return 1
+    }
+}
+
+@_cdecl("SwiftAudio_setChannelPair")
+public func SwiftAudio_setChannelPair(_ playerID: Int32, _ pair: Int32) -> Int32 {
+    guard let manager = gAudioEngineManager else {
+        print("Error: Audio engine not initialized.")
+        return 1
+    }
+
+    do {
+        try manager.setChannelPair(playerID, pair: Int(pair))
+        return 0
+    } catch {
+        print("Error setting channel pair: \(error)")
+        return 1
+    }
+}
+
+@_cdecl("SwiftAudio_setMaxVoices")
+public func SwiftAudio_setMaxVoices(_ count: Int32) -> Int32 {
+    guard let manager = gAudioEngineManager else {
+        print("Error: Audio engine not initialized.")
+        return 1
+    }
+
+    manager.setMaxVoices(Int(count))
+    return 0
+}
+
+@_cdecl("SwiftAudio_setMasterVolume")
+public func SwiftAudio_setMasterVolume(_ volume: Float) -> Int32 {
+    guard let manager = gAudioEngineManager else {
+        print("Error: Audio engine not initialized.")
+        return 1
+    }
+
+    manager.setMasterVolume(volume)
+    return 0
+}
+
+@_cdecl("SwiftAudio_startBounce")
+public func SwiftAudio_startBounce(_ filename: UnsafePointer<CChar>) -> Int32 {
+    guard let manager = gAudioEngineManager else {
+        print("Error: Audio engine not initialized.")
+        return 1
+    }
+
+    do {
+        try manager.startBounce(to: URL(fileURLWithPath: String(cString: filename)))
+        return 0
+    } catch {
+        print("Error starting bounce: \(error)")
+        return 1
+    }
+}
+
+@_cdecl("SwiftAudio_stopBounce")
+public func SwiftAudio_stopBounce() -> Int32 {
+    guard let manager = gAudioEngineManager else {
+        return 1
+    }
+
+    manager.stopBounce()
+    return 0
+}
+
+@_cdecl("SwiftAudio_setLimiter")
+public func SwiftAudio_setLimiter(_ enabled: Int32) -> Int32 {
+    guard let manager = gAudioEngineManager else {
+        print("Error: Audio engine not initialized.")
+        return 1
+    }
+
+    manager.setLimiter(enabled != 0)
+    return 0
+}
+
+@_cdecl("SwiftAudio_activeVoices")
+public func SwiftAudio_activeVoices() -> Int32 {
+    guard let manager = gAudioEngineManager else {
+        return 0
+    }
+
+    return Int32(manager.activeVoiceCount())
+}
+
 @_cdecl("SwiftAudio_trimFile")
 public func SwiftAudio_trimFile(
     _ filename: UnsafePointer<CChar>, _ startFrame: Int32, _ endFrame: Int32
@@ -681,7 +2404,9 @@ public func SwiftAudio_trimFile(
         let tempURL = fileURL.deletingLastPathComponent().appendingPathComponent(
             "temp_\(UUID().uuidString).wav")
         let outputFile = try AVAudioFile(
-            forWriting: tempURL, settings: audioFile.fileFormat.settings)
+            forWriting: tempURL,
+            settings: multichannelSettings(
+                audioFile.fileFormat.settings, channels: processingFormat.channelCount))
         try outputFile.write(from: buffer)
 
         // Replace original file
@@ -696,120 +2421,56 @@ public func SwiftAudio_trimFile(
     }
 }
 
-@_cdecl("SwiftAudio_renderPitchedFile")
-public func SwiftAudio_renderPitchedFile(
-    _ sourceFilename: UnsafePointer<CChar>, _ targetFilename: UnsafePointer<CChar>, _ cents: Float
+@_cdecl("SwiftAudio_convertFile")
+public func SwiftAudio_convertFile(
+    _ sourceFilename: UnsafePointer<CChar>, _ targetFilename: UnsafePointer<CChar>
 ) -> Int32 {
-    let sourceStr = String(cString: sourceFilename)
-    let targetStr = String(cString: targetFilename)
-    let sourceURL = URL(fileURLWithPath: sourceStr)
-    let targetURL = URL(fileURLWithPath: targetStr)
+    let sourceURL = URL(fileURLWithPath: String(cString: sourceFilename))
+    let targetURL = URL(fileURLWithPath: String(cString: targetFilename))
 
     do {
-        // Load source audio file
-        let sourceFile = try AVAudioFile(forReading: sourceURL)
-        let sampleRate = sourceFile.processingFormat.sampleRate
-        let channels = sourceFile.processingFormat.channelCount
-        let sourceLength = sourceFile.length
-
-        // Read source buffer
-        guard
-            let sourceBuffer = AVAudioPCMBuffer(
-                pcmFormat: sourceFile.processingFormat,
-                frameCapacity: AVAudioFrameCount(sourceLength)
-            )
-        else {
-            print("Error: Failed to create source buffer")
-            return 1
-        }
-        try sourceFile.read(into: sourceBuffer)
-        sourceBuffer.frameLength = AVAudioFrameCount(sourceLength)
-
-        // Calculate pitch ratio from semitones
-        let semitones = Double(cents) / 100.0
-        let pitchRatio = pow(2.0, semitones / 12.0)
-
-        // Create Rubberband stretcher using C API
-        let options: RubberBandOptions = Int32(
-            RubberBandOptionProcessOffline.rawValue
-                | RubberBandOptionPitchHighQuality.rawValue
-                | RubberBandOptionChannelsApart.rawValue
-                | RubberBandOptionEngineFiner.rawValue
-        )
-
-        let rb = rubberband_new(
-            UInt32(sampleRate),
-            UInt32(channels),
-            options,
-            1.0,  // Time ratio (no time stretching)
-            pitchRatio
-        )
-
-        guard rb != nil else {
-            print("Error: Failed to create Rubberband stretcher")
-            return 1
-        }
-        defer { rubberband_delete(rb) }
-
-        // Set expected input duration and max process size
-        rubberband_set_expected_input_duration(rb, UInt32(sourceLength))
-        rubberband_set_max_process_size(rb, UInt32(sourceLength))
-
-        // Prepare input pointers
-        var inputPtrs = [UnsafePointer<Float>?](repeating: nil, count: Int(channels))
-        for i in 0..<Int(channels) {
-            inputPtrs[i] = UnsafePointer(sourceBuffer.floatChannelData![i])
-        }
-
-        // Study and process (offline mode)
-        inputPtrs.withUnsafeBufferPointer { ptrsBuffer in
-            rubberband_study(rb, ptrsBuffer.baseAddress, UInt32(sourceLength), 1)
-            rubberband_process(rb, ptrsBuffer.baseAddress, UInt32(sourceLength), 1)
-        }
-
-        // Get output frame count
-        let outputFrames = Int(rubberband_available(rb))
-
-        guard
-            let outputBuffer = AVAudioPCMBuffer(
-                pcmFormat: sourceFile.processingFormat,
-                frameCapacity: AVAudioFrameCount(outputFrames)
-            )
-        else {
-            print("Error: Failed to create output buffer")
-            return 1
-        }
-
-        // Retrieve processed audio
-        var outputPtrs = [UnsafeMutablePointer<Float>?](repeating: nil, count: Int(channels))
-        for i in 0..<Int(channels) {
-            outputPtrs[i] = outputBuffer.floatChannelData![i]
-        }
-
-        let retrieved = outputPtrs.withUnsafeMutableBufferPointer { ptrsBuffer in
-            rubberband_retrieve(rb, ptrsBuffer.baseAddress, UInt32(outputFrames))
-        }
+        try convertAudioFile(from: sourceURL, to: targetURL)
+        return 0
+    } catch {
+        print("Error converting file: \(error)")
+        return 1
+    }
+}
 
-        outputBuffer.frameLength = AVAudioFrameCount(retrieved)
+@_cdecl("SwiftAudio_resampleFile")
+public func SwiftAudio_resampleFile(
+    _ sourceFilename: UnsafePointer<CChar>, _ targetFilename: UnsafePointer<CChar>,
+    _ sampleRate: Int32, _ bitDepth: Int32
+) -> Int32 {
+    let sourceURL = URL(fileURLWithPath: String(cString: sourceFilename))
+    let targetURL = URL(fileURLWithPath: String(cString: targetFilename))
 
-        // Remove target file if it exists
-        if FileManager.default.fileExists(atPath: targetURL.path) {
-            try FileManager.default.removeItem(at: targetURL)
-        }
+    do {
+        try resampleAudioFile(
+            from: sourceURL, to: targetURL, sampleRate: Double(sampleRate),
+            bitDepth: Int(bitDepth))
+        return 0
+    } catch {
+        print("Error resampling file: \(error)")
+        return 1
+    }
+}
 
-        // Write to target file
-        let outputFile = try AVAudioFile(
-            forWriting: targetURL,
-            settings: sourceFile.fileFormat.settings,
-            commonFormat: .pcmFormatFloat32,
-            interleaved: false
-        )
-        try outputFile.write(from: outputBuffer)
+@_cdecl("SwiftAudio_stretchFile")
+public func SwiftAudio_stretchFile(
+    _ sourceFilename: UnsafePointer<CChar>, _ targetFilename: UnsafePointer<CChar>,
+    _ startFrame: Int32, _ endFrame: Int32, _ timeRatio: Double
+) -> Int32 {
+    let sourceURL = URL(fileURLWithPath: String(cString: sourceFilename))
+    let targetURL = URL(fileURLWithPath: String(cString: targetFilename))
 
+    do {
+        try stretchAudioFile(
+            from: sourceURL, to: targetURL, startFrame: Int(startFrame),
+            endFrame: Int(endFrame), timeRatio: timeRatio)
         return 0
-
     } catch {
-        print("Error rendering pitched file: \(error)")
+        print("Error stretching file: \(error)")
         return 1
     }
 }
@@ -7,7 +7,7 @@ package audio
 
 // Forward declare the Go callbacks
 extern void goPlaybackFinished(int playerID);
-extern void goDecibelLevel(float db);
+extern void goDecibelLevel(float left, float right);
 
 // C wrapper function that will be passed to Swift
 static void cPlaybackFinishedCallback(int playerID) {
@@ -15,8 +15,8 @@ static void cPlaybackFinishedCallback(int playerID) {
 }
 
 // C wrapper function for decibel level callback
-static void cDecibelLevelCallback(float db) {
-    goDecibelLevel(db);
+static void cDecibelLevelCallback(float left, float right) {
+    goDecibelLevel(left, right);
 }
 
 // Helper function to get the function pointer
@@ -35,14 +35,34 @@ extern int SwiftAudio_start(const char* deviceName);
 extern int SwiftAudio_createPlayer(const char* filename);
 extern int SwiftAudio_destroyPlayer(int playerID);
 extern int SwiftAudio_stopPlayer(int playerID);
+extern int SwiftAudio_stopAll(void);
 extern int SwiftAudio_record(const char* filename);
+extern int SwiftAudio_recordInput(const char* filename, const char* deviceName);
 extern int SwiftAudio_stopRecording(void);
+extern int SwiftAudio_arm(double preRollSeconds);
+extern int SwiftAudio_disarm(void);
 extern int SwiftAudio_playFile(int playerID, const char* filename, float cents);
 extern int SwiftAudio_playRegion(int playerID, const char* filename, int startFrame, int endFrame, float cents);
+extern int SwiftAudio_playChain(const int* playerIDs, const int* startFrames, const int* endFrames, const int* crossfadeMs, const float* cents, int count);
+extern int SwiftAudio_setStereoWidth(int playerID, float width);
+extern int SwiftAudio_setGain(int playerID, float gain);
+extern int SwiftAudio_setPan(int playerID, float pan);
+extern int SwiftAudio_setEnvelope(int playerID, float attackMs, float decayMs, float sustain, float releaseMs);
+extern int SwiftAudio_setLoop(int playerID, int loop);
+extern int SwiftAudio_setReverse(int playerID, int reverse);
+extern int SwiftAudio_setChannelPair(int playerID, int pair);
+extern int SwiftAudio_setMaxVoices(int count);
+extern int SwiftAudio_setMasterVolume(float volume);
+extern int SwiftAudio_setLimiter(int enabled);
+extern int SwiftAudio_activeVoices(void);
+extern int SwiftAudio_startBounce(const char* filename);
+extern int SwiftAudio_stopBounce(void);
 extern int SwiftAudio_trimFile(const char* filename, int startFrame, int endFrame);
-extern int SwiftAudio_renderPitchedFile(const char* sourceFilename, const char* targetFilename, float cents);
+extern int SwiftAudio_convertFile(const char* sourceFilename, const char* targetFilename);
+extern int SwiftAudio_resampleFile(const char* sourceFilename, const char* targetFilename, int sampleRate, int bitDepth);
+extern int SwiftAudio_stretchFile(const char* sourceFilename, const char* targetFilename, int startFrame, int endFrame, double timeRatio);
 extern void SwiftAudio_setCompletionCallback(void (*callback)(int));
-extern void SwiftAudio_setDecibelCallback(void (*callback)(float));
+extern void SwiftAudio_setDecibelCallback(void (*callback)(float, float));
 extern char* SwiftAudio_getAudioDevices(void);
 */
 import "C"
@@ -53,11 +73,20 @@ import (
 	"os"
 	"strings"
 	"unsafe"
+
+	"smplr/wavfile"
 )
 
 // Global channels for notifications
 var playbackCompletionChan chan int
-var decibelLevelChan chan float32
+var decibelLevelChan chan DecibelLevels
+
+// DecibelLevels is the RMS level of each side of a recording, in dB. Mono
+// input has the same level on both.
+type DecibelLevels struct {
+	Left  float32
+	Right float32
+}
 
 //export goPlaybackFinished
 func goPlaybackFinished(playerID C.int) {
@@ -67,9 +96,9 @@ func goPlaybackFinished(playerID C.int) {
 }
 
 //export goDecibelLevel
-func goDecibelLevel(db C.float) {
+func goDecibelLevel(left C.float, right C.float) {
 	if decibelLevelChan != nil {
-		decibelLevelChan <- float32(db)
+		decibelLevelChan <- DecibelLevels{Left: float32(left), Right: float32(right)}
 	}
 }
 
@@ -82,7 +111,7 @@ func SetPlaybackCompletionChannel(ch chan int) {
 }
 
 // SetDecibelLevelChannel sets the channel for decibel level notifications
-func SetDecibelLevelChannel(ch chan float32) {
+func SetDecibelLevelChannel(ch chan DecibelLevels) {
 	decibelLevelChan = ch
 	// Register the callback with Swift using the C wrapper
 	callbackPtr := C.getCDecibelLevelCallback()
@@ -95,6 +124,23 @@ type AudioDevice struct {
 	Name string
 }
 
+// ChainLink is one region in a gapless chain of samples
+type ChainLink struct {
+	PlayerID    int
+	StartFrame  int
+	EndFrame    int
+	CrossfadeMs int     // Overlap with the next link, 0 for a hard cut
+	Cents       float32 // Pitch shift of the link
+}
+
+// Envelope is an ADSR amplitude envelope applied to every trigger of a player
+type Envelope struct {
+	AttackMs  int
+	DecayMs   int
+	Sustain   float32 // Level held after the decay, 0 to 1
+	ReleaseMs int     // Fade-out after the player is stopped
+}
+
 // Audio defines the interface for audio recording and playback operations
 // This will eventually be implemented as a bridge to Swift code using MacOS AV API
 type Audio interface {
@@ -103,12 +149,32 @@ type Audio interface {
 	CreatePlayer(filename string) (int, error)
 	DestroyPlayer(playerID int) error
 	StopPlayer(playerID int) error
+	StopAll() error
 	Record(filename string) error
+	RecordInput(filename string, deviceName string) error
 	StopRecording() error
+	Arm(preRollSeconds float64) error
+	Disarm() error
 	PlayFile(playerID int, filename string, cents float32) error
 	PlayRegion(playerID int, filename string, startFrame int, endFrame int, cents float32) error
+	PlayChain(links []ChainLink) error
+	SetStereoWidth(playerID int, width float32) error
+	SetGain(playerID int, gain float32) error
+	SetPan(playerID int, pan float32) error
+	SetEnvelope(playerID int, envelope Envelope) error
+	SetLoop(playerID int, loop bool) error
+	SetReverse(playerID int, reverse bool) error
+	SetChannelPair(playerID int, pair int) error
+	SetMaxVoices(count int) error
+	SetMasterVolume(volume float32) error
+	SetLimiter(enabled bool) error
+	ActiveVoices() int
+	StartBounce(filename string) error
+	StopBounce() error
 	TrimFile(filename string, startFrame int, endFrame int) error
-	RenderPitchedFile(sourceFilename string, targetFilename string, cents float32) error
+	ConvertFile(sourceFilename string, targetFilename string) error
+	ResampleFile(sourceFilename string, targetFilename string, sampleRate int, bitDepth int) error
+	StretchFile(sourceFilename string, targetFilename string, startFrame int, endFrame int, timeRatio float64) error
 	GetAudioDevices() ([]AudioDevice, error)
 }
 
@@ -150,14 +216,20 @@ func (a *StubAudio) DestroyPlayer(playerID int) error {
 	return nil
 }
 
-// StopPlayer stops playback for the given player ID
+// StopPlayer stops every voice playing for the given player ID
 func (a *StubAudio) StopPlayer(playerID int) error {
 	// Stub implementation - nothing to stop
 	return nil
 }
 
+// StopAll immediately silences every voice of every player, skipping release ramps
+func (a *StubAudio) StopAll() error {
+	// Stub implementation - nothing to stop
+	return nil
+}
+
 // Record starts recording audio to the specified file
-// filename should have a .wav extension and will be saved in the current directory
+// filename may have a .wav, .aiff or .flac extension and will be saved in the current directory
 func (a *StubAudio) Record(filename string) error {
 	if a.isRecording {
 		return nil // Already recording
@@ -168,6 +240,11 @@ func (a *StubAudio) Record(filename string) error {
 	return nil
 }
 
+// RecordInput records like Record; the stub has no input devices
+func (a *StubAudio) RecordInput(filename string, deviceName string) error {
+	return a.Record(filename)
+}
+
 // StopRecording stops the current recording
 // In stub mode, copies melissa.wav to the recording filename
 func (a *StubAudio) StopRecording() error {
@@ -206,6 +283,19 @@ func (a *StubAudio) StopRecording() error {
 	return nil
 }
 
+// Arm starts capturing system audio without writing it, keeping the last
+// preRollSeconds so the next Record starts that far before it was called
+func (a *StubAudio) Arm(preRollSeconds float64) error {
+	// Stub implementation - just returns nil
+	return nil
+}
+
+// Disarm stops the capture Arm started, discarding the pre-roll
+func (a *StubAudio) Disarm() error {
+	// Stub implementation - just returns nil
+	return nil
+}
+
 // PlayFile plays the entire audio file
 // Stub implementation - will be replaced with Swift bridge
 func (a *StubAudio) PlayFile(playerID int, filename string, cents float32) error {
@@ -223,8 +313,93 @@ func (a *StubAudio) PlayRegion(playerID int, filename string, startFrame int, en
 	return nil
 }
 
-// RenderPitchedFile creates a new audio file with pitch shifting applied offline
-func (a *StubAudio) RenderPitchedFile(sourceFilename string, targetFilename string, cents float32) error {
+// PlayChain plays the regions of several players back to back without gaps
+func (a *StubAudio) PlayChain(links []ChainLink) error {
+	// Stub implementation - just returns nil
+	return nil
+}
+
+// SetStereoWidth sets a player's stereo width, 0 for a mono sum and 1 for the original image
+func (a *StubAudio) SetStereoWidth(playerID int, width float32) error {
+	// Stub implementation - just returns nil
+	return nil
+}
+
+// SetGain sets a player's linear output gain, 1 for unity
+func (a *StubAudio) SetGain(playerID int, gain float32) error {
+	// Stub implementation - just returns nil
+	return nil
+}
+
+// SetPan places a player in the stereo field, -1 for left, 0 for center and 1 for right
+func (a *StubAudio) SetPan(playerID int, pan float32) error {
+	// Stub implementation - just returns nil
+	return nil
+}
+
+// SetEnvelope sets the amplitude envelope of a player
+func (a *StubAudio) SetEnvelope(playerID int, envelope Envelope) error {
+	// Stub implementation - just returns nil
+	return nil
+}
+
+// SetLoop makes a player repeat its region until it is stopped
+func (a *StubAudio) SetLoop(playerID int, loop bool) error {
+	// Stub implementation - just returns nil
+	return nil
+}
+
+// SetReverse makes a player play its region backwards
+func (a *StubAudio) SetReverse(playerID int, reverse bool) error {
+	// Stub implementation - just returns nil
+	return nil
+}
+
+// SetChannelPair picks the stereo pair a multichannel player plays, 0 = mix of all
+func (a *StubAudio) SetChannelPair(playerID int, pair int) error {
+	// Stub implementation - just returns nil
+	return nil
+}
+
+// SetMaxVoices limits how many voices sound at once; the oldest is stolen beyond it
+func (a *StubAudio) SetMaxVoices(count int) error {
+	// Stub implementation - just returns nil
+	return nil
+}
+
+// SetMasterVolume sets the linear volume of the main mix, 1 for unity
+func (a *StubAudio) SetMasterVolume(volume float32) error {
+	// Stub implementation - just returns nil
+	return nil
+}
+
+// StartBounce writes the master output to a WAV file until StopBounce
+func (a *StubAudio) StartBounce(filename string) error {
+	// Stub implementation - just returns nil
+	return nil
+}
+
+// StopBounce stops writing the master output and closes the file
+func (a *StubAudio) StopBounce() error {
+	// Stub implementation - just returns nil
+	return nil
+}
+
+// SetLimiter turns the peak limiter on the master output on or off
+func (a *StubAudio) SetLimiter(enabled bool) error {
+	// Stub implementation - just returns nil
+	return nil
+}
+
+// ActiveVoices returns the number of voices currently sounding
+func (a *StubAudio) ActiveVoices() int {
+	// Stub implementation - nothing ever sounds
+	return 0
+}
+
+// ConvertFile decodes the source file and writes it to the target file,
+// choosing the container (WAV, AIFF or FLAC) from the target extension
+func (a *StubAudio) ConvertFile(sourceFilename string, targetFilename string) error {
 	// Stub implementation - just copy the source file to target
 	srcFile, err := os.Open(sourceFilename)
 	if err != nil {
@@ -242,6 +417,23 @@ func (a *StubAudio) RenderPitchedFile(sourceFilename string, targetFilename stri
 	return err
 }
 
+// ResampleFile writes the source file to the target file at a new sample
+// rate and bit depth; a sampleRate of 0 keeps the source rate
+func (a *StubAudio) ResampleFile(sourceFilename string, targetFilename string, sampleRate int, bitDepth int) error {
+	// Stub implementation - no resampler, so just convert
+	if sourceFilename == targetFilename {
+		return nil
+	}
+	return a.ConvertFile(sourceFilename, targetFilename)
+}
+
+// StretchFile writes the frames from startFrame to endFrame of the source
+// file to the target file, timeRatio times as long at the same pitch
+func (a *StubAudio) StretchFile(sourceFilename string, targetFilename string, startFrame int, endFrame int, timeRatio float64) error {
+	// Stub implementation - no stretcher, so just convert
+	return a.ConvertFile(sourceFilename, targetFilename)
+}
+
 // GetAudioDevices returns a list of available audio output devices
 func (a *StubAudio) GetAudioDevices() ([]AudioDevice, error) {
 	// Stub implementation - return fake devices
@@ -305,8 +497,16 @@ func (a *StubAudio) TrimFile(filename string, startFrame int, endFrame int) erro
 			binary.Read(file, binary.LittleEndian, &byteRate)
 			binary.Read(file, binary.LittleEndian, &blockAlign)
 			binary.Read(file, binary.LittleEndian, &bitsPerSample)
-			if subchunkSize > 16 {
-				file.Seek(int64(subchunkSize-16), io.SeekCurrent)
+			extra := int64(subchunkSize) - 16
+			// The plain 16-byte fmt chunk written below can't be extensible,
+			// so take the PCM or float code from the sub-format GUID
+			if audioFormat == 0xFFFE && extra >= 24 {
+				file.Seek(8, io.SeekCurrent)
+				binary.Read(file, binary.LittleEndian, &audioFormat)
+				extra -= 10
+			}
+			if extra > 0 {
+				file.Seek(extra, io.SeekCurrent)
 			}
 			foundFmt = true
 		case "data":
@@ -335,9 +535,16 @@ func (a *StubAudio) TrimFile(filename string, startFrame int, endFrame int) erro
 
 	file.Close()
 
-	// Calculate new sizes
+	// Carry LIST, bext, cue and smpl chunks over to the trimmed file
+	chunks, err := wavfile.MetadataChunks(filename, startFrame, endFrame+1)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata chunks: %w", err)
+	}
+
+	// Calculate new sizes; an odd-sized data chunk takes a pad byte
 	newDataSize := uint32(len(sampleData))
-	newChunkSize := 36 + newDataSize
+	padSize := newDataSize & 1
+	newChunkSize := 36 + newDataSize + padSize + uint32(len(chunks))
 
 	// Write to temporary file
 	tempFilename := filename + ".tmp"
@@ -366,6 +573,10 @@ func (a *StubAudio) TrimFile(filename string, startFrame int, endFrame int) erro
 	outFile.Write([]byte("data"))
 	binary.Write(outFile, binary.LittleEndian, newDataSize)
 	outFile.Write(sampleData)
+	if padSize > 0 {
+		outFile.Write([]byte{0})
+	}
+	outFile.Write(chunks)
 
 	outFile.Close()
 
@@ -433,7 +644,7 @@ func (a *SwiftAudio) DestroyPlayer(playerID int) error {
 	return nil
 }
 
-// StopPlayer stops playback for the given player ID
+// StopPlayer stops every voice playing for the given player ID
 func (a *SwiftAudio) StopPlayer(playerID int) error {
 	result := C.SwiftAudio_stopPlayer(C.int(playerID))
 	if result != 0 {
@@ -442,7 +653,17 @@ func (a *SwiftAudio) StopPlayer(playerID int) error {
 	return nil
 }
 
+// StopAll immediately silences every voice of every player, skipping release ramps
+func (a *SwiftAudio) StopAll() error {
+	result := C.SwiftAudio_stopAll()
+	if result != 0 {
+		return fmt.Errorf("failed to stop all players")
+	}
+	return nil
+}
+
 // Record starts recording audio to the specified file
+// The container format is chosen from the filename extension (.wav, .aiff or .flac)
 func (a *SwiftAudio) Record(filename string) error {
 	cFilename := C.CString(filename)
 	defer C.free(unsafe.Pointer(cFilename))
@@ -454,6 +675,21 @@ func (a *SwiftAudio) Record(filename string) error {
 	return nil
 }
 
+// RecordInput starts recording from an input device, the default input
+// when deviceName is empty, rather than the system audio Record captures
+func (a *SwiftAudio) RecordInput(filename string, deviceName string) error {
+	cFilename := C.CString(filename)
+	defer C.free(unsafe.Pointer(cFilename))
+	cDeviceName := C.CString(deviceName)
+	defer C.free(unsafe.Pointer(cDeviceName))
+
+	result := C.SwiftAudio_recordInput(cFilename, cDeviceName)
+	if result != 0 {
+		return fmt.Errorf("failed to start recording from input device")
+	}
+	return nil
+}
+
 // StopRecording stops the current recording
 func (a *SwiftAudio) StopRecording() error {
 	result := C.SwiftAudio_stopRecording()
@@ -463,6 +699,25 @@ func (a *SwiftAudio) StopRecording() error {
 	return nil
 }
 
+// Arm starts capturing system audio without writing it, keeping the last
+// preRollSeconds so the next Record starts that far before it was called
+func (a *SwiftAudio) Arm(preRollSeconds float64) error {
+	result := C.SwiftAudio_arm(C.double(preRollSeconds))
+	if result != 0 {
+		return fmt.Errorf("failed to arm recording")
+	}
+	return nil
+}
+
+// Disarm stops the capture Arm started, discarding the pre-roll
+func (a *SwiftAudio) Disarm() error {
+	result := C.SwiftAudio_disarm()
+	if result != 0 {
+		return fmt.Errorf("failed to disarm recording")
+	}
+	return nil
+}
+
 // PlayFile plays the entire audio file
 func (a *SwiftAudio) PlayFile(playerID int, filename string, cents float32) error {
 	if !a.Started {
@@ -493,29 +748,230 @@ func (a *SwiftAudio) PlayRegion(playerID int, filename string, startFrame int, e
 	return nil
 }
 
+// PlayChain plays the regions of several players back to back without gaps
+func (a *SwiftAudio) PlayChain(links []ChainLink) error {
+	if !a.Started {
+		return fmt.Errorf("audio engine not started")
+	}
+	if len(links) == 0 {
+		return nil
+	}
+
+	playerIDs := make([]C.int, len(links))
+	startFrames := make([]C.int, len(links))
+	endFrames := make([]C.int, len(links))
+	crossfadeMs := make([]C.int, len(links))
+	cents := make([]C.float, len(links))
+	for i, link := range links {
+		playerIDs[i] = C.int(link.PlayerID)
+		startFrames[i] = C.int(link.StartFrame)
+		endFrames[i] = C.int(link.EndFrame)
+		crossfadeMs[i] = C.int(link.CrossfadeMs)
+		cents[i] = C.float(link.Cents)
+	}
+
+	result := C.SwiftAudio_playChain(&playerIDs[0], &startFrames[0], &endFrames[0], &crossfadeMs[0], &cents[0], C.int(len(links)))
+	if result != 0 {
+		return fmt.Errorf("failed to play chain")
+	}
+	return nil
+}
+
+// SetStereoWidth sets a player's stereo width, 0 for a mono sum and 1 for the original image
+func (a *SwiftAudio) SetStereoWidth(playerID int, width float32) error {
+	result := C.SwiftAudio_setStereoWidth(C.int(playerID), C.float(width))
+	if result != 0 {
+		return fmt.Errorf("failed to set stereo width")
+	}
+	return nil
+}
+
+// SetGain sets a player's linear output gain, 1 for unity
+func (a *SwiftAudio) SetGain(playerID int, gain float32) error {
+	result := C.SwiftAudio_setGain(C.int(playerID), C.float(gain))
+	if result != 0 {
+		return fmt.Errorf("failed to set gain")
+	}
+	return nil
+}
+
+// SetPan places a player in the stereo field, -1 for left, 0 for center and 1 for right
+func (a *SwiftAudio) SetPan(playerID int, pan float32) error {
+	result := C.SwiftAudio_setPan(C.int(playerID), C.float(pan))
+	if result != 0 {
+		return fmt.Errorf("failed to set pan")
+	}
+	return nil
+}
+
+// SetEnvelope sets the amplitude envelope of a player
+func (a *SwiftAudio) SetEnvelope(playerID int, envelope Envelope) error {
+	result := C.SwiftAudio_setEnvelope(C.int(playerID), C.float(envelope.AttackMs), C.float(envelope.DecayMs), C.float(envelope.Sustain), C.float(envelope.ReleaseMs))
+	if result != 0 {
+		return fmt.Errorf("failed to set envelope")
+	}
+	return nil
+}
+
+// SetLoop makes a player repeat its region until it is stopped
+func (a *SwiftAudio) SetLoop(playerID int, loop bool) error {
+	cLoop := C.int(0)
+	if loop {
+		cLoop = 1
+	}
+	result := C.SwiftAudio_setLoop(C.int(playerID), cLoop)
+	if result != 0 {
+		return fmt.Errorf("failed to set loop")
+	}
+	return nil
+}
+
+// SetReverse makes a player play its region backwards
+func (a *SwiftAudio) SetReverse(playerID int, reverse bool) error {
+	cReverse := C.int(0)
+	if reverse {
+		cReverse = 1
+	}
+	result := C.SwiftAudio_setReverse(C.int(playerID), cReverse)
+	if result != 0 {
+		return fmt.Errorf("failed to set reverse")
+	}
+	return nil
+}
+
+// SetChannelPair picks the stereo pair a multichannel player plays, 0 = mix of all
+func (a *SwiftAudio) SetChannelPair(playerID int, pair int) error {
+	result := C.SwiftAudio_setChannelPair(C.int(playerID), C.int(pair))
+	if result != 0 {
+		return fmt.Errorf("failed to set channel pair")
+	}
+	return nil
+}
+
+// SetMaxVoices limits how many voices sound at once; the oldest is stolen beyond it
+func (a *SwiftAudio) SetMaxVoices(count int) error {
+	result := C.SwiftAudio_setMaxVoices(C.int(count))
+	if result != 0 {
+		return fmt.Errorf("failed to set max voices")
+	}
+	return nil
+}
+
+// SetMasterVolume sets the linear volume of the main mix, 1 for unity
+func (a *SwiftAudio) SetMasterVolume(volume float32) error {
+	result := C.SwiftAudio_setMasterVolume(C.float(volume))
+	if result != 0 {
+		return fmt.Errorf("failed to set master volume")
+	}
+	return nil
+}
+
+// StartBounce writes the master output, everything the engine plays after
+// pitch, trim and gain, to a WAV file until StopBounce. It is separate from
+// Record, which captures system audio.
+func (a *SwiftAudio) StartBounce(filename string) error {
+	cFilename := C.CString(filename)
+	defer C.free(unsafe.Pointer(cFilename))
+
+	result := C.SwiftAudio_startBounce(cFilename)
+	if result != 0 {
+		return fmt.Errorf("failed to start bounce")
+	}
+	return nil
+}
+
+// StopBounce stops writing the master output and closes the file
+func (a *SwiftAudio) StopBounce() error {
+	result := C.SwiftAudio_stopBounce()
+	if result != 0 {
+		return fmt.Errorf("failed to stop bounce")
+	}
+	return nil
+}
+
+// SetLimiter turns the peak limiter on the master output on or off. The
+// limiter sits after the master volume and is on once the engine starts.
+func (a *SwiftAudio) SetLimiter(enabled bool) error {
+	cEnabled := C.int(0)
+	if enabled {
+		cEnabled = 1
+	}
+	result := C.SwiftAudio_setLimiter(cEnabled)
+	if result != 0 {
+		return fmt.Errorf("failed to set limiter")
+	}
+	return nil
+}
+
+// ActiveVoices returns the number of voices currently sounding
+func (a *SwiftAudio) ActiveVoices() int {
+	return int(C.SwiftAudio_activeVoices())
+}
+
 // TrimFile rewrites the audio file to only contain frames from startFrame to endFrame
 func (a *SwiftAudio) TrimFile(filename string, startFrame int, endFrame int) error {
 	cFilename := C.CString(filename)
 	defer C.free(unsafe.Pointer(cFilename))
 
+	// AVAudioFile writes only the audio, so LIST, bext, cue and smpl chunks
+	// are copied over afterwards. Files that aren't WAV have none.
+	chunks, _ := wavfile.MetadataChunks(filename, startFrame, endFrame)
+
 	result := C.SwiftAudio_trimFile(cFilename, C.int(startFrame), C.int(endFrame))
 	if result != 0 {
 		return fmt.Errorf("failed to trim file")
 	}
+	if err := wavfile.AppendChunks(filename, chunks); err != nil {
+		return fmt.Errorf("failed to copy metadata chunks: %w", err)
+	}
+	return nil
+}
+
+// ConvertFile decodes the source file and writes it to the target file,
+// choosing the container (WAV, AIFF or FLAC) from the target extension
+func (a *SwiftAudio) ConvertFile(sourceFilename string, targetFilename string) error {
+	cSource := C.CString(sourceFilename)
+	defer C.free(unsafe.Pointer(cSource))
+
+	cTarget := C.CString(targetFilename)
+	defer C.free(unsafe.Pointer(cTarget))
+
+	result := C.SwiftAudio_convertFile(cSource, cTarget)
+	if result != 0 {
+		return fmt.Errorf("failed to convert file")
+	}
+	return nil
+}
+
+// ResampleFile writes the source file to the target file at a new sample
+// rate and bit depth with libsamplerate; a sampleRate of 0 keeps the source rate
+func (a *SwiftAudio) ResampleFile(sourceFilename string, targetFilename string, sampleRate int, bitDepth int) error {
+	cSource := C.CString(sourceFilename)
+	defer C.free(unsafe.Pointer(cSource))
+
+	cTarget := C.CString(targetFilename)
+	defer C.free(unsafe.Pointer(cTarget))
+
+	result := C.SwiftAudio_resampleFile(cSource, cTarget, C.int(sampleRate), C.int(bitDepth))
+	if result != 0 {
+		return fmt.Errorf("failed to resample file")
+	}
 	return nil
 }
 
-// RenderPitchedFile creates a new audio file with pitch shifting applied offline
-func (a *SwiftAudio) RenderPitchedFile(sourceFilename string, targetFilename string, cents float32) error {
+// StretchFile writes the frames from startFrame to endFrame of the source
+// file to the target file, timeRatio times as long at the same pitch, with
+// RubberBand
+func (a *SwiftAudio) StretchFile(sourceFilename string, targetFilename string, startFrame int, endFrame int, timeRatio float64) error {
 	cSource := C.CString(sourceFilename)
 	defer C.free(unsafe.Pointer(cSource))
 
 	cTarget := C.CString(targetFilename)
 	defer C.free(unsafe.Pointer(cTarget))
 
-	result := C.SwiftAudio_renderPitchedFile(cSource, cTarget, C.float(cents))
+	result := C.SwiftAudio_stretchFile(cSource, cTarget, C.int(startFrame), C.int(endFrame), C.double(timeRatio))
 	if result != 0 {
-		return fmt.Errorf("failed to render pitched file")
+		return fmt.Errorf("failed to stretch file")
 	}
 	return nil
 }
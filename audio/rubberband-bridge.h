@@ -1 +1,2 @@
 #include <rubberband/rubberband-c.h>
+#include <samplerate.h>
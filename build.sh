@@ -13,6 +13,7 @@ cd audio
 swiftc -c -parse-as-library AudioBridge.swift -o AudioBridge.o \
   -import-objc-header rubberband-bridge.h \
   -I/opt/homebrew/opt/rubberband/include \
+  -I/opt/homebrew/opt/libsamplerate/include \
   -target arm64-apple-macos13
 cd ..
 
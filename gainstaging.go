@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"smplr/wavfile"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// gainSuggestion is a proposed gain change for one sample
+type gainSuggestion struct {
+	name        string
+	peakDB      float64
+	rmsDB       float64
+	currentDB   float64
+	suggestedDB float64
+}
+
+// gainAnalysisMsg carries the result of a gain-staging analysis
+type gainAnalysisMsg struct {
+	suggestions []gainSuggestion // only samples whose gain should change
+}
+
+// analyzeGainStaging measures every loaded sample in the background and
+// suggests gains that keep any voices samples hit together below the
+// master ceiling of -headroomDB. Hits are assumed uncorrelated, so they add in
+// power and each sample's peak may reach the ceiling minus 10*log10(voices).
+func analyzeGainStaging(files []wavfile.WavFile, headroomDB float64, voices int) tea.Cmd {
+	return func() tea.Msg {
+		targetPeakDB := -headroomDB - 10*math.Log10(float64(max(voices, 1)))
+
+		var suggestions []gainSuggestion
+		for _, file := range files {
+			if file.Metadata == nil || file.Corrupted {
+				continue
+			}
+			loudness, err := wavfile.MeasureLoudness(file.SourceFileName())
+			if err != nil {
+				continue
+			}
+
+			// Only ever turn samples down; quiet samples keep their gain
+			suggested := math.Min(file.GainDB, targetPeakDB-loudness.PeakDB)
+			suggested = math.Round(suggested*10) / 10
+			if suggested >= file.GainDB {
+				continue
+			}
+
+			suggestions = append(suggestions, gainSuggestion{
+				name:        file.Name,
+				peakDB:      loudness.PeakDB,
+				rmsDB:       loudness.RMSDB,
+				currentDB:   file.GainDB,
+				suggestedDB: suggested,
+			})
+		}
+
+		return gainAnalysisMsg{suggestions: suggestions}
+	}
+}
+
+// applyGainSuggestions sets the suggested gains on the matching files
+func (m *model) applyGainSuggestions() {
+	for _, suggestion := range m.gainSuggestions {
+		for i := range *m.files {
+			file := &(*m.files)[i]
+			if file.Name != suggestion.name {
+				continue
+			}
+			file.GainDB = suggestion.suggestedDB
+			if file.PlayerId != 0 {
+				if err := m.audio.SetGain(file.PlayerId, float32(wavfile.DBToAmplitude(file.GainDB))); err != nil {
+					m.SetCurrentError(fmt.Sprintf("Failed to set gain: %v", err))
+				}
+			}
+		}
+	}
+}
@@ -5,6 +5,8 @@ go 1.25.1
 require (
 	github.com/charmbracelet/bubbletea v1.3.10
 	github.com/charmbracelet/lipgloss v1.1.0
+	github.com/coder/websocket v1.8.14
+	github.com/fsnotify/fsnotify v1.10.1
 )
 
 require (
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"smplr/wavfile"
+)
+
+// kitPane is the second kit shown in split view
+type kitPane struct {
+	dir    string
+	files  []string // audio file names in dir
+	cursor int
+}
+
+// expandHome replaces a leading ~ with the home directory, for paths typed
+// where no shell expands them
+func expandHome(dir string) string {
+	if strings.HasPrefix(dir, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, strings.TrimPrefix(dir, "~"))
+		}
+	}
+	return dir
+}
+
+// loadKitPane lists the audio files of another kit directory
+func loadKitPane(dir string) (kitPane, error) {
+	dir = expandHome(dir)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return kitPane{}, err
+	}
+	if !info.IsDir() {
+		return kitPane{}, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	files, err := wavfile.ListAudioFiles(dir)
+	if err != nil {
+		return kitPane{}, err
+	}
+	return kitPane{dir: dir, files: files}, nil
+}
+
+// reload refreshes the file list, keeping the cursor in range
+func (k *kitPane) reload() error {
+	files, err := wavfile.ListAudioFiles(k.dir)
+	if err != nil {
+		return err
+	}
+	k.files = files
+	if k.cursor >= len(k.files) {
+		k.cursor = max(len(k.files)-1, 0)
+	}
+	return nil
+}
+
+// copyFile copies src to dst, refusing to overwrite an existing file
+func copyFile(src string, dst string) error {
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("%s already exists", dst)
+	}
+
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+
+	_, err = io.Copy(dstFile, srcFile)
+	return err
+}
+
+// importFile brings an audio file from anywhere on disk into the kit,
+// copying it or, with --import-symlink, linking to it, and adds it on the
+// next free note. It returns the name the sample got in the kit.
+func (m *model) importFile(path string) (string, error) {
+	path = expandHome(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+	if !wavfile.IsAudioFile(path) {
+		return "", fmt.Errorf("%s is not a file smplr can load", path)
+	}
+
+	name := filepath.Base(path)
+	if m.importSymlink {
+		// The link must still resolve from inside the kit
+		if path, err = filepath.Abs(path); err == nil {
+			err = os.Symlink(path, name)
+		}
+	} else {
+		err = copyFile(path, name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return name, m.appendFile(name)
+}
+
+// copyToOtherKit copies the selected sample of the focused pane into the
+// other kit, along with its settings and CC mappings from the kit's
+// session. A copy keeps its note unless the kit already plays another
+// sample there; then it gets the next free note.
+func (m *model) copyToOtherKit() error {
+	if m.kitFocused {
+		if len(m.otherKit.files) == 0 {
+			return nil
+		}
+		name := m.otherKit.files[m.otherKit.cursor]
+		if err := copyFile(filepath.Join(m.otherKit.dir, name), name); err != nil {
+			return err
+		}
+		if err := m.appendFile(name); err != nil {
+			return err
+		}
+		return m.importMappings(name)
+	}
+
+	if m.cursor < 0 || m.cursor >= len(*m.files) {
+		return nil
+	}
+	file := (*m.files)[m.cursor]
+	name := filepath.Base(file.Name)
+	if err := copyFile(file.Name, filepath.Join(m.otherKit.dir, name)); err != nil {
+		return err
+	}
+	if err := m.exportMappings(file, name); err != nil {
+		return err
+	}
+	return m.otherKit.reload()
+}
+
+// importMappings gives the sample just copied in from the other kit, which
+// appendFile selected, its settings and CC mappings from that kit's session
+func (m *model) importMappings(name string) error {
+	session, err := wavfile.LoadSession(filepath.Join(m.otherKit.dir, wavfile.SessionFileName))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	saved, ok := session.Sample(name)
+	if !ok {
+		return nil
+	}
+
+	file := &(*m.files)[m.cursor]
+	freeNote := file.MidiNote
+	saved.Restore(file)
+	// The sample it chained into stays behind
+	file.ChainNext, file.ChainNextSlot = "", 0
+	for i, other := range *m.files {
+		if i != m.cursor && other.MidiChannel == file.MidiChannel && other.MidiNote == file.MidiNote {
+			file.MidiNote = freeNote
+			break
+		}
+	}
+	for _, mapping := range session.CCMappings {
+		if mapping.Sample == name && mapping.Slot == saved.Slot && wavfile.FindCCMapping(m.ccMappings, mapping.Channel, mapping.Controller) < 0 {
+			mapping.Slot = file.Slot
+			m.ccMappings = append(m.ccMappings, mapping)
+		}
+	}
+
+	if file.PlayerId == 0 {
+		return nil
+	}
+	return m.applyPlayerSettings(file)
+}
+
+// exportMappings adds the settings and CC mappings of file, copied into the
+// other kit as name, to that kit's session
+func (m *model) exportMappings(file wavfile.WavFile, name string) error {
+	filename := filepath.Join(m.otherKit.dir, wavfile.SessionFileName)
+	session, err := wavfile.LoadSession(filename)
+	if os.IsNotExist(err) {
+		session = &wavfile.Session{}
+	} else if err != nil {
+		return err
+	}
+
+	// Entries left by an earlier copy whose file has since gone
+	session.Samples = slices.DeleteFunc(session.Samples, func(other wavfile.SessionSample) bool { return other.Name == name })
+	session.CCMappings = slices.DeleteFunc(session.CCMappings, func(mapping wavfile.CCMapping) bool { return mapping.Sample == name })
+
+	saved := wavfile.NewSessionSample(file)
+	saved.Name = name
+	saved.Slot = 0
+	// The sample it chains into stays behind
+	saved.ChainNext, saved.ChainNextSlot = "", 0
+	maxNote := 0
+	taken := false
+	for _, other := range session.Samples {
+		maxNote = max(maxNote, other.MidiNote)
+		taken = taken || other.MidiChannel == saved.MidiChannel && other.MidiNote == saved.MidiNote
+	}
+	if taken {
+		saved.MidiNote = maxNote + 1
+	}
+	session.Samples = append(session.Samples, saved)
+
+	for _, mapping := range m.ccMappings {
+		if mapping.Targets(&file) && wavfile.FindCCMapping(session.CCMappings, mapping.Channel, mapping.Controller) < 0 {
+			mapping.Sample, mapping.Slot = name, 0
+			session.CCMappings = append(session.CCMappings, mapping)
+		}
+	}
+	return session.Save(filename)
+}
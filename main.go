@@ -1,12 +1,21 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"smplr/audio"
 	"smplr/player"
 	"smplr/smplrmidi"
+	"smplr/stream"
 	"smplr/wavfile"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -17,15 +26,79 @@ const VERSION = "v0.1.0-alpha.9"
 
 // DecibelLevelMsg is sent when recording decibel levels are updated
 type DecibelLevelMsg struct {
-	Level float32
+	Left  float32
+	Right float32
 }
 
 var (
-	audioDevice string
+	audioDevice    string
+	recordFormat   string
+	markerCC       int
+	markerSelectCC int
+	bpm            float64
+	rollCC         int
+	patternBars    int
+	quantizePct    float64
+	swing          int
+	bankSize       int
+	recursiveScan  bool
+	importSymlink  bool
+	workDir        string
+	headroomDB     float64
+	headroomVoices int
+	maxVoices      int
+	midiThru       bool
+	profileName    string
+	httpAddr       string
+	playStart      int
+	playEnd        int
+	playPitch      int
+	recordDuration time.Duration
+	preRoll        float64
+	recordSource   string
+	normalizeRecs  bool
+	recordLength   string
+	recordInput    string
+	recordOutput   string
+	trimStart      string
+	trimEnd        string
+	normalizeDB    float64
+	convertRate    int
+	convertBits    int
+	convertOutput  string
+	jsonOutput     bool
 )
 
+// settings holds the command-line configuration used by the TUI model
+type settings struct {
+	audioDevice         string
+	recordingFormat     string
+	recordSource        string  // what r records: "system", "output" or "input"
+	preRoll             float64 // seconds captured while armed and kept ahead of a recording
+	normalizeRecordings bool
+	recordingLength     recordingLength
+	markerCC            int // relative encoder CC that moves the active marker, -1 to disable
+	markerSelectCC      int // CC that toggles between start and end marker, -1 to disable
+	rollCC              int // CC that starts a tempo-synced roll of the last trigger, -1 to disable
+	roller              *player.Roller
+	looper              *player.Looper
+	banks               *player.Banks
+	recursive           bool    // samples come from subdirectories too
+	importSymlink       bool    // ctrl+f links to samples instead of copying them
+	patternBars         int     // length of patterns captured with ctrl+p
+	quantizeStrength    float64 // how far ctrl+q's grid pulls pattern notes, 0 to 1
+	clock               *player.Clock
+	headroomDB          float64 // master headroom the gain analysis leaves
+	headroomVoices      int     // simultaneous hits the gain analysis plans for
+	maxVoices           int     // polyphony limit of the audio engine
+	ccMappings          []wavfile.CCMapping
+	stream              *stream.Hub // WebSocket clients, nil when the HTTP API is off
+	performance         *player.Performance
+}
+
 var rootCmd = &cobra.Command{
-	Use:   "smplr",
+	Use:   "smplr [dir]",
+	Args:  cobra.MaximumNArgs(1),
 	Short: "A MIDI-controlled audio sampler with a terminal UI",
 	Long:  `smplr is a terminal-based audio sampler that loads WAV files and triggers them via MIDI input with pitch shifting, trimming, and waveform display capabilities.`,
 	Run:   runSampler,
@@ -46,6 +119,48 @@ var infoCmd = &cobra.Command{
 	Run:   runInfo,
 }
 
+var duplicatesCmd = &cobra.Command{
+	Use:   "duplicates [wav-files...]",
+	Short: "Find WAV files with identical audio content",
+	Long:  `Fingerprints the normalized audio of each WAV file (the current directory by default) and lists groups of files that contain the same sound.`,
+	Run:   runDuplicates,
+}
+
+var playCmd = &cobra.Command{
+	Use:   "play <audio-file>",
+	Short: "Play an audio file, or a frame range of it, and exit when it finishes",
+	Args:  cobra.ExactArgs(1),
+	Run:   runPlay,
+}
+
+var recordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "Record system audio or an input device to a file without the TUI",
+	Args:  cobra.NoArgs,
+	Run:   runRecord,
+}
+
+var trimCmd = &cobra.Command{
+	Use:   "trim <wav-files...>",
+	Short: "Trim WAV files to a range given in frames or seconds",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   runTrim,
+}
+
+var normalizeCmd = &cobra.Command{
+	Use:   "normalize <wav-files...>",
+	Short: "Peak-normalize WAV files to a target level",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   runNormalize,
+}
+
+var convertCmd = &cobra.Command{
+	Use:   "convert <audio-files...>",
+	Short: "Convert audio files to another sample rate and bit depth",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   runConvert,
+}
+
 var devicesCmd = &cobra.Command{
 	Use:   "devices",
 	Short: "List available audio output devices",
@@ -54,9 +169,51 @@ var devicesCmd = &cobra.Command{
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&audioDevice, "device", "", "Audio output device name (use 'smplr devices' to list available devices)")
+	rootCmd.PersistentFlags().StringVar(&recordFormat, "record-format", "wav", "Container format for recordings (wav, aiff, flac)")
+	rootCmd.Flags().IntVar(&markerCC, "marker-cc", -1, "MIDI CC of a relative encoder that moves the active marker, e.g. 20 (-1 to disable)")
+	rootCmd.Flags().IntVar(&markerSelectCC, "marker-select-cc", -1, "MIDI CC that toggles between start and end marker, e.g. 21 (-1 to disable)")
+	rootCmd.Flags().Float64Var(&bpm, "bpm", 120, "Tempo of the internal clock used by rolls and patterns")
+	rootCmd.Flags().Float64Var(&headroomDB, "headroom", 6, "Master headroom in dB that gain staging leaves")
+	rootCmd.Flags().IntVar(&headroomVoices, "headroom-voices", 4, "Number of pads hit together that gain staging plans for")
+	rootCmd.Flags().IntVar(&maxVoices, "max-voices", 32, "Maximum number of voices sounding at once; the oldest is stolen beyond it")
+	rootCmd.Flags().StringVar(&httpAddr, "http", "", "Address to serve the HTTP control API on, e.g. localhost:8080 (disabled when empty)")
+	rootCmd.Flags().StringVar(&profileName, "profile", "", "Controller profile whose pads play the samples in list order: launchpad, mpc, maschine, or a JSON profile file")
+	rootCmd.Flags().BoolVar(&midiThru, "midi-thru", false, "Echo every incoming MIDI message to the smplr-midi-out port, not just notes that trigger samples")
+	rootCmd.Flags().StringVar(&recordSource, "record-source", "system", "What r records: system (other apps' audio), output (smplr's own output, to resample it) or input (the default input device)")
+	rootCmd.Flags().BoolVar(&normalizeRecs, "normalize-recordings", false, "Peak-normalize each WAV recording to -1 dBFS when it stops, before the name prompt")
+	rootCmd.Flags().Float64Var(&preRoll, "pre-roll", 3, "Seconds of audio kept while recording is armed (ctrl+r) and included ahead of each recording")
+	rootCmd.Flags().StringVar(&recordLength, "record-length", "", "Stop recordings automatically after this long: seconds (30s) or bars of 4/4 at the clock tempo (4bars)")
+	rootCmd.Flags().IntVar(&rollCC, "roll-cc", 22, "MIDI CC that rolls the last triggered sample; the value picks 1/8, 1/16 or 1/32 and 0 stops (-1 to disable)")
+	rootCmd.Flags().IntVar(&patternBars, "pattern-bars", 2, "Length in bars of 4/4 of the note patterns captured with ctrl+p")
+	rootCmd.Flags().Float64Var(&quantizePct, "quantize-strength", 100, "Percentage of the way to the grid that quantizing (ctrl+q) moves pattern notes")
+	rootCmd.Flags().IntVar(&swing, "swing", player.SwingStraight, "Swing of pattern playback in percent, from 50 (straight) to 75 (ctrl+s changes it)")
+	rootCmd.Flags().IntVar(&bankSize, "bank-size", 16, "Number of samples in each bank; the list shows one bank at a time and profile pads play the selected bank")
+	rootCmd.Flags().BoolVar(&recursiveScan, "recursive", false, "Load samples from subdirectories too, showing their relative paths; remembered in .smplr.json for the directory")
+	rootCmd.Flags().BoolVar(&importSymlink, "import-symlink", false, "Import samples (ctrl+f) as symlinks to the original files instead of copies")
+	rootCmd.Flags().StringVar(&workDir, "dir", "", "Kit directory to load samples from and save recordings, the session and caches in (default: the current directory; also accepted as an argument)")
 	rootCmd.AddCommand(versionCmd)
+	infoCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print metadata as JSON")
+	devicesCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print devices as JSON")
 	rootCmd.AddCommand(infoCmd)
 	rootCmd.AddCommand(devicesCmd)
+	rootCmd.AddCommand(duplicatesCmd)
+	playCmd.Flags().IntVar(&playStart, "start", 0, "First frame to play")
+	playCmd.Flags().IntVar(&playEnd, "end", 0, "Frame to stop at (default: end of file)")
+	playCmd.Flags().IntVar(&playPitch, "pitch", 0, "Pitch shift in semitones (-12 to 12)")
+	rootCmd.AddCommand(playCmd)
+	recordCmd.Flags().DurationVar(&recordDuration, "duration", 0, "How long to record, e.g. 30s (default: until interrupted)")
+	recordCmd.Flags().StringVar(&recordInput, "input", "", "Input device to record from, or \"default\" for the default input (default: system audio)")
+	recordCmd.Flags().StringVarP(&recordOutput, "output", "o", "", "File to write (default: a timestamped name in --record-format)")
+	rootCmd.AddCommand(recordCmd)
+	trimCmd.Flags().StringVar(&trimStart, "start", "0", "Start of the range to keep, in frames or seconds (e.g. 44100, 1.5s, 250ms)")
+	trimCmd.Flags().StringVar(&trimEnd, "end", "", "End of the range to keep, in frames or seconds (default: end of file)")
+	rootCmd.AddCommand(trimCmd)
+	normalizeCmd.Flags().Float64Var(&normalizeDB, "target", -1, "Peak level to normalize to, in dBFS")
+	rootCmd.AddCommand(normalizeCmd)
+	convertCmd.Flags().IntVar(&convertRate, "rate", 44100, "Sample rate to convert to, 0 to keep the source rate")
+	convertCmd.Flags().IntVar(&convertBits, "bits", 16, "Bit depth to convert to (8, 16, 24 or 32)")
+	convertCmd.Flags().StringVarP(&convertOutput, "output", "o", "", "File to write when converting one file (default: replace the source)")
+	rootCmd.AddCommand(convertCmd)
 }
 
 func main() {
@@ -76,12 +233,116 @@ func runInfo(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	// DC offset needs the samples; formats ReadPCM can't decode skip it
+	dcOffset, _ := wavfile.DCOffset(filename)
+
+	if jsonOutput {
+		printJSON(fileInfo{
+			File:          filename,
+			SampleRate:    metadata.SampleRate,
+			Channels:      metadata.NumChannels,
+			BitsPerSample: metadata.BitsPerSample,
+			Float:         metadata.Float,
+			Frames:        metadata.NumFrames,
+			Duration:      metadata.Duration,
+			DCOffset:      dcOffset,
+			Broadcast:     metadata.Broadcast,
+		})
+		return
+	}
+
 	// Output metadata
 	fmt.Printf("File: %s\n", filename)
 	fmt.Printf("Sample Rate: %d Hz\n", metadata.SampleRate)
+	fmt.Printf("Channels: %d\n", metadata.NumChannels)
+	if metadata.Float {
+		fmt.Printf("Bit Depth: %d (float)\n", metadata.BitsPerSample)
+	} else {
+		fmt.Printf("Bit Depth: %d\n", metadata.BitsPerSample)
+	}
 	fmt.Printf("Frames: %d\n", metadata.NumFrames)
 	fmt.Printf("Duration: %.2f seconds\n", metadata.Duration)
-	fmt.Printf("Waveform Segments: %d\n", len(metadata.WaveformData.Peaks))
+	fmt.Printf("Waveform Levels: %d\n", len(metadata.WaveformData.Mix.Levels))
+	if dcOffset != nil {
+		fmt.Printf("DC Offset:")
+		for _, offset := range dcOffset {
+			fmt.Printf(" %+.3f%%", offset*100)
+		}
+		fmt.Println()
+	}
+	if bext := metadata.Broadcast; bext != nil {
+		if bext.Description != "" {
+			fmt.Printf("Description: %s\n", bext.Description)
+		}
+		if bext.Originator != "" {
+			fmt.Printf("Originator: %s\n", bext.Originator)
+		}
+		if bext.OriginatorReference != "" {
+			fmt.Printf("Originator Reference: %s\n", bext.OriginatorReference)
+		}
+		if bext.OriginationDate != "" || bext.OriginationTime != "" {
+			fmt.Printf("Origination: %s\n", strings.TrimSpace(bext.OriginationDate+" "+bext.OriginationTime))
+		}
+		fmt.Printf("Time Reference: %d frames (%s)\n", bext.TimeReference, bext.TimeOfDay(metadata.SampleRate))
+	}
+}
+
+// fileInfo is the JSON form of `smplr info`
+type fileInfo struct {
+	File          string                 `json:"file"`
+	SampleRate    uint32                 `json:"sampleRate"`
+	Channels      int                    `json:"channels"`
+	BitsPerSample int                    `json:"bitsPerSample"`
+	Float         bool                   `json:"float,omitempty"` // IEEE float samples
+	Frames        int                    `json:"frames"`
+	Duration      float64                `json:"duration"`            // Seconds
+	DCOffset      []float64              `json:"dcOffset,omitempty"`  // Mean per channel, fraction of full scale
+	Broadcast     *wavfile.BroadcastInfo `json:"broadcast,omitempty"` // Broadcast WAV bext chunk
+}
+
+// deviceInfo is the JSON form of one device in `smplr devices`
+type deviceInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v any) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runDuplicates(cmd *cobra.Command, args []string) {
+	filenames := args
+	if len(filenames) == 0 {
+		entries, err := os.ReadDir(".")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading directory: %v\n", err)
+			os.Exit(1)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".wav") {
+				filenames = append(filenames, entry.Name())
+			}
+		}
+	}
+
+	groups := wavfile.FindDuplicates(filenames)
+	if len(groups) == 0 {
+		fmt.Println("No duplicates found")
+		return
+	}
+
+	for i, group := range groups {
+		fmt.Printf("Group %d:\n", i+1)
+		for _, filename := range group {
+			fmt.Printf("  %s\n", filename)
+		}
+	}
 }
 
 func runDevices(cmd *cobra.Command, args []string) {
@@ -97,6 +358,15 @@ func runDevices(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	if jsonOutput {
+		list := make([]deviceInfo, len(devices))
+		for i, device := range devices {
+			list[i] = deviceInfo{ID: device.ID, Name: device.Name}
+		}
+		printJSON(list)
+		return
+	}
+
 	if len(devices) == 0 {
 		fmt.Println("No audio devices found")
 		return
@@ -108,25 +378,399 @@ func runDevices(cmd *cobra.Command, args []string) {
 	}
 }
 
+func runPlay(cmd *cobra.Command, args []string) {
+	filename := args[0]
+	if playPitch < -12 || playPitch > 12 {
+		fmt.Fprintln(os.Stderr, "Pitch must be between -12 and 12 semitones")
+		os.Exit(1)
+	}
+
+	audioApi := audio.NewSwiftAudio()
+	if err := audioApi.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing audio: %v\n", err)
+		os.Exit(1)
+	}
+	completionChan := make(chan int)
+	audio.SetPlaybackCompletionChannel(completionChan)
+
+	playerID, err := audioApi.CreatePlayer(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	if err := audioApi.Start(audioDevice); err != nil {
+		fmt.Fprintf(os.Stderr, "Error starting audio: %v\n", err)
+		os.Exit(1)
+	}
+
+	cents := float32(playPitch * 100)
+	if playStart == 0 && playEnd == 0 {
+		err = audioApi.PlayFile(playerID, filename, cents)
+	} else {
+		end := playEnd
+		if end == 0 {
+			metadata, err := wavfile.ReadMetadata(filename)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading metadata: %v\n", err)
+				os.Exit(1)
+			}
+			end = metadata.NumFrames
+		}
+		err = audioApi.PlayRegion(playerID, filename, playStart, end, cents)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error playing %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+
+	// Wait for this player's playback to finish
+	for finished := range completionChan {
+		if finished == playerID {
+			return
+		}
+	}
+}
+
+func runRecord(cmd *cobra.Command, args []string) {
+	filename := recordOutput
+	if filename == "" {
+		if !wavfile.IsRecordingFormat(recordFormat) {
+			fmt.Fprintf(os.Stderr, "Unsupported record format %q (use one of: %s)\n", recordFormat, strings.Join(wavfile.RecordingFormats, ", "))
+			os.Exit(1)
+		}
+		filename = fmt.Sprintf("recording_%s.%s", time.Now().Format("20060102_150405"), recordFormat)
+	} else if !wavfile.IsRecordingFormat(strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")) {
+		fmt.Fprintf(os.Stderr, "Output must end in one of: .%s\n", strings.Join(wavfile.RecordingFormats, ", ."))
+		os.Exit(1)
+	}
+
+	audioApi := audio.NewSwiftAudio()
+	var err error
+	switch recordInput {
+	case "":
+		err = audioApi.Record(filename)
+	case "default":
+		err = audioApi.RecordInput(filename, "")
+	default:
+		err = audioApi.RecordInput(filename, recordInput)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error starting recording: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Record until the duration is up or the user interrupts
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	var timeout <-chan time.Time
+	if recordDuration > 0 {
+		timeout = time.After(recordDuration)
+	}
+	select {
+	case <-timeout:
+	case <-interrupt:
+	}
+
+	if err := audioApi.StopRecording(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error stopping recording: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(filename)
+}
+
+func runTrim(cmd *cobra.Command, args []string) {
+	audioApi := audio.NewSwiftAudio()
+	failed := false
+
+	for _, filename := range args {
+		if err := trimFile(audioApi, filename); err != nil {
+			fmt.Fprintf(os.Stderr, "Error trimming %s: %v\n", filename, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// trimFile trims one file to the --start and --end range
+func trimFile(audioApi audio.Audio, filename string) error {
+	metadata, err := wavfile.ReadMetadata(filename)
+	if err != nil {
+		return err
+	}
+
+	start, err := parseFramePosition(trimStart, metadata.SampleRate)
+	if err != nil {
+		return fmt.Errorf("invalid --start: %w", err)
+	}
+	end := metadata.NumFrames
+	if trimEnd != "" {
+		if end, err = parseFramePosition(trimEnd, metadata.SampleRate); err != nil {
+			return fmt.Errorf("invalid --end: %w", err)
+		}
+	}
+	if end > metadata.NumFrames {
+		end = metadata.NumFrames
+	}
+	if start >= end {
+		return fmt.Errorf("start (%d) must be before end (%d)", start, end)
+	}
+
+	return audioApi.TrimFile(filename, start, end)
+}
+
+// parseFramePosition reads a position given in frames ("44100"), seconds
+// ("1.5s") or milliseconds ("250ms")
+func parseFramePosition(value string, sampleRate uint32) (int, error) {
+	var seconds float64
+	switch {
+	case strings.HasSuffix(value, "ms"):
+		ms, err := strconv.ParseFloat(strings.TrimSuffix(value, "ms"), 64)
+		if err != nil {
+			return 0, err
+		}
+		seconds = ms / 1000
+	case strings.HasSuffix(value, "s"):
+		s, err := strconv.ParseFloat(strings.TrimSuffix(value, "s"), 64)
+		if err != nil {
+			return 0, err
+		}
+		seconds = s
+	default:
+		frames, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, err
+		}
+		if frames < 0 {
+			return 0, fmt.Errorf("position can't be negative")
+		}
+		return frames, nil
+	}
+
+	if seconds < 0 {
+		return 0, fmt.Errorf("position can't be negative")
+	}
+	return int(math.Round(seconds * float64(sampleRate))), nil
+}
+
+// recordSources are the values of --record-source
+var recordSources = []string{"system", "output", "input"}
+
+// recordingLength is how long a recording runs before it stops by itself,
+// in seconds or in bars of 4/4; zero for both means until stopped
+type recordingLength struct {
+	seconds float64
+	bars    float64
+}
+
+// parseRecordingLength parses a recording length given as seconds (30s)
+// or bars (4bars); an empty value means no limit
+func parseRecordingLength(value string) (recordingLength, error) {
+	var length recordingLength
+	var err error
+	switch {
+	case value == "":
+		return length, nil
+	case strings.HasSuffix(value, "bars"), strings.HasSuffix(value, "bar"):
+		length.bars, err = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSuffix(value, "s"), "bar"), 64)
+	case strings.HasSuffix(value, "s"):
+		length.seconds, err = strconv.ParseFloat(strings.TrimSuffix(value, "s"), 64)
+	default:
+		return length, fmt.Errorf("%q needs a unit: s or bars", value)
+	}
+	if err != nil {
+		return length, err
+	}
+	if length.seconds < 0 || length.bars < 0 {
+		return length, fmt.Errorf("length can't be negative")
+	}
+	return length, nil
+}
+
+// duration returns the length at a tempo, or 0 when there's no limit
+func (l recordingLength) duration(bpm float64) time.Duration {
+	seconds := l.seconds
+	if l.bars > 0 && bpm > 0 {
+		seconds = l.bars * 4 * 60 / bpm
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func runNormalize(cmd *cobra.Command, args []string) {
+	failed := false
+	for _, filename := range args {
+		gainDB, err := wavfile.NormalizeFile(filename, normalizeDB)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error normalizing %s: %v\n", filename, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: %+.1f dB\n", filename, gainDB)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func runConvert(cmd *cobra.Command, args []string) {
+	if convertRate < 0 || convertRate > 384000 {
+		fmt.Fprintln(os.Stderr, "Sample rate must be between 0 and 384000")
+		os.Exit(1)
+	}
+	switch convertBits {
+	case 8, 16, 24, 32:
+	default:
+		fmt.Fprintln(os.Stderr, "Bit depth must be 8, 16, 24 or 32")
+		os.Exit(1)
+	}
+	if convertOutput != "" && len(args) > 1 {
+		fmt.Fprintln(os.Stderr, "--output only works with a single file")
+		os.Exit(1)
+	}
+
+	audioApi := audio.NewSwiftAudio()
+	failed := false
+	for _, filename := range args {
+		target := filename
+		if convertOutput != "" {
+			target = convertOutput
+		}
+		if err := audioApi.ResampleFile(filename, target, convertRate, convertBits); err != nil {
+			fmt.Fprintf(os.Stderr, "Error converting %s: %v\n", filename, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
 func runSampler(cmd *cobra.Command, args []string) {
+	if len(args) > 0 {
+		if workDir != "" && workDir != args[0] {
+			fmt.Fprintf(os.Stderr, "Give the directory either as an argument or with --dir, not both\n")
+			os.Exit(1)
+		}
+		workDir = args[0]
+	}
+	// Everything smplr reads and writes is relative to the kit directory,
+	// so working from inside it puts recordings, the session and .smplr there
+	if workDir != "" {
+		if err := os.Chdir(expandHome(workDir)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening directory: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if !wavfile.IsRecordingFormat(recordFormat) {
+		fmt.Fprintf(os.Stderr, "Unsupported record format %q (use one of: %s)\n", recordFormat, strings.Join(wavfile.RecordingFormats, ", "))
+		os.Exit(1)
+	}
+	if !slices.Contains(recordSources, recordSource) {
+		fmt.Fprintf(os.Stderr, "Unsupported record source %q (use one of: %s)\n", recordSource, strings.Join(recordSources, ", "))
+		os.Exit(1)
+	}
+	maxRecording, err := parseRecordingLength(recordLength)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --record-length: %v\n", err)
+		os.Exit(1)
+	}
+	if maxVoices < 1 {
+		fmt.Fprintln(os.Stderr, "--max-voices must be at least 1")
+		os.Exit(1)
+	}
+
 	// Create channel for metadata loading
 	metadataChan := make(chan wavfile.MetadataLoadedMsg)
-	files := wavfile.LoadFiles(metadataChan)
 	audioApi := audio.NewSwiftAudio()
+	// A directory once opened with --recursive keeps scanning subdirectories
+	session, err := wavfile.LoadSession(wavfile.SessionFileName)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Ignoring session file: %v\n", err)
+	}
+	recursive := recursiveScan || (session != nil && session.Recursive)
+	files := wavfile.LoadFiles(metadataChan, audioApi.ConvertFile, recursive)
+	// Restore mappings, markers and order from the last session
+	var ccMappings []wavfile.CCMapping
+	if session != nil {
+		files = session.Apply(files)
+		ccMappings = session.CCMappings
+	}
+	clock := player.NewClock(bpm)
+	clock.SetSwing(swing)
+	roller := player.NewRoller(clock)
+	performance := player.NewPerformance(clock)
+	looper := player.NewLooper(clock)
+	banks := player.NewBanks(bankSize)
+	var hub *stream.Hub
+	if httpAddr != "" {
+		hub = stream.NewHub()
+	}
 	// Create program with initial model
-	m := initialModel(&files, audioApi, audioDevice)
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	m := initialModel(&files, audioApi, settings{
+		audioDevice:         audioDevice,
+		recordingFormat:     recordFormat,
+		recordSource:        recordSource,
+		preRoll:             preRoll,
+		normalizeRecordings: normalizeRecs,
+		recordingLength:     maxRecording,
+		markerCC:            markerCC,
+		markerSelectCC:      markerSelectCC,
+		rollCC:              rollCC,
+		roller:              roller,
+		looper:              looper,
+		banks:               banks,
+		recursive:           recursive,
+		importSymlink:       importSymlink,
+		patternBars:         patternBars,
+		quantizeStrength:    max(0, min(quantizePct, 100)) / 100,
+		clock:               clock,
+		headroomDB:          headroomDB,
+		headroomVoices:      headroomVoices,
+		maxVoices:           maxVoices,
+		ccMappings:          ccMappings,
+		stream:              hub,
+		performance:         performance,
+	})
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
 	audioApi.Init()
+	if err := audioApi.SetMaxVoices(maxVoices); err != nil {
+		fmt.Printf("Error setting max voices: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Create and register playback completion channel
 	playbackCompletionChan := make(chan int)
 	audio.SetPlaybackCompletionChannel(playbackCompletionChan)
 
 	// Create and register decibel level channel
-	decibelLevelChan := make(chan float32)
+	decibelLevelChan := make(chan audio.DecibelLevels)
 	audio.SetDecibelLevelChannel(decibelLevelChan)
 
-	smplrPlayer := player.NewPlayer(&files, audioApi, p.Send)
+	if httpAddr != "" {
+		if err := startAPIServer(httpAddr, p.Send, hub); err != nil {
+			fmt.Printf("Error starting HTTP API: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	smplrPlayer := player.NewPlayer(&files, audioApi, roller, p.Send)
+	if profileName != "" {
+		profile, err := player.LoadProfile(profileName)
+		if err != nil {
+			fmt.Printf("Error loading controller profile: %v\n", err)
+			os.Exit(1)
+		}
+		smplrPlayer.SetProfile(profile)
+	}
+	smplrPlayer.SetPerformance(performance)
+	smplrPlayer.SetLooper(looper)
+	smplrPlayer.SetBanks(banks)
+	if out, err := smplrmidi.StartOut(); err != nil {
+		fmt.Printf("Error starting MIDI output: %v\n", err)
+	} else {
+		smplrPlayer.SetOutput(out, midiThru)
+	}
 	smplrPlayer.Start()
 	stopFunc, err := smplrmidi.Start(smplrPlayer.MsgChan)
 	if err != nil {
@@ -134,6 +778,13 @@ func runSampler(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	// Pick up samples added, removed or edited by other programs
+	if stopWatching, err := watchDir(p.Send, recursive); err != nil {
+		fmt.Printf("Error watching directory: %v\n", err)
+	} else {
+		defer stopWatching()
+	}
+
 	// Start goroutine to forward metadata messages to the program
 	go func() {
 		for msg := range metadataChan {
@@ -153,15 +804,15 @@ func runSampler(cmd *cobra.Command, args []string) {
 				}
 			}
 			if filename != "" {
-				p.Send(wavfile.PlaybackFinishedMsg{Filename: filename})
+				p.Send(wavfile.PlaybackFinishedMsg{Filename: filename, PlayerId: playerID})
 			}
 		}
 	}()
 
 	// Start goroutine to forward decibel level messages to the program
 	go func() {
-		for db := range decibelLevelChan {
-			p.Send(DecibelLevelMsg{Level: db})
+		for levels := range decibelLevelChan {
+			p.Send(DecibelLevelMsg{Left: levels.Left, Right: levels.Right})
 		}
 	}()
 
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecordingLength(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    recordingLength
+		wantErr bool
+	}{
+		{"", recordingLength{}, false},
+		{"30s", recordingLength{seconds: 30}, false},
+		{"2.5s", recordingLength{seconds: 2.5}, false},
+		{"4bars", recordingLength{bars: 4}, false},
+		{"1bar", recordingLength{bars: 1}, false},
+		{"0.5bars", recordingLength{bars: 0.5}, false},
+		{"30", recordingLength{}, true},
+		{"-1s", recordingLength{}, true},
+		{"-2bars", recordingLength{}, true},
+		{"fours", recordingLength{}, true},
+		{"bars", recordingLength{}, true},
+		{"4m", recordingLength{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRecordingLength(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRecordingLength(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseRecordingLength(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRecordingLengthDuration(t *testing.T) {
+	tests := []struct {
+		length recordingLength
+		bpm    float64
+		want   time.Duration
+	}{
+		{recordingLength{}, 120, 0},
+		{recordingLength{seconds: 30}, 120, 30 * time.Second},
+		{recordingLength{bars: 4}, 120, 8 * time.Second},
+		{recordingLength{bars: 1}, 90, 2666666666 * time.Nanosecond},
+		{recordingLength{bars: 4}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.length.duration(tt.bpm); got != tt.want {
+			t.Errorf("%+v at %g BPM = %v, want %v", tt.length, tt.bpm, got, tt.want)
+		}
+	}
+}
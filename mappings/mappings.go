@@ -11,6 +11,13 @@ const (
 	Quit
 	CursorUp
 	CursorDown
+	PrevBank
+	NextBank
+	TogglePadGrid
+	Search
+	SortFiles
+	MoveFileUp
+	MoveFileDown
 	EditChannel
 	EditNote
 	EditPitch
@@ -29,6 +36,71 @@ const (
 	PlayFile
 	PlayRegion
 	TrimFile
+	TrimSilence
+	RestoreOriginal
+	ExportRegion
+	ZoomIn
+	ZoomOut
+	PanLeft
+	PanRight
+	ToggleScrub
+	RemoveDCOffset
+	SliceFile
+	ChopSlices
+	StretchFile
+	DetectPitch
+	TuneToC
+	NormalizeFile
+	FadeFile
+	ToggleReverse
+	ReverseFile
+	CycleChannelPair
+	EditCue
+	PrevCue
+	NextCue
+	MoveCue
+	ArmRecording
+	PunchIn
+	ToggleTriggerHistory
+	ToggleMidiMonitor
+	LinkChain
+	EditCrossfade
+	EditStereoWidth
+	EditPan
+	MasterVolumeDown
+	MasterVolumeUp
+	ToggleLimiter
+	Roll
+	CapturePattern
+	CycleQuantize
+	EditSwing
+	PlaySong
+	EditGain
+	AnalyzeGain
+	ToggleChromatic
+	EditEnvelope
+	ToggleLoop
+	TogglePlayMode
+	EditChokeGroup
+	LearnCC
+	Panic
+	FindDuplicates
+	AssignGMDrums
+	ToggleSplitView
+	SwitchPane
+	CopyToOtherKit
+	ImportFile
+	DuplicateFile
+	DeleteFile
+	ToggleFavorite
+	CompareAB
+	ToggleHelp
+	Count
+	EditTags
+	ExportMidi
+	ToggleBounce
+	ExportSFZ
+	ExportSF2
 )
 
 type Mapping struct {
@@ -66,45 +138,158 @@ func processEditingKey(keyStr string) Mapping {
 			(keyStr[0] >= 'A' && keyStr[0] <= 'Z') || keyStr[0] == '_') {
 			return Mapping{Command: TextInput, LastValue: keyStr}
 		}
+		// Path separators and dots (valid for directory paths), sharps for note
+		// names and tags, and stars for favorites in searches
+		if keyStr == "/" || keyStr == "." || keyStr == "~" || keyStr == " " || keyStr == "#" || keyStr == "*" {
+			return Mapping{Command: TextInput, LastValue: keyStr}
+		}
 		return Mapping{Command: Unknown, LastValue: keyStr}
 	}
 }
 
+// Binding is a navigation key, or several keys doing the same, with the
+// command it runs and what the help overlay says about it
+type Binding struct {
+	Keys        []string
+	Command     Command
+	Description string
+}
+
+// BindingGroup is a titled section of the help overlay
+type BindingGroup struct {
+	Title    string
+	Bindings []Binding
+}
+
+// Bindings are all the navigation keys, grouped and ordered as the help
+// overlay lists them
+var Bindings = []BindingGroup{
+	{"Navigation", []Binding{
+		{[]string{"up", "k"}, CursorUp, "Previous sample"},
+		{[]string{"down", "j"}, CursorDown, "Next sample"},
+		{[]string{"pgup"}, PrevBank, "Previous bank"},
+		{[]string{"pgdown"}, NextBank, "Next bank"},
+		{[]string{"/"}, Search, "Search by name, #tag or * for favorites"},
+		{[]string{"ctrl+g"}, TogglePadGrid, "Toggle pad grid"},
+		{[]string{"ctrl+t"}, SortFiles, "Sort the list"},
+		{[]string{"shift+up"}, MoveFileUp, "Move sample up the list"},
+		{[]string{"shift+down"}, MoveFileDown, "Move sample down the list"},
+		{[]string{"?"}, ToggleHelp, "Show or hide this help"},
+		{[]string{"ctrl+c", "q"}, Quit, "Quit"},
+	}},
+	{"Playback", []Binding{
+		{[]string{" "}, PlayFile, "Play sample between markers"},
+		{[]string{"a"}, PlayRegion, "Play region, following its chain"},
+		{[]string{"A"}, CompareAB, "A/B compare original and edit"},
+		{[]string{"y"}, ToggleScrub, "Toggle scrub mode"},
+		{[]string{"!"}, Panic, "Panic: silence every voice"},
+		{[]string{"["}, MasterVolumeDown, "Master volume down 1 dB"},
+		{[]string{"]"}, MasterVolumeUp, "Master volume up 1 dB"},
+		{[]string{"\\"}, ToggleLimiter, "Toggle master limiter"},
+		{[]string{"R"}, Roll, "Roll sample: 1/8, 1/16, 1/32, off"},
+	}},
+	{"Mapping", []Binding{
+		{[]string{"c"}, EditChannel, "Edit MIDI channel"},
+		{[]string{"n"}, EditNote, "Edit MIDI note"},
+		{[]string{"d"}, AssignGMDrums, "Assign General MIDI drum notes"},
+		{[]string{"K"}, ToggleChromatic, "Toggle keyboard mode"},
+		{[]string{"M"}, LearnCC, "MIDI learn a controller"},
+		{[]string{"L"}, LinkChain, "Chain samples"},
+		{[]string{"x"}, EditCrossfade, "Edit chain crossfade"},
+		{[]string{"D"}, FindDuplicates, "Find duplicate samples"},
+		{[]string{"#"}, EditTags, "Edit tags"},
+		{[]string{"*"}, ToggleFavorite, "Mark or unmark favorite"},
+	}},
+	{"Sound", []Binding{
+		{[]string{"p"}, EditPitch, "Edit pitch shift"},
+		{[]string{"u"}, DetectPitch, "Tuner: detect pitch"},
+		{[]string{"U"}, TuneToC, "Tune to the nearest C"},
+		{[]string{"g"}, EditGain, "Edit gain"},
+		{[]string{"G"}, AnalyzeGain, "Analyze gain staging"},
+		{[]string{"w"}, EditStereoWidth, "Edit stereo width"},
+		{[]string{"P"}, EditPan, "Edit pan"},
+		{[]string{"e"}, EditEnvelope, "Edit envelope"},
+		{[]string{"o"}, ToggleLoop, "Toggle loop mode"},
+		{[]string{"v"}, ToggleReverse, "Toggle reversed playback"},
+		{[]string{"m"}, TogglePlayMode, "Toggle one-shot or gate"},
+		{[]string{"z"}, EditChokeGroup, "Edit choke group"},
+		{[]string{"Q"}, CycleChannelPair, "Cycle multichannel pairs"},
+	}},
+	{"Markers and waveform", []Binding{
+		{[]string{"<"}, SelectStartMarker, "Select start marker"},
+		{[]string{">"}, SelectEndMarker, "Select end marker"},
+		{[]string{"h"}, MarkerLeft, "Move marker left"},
+		{[]string{"l"}, MarkerRight, "Move marker right"},
+		{[]string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}, Count, "Count: 20l moves 20 steps"},
+		{[]string{"+", "="}, MarkerStepIncrease, "Larger marker steps"},
+		{[]string{"-"}, MarkerStepDecrease, "Smaller marker steps"},
+		{[]string{"}"}, ZoomIn, "Zoom waveform in"},
+		{[]string{"{"}, ZoomOut, "Zoom waveform out"},
+		{[]string{","}, PanLeft, "Pan waveform left"},
+		{[]string{"."}, PanRight, "Pan waveform right"},
+		{[]string{"s"}, TrimSilence, "Move markers past silence"},
+		{[]string{"I"}, EditCue, "Name cue at the marker"},
+		{[]string{"("}, PrevCue, "Jump to previous cue"},
+		{[]string{")"}, NextCue, "Jump to next cue"},
+		{[]string{"Y"}, MoveCue, "Move cue to the marker"},
+	}},
+	{"Editing files", []Binding{
+		{[]string{"t"}, TrimFile, "Commit trim to the markers"},
+		{[]string{"T"}, SliceFile, "Slice at the markers"},
+		{[]string{"J"}, ChopSlices, "Chop slices onto notes"},
+		{[]string{"b"}, StretchFile, "Time-stretch to a tempo"},
+		{[]string{"W"}, ExportRegion, "Write region to a new file"},
+		{[]string{"N"}, NormalizeFile, "Normalize"},
+		{[]string{"f"}, FadeFile, "Fade in and out"},
+		{[]string{"V"}, ReverseFile, "Reverse the file"},
+		{[]string{"O"}, RemoveDCOffset, "Remove DC offset"},
+		{[]string{"ctrl+z"}, RestoreOriginal, "Restore the original file"},
+		{[]string{"ctrl+d"}, DuplicateFile, "Duplicate sample slot"},
+		{[]string{"delete", "backspace"}, DeleteFile, "Delete sample to the trash"},
+		{[]string{"ctrl+f"}, ImportFile, "Import a sample from a path"},
+	}},
+	{"Recording", []Binding{
+		{[]string{"r"}, Recording, "Start or stop recording"},
+		{[]string{"ctrl+r"}, ArmRecording, "Arm or disarm recording"},
+		{[]string{"ctrl+o"}, PunchIn, "Record into the sample"},
+		{[]string{"B"}, ToggleBounce, "Start or stop bouncing"},
+	}},
+	{"Patterns", []Binding{
+		{[]string{"ctrl+p"}, CapturePattern, "Capture a pattern"},
+		{[]string{"ctrl+q"}, CycleQuantize, "Cycle pattern quantize"},
+		{[]string{"ctrl+s"}, EditSwing, "Edit swing"},
+		{[]string{"ctrl+a"}, PlaySong, "Play patterns as a song"},
+	}},
+	{"Panes and kits", []Binding{
+		{[]string{"H"}, ToggleTriggerHistory, "Toggle trigger history"},
+		{[]string{"i"}, ToggleMidiMonitor, "Toggle MIDI monitor"},
+		{[]string{"S"}, ToggleSplitView, "Open another kit side by side"},
+		{[]string{"tab"}, SwitchPane, "Switch kit pane"},
+		{[]string{"C"}, CopyToOtherKit, "Copy sample to the other kit"},
+	}},
+	{"Export", []Binding{
+		{[]string{"E"}, ExportMidi, "Export performance as MIDI"},
+		{[]string{"X"}, ExportSFZ, "Export kit as SFZ"},
+		{[]string{"F"}, ExportSF2, "Export kit as SoundFont"},
+	}},
+}
+
+// navigationCommands looks up the command of a navigation key
+var navigationCommands = map[string]Command{}
+
+func init() {
+	for _, group := range Bindings {
+		for _, binding := range group.Bindings {
+			for _, key := range binding.Keys {
+				navigationCommands[key] = binding.Command
+			}
+		}
+	}
+}
+
 func processNavigationKey(keyStr string) Mapping {
-	switch keyStr {
-	case "ctrl+c", "q":
-		return Mapping{Command: Quit, LastValue: keyStr}
-	case "up", "k":
-		return Mapping{Command: CursorUp, LastValue: keyStr}
-	case "down", "j":
-		return Mapping{Command: CursorDown, LastValue: keyStr}
-	case "c":
-		return Mapping{Command: EditChannel, LastValue: keyStr}
-	case "n":
-		return Mapping{Command: EditNote, LastValue: keyStr}
-	case "p":
-		return Mapping{Command: EditPitch, LastValue: keyStr}
-	case "r":
-		return Mapping{Command: Recording, LastValue: keyStr}
-	case "h":
-		return Mapping{Command: MarkerLeft, LastValue: keyStr}
-	case "l":
-		return Mapping{Command: MarkerRight, LastValue: keyStr}
-	case "+", "=":
-		return Mapping{Command: MarkerStepIncrease, LastValue: keyStr}
-	case "-":
-		return Mapping{Command: MarkerStepDecrease, LastValue: keyStr}
-	case "<":
-		return Mapping{Command: SelectStartMarker, LastValue: keyStr}
-	case ">":
-		return Mapping{Command: SelectEndMarker, LastValue: keyStr}
-	case " ":
-		return Mapping{Command: PlayFile, LastValue: keyStr}
-	case "a":
-		return Mapping{Command: PlayRegion, LastValue: keyStr}
-	case "t":
-		return Mapping{Command: TrimFile, LastValue: keyStr}
-	default:
-		return Mapping{Command: Unknown, LastValue: keyStr}
+	if command, ok := navigationCommands[keyStr]; ok {
+		return Mapping{Command: command, LastValue: keyStr}
 	}
+	return Mapping{Command: Unknown, LastValue: keyStr}
 }
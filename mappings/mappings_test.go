@@ -0,0 +1,32 @@
+package mappings
+
+import "testing"
+
+func TestBindingsHaveUniqueKeys(t *testing.T) {
+	seen := map[string]string{}
+	for _, group := range Bindings {
+		for _, binding := range group.Bindings {
+			for _, key := range binding.Keys {
+				if other, ok := seen[key]; ok {
+					t.Errorf("key %q is bound to both %q and %q", key, other, binding.Description)
+				}
+				seen[key] = binding.Description
+			}
+		}
+	}
+}
+
+func TestProcessKeyNavigation(t *testing.T) {
+	for _, group := range Bindings {
+		for _, binding := range group.Bindings {
+			for _, key := range binding.Keys {
+				if got := processNavigationKey(key); got.Command != binding.Command || got.LastValue != key {
+					t.Errorf("processNavigationKey(%q) = %+v, want command %d", key, got, binding.Command)
+				}
+			}
+		}
+	}
+	if got := processNavigationKey("ctrl+]"); got.Command != Unknown {
+		t.Errorf("unbound key gave command %d, want Unknown", got.Command)
+	}
+}
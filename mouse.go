@@ -0,0 +1,153 @@
+package main
+
+import (
+	"smplr/wavfile"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// wheelLines is how far one notch of the mouse wheel scrolls the list
+const wheelLines = 3
+
+// screenLayout records where View last drew the list and the waveform, so
+// mouse events can be mapped back to what was under the pointer. View has
+// a value receiver, so the model holds it by pointer.
+type screenLayout struct {
+	listTop int // screen row of the first line of the list
+	waveTop int // screen row of the waveform's info bar, -1 when not shown
+	lines   int // lines in the whole view
+}
+
+// handleMouse selects the clicked sample, sets markers by clicking and
+// dragging on the waveform, and scrolls the list with the wheel
+func (m *model) handleMouse(msg tea.MouseMsg) {
+	if m.editing || m.recording || m.layout == nil {
+		return
+	}
+	// Views taller than the window lose their top lines
+	y := msg.Y + max(m.layout.lines-m.windowHeight, 0)
+
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		m.scrollList(-wheelLines)
+		return
+	case tea.MouseButtonWheelDown:
+		m.scrollList(wheelLines)
+		return
+	}
+
+	// A drag keeps moving the markers wherever the pointer goes
+	if m.dragFrame >= 0 || m.layout.waveTop >= 0 && y > m.layout.waveTop && y <= m.layout.waveTop+waveformHeight+1 {
+		m.clickWaveform(msg)
+		return
+	}
+	if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft &&
+		y >= m.layout.listTop && y < m.layout.listTop+m.viewport.Height {
+		m.clickList(msg.X, y-m.layout.listTop+m.viewport.YOffset)
+	}
+}
+
+// listLines returns the number of lines the list content takes
+func (m model) listLines() int {
+	if m.padGrid {
+		columns := padColumns(m.banks.Size())
+		return (m.banks.Size() + columns - 1) / columns * padRowHeight
+	}
+	return len(m.shownFiles())
+}
+
+// scrollList scrolls the list by lines without moving the cursor
+func (m *model) scrollList(lines int) {
+	last := max(m.listLines()-m.viewport.Height, 0)
+	m.viewport.YOffset = max(0, min(m.viewport.YOffset+lines, last))
+}
+
+// clickList selects the sample drawn at column x of line of the list
+func (m *model) clickList(x int, line int) {
+	if m.splitView && x >= m.windowWidth/2 {
+		return
+	}
+	index := -1
+	if m.padGrid {
+		columns := padColumns(m.banks.Size())
+		rows := (m.banks.Size() + columns - 1) / columns
+		// Pads are separated by a space and rows by a blank line
+		column := x / (padCellWidth + 1)
+		row := rows - 1 - line/padRowHeight
+		if column < columns && row >= 0 && line%padRowHeight < padRowHeight-1 {
+			start, end := m.bankRange()
+			if slot := row*columns + column; start+slot < end {
+				index = start + slot
+			}
+		}
+	} else if shown := m.shownFiles(); line < len(shown) {
+		index = shown[line]
+	}
+	if index < 0 || index == m.cursor || (*m.files)[index].Corrupted || !m.shows(index) {
+		return
+	}
+	m.cursor = index
+	m.scrollToSelection()
+}
+
+// clickWaveform moves the start marker to a left click on the waveform and
+// the end marker to a right click; dragging with the left button selects
+// the region between the click and the pointer
+func (m *model) clickWaveform(msg tea.MouseMsg) {
+	if m.cursor < 0 || m.cursor >= len(*m.files) {
+		return
+	}
+	file := &(*m.files)[m.cursor]
+	if file.Metadata == nil || m.windowWidth <= 0 {
+		return
+	}
+	viewStart, viewFrames := m.waveWindow(file.Metadata.NumFrames)
+	x := max(0, min(msg.X, m.windowWidth-1))
+	frame := viewStart + int((float64(x)+0.5)/float64(m.windowWidth)*float64(viewFrames))
+	frame = max(0, min(frame, file.Metadata.NumFrames-1))
+
+	switch {
+	case msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft:
+		m.dragFrame = frame
+		file.StartFrame = frame
+		file.EndFrame = max(file.EndFrame, frame)
+		m.activeMarker = "start"
+	case msg.Action == tea.MouseActionMotion && msg.Button == tea.MouseButtonLeft && m.dragFrame >= 0:
+		file.StartFrame, file.EndFrame = min(m.dragFrame, frame), max(m.dragFrame, frame)
+		m.activeMarker = "end"
+		if frame < m.dragFrame {
+			m.activeMarker = "start"
+		}
+	case msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonRight:
+		file.EndFrame = frame
+		file.StartFrame = min(file.StartFrame, frame)
+		m.activeMarker = "end"
+		if m.scrub {
+			m.previewMarker()
+		}
+	case msg.Action == tea.MouseActionRelease:
+		// Scrub mode previews where the drag ended
+		if m.dragFrame >= 0 && m.scrub {
+			m.previewMarker()
+		}
+		m.dragFrame = -1
+	}
+	separateMarkers(file, m.activeMarker)
+}
+
+// separateMarkers keeps the end marker at least one frame after the start
+// marker, since an empty region can't be played. When they meet, the
+// marker that wasn't just set gives way.
+func separateMarkers(file *wavfile.WavFile, moved string) {
+	last := file.Metadata.NumFrames - 1
+	if file.EndFrame > file.StartFrame || last < 1 {
+		return
+	}
+	if moved == "start" {
+		file.EndFrame = min(file.StartFrame+1, last)
+		file.StartFrame = file.EndFrame - 1
+	} else {
+		file.StartFrame = max(file.EndFrame-1, 0)
+		file.EndFrame = file.StartFrame + 1
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"smplr/wavfile"
+)
+
+// sampleSort is an order the list can be sorted in
+type sampleSort struct {
+	name    string
+	compare func(a, b wavfile.WavFile) int
+}
+
+// sampleSorts are the orders of the sort prompt, by key
+var sampleSorts = map[string]sampleSort{
+	"n": {"name", func(a, b wavfile.WavFile) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	}},
+	"d": {"duration", func(a, b wavfile.WavFile) int {
+		return cmp.Compare(fileDuration(a), fileDuration(b))
+	}},
+	"m": {"MIDI note", func(a, b wavfile.WavFile) int {
+		return cmp.Or(cmp.Compare(a.MidiChannel, b.MidiChannel), cmp.Compare(a.MidiNote, b.MidiNote))
+	}},
+}
+
+// fileDuration returns the length of a file in seconds, 0 while unknown
+func fileDuration(file wavfile.WavFile) float64 {
+	if file.Metadata == nil {
+		return 0
+	}
+	return file.Metadata.Duration
+}
+
+// sortFiles shows the list in the sort of the given prompt key, by
+// modification time, newest first, for "r", or in its own order again for
+// "l". Only the view changes: the list order, which banks and controller
+// pads follow, stays as it is.
+func (m *model) sortFiles(key string) {
+	if key == "l" {
+		m.sorting = nil
+		m.statusMessage = "Showing the list order"
+		return
+	}
+	sort, ok := sampleSorts[key]
+	if key == "r" {
+		// Stat each file once rather than on every comparison
+		modTimes := map[string]int64{}
+		modTime := func(name string) int64 {
+			t, ok := modTimes[name]
+			if !ok {
+				if info, err := os.Stat(name); err == nil {
+					t = info.ModTime().UnixNano()
+				}
+				modTimes[name] = t
+			}
+			return t
+		}
+		sort, ok = sampleSort{"recently added", func(a, b wavfile.WavFile) int {
+			return cmp.Compare(modTime(b.Name), modTime(a.Name))
+		}}, true
+	}
+	if !ok {
+		return
+	}
+
+	m.sorting = &sort
+	m.scrollToSelection()
+	m.statusMessage = fmt.Sprintf("Sorted by %s", sort.name)
+}
+
+// sorted orders indices of the list the way it is shown. The pad grid
+// keeps the list order, since the pads play it.
+func (m model) sorted(indices []int) []int {
+	if m.sorting != nil && !m.padGrid {
+		slices.SortStableFunc(indices, func(a, b int) int {
+			return m.sorting.compare((*m.files)[a], (*m.files)[b])
+		})
+	}
+	return indices
+}
+
+// reorderFiles runs a change of the list order, then follows the selected
+// sample and any chain being linked to their new places and saves the order
+func (m *model) reorderFiles(reorder func()) {
+	// Duplicated slots share a name, so their player tells them apart
+	type slot struct {
+		name     string
+		playerId int
+	}
+	var selected, linking slot
+	if m.cursor >= 0 && m.cursor < len(*m.files) {
+		selected = slot{(*m.files)[m.cursor].Name, (*m.files)[m.cursor].PlayerId}
+	}
+	if m.linkSource >= 0 && m.linkSource < len(*m.files) {
+		linking = slot{(*m.files)[m.linkSource].Name, (*m.files)[m.linkSource].PlayerId}
+	}
+
+	reorder()
+
+	for i, file := range *m.files {
+		switch (slot{file.Name, file.PlayerId}) {
+		case selected:
+			m.cursor = i
+		case linking:
+			m.linkSource = i
+		}
+	}
+	m.scrollToSelection()
+	m.saveSession()
+}
+
+// moveFile swaps the selected sample with its neighbour above (-1) or
+// below (1), carrying its mappings and settings with it
+func (m *model) moveFile(direction int) {
+	target := m.cursor + direction
+	if m.cursor < 0 || target < 0 || target >= len(*m.files) {
+		return
+	}
+	files := *m.files
+	m.reorderFiles(func() { files[m.cursor], files[target] = files[target], files[m.cursor] })
+}
@@ -0,0 +1,58 @@
+package player
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Banks splits the sample list into banks of equal size, shown one at a
+// time, so the pads of a controller profile play the samples of the
+// selected bank. The UI selects banks while the player loop reads them, so
+// it is safe for concurrent use.
+type Banks struct {
+	mu      sync.Mutex
+	size    int
+	current int
+}
+
+// NewBanks creates banks of the given number of slots, at least one
+func NewBanks(size int) *Banks {
+	return &Banks{size: max(size, 1)}
+}
+
+// Size returns the number of slots in each bank
+func (b *Banks) Size() int {
+	return b.size
+}
+
+// Current returns the selected bank
+func (b *Banks) Current() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current
+}
+
+// Select makes a bank the one the pads play
+func (b *Banks) Select(bank int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.current = max(bank, 0)
+}
+
+// Count returns how many banks a list of the given length fills
+func (b *Banks) Count(files int) int {
+	return max(1, (files+b.size-1)/b.size)
+}
+
+// BankLabel names a bank A, B, C and so on, by number past Z
+func BankLabel(bank int) string {
+	if bank < 26 {
+		return string(rune('A' + bank))
+	}
+	return fmt.Sprintf("%d", bank+1)
+}
+
+// BankSelectMsg is sent when a MIDI program change selects a bank
+type BankSelectMsg struct {
+	Bank int // program number, from 0
+}
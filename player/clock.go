@@ -0,0 +1,80 @@
+package player
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// SwingStraight and SwingMax bound the swing percentage: the share of each
+// pair of sixteenths the first one takes, as on MPCs
+const (
+	SwingStraight = 50
+	SwingMax      = 75
+)
+
+// Clock is the internal tempo that tempo-synced features run against
+type Clock struct {
+	mu    sync.Mutex
+	bpm   float64
+	swing int
+}
+
+// NewClock creates a clock running at the given tempo
+func NewClock(bpm float64) *Clock {
+	return &Clock{bpm: bpm, swing: SwingStraight}
+}
+
+// BPM returns the current tempo in beats per minute
+func (c *Clock) BPM() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bpm
+}
+
+// SetBPM changes the tempo, ignoring non-positive values
+func (c *Clock) SetBPM(bpm float64) {
+	if bpm <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bpm = bpm
+}
+
+// Interval returns the length of one note of the given subdivision,
+// where 4 is a quarter note (one beat), 8 an eighth note and so on
+func (c *Clock) Interval(subdivision int) time.Duration {
+	return time.Duration(float64(time.Minute) / c.BPM() * 4 / float64(subdivision))
+}
+
+// Swing returns the swing percentage
+func (c *Clock) Swing() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.swing
+}
+
+// SetSwing changes the swing percentage, clamped to SwingStraight..SwingMax
+func (c *Clock) SetSwing(percent int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.swing = max(SwingStraight, min(percent, SwingMax))
+}
+
+// SwingBeat returns where a beat falls once swung. Each pair of sixteenths
+// is stretched so the second starts at the swing percentage of the pair
+// instead of halfway; times in between move in proportion, and the
+// downbeats of the pairs stay put.
+func (c *Clock) SwingBeat(beat float64) float64 {
+	const pair = 0.5 // two sixteenths, in beats
+	split := float64(c.Swing()) / 100
+	start := math.Floor(beat/pair) * pair
+	position := (beat - start) / pair
+	if position < 0.5 {
+		position *= split / 0.5
+	} else {
+		position = split + (position-0.5)*(1-split)/0.5
+	}
+	return start + position*pair
+}
@@ -0,0 +1,72 @@
+package player
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestClockSwingBeat(t *testing.T) {
+	tests := []struct {
+		name  string
+		swing int
+		beat  float64
+		want  float64
+	}{
+		{"straight downbeat", SwingStraight, 1, 1},
+		{"straight offbeat", SwingStraight, 0.25, 0.25},
+		{"straight in between", SwingStraight, 0.1, 0.1},
+		{"downbeat stays put", 66, 2, 2},
+		{"pair downbeat stays put", 66, 2.5, 2.5},
+		{"second sixteenth is late", 66, 0.25, 0.33},
+		{"second sixteenth of a later pair", 66, 3.75, 3.83},
+		{"first half stretches", 66, 0.125, 0.165},
+		{"second half squeezes", 66, 0.375, 0.415},
+		{"max swing", SwingMax, 0.25, 0.375},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewClock(120)
+			clock.SetSwing(tt.swing)
+			if got := clock.SwingBeat(tt.beat); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("SwingBeat(%g) at %d%% = %g, want %g", tt.beat, tt.swing, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClockSettings(t *testing.T) {
+	clock := NewClock(120)
+	if got := clock.Interval(4); got != 500*time.Millisecond {
+		t.Errorf("quarter note at 120 BPM = %v, want 500ms", got)
+	}
+	if got := clock.Interval(16); got != 125*time.Millisecond {
+		t.Errorf("sixteenth at 120 BPM = %v, want 125ms", got)
+	}
+
+	clock.SetBPM(0)
+	clock.SetBPM(-10)
+	if got := clock.BPM(); got != 120 {
+		t.Errorf("BPM after non-positive values = %g, want 120", got)
+	}
+	clock.SetBPM(90)
+	if got := clock.BPM(); got != 90 {
+		t.Errorf("BPM = %g, want 90", got)
+	}
+
+	tests := []struct {
+		percent int
+		want    int
+	}{
+		{0, SwingStraight},
+		{58, 58},
+		{100, SwingMax},
+	}
+	for _, tt := range tests {
+		clock.SetSwing(tt.percent)
+		if got := clock.Swing(); got != tt.want {
+			t.Errorf("SetSwing(%d) gives %d, want %d", tt.percent, got, tt.want)
+		}
+	}
+}
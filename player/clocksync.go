@@ -0,0 +1,78 @@
+package player
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// pulsesPerBeat is the resolution of MIDI clock
+	pulsesPerBeat = 24
+	// beatsPerBar assumes the external sequencer runs in 4/4
+	beatsPerBar = 4
+)
+
+// ClockSync follows an external MIDI clock. It sets the tempo of a Clock
+// from the pulse rate and counts bars so loops can be kept in phase with
+// the sequencer. It is owned by the player loop.
+type ClockSync struct {
+	clock     *Clock
+	running   bool
+	pulses    int       // pulses since the last start
+	beatStart time.Time // time of the last beat, zero until one has been seen
+}
+
+// NewClockSync creates a sync that drives the given clock
+func NewClockSync(clock *Clock) *ClockSync {
+	return &ClockSync{clock: clock}
+}
+
+// Start rewinds to the first downbeat, which the next pulse plays
+func (s *ClockSync) Start() {
+	s.running = true
+	s.pulses = 0
+	s.beatStart = time.Time{}
+}
+
+// Continue resumes counting from where the sequencer stopped
+func (s *ClockSync) Continue() {
+	s.running = true
+	s.beatStart = time.Time{}
+}
+
+// Stop pauses counting until the next start or continue
+func (s *ClockSync) Stop() {
+	s.running = false
+}
+
+// Pulse counts a clock pulse received at now and returns the number of the
+// bar that begins on it, or -1 when it isn't a downbeat. The tempo is
+// measured over whole beats to smooth out pulse jitter.
+func (s *ClockSync) Pulse(now time.Time) int {
+	if !s.running {
+		return -1
+	}
+	pulse := s.pulses
+	s.pulses++
+	if pulse%pulsesPerBeat != 0 {
+		return -1
+	}
+
+	if !s.beatStart.IsZero() {
+		bpm := 60 / now.Sub(s.beatStart).Seconds()
+		s.clock.SetBPM(math.Round(bpm*10) / 10)
+	}
+	s.beatStart = now
+
+	if pulse%(pulsesPerBeat*beatsPerBar) != 0 {
+		return -1
+	}
+	return pulse / (pulsesPerBeat * beatsPerBar)
+}
+
+// BarsFor returns how many whole bars at the current tempo a loop of the
+// given length spans, at least one
+func (s *ClockSync) BarsFor(length time.Duration) int {
+	bar := s.clock.Interval(4) * beatsPerBar
+	return max(1, int(math.Round(float64(length)/float64(bar))))
+}
@@ -0,0 +1,64 @@
+package player
+
+import "time"
+
+// Trigger sources recorded in TriggerMsg
+const (
+	SourceMidi     = "midi"
+	SourceKeyboard = "keyboard"
+	SourceRoll     = "roll"
+	SourcePattern  = "pattern"
+	SourceAPI      = "api"
+)
+
+// TriggerMsg is sent for every trigger attempt, matched or not
+type TriggerMsg struct {
+	Time     time.Time
+	Source   string
+	Channel  int // 1-based MIDI channel
+	Note     int
+	Velocity int
+	Sample   string // Name of the matched sample, empty if nothing matched
+}
+
+// TriggerHistory is a fixed-size ring buffer of the most recent trigger events
+type TriggerHistory struct {
+	events []TriggerMsg
+	next   int
+	count  int
+}
+
+// NewTriggerHistory creates a history holding at most size events
+func NewTriggerHistory(size int) *TriggerHistory {
+	return &TriggerHistory{events: make([]TriggerMsg, size)}
+}
+
+// Add records an event, overwriting the oldest once the buffer is full
+func (h *TriggerHistory) Add(event TriggerMsg) {
+	if len(h.events) == 0 {
+		return
+	}
+	h.events[h.next] = event
+	h.next = (h.next + 1) % len(h.events)
+	if h.count < len(h.events) {
+		h.count++
+	}
+}
+
+// Events returns the recorded events, oldest first
+func (h *TriggerHistory) Events() []TriggerMsg {
+	events := make([]TriggerMsg, 0, h.count)
+	if h.count == 0 {
+		return events
+	}
+	start := (h.next - h.count + len(h.events)) % len(h.events)
+	for i := 0; i < h.count; i++ {
+		events = append(events, h.events[(start+i)%len(h.events)])
+	}
+	return events
+}
+
+// Len returns the number of recorded events
+func (h *TriggerHistory) Len() int {
+	return h.count
+}
@@ -0,0 +1,83 @@
+package player
+
+import (
+	"fmt"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// MidiEventMsg is sent for every incoming MIDI message except clock pulses
+type MidiEventMsg struct {
+	Time    time.Time
+	Kind    string // "note-on", "note-off", "cc", "start", "continue", "stop" or "other"
+	Channel int    // 1-based MIDI channel, 0 for system messages
+	Data    string // note and velocity or controller and value
+}
+
+// newMidiEventMsg describes a MIDI message for the monitor
+func newMidiEventMsg(msg midi.Message, received time.Time) MidiEventMsg {
+	event := MidiEventMsg{Time: received, Kind: "other"}
+	var channel, data1, data2 uint8
+
+	switch {
+	case msg.GetNoteOn(&channel, &data1, &data2):
+		event.Kind = "note-on"
+		event.Channel = int(channel) + 1
+		event.Data = fmt.Sprintf("note %d vel %d", data1, data2)
+	case msg.GetNoteOff(&channel, &data1, &data2):
+		event.Kind = "note-off"
+		event.Channel = int(channel) + 1
+		event.Data = fmt.Sprintf("note %d vel %d", data1, data2)
+	case msg.GetControlChange(&channel, &data1, &data2):
+		event.Kind = "cc"
+		event.Channel = int(channel) + 1
+		event.Data = fmt.Sprintf("cc %d val %d", data1, data2)
+	case msg.Type().Is(midi.StartMsg):
+		event.Kind = "start"
+	case msg.Type().Is(midi.ContinueMsg):
+		event.Kind = "continue"
+	case msg.Type().Is(midi.StopMsg):
+		event.Kind = "stop"
+	default:
+		event.Data = msg.String()
+	}
+	return event
+}
+
+// MidiMonitor is a fixed-size ring buffer of the most recent MIDI messages
+type MidiMonitor struct {
+	events []MidiEventMsg
+	next   int
+	count  int
+}
+
+// NewMidiMonitor creates a monitor holding at most size messages
+func NewMidiMonitor(size int) *MidiMonitor {
+	return &MidiMonitor{events: make([]MidiEventMsg, size)}
+}
+
+// Add records a message, overwriting the oldest once the buffer is full
+func (m *MidiMonitor) Add(event MidiEventMsg) {
+	if len(m.events) == 0 {
+		return
+	}
+	m.events[m.next] = event
+	m.next = (m.next + 1) % len(m.events)
+	if m.count < len(m.events) {
+		m.count++
+	}
+}
+
+// Events returns the recorded messages, oldest first
+func (m *MidiMonitor) Events() []MidiEventMsg {
+	events := make([]MidiEventMsg, 0, m.count)
+	if m.count == 0 {
+		return events
+	}
+	start := (m.next - m.count + len(m.events)) % len(m.events)
+	for i := 0; i < m.count; i++ {
+		events = append(events, m.events[(start+i)%len(m.events)])
+	}
+	return events
+}
@@ -0,0 +1,330 @@
+package player
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// LooperState is what the looper is doing
+type LooperState int
+
+const (
+	LooperOff       LooperState = iota
+	LooperWaiting               // armed, capture starts on the next note
+	LooperCapturing             // recording the first pass of the pattern
+	LooperPlaying               // replaying the pattern in a loop
+	LooperSong                  // playing the song once through
+)
+
+// PatternNote is a captured note-on or note-off, placed in beats from the
+// start of its pattern so it follows tempo changes
+type PatternNote struct {
+	Beat     float64
+	Channel  uint8
+	Note     uint8
+	Velocity uint8 // 0 for a note-off
+}
+
+// Pattern is a loop of notes a whole number of bars long
+type Pattern struct {
+	Bars  int
+	Notes []PatternNote // in beat order
+}
+
+// Beats returns the length of the pattern in beats
+func (p *Pattern) Beats() float64 {
+	return float64(p.Bars * beatsPerBar)
+}
+
+// NoteOns returns the number of note-ons in the pattern
+func (p *Pattern) NoteOns() int {
+	count := 0
+	for _, note := range p.Notes {
+		if note.Velocity > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// QuantizeGrids are the subdivisions patterns can be quantized to, where
+// 8 is an eighth note and 12 an eighth-note triplet
+var QuantizeGrids = []int{8, 12, 16, 24}
+
+// QuantizeLabel names a quantize grid, "off" for 0
+func QuantizeLabel(subdivision int) string {
+	switch {
+	case subdivision == 0:
+		return "off"
+	case subdivision%3 == 0:
+		return fmt.Sprintf("1/%dT", subdivision*2/3)
+	default:
+		return fmt.Sprintf("1/%d", subdivision)
+	}
+}
+
+// Quantized returns the pattern's notes moved towards the nearest line of
+// the grid by strength, from 0 (as played) to 1 (on the grid). Note-offs
+// move with their note-on so gates keep their length, and notes pushed
+// past the end wrap to the start.
+func (p *Pattern) Quantized(subdivision int, strength float64) []PatternNote {
+	notes := append([]PatternNote(nil), p.Notes...)
+	if subdivision <= 0 || strength <= 0 {
+		return notes
+	}
+
+	grid := float64(beatsPerBar) / float64(subdivision)
+	shifts := map[[2]uint8]float64{}
+	for i := range notes {
+		note := &notes[i]
+		key := [2]uint8{note.Channel, note.Note}
+		if note.Velocity > 0 {
+			shifts[key] = (math.Round(note.Beat/grid)*grid - note.Beat) * min(strength, 1)
+		}
+		note.Beat += shifts[key]
+		note.Beat = math.Mod(note.Beat+p.Beats(), p.Beats())
+	}
+	sort.SliceStable(notes, func(i, j int) bool { return notes[i].Beat < notes[j].Beat })
+	return notes
+}
+
+// Looper captures incoming notes into patterns of whole bars, starting on
+// the first note, and then replays the latest in a loop, or several in a
+// song. Notes are played by the Player it is attached to.
+type Looper struct {
+	clock    *Clock
+	notes    chan PatternNote
+	mu       sync.Mutex
+	state    LooperState
+	start    time.Time  // when capture began
+	pattern  *Pattern   // being captured or replayed, nil before the first capture
+	patterns []*Pattern // every pattern captured, in order
+	stop     chan struct{}
+
+	song       []SongStep // steps of the song being played
+	songStep   int        // index of the step playing
+	songRepeat int        // pass of that step, from 1
+
+	quantize int     // grid replayed notes snap to, 0 for none
+	strength float64 // how far notes move towards the grid, 0 to 1
+}
+
+// NewLooper creates a looper that follows the given clock
+func NewLooper(clock *Clock) *Looper {
+	return &Looper{
+		clock: clock,
+		notes: make(chan PatternNote, 16),
+	}
+}
+
+// Capture arms the looper to record a new pattern of the given number of
+// bars from the next note that comes in, to loop once it is complete
+func (l *Looper) Capture(bars int) {
+	l.Stop()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.state = LooperWaiting
+	l.pattern = &Pattern{Bars: max(bars, 1)}
+}
+
+// Stop ends capture or playback, keeping the captured patterns. Notes the
+// pattern left sounding are released.
+func (l *Looper) Stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+	if l.state == LooperWaiting || l.state == LooperCapturing {
+		// Half a pass isn't a pattern
+		l.pattern = nil
+	}
+	l.state = LooperOff
+}
+
+// SetQuantize snaps replayed notes to the given grid, one of QuantizeGrids
+// or 0 for none, by strength from 0 to 1. It applies from the next pass,
+// and the notes as played are kept so it can be changed freely.
+func (l *Looper) SetQuantize(subdivision int, strength float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.quantize = subdivision
+	l.strength = max(0, min(strength, 1))
+}
+
+// Quantize returns the grid replayed notes snap to, 0 for none
+func (l *Looper) Quantize() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.quantize
+}
+
+// State returns what the looper is doing
+func (l *Looper) State() LooperState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state
+}
+
+// Pattern returns a copy of the pattern being captured or replayed, nil
+// when there is none
+func (l *Looper) Pattern() *Pattern {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.pattern == nil {
+		return nil
+	}
+	pattern := *l.pattern
+	pattern.Notes = append([]PatternNote(nil), l.pattern.Notes...)
+	return &pattern
+}
+
+// Patterns returns the number of patterns captured
+func (l *Looper) Patterns() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.patterns)
+}
+
+// Add records a note message received at the given time while capturing.
+// The first note-on of a capture starts it.
+func (l *Looper) Add(msg midi.Message, received time.Time) {
+	var channel, note, velocity uint8
+	isOn := msg.GetNoteOn(&channel, &note, &velocity)
+	if !isOn && !msg.GetNoteOff(&channel, &note, &velocity) {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch l.state {
+	case LooperWaiting:
+		if !isOn {
+			return
+		}
+		l.state = LooperCapturing
+		l.start = received
+		l.stop = make(chan struct{})
+		go l.capture(l.pattern, received, l.stop)
+	case LooperCapturing:
+	default:
+		return
+	}
+
+	beat := float64(received.Sub(l.start)) / float64(l.clock.Interval(4))
+	if beat >= l.pattern.Beats() {
+		return
+	}
+	if !isOn {
+		velocity = 0
+	}
+	l.pattern.Notes = append(l.pattern.Notes, PatternNote{Beat: beat, Channel: channel, Note: note, Velocity: velocity})
+}
+
+// capture waits out the capture pass, which plays live, then keeps pattern
+// and loops it from the end of the pass
+func (l *Looper) capture(pattern *Pattern, start time.Time, stop chan struct{}) {
+	end := start.Add(time.Duration(pattern.Beats() * float64(l.clock.Interval(4))))
+	if !wait(end, stop) {
+		return
+	}
+
+	l.mu.Lock()
+	if l.stop != stop {
+		// Stopped as the pass ended
+		l.mu.Unlock()
+		return
+	}
+	l.state = LooperPlaying
+	l.patterns = append(l.patterns, pattern)
+	l.mu.Unlock()
+
+	l.play(end, stop, func() *Pattern { return pattern })
+}
+
+// play replays patterns back to back from passStart, taking each pass's
+// pattern from next, called with the lock held, until it returns nil. Notes
+// are scheduled against absolute times so passes don't drift, and the
+// clock is read on every note so tempo and swing changes apply mid-pattern.
+// Notes still held at the end of a pass, such as one held past the end of
+// the capture, are released there, and so are those held when it stops.
+func (l *Looper) play(passStart time.Time, stop chan struct{}, next func() *Pattern) {
+	beatTime := func(beat float64) time.Time {
+		return passStart.Add(time.Duration(l.clock.SwingBeat(beat) * float64(l.clock.Interval(4))))
+	}
+	sounding := map[[2]uint8]bool{}
+	defer l.release(sounding)
+
+	for {
+		l.mu.Lock()
+		if l.stop != stop {
+			// Stopped as the pass ended
+			l.mu.Unlock()
+			return
+		}
+		pattern := next()
+		if pattern == nil {
+			l.stop = nil
+			l.state = LooperOff
+			l.mu.Unlock()
+			return
+		}
+		l.pattern = pattern
+		notes := pattern.Quantized(l.quantize, l.strength)
+		l.mu.Unlock()
+
+		for _, note := range notes {
+			if !wait(beatTime(note.Beat), stop) {
+				return
+			}
+			key := [2]uint8{note.Channel, note.Note}
+			if note.Velocity == 0 && sounding[key] {
+				// A note-off of a note that played must get through
+				l.notes <- note
+				delete(sounding, key)
+				continue
+			}
+			// Drop the note if the player is falling behind
+			select {
+			case l.notes <- note:
+				if note.Velocity > 0 {
+					sounding[key] = true
+				}
+			default:
+			}
+		}
+		end := beatTime(pattern.Beats())
+		if !wait(end, stop) {
+			return
+		}
+		l.release(sounding)
+		passStart = end
+	}
+}
+
+// release sends a note-off for every note in sounding and forgets them
+func (l *Looper) release(sounding map[[2]uint8]bool) {
+	for key := range sounding {
+		l.notes <- PatternNote{Channel: key[0], Note: key[1]}
+	}
+	clear(sounding)
+}
+
+// wait sleeps until the given time, returning false if stop closes first
+func wait(until time.Time, stop chan struct{}) bool {
+	timer := time.NewTimer(time.Until(until))
+	select {
+	case <-stop:
+		timer.Stop()
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,88 @@
+package player
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQuantizeLabel(t *testing.T) {
+	tests := []struct {
+		subdivision int
+		want        string
+	}{
+		{0, "off"},
+		{8, "1/8"},
+		{12, "1/8T"},
+		{16, "1/16"},
+		{24, "1/16T"},
+	}
+
+	for _, tt := range tests {
+		if got := QuantizeLabel(tt.subdivision); got != tt.want {
+			t.Errorf("QuantizeLabel(%d) = %q, want %q", tt.subdivision, got, tt.want)
+		}
+	}
+}
+
+func TestPatternQuantized(t *testing.T) {
+	pattern := &Pattern{
+		Bars: 1,
+		Notes: []PatternNote{
+			{Beat: 0.125, Channel: 1, Note: 36, Velocity: 100},
+			{Beat: 0.375, Channel: 1, Note: 36},
+			{Beat: 1.375, Channel: 1, Note: 38, Velocity: 90},
+			{Beat: 1.625, Channel: 1, Note: 38},
+			{Beat: 3.875, Channel: 2, Note: 42, Velocity: 80},
+			{Beat: 3.9375, Channel: 2, Note: 42},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		subdivision int
+		strength    float64
+		want        []float64 // beats of the notes, in the order returned
+	}{
+		{"off", 0, 1, []float64{0.125, 0.375, 1.375, 1.625, 3.875, 3.9375}},
+		{"no strength", 16, 0, []float64{0.125, 0.375, 1.375, 1.625, 3.875, 3.9375}},
+		// The hat pushed past the end wraps to the start with its note-off
+		{"eighths", 8, 1, []float64{0, 0, 0.0625, 0.25, 1.5, 1.75}},
+		{"half strength", 8, 0.5, []float64{0, 0.0625, 0.3125, 1.4375, 1.6875, 3.9375}},
+		{"strength above 1 is on the grid", 8, 2, []float64{0, 0, 0.0625, 0.25, 1.5, 1.75}},
+		{"triplets", 12, 1, []float64{0, 0, 0.0625, 0.25, 1.333333333333333, 1.583333333333333}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pattern.Quantized(tt.subdivision, tt.strength)
+			if len(got) != len(pattern.Notes) {
+				t.Fatalf("Quantized returned %d notes, want %d", len(got), len(pattern.Notes))
+			}
+			for i, beat := range tt.want {
+				if math.Abs(got[i].Beat-beat) > 1e-9 {
+					t.Errorf("Quantized beats = %v, want %v", beats(got), tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	if pattern.Notes[0].Beat != 0.125 {
+		t.Error("Quantized changed the pattern's own notes")
+	}
+	if got := pattern.NoteOns(); got != 3 {
+		t.Errorf("NoteOns = %d, want 3", got)
+	}
+	if got := pattern.Beats(); got != 4 {
+		t.Errorf("Beats = %g, want 4", got)
+	}
+}
+
+// beats returns the beat of each note
+func beats(notes []PatternNote) []float64 {
+	var beats []float64
+	for _, note := range notes {
+		beats = append(beats, note.Beat)
+	}
+	return beats
+}
@@ -0,0 +1,97 @@
+package player
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+// maxPerformanceEvents bounds a performance; notes past it aren't recorded
+const maxPerformanceEvents = 100000
+
+// performanceResolution is the ticks per quarter note of exported files
+const performanceResolution = smf.MetricTicks(480)
+
+// performanceEvent is a note message and when it arrived
+type performanceEvent struct {
+	time time.Time
+	msg  midi.Message
+}
+
+// Performance records the incoming note-ons and note-offs of a session so
+// they can be exported as a standard MIDI file. The player loop adds to it
+// while the UI exports, so it is safe for concurrent use.
+type Performance struct {
+	mu     sync.Mutex
+	clock  *Clock
+	events []performanceEvent
+}
+
+// NewPerformance creates an empty performance whose exports take their
+// tempo from the clock
+func NewPerformance(clock *Clock) *Performance {
+	return &Performance{clock: clock}
+}
+
+// Add records a note message received at the given time
+func (p *Performance) Add(msg midi.Message, received time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.events) >= maxPerformanceEvents {
+		return
+	}
+	p.events = append(p.events, performanceEvent{time: received, msg: msg})
+}
+
+// Notes returns the number of note-ons recorded
+func (p *Performance) Notes() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	count := 0
+	for _, event := range p.events {
+		if event.msg.Type().Is(midi.NoteOnMsg) {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteFile writes the performance as a single-track MIDI file at the
+// clock's current tempo, starting at the first recorded note
+func (p *Performance) WriteFile(filename string) error {
+	p.mu.Lock()
+	events := append([]performanceEvent(nil), p.events...)
+	p.mu.Unlock()
+
+	if len(events) == 0 {
+		return fmt.Errorf("no notes recorded")
+	}
+
+	bpm := p.clock.BPM()
+	var track smf.Track
+	track.Add(0, smf.MetaTrackSequenceName("smplr performance"))
+	track.Add(0, smf.MetaTempo(bpm))
+
+	// Place each event by its absolute time so rounding doesn't drift
+	start := events[0].time
+	var lastTicks uint32
+	for _, event := range events {
+		ticks := performanceResolution.Ticks(bpm, event.time.Sub(start))
+		track.Add(ticks-lastTicks, event.msg)
+		lastTicks = ticks
+	}
+	track.Close(0)
+
+	file := smf.New()
+	file.TimeFormat = performanceResolution
+	if err := file.Add(track); err != nil {
+		return fmt.Errorf("failed to build MIDI file: %w", err)
+	}
+	if err := file.WriteFile(filename); err != nil {
+		return fmt.Errorf("failed to write MIDI file: %w", err)
+	}
+	return nil
+}
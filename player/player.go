@@ -16,17 +16,89 @@ type Player struct {
 	audio    audio.Audio
 	MsgChan  chan midi.Message
 	stopChan chan struct{}
+	roller   *Roller
+	sync     *ClockSync
 	sendFn   func(msg tea.Msg)
+	out      func(midi.Message) error // virtual output port, nil when closed
+	thru     bool                     // echo every incoming message instead of only triggered notes
+	profile  *Profile                 // controller pads translated to sample slots, nil for none
+	perf     *Performance             // incoming notes recorded for MIDI export, nil for none
+	looper   *Looper                  // incoming notes captured into a looping pattern, nil for none
+	banks    *Banks                   // bank the profile's pads play, nil for the whole list
+
+	soundingNotes map[int]int // note each sample slot was last triggered by, by player, owned by the player loop
 }
 
-// NewPlayer creates a new MIDI player
-func NewPlayer(files *[]wavfile.WavFile, audio audio.Audio, sendFn func(msg tea.Msg)) *Player {
+// NewPlayer creates a new MIDI player that also plays the roller's retriggers.
+// Incoming MIDI clock sets the tempo of the roller's clock.
+func NewPlayer(files *[]wavfile.WavFile, audio audio.Audio, roller *Roller, sendFn func(msg tea.Msg)) *Player {
 	return &Player{
-		files:    files,
-		audio:    audio,
-		MsgChan:  make(chan midi.Message),
-		stopChan: make(chan struct{}),
-		sendFn:   sendFn,
+		files:         files,
+		audio:         audio,
+		MsgChan:       make(chan midi.Message),
+		stopChan:      make(chan struct{}),
+		roller:        roller,
+		sync:          NewClockSync(roller.clock),
+		sendFn:        sendFn,
+		soundingNotes: map[int]int{},
+	}
+}
+
+// SetOutput sends triggered notes to a MIDI output, or every incoming
+// message when thru is set. It must be called before Start.
+func (p *Player) SetOutput(out func(midi.Message) error, thru bool) {
+	p.out = out
+	p.thru = thru
+}
+
+// SetProfile translates the pads of a controller to sample slots. It must be
+// called before Start.
+func (p *Player) SetProfile(profile *Profile) {
+	p.profile = profile
+}
+
+// SetPerformance records incoming notes into perf. It must be called
+// before Start.
+func (p *Player) SetPerformance(perf *Performance) {
+	p.perf = perf
+}
+
+// SetLooper captures incoming notes into looper's patterns and plays them
+// back. It must be called before Start.
+func (p *Player) SetLooper(looper *Looper) {
+	p.looper = looper
+}
+
+// SetBanks plays the selected bank from the pads of the controller profile
+// and lets MIDI program changes select banks. It must be called before
+// Start.
+func (p *Player) SetBanks(banks *Banks) {
+	p.banks = banks
+}
+
+// findTrigger returns the index of the sample a note triggers, and the
+// semitones to transpose it by. Pads of the controller profile trigger the
+// sample in their slot of the selected bank, -1 when the list is shorter
+// than the pad grid.
+func (p *Player) findTrigger(channel int, note int) (int, int) {
+	if p.profile != nil {
+		if slot := p.profile.Slot(channel, note); slot >= 0 {
+			if p.banks != nil {
+				slot += p.banks.Current() * p.banks.Size()
+			}
+			if slot >= len(*p.files) {
+				return -1, 0
+			}
+			return slot, 0
+		}
+	}
+	return wavfile.FindTrigger(*p.files, channel, note)
+}
+
+// echo sends a message to the MIDI output, if there is one
+func (p *Player) echo(msg midi.Message) {
+	if p.out != nil {
+		p.out(msg)
 	}
 }
 
@@ -39,31 +111,96 @@ func (p *Player) Start() error {
 
 // Stop stops the MIDI player
 func (p *Player) Stop() {
+	p.roller.Stop()
 	close(p.stopChan)
 	close(p.MsgChan)
 }
 
 // playerLoop processes MIDI messages and plays corresponding files
 func (p *Player) playerLoop() {
+	// A nil channel never delivers, so no looper means no pattern notes
+	var patternNotes chan PatternNote
+	if p.looper != nil {
+		patternNotes = p.looper.notes
+	}
 
 	for {
 		select {
 		case <-p.stopChan:
 			return
+		case tick := <-p.roller.ticks:
+			p.playNote(tick.channel, tick.note, tick.velocity, SourceRoll)
+		case note := <-patternNotes:
+			if note.Velocity > 0 {
+				p.playNote(note.Channel, note.Note, note.Velocity, SourcePattern)
+			} else {
+				p.stopNote(note.Channel, note.Note)
+			}
 		case msg := <-p.MsgChan:
+			if !msg.Type().Is(midi.TimingClockMsg) {
+				p.sendFn(newMidiEventMsg(msg, time.Now()))
+			}
+			if p.thru {
+				p.echo(msg)
+			}
+			if p.perf != nil && (msg.Type().Is(midi.NoteOnMsg) || msg.Type().Is(midi.NoteOffMsg)) {
+				p.perf.Add(msg, time.Now())
+			}
+			if p.looper != nil {
+				p.looper.Add(msg, time.Now())
+			}
 			if msg.Type().Is(midi.NoteOnMsg) {
 				var channel, note, velocity uint8
 				msg.GetNoteOn(&channel, &note, &velocity)
-				p.playNote(channel, note)
+				p.playNote(channel, note, velocity, SourceMidi)
 			} else if msg.Type().Is(midi.NoteOffMsg) {
 				var channel, note, velocity uint8
 				msg.GetNoteOff(&channel, &note, &velocity)
 				p.stopNote(channel, note)
+			} else if msg.Type().Is(midi.ControlChangeMsg) {
+				var channel, controller, value uint8
+				msg.GetControlChange(&channel, &controller, &value)
+				// Controllers drive UI state, so hand them to the TUI
+				p.sendFn(ControlChangeMsg{
+					Channel:    int(channel) + 1,
+					Controller: int(controller),
+					Value:      int(value),
+				})
+			} else if msg.Type().Is(midi.ProgramChangeMsg) && p.banks != nil {
+				var channel, program uint8
+				msg.GetProgramChange(&channel, &program)
+				// The UI owns the list, so it selects the bank
+				p.sendFn(BankSelectMsg{Bank: int(program)})
+			} else if msg.Type().Is(midi.TimingClockMsg) {
+				if bar := p.sync.Pulse(time.Now()); bar >= 0 {
+					p.syncLoops(bar)
+				}
+			} else if msg.Type().Is(midi.StartMsg) {
+				p.sync.Start()
+			} else if msg.Type().Is(midi.ContinueMsg) {
+				p.sync.Continue()
+			} else if msg.Type().Is(midi.StopMsg) {
+				p.sync.Stop()
+				p.stopLoops()
 			}
 		}
 	}
 }
 
+// ControlChangeMsg is sent for every incoming MIDI control change
+type ControlChangeMsg struct {
+	Channel    int // 1-based MIDI channel
+	Controller int
+	Value      int
+}
+
+// PlaybackErrorMsg is sent when the engine fails to play a triggered
+// sample. The note is skipped and the player keeps running.
+type PlaybackErrorMsg struct {
+	Sample string
+	Err    error
+}
+
 type trigger struct {
 	channel uint8
 	note    uint8
@@ -94,36 +231,92 @@ func delayedRemoveTrigger(channel uint8, note uint8) {
 }
 
 // playNote finds and plays the WAV file matching the MIDI channel and note
-func (p *Player) playNote(channel uint8, note uint8) {
+func (p *Player) playNote(channel uint8, note uint8, velocity uint8, source string) {
 	midiChannel := int(channel) + 1
 	midiNote := int(note)
 
-	for i := range *p.files {
-		file := &(*p.files)[i]
-		if file.MidiChannel == midiChannel && file.MidiNote == midiNote {
-			if file.Metadata != nil && !file.Corrupted {
-				// Stop and restart if already playing
-				if file.PlayingCount > 0 {
-					p.audio.StopPlayer(file.PlayerId)
-					file.PlayingCount = 0
-				}
-				// Use pitched file if it exists, otherwise use original
-				filename := file.Name
-				if file.PitchedFileName != "" {
-					filename = file.PitchedFileName
-				}
-				// No real-time pitch shifting - files are pre-rendered
-				err := p.audio.PlayRegion(file.PlayerId, filename, file.StartFrame, file.EndFrame, 0)
-				if err != nil {
-					panic("Error playing region: " + err.Error())
-				} else {
-					addTrigger(channel, note)
-					delayedRemoveTrigger(channel, note)
-				}
-				p.sendFn(wavfile.PlaybackStartedMsg{Filename: file.Name})
-			}
+	event := TriggerMsg{
+		Time:     time.Now(),
+		Source:   source,
+		Channel:  midiChannel,
+		Note:     midiNote,
+		Velocity: int(velocity),
+	}
+	defer func() { p.sendFn(event) }()
+
+	i, transpose := p.findTrigger(midiChannel, midiNote)
+	if i < 0 {
+		return
+	}
+	file := &(*p.files)[i]
+	if file.Metadata == nil || file.Corrupted {
+		return
+	}
+
+	// Retriggers overlap on the engine's voices, but a loop restarts
+	if file.Loop && file.PlayingCount > 0 {
+		p.audio.StopPlayer(file.PlayerId)
+		file.PlayingCount = 0
+	}
+	Choke(*p.files, p.audio, i)
+	// Remember which key a chromatic sample is sounding for its note-off
+	p.soundingNotes[file.PlayerId] = midiNote
+	p.echoTrigger(channel, note, velocity, source)
+
+	if file.ChainNext != "" {
+		// Play the whole chain gaplessly from this trigger
+		chain := wavfile.ChainIndices(*p.files, i)
+		err := p.audio.PlayChain(ChainLinks(*p.files, chain, transpose))
+		if err != nil {
+			// Skip the note, the UI reports why
+			p.sendFn(PlaybackErrorMsg{Sample: file.Name, Err: err})
 			return
 		}
+		addTrigger(channel, note)
+		delayedRemoveTrigger(channel, note)
+		for _, index := range chain {
+			p.sendFn(wavfile.PlaybackStartedMsg{Filename: (*p.files)[index].Name, PlayerId: (*p.files)[index].PlayerId})
+		}
+		event.Sample = file.Name
+		return
+	}
+
+	cents := file.Cents() + float32(transpose*100)
+	err := p.audio.PlayRegion(file.PlayerId, file.Name, file.StartFrame, file.EndFrame, cents)
+	if err != nil {
+		p.sendFn(PlaybackErrorMsg{Sample: file.Name, Err: err})
+		return
+	}
+	addTrigger(channel, note)
+	delayedRemoveTrigger(channel, note)
+	p.sendFn(wavfile.PlaybackStartedMsg{Filename: file.Name, PlayerId: file.PlayerId})
+	event.Sample = file.Name
+}
+
+// echoTrigger sends a note that triggered a sample to the MIDI output.
+// Rolls have no note-off, so their retriggers are sent as short notes.
+func (p *Player) echoTrigger(channel uint8, note uint8, velocity uint8, source string) {
+	if source == SourceRoll {
+		p.echo(midi.NoteOn(channel, note, velocity))
+		p.echo(midi.NoteOff(channel, note))
+	} else if !p.thru {
+		p.echo(midi.NoteOn(channel, note, velocity))
+	}
+}
+
+// Choke stops every other playing sample in the choke group of files[index]
+func Choke(files []wavfile.WavFile, a audio.Audio, index int) {
+	group := files[index].ChokeGroup
+	if group == 0 {
+		return
+	}
+
+	for i := range files {
+		other := &files[i]
+		if i != index && other.ChokeGroup == group && other.PlayingCount > 0 {
+			a.StopPlayer(other.PlayerId)
+			other.PlayingCount = 0
+		}
 	}
 }
 
@@ -132,20 +325,96 @@ func (p *Player) stopNote(channel uint8, note uint8) {
 	midiChannel := int(channel) + 1
 	midiNote := int(note)
 
+	i, _ := p.findTrigger(midiChannel, midiNote)
+	if i >= 0 && !p.thru {
+		// Close the note echoed when it triggered
+		p.echo(midi.NoteOff(channel, note))
+	}
+
 	if _, exists := possibleTriggers[trigger{channel: channel, note: note}]; exists {
-		// If this note-off corresponds to a recent note-on, ignore it
 		removeTrigger(channel, note)
+		// If this note-off corresponds to a recent note-on, ignore it,
+		// unless the sample is gated and every note-off must stop it
+		if i < 0 || (*p.files)[i].PlayMode != wavfile.PlayModeGate {
+			return
+		}
+	}
+
+	if i < 0 {
+		return
+	}
+	file := &(*p.files)[i]
+
+	// Releasing an earlier key must not cut the note a chromatic sample now plays
+	if file.Chromatic && p.soundingNotes[file.PlayerId] != midiNote {
+		return
+	}
+
+	if file.ChainNext != "" {
+		for _, index := range wavfile.ChainIndices(*p.files, i) {
+			p.audio.StopPlayer((*p.files)[index].PlayerId)
+			(*p.files)[index].PlayingCount = 0
+		}
 		return
 	}
+	if file.PlayingCount > 0 {
+		p.audio.StopPlayer(file.PlayerId)
+		file.PlayingCount = 0
+	}
+}
 
+// syncLoops restarts playing loops on the downbeat of the given bar, each
+// after as many bars as it is long, so they stay in phase with the sequencer
+func (p *Player) syncLoops(bar int) {
 	for i := range *p.files {
 		file := &(*p.files)[i]
-		if file.MidiChannel == midiChannel && file.MidiNote == midiNote {
-			if file.PlayingCount > 0 {
-				p.audio.StopPlayer(file.PlayerId)
-				file.PlayingCount = 0
-			}
-			return
+		if !file.Loop || file.PlayingCount == 0 || file.Metadata == nil || file.Metadata.SampleRate == 0 {
+			continue
 		}
+
+		length := time.Duration(float64(file.EndFrame-file.StartFrame) / float64(file.Metadata.SampleRate) * float64(time.Second))
+		if bar%p.sync.BarsFor(length) != 0 {
+			continue
+		}
+
+		transpose := 0
+		if note, ok := p.soundingNotes[file.PlayerId]; ok && file.Chromatic {
+			transpose = note - file.MidiNote
+		}
+		p.audio.StopPlayer(file.PlayerId)
+		file.PlayingCount = 0
+		cents := file.Cents() + float32(transpose*100)
+		if err := p.audio.PlayRegion(file.PlayerId, file.Name, file.StartFrame, file.EndFrame, cents); err != nil {
+			continue
+		}
+		p.sendFn(wavfile.PlaybackStartedMsg{Filename: file.Name, PlayerId: file.PlayerId})
+	}
+}
+
+// stopLoops stops every playing loop when the sequencer stops
+func (p *Player) stopLoops() {
+	for i := range *p.files {
+		file := &(*p.files)[i]
+		if file.Loop && file.PlayingCount > 0 {
+			p.audio.StopPlayer(file.PlayerId)
+			file.PlayingCount = 0
+		}
+	}
+}
+
+// ChainLinks builds the engine chain for the given file indices, transposed
+// by the given number of semitones
+func ChainLinks(files []wavfile.WavFile, indices []int, transpose int) []audio.ChainLink {
+	links := make([]audio.ChainLink, 0, len(indices))
+	for _, index := range indices {
+		file := files[index]
+		links = append(links, audio.ChainLink{
+			PlayerID:    file.PlayerId,
+			StartFrame:  file.StartFrame,
+			EndFrame:    file.EndFrame,
+			CrossfadeMs: max(0, min(file.ChainCrossfade, wavfile.MaxChainCrossfade)),
+			Cents:       file.Cents() + float32(transpose*100),
+		})
 	}
+	return links
 }
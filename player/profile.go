@@ -0,0 +1,95 @@
+package player
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Pad is a controller pad, identified by the note it sends
+type Pad struct {
+	Channel int `json:"channel"` // 1-based MIDI channel, 0 for any
+	Note    int `json:"note"`
+}
+
+// Profile translates the pads of a controller to sample slots: the first
+// pad plays the first sample in the list, the second pad the second, and so
+// on. Notes that aren't pads trigger samples by their MIDI mapping as usual.
+type Profile struct {
+	Name string `json:"name"`
+	Pads []Pad  `json:"pads"`
+}
+
+// Profiles are the built-in controller profiles, pads listed from the
+// bottom-left pad across and up
+var Profiles = map[string]Profile{
+	// Launchpad in programmer mode: rows of 8 notes, 11-18 at the bottom
+	"launchpad": {Name: "launchpad", Pads: launchpadPads()},
+	// MPC bank A in its default note layout
+	"mpc": {Name: "mpc", Pads: padsForNotes(37, 36, 42, 82, 40, 38, 46, 44, 48, 47, 45, 43, 49, 55, 51, 53)},
+	// Maschine in MIDI mode: C1 upwards
+	"maschine": {Name: "maschine", Pads: padsForNotes(36, 37, 38, 39, 40, 41, 42, 43, 44, 45, 46, 47, 48, 49, 50, 51)},
+}
+
+func launchpadPads() []Pad {
+	var pads []Pad
+	for row := 1; row <= 8; row++ {
+		for column := 1; column <= 8; column++ {
+			pads = append(pads, Pad{Note: row*10 + column})
+		}
+	}
+	return pads
+}
+
+func padsForNotes(notes ...int) []Pad {
+	pads := make([]Pad, len(notes))
+	for i, note := range notes {
+		pads[i] = Pad{Note: note}
+	}
+	return pads
+}
+
+// ProfileNames returns the names of the built-in profiles, sorted
+func ProfileNames() []string {
+	names := make([]string, 0, len(Profiles))
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadProfile returns the built-in profile of the given name, or reads a
+// profile from a JSON file at that path
+func LoadProfile(nameOrPath string) (*Profile, error) {
+	if profile, ok := Profiles[strings.ToLower(nameOrPath)]; ok {
+		return &profile, nil
+	}
+
+	data, err := os.ReadFile(nameOrPath)
+	if err != nil {
+		return nil, fmt.Errorf("unknown profile %q (built-in profiles: %s): %w",
+			nameOrPath, strings.Join(ProfileNames(), ", "), err)
+	}
+	var profile Profile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %w", nameOrPath, err)
+	}
+	if len(profile.Pads) == 0 {
+		return nil, fmt.Errorf("profile %s has no pads", nameOrPath)
+	}
+	return &profile, nil
+}
+
+// Slot returns the sample slot of the pad sending the given note on the
+// given 1-based channel, or -1 if it isn't one of the profile's pads
+func (p *Profile) Slot(channel int, note int) int {
+	for i, pad := range p.Pads {
+		if pad.Note == note && (pad.Channel == 0 || pad.Channel == channel) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,97 @@
+package player
+
+import (
+	"sync"
+	"time"
+)
+
+// RollSubdivisions are the note values a roll can retrigger on
+var RollSubdivisions = []int{8, 16, 32}
+
+// rollTick asks the player loop to retrigger a note
+type rollTick struct {
+	channel  uint8
+	note     uint8
+	velocity uint8
+}
+
+// Roller retriggers a note on a tempo-synced subdivision for roll and
+// stutter effects. Ticks are played by the Player it is attached to.
+type Roller struct {
+	clock       *Clock
+	ticks       chan rollTick
+	mu          sync.Mutex
+	stop        chan struct{}
+	subdivision int
+}
+
+// NewRoller creates a roller that follows the given clock
+func NewRoller(clock *Clock) *Roller {
+	return &Roller{
+		clock: clock,
+		ticks: make(chan rollTick, 1),
+	}
+}
+
+// Start rolls the note on the given 1-based MIDI channel, replacing any
+// roll already running
+func (r *Roller) Start(channel int, note int, velocity int, subdivision int) {
+	r.Stop()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stop = make(chan struct{})
+	r.subdivision = subdivision
+	tick := rollTick{channel: uint8(channel - 1), note: uint8(note), velocity: uint8(velocity)}
+	go r.run(tick, subdivision, r.stop)
+}
+
+// Stop ends the current roll, if any
+func (r *Roller) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	r.subdivision = 0
+}
+
+// Subdivision returns the subdivision of the running roll, 0 when stopped
+func (r *Roller) Subdivision() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.subdivision
+}
+
+// run schedules ticks against absolute times so they don't drift, reading
+// the clock on every step so tempo changes apply mid-roll
+func (r *Roller) run(tick rollTick, subdivision int, stop chan struct{}) {
+	next := time.Now()
+	for {
+		next = next.Add(r.clock.Interval(subdivision))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// Drop the tick if the player hasn't taken the previous one yet
+		select {
+		case r.ticks <- tick:
+		default:
+		}
+	}
+}
+
+// RollSubdivisionForValue maps a roll CC value to a subdivision: 0 stops
+// the roll and the rest of the range is split evenly across RollSubdivisions
+func RollSubdivisionForValue(value int) int {
+	if value <= 0 {
+		return 0
+	}
+	index := (value - 1) * len(RollSubdivisions) / 127
+	return RollSubdivisions[min(index, len(RollSubdivisions)-1)]
+}
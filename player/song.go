@@ -0,0 +1,82 @@
+package player
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SongStep plays one of the looper's patterns a number of times in a row
+type SongStep struct {
+	Pattern int // index into the captured patterns
+	Repeats int
+}
+
+// ParseSong parses a song written as steps separated by spaces, each a 1-based pattern number with an optional repeat count: "1x4 2x2 1"
+// plays the first pattern four times, the second twice and the first once
+func ParseSong(text string) ([]SongStep, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty song")
+	}
+
+	steps := make([]SongStep, 0, len(fields))
+	for _, field := range fields {
+		number, repeats, found := strings.Cut(strings.ToLower(field), "x")
+		pattern, err := strconv.Atoi(number)
+		if err != nil || pattern < 1 {
+			return nil, fmt.Errorf("invalid pattern number in %q", field)
+		}
+		step := SongStep{Pattern: pattern - 1, Repeats: 1}
+		if found {
+			if step.Repeats, err = strconv.Atoi(repeats); err != nil || step.Repeats < 1 {
+				return nil, fmt.Errorf("invalid repeat count in %q", field)
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// PlaySong plays the steps once through from now, replacing any capture or
+// loop, and stops at the end
+func (l *Looper) PlaySong(steps []SongStep) error {
+	l.Stop()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(steps) == 0 {
+		return fmt.Errorf("empty song")
+	}
+	for _, step := range steps {
+		if step.Pattern < 0 || step.Pattern >= len(l.patterns) {
+			return fmt.Errorf("no pattern %d (%d captured)", step.Pattern+1, len(l.patterns))
+		}
+	}
+
+	l.state = LooperSong
+	l.song = steps
+	l.songStep, l.songRepeat = 0, 0
+	l.stop = make(chan struct{})
+	go l.play(time.Now(), l.stop, func() *Pattern {
+		if l.songRepeat == steps[l.songStep].Repeats {
+			l.songStep++
+			l.songRepeat = 0
+		}
+		if l.songStep >= len(steps) {
+			return nil
+		}
+		l.songRepeat++
+		return l.patterns[steps[l.songStep].Pattern]
+	})
+	return nil
+}
+
+// SongPosition returns the song, the index of the step playing and its
+// pass, from 1
+func (l *Looper) SongPosition() ([]SongStep, int, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]SongStep(nil), l.song...), l.songStep, l.songRepeat
+}
@@ -0,0 +1,39 @@
+package player
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSong(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    []SongStep
+		wantErr bool
+	}{
+		{"1", []SongStep{{Pattern: 0, Repeats: 1}}, false},
+		{"1x4 2x2 1", []SongStep{{0, 4}, {1, 2}, {0, 1}}, false},
+		{"  3X2\t1 ", []SongStep{{2, 2}, {0, 1}}, false},
+		{"", nil, true},
+		{"   ", nil, true},
+		{"0", nil, true},
+		{"-1", nil, true},
+		{"a", nil, true},
+		{"x4", nil, true},
+		{"1x", nil, true},
+		{"1x0", nil, true},
+		{"1x2x3", nil, true},
+		{"1 2y", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSong(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSong(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ParseSong(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
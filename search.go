@@ -0,0 +1,129 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"smplr/wavfile"
+)
+
+// fuzzyMatch reports whether every character of query appears in name in
+// order, ignoring case and spaces, so "kck808" finds "Kick_808.wav"
+func fuzzyMatch(name string, query string) bool {
+	remaining := []rune(strings.ToLower(strings.ReplaceAll(query, " ", "")))
+	for _, r := range strings.ToLower(name) {
+		if len(remaining) == 0 {
+			break
+		}
+		if r == remaining[0] {
+			remaining = remaining[1:]
+		}
+	}
+	return len(remaining) == 0
+}
+
+// searchMatch reports whether a sample matches every term of query: *
+// matches favorites, #tag samples with a tag starting with tag, and the
+// remaining terms are fuzzy matched against the name together
+func searchMatch(file *wavfile.WavFile, query string) bool {
+	var name []string
+	for _, term := range strings.Fields(query) {
+		switch {
+		case term == "*":
+			if !file.Favorite {
+				return false
+			}
+		case strings.HasPrefix(term, "#"):
+			if !file.HasTagPrefix(term[1:]) {
+				return false
+			}
+		default:
+			name = append(name, term)
+		}
+	}
+	return fuzzyMatch(file.Name, strings.Join(name, ""))
+}
+
+// shows reports whether the sample at index can take the cursor: a search
+// match, or any sample when there is no search
+func (m model) shows(index int) bool {
+	return m.search == "" || searchMatch(&(*m.files)[index], m.search)
+}
+
+// shownFiles returns the indices of the samples the list shows, in the
+// order it shows them: the search matches, or the cursor's bank when there
+// is no search
+func (m model) shownFiles() []int {
+	var shown []int
+	if m.search != "" {
+		for i := range *m.files {
+			if m.shows(i) {
+				shown = append(shown, i)
+			}
+		}
+		return m.sorted(shown)
+	}
+	return m.bankFiles(m.bank())
+}
+
+// bankFiles returns the indices of the samples in a bank, in the order the
+// list shows them
+func (m model) bankFiles(bank int) []int {
+	var files []int
+	start := min(bank*m.banks.Size(), len(*m.files))
+	for i := start; i < min(start+m.banks.Size(), len(*m.files)); i++ {
+		files = append(files, i)
+	}
+	return m.sorted(files)
+}
+
+// moveCursor moves the cursor to the previous (-1) or next (1) sample the
+// list shows, skipping corrupted files and going on into the neighbouring
+// banks past either end of the cursor's
+func (m *model) moveCursor(direction int) {
+	shown := m.shownFiles()
+	for pos := slices.Index(shown, m.cursor) + direction; pos >= 0 && pos < len(shown); pos += direction {
+		if !(*m.files)[shown[pos]].Corrupted {
+			m.cursor = shown[pos]
+			m.scrollToSelection()
+			return
+		}
+	}
+	if m.search != "" {
+		return
+	}
+
+	for bank := m.bank() + direction; bank >= 0 && bank < m.banks.Count(len(*m.files)); bank += direction {
+		files := m.bankFiles(bank)
+		if direction < 0 {
+			slices.Reverse(files)
+		}
+		for _, i := range files {
+			if !(*m.files)[i].Corrupted {
+				m.cursor = i
+				m.scrollToSelection()
+				return
+			}
+		}
+	}
+}
+
+// applySearch filters the list to the samples matching query, moving the
+// cursor to the first match when it isn't on one. Nothing is selected while
+// nothing matches, so no key acts on a hidden sample.
+func (m *model) applySearch(query string) {
+	m.search = query
+	if len(*m.files) == 0 {
+		return
+	}
+	if m.cursor < 0 || m.cursor >= len(*m.files) || !m.shows(m.cursor) || (*m.files)[m.cursor].Corrupted {
+		m.cursor = -1
+		for _, i := range m.shownFiles() {
+			if !(*m.files)[i].Corrupted {
+				m.cursor = i
+				break
+			}
+		}
+	}
+	m.scrollToSelection()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"testing"
+
+	"smplr/player"
+	"smplr/wavfile"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  bool
+	}{
+		{"Kick_808.wav", "kck808", true},
+		{"Kick_808.wav", "KICK", true},
+		{"Kick_808.wav", "kick 808", true},
+		{"Kick_808.wav", "", true},
+		{"Kick_808.wav", "808kick", false},
+		{"Kick_808.wav", "snare", false},
+		{"drums/snare.wav", "dsn", true},
+		{"hat.wav", "hatt", false},
+	}
+
+	for _, tt := range tests {
+		if got := fuzzyMatch(tt.name, tt.query); got != tt.want {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, want %v", tt.name, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchMatch(t *testing.T) {
+	kick := &wavfile.WavFile{Name: "Kick_808.wav", Tags: []string{"drums", "808"}, Favorite: true}
+	pad := &wavfile.WavFile{Name: "pad.wav", Tags: []string{"synth"}}
+
+	tests := []struct {
+		query string
+		file  *wavfile.WavFile
+		want  bool
+	}{
+		{"", pad, true},
+		{"*", kick, true},
+		{"*", pad, false},
+		{"#dr", kick, true},
+		{"#DRUMS", kick, true},
+		{"#dr", pad, false},
+		{"#", pad, true},
+		{"* #808 kck", kick, true},
+		{"#808 pad", kick, false},
+		{"k 808", kick, true},
+		{"#synth pd", pad, true},
+	}
+
+	for _, tt := range tests {
+		if got := searchMatch(tt.file, tt.query); got != tt.want {
+			t.Errorf("searchMatch(%s, %q) = %v, want %v", tt.file.Name, tt.query, got, tt.want)
+		}
+	}
+}
+
+// testModel returns a model of the named files in banks of bankSize
+func testModel(bankSize int, names ...string) model {
+	files := make([]wavfile.WavFile, len(names))
+	for i, name := range names {
+		files[i] = wavfile.WavFile{Name: name}
+	}
+	return model{files: &files, banks: player.NewBanks(bankSize)}
+}
+
+func TestApplySearch(t *testing.T) {
+	tests := []struct {
+		name       string
+		cursor     int
+		corrupted  int
+		query      string
+		wantCursor int
+	}{
+		{"cursor on a match stays", 2, -1, "kick", 2},
+		{"cursor moves to the first match", 1, -1, "kick", 0},
+		{"corrupted matches are skipped", 1, 0, "kick", 2},
+		{"nothing matches", 1, -1, "zzz", -1},
+		{"clearing keeps the cursor", 1, -1, "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testModel(2, "kick.wav", "snare.wav", "kick2.wav", "hat.wav")
+			m.cursor = tt.cursor
+			if tt.corrupted >= 0 {
+				(*m.files)[tt.corrupted].Corrupted = true
+			}
+			m.applySearch(tt.query)
+			if m.cursor != tt.wantCursor {
+				t.Errorf("cursor = %d, want %d", m.cursor, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestMoveCursor(t *testing.T) {
+	names := []string{"d.wav", "b.wav", "c.wav", "a.wav", "e.wav"}
+	tests := []struct {
+		name       string
+		query      string
+		sortKey    string
+		cursor     int
+		direction  int
+		corrupted  int
+		wantCursor int
+	}{
+		{"down in the bank", "", "", 0, 1, -1, 1},
+		{"down into the next bank", "", "", 2, 1, -1, 3},
+		{"up into the previous bank", "", "", 3, -1, -1, 2},
+		{"stops at the end", "", "", 4, 1, -1, 4},
+		{"skips corrupted", "", "", 0, 1, 1, 2},
+		{"follows the sorted view", "", "n", 1, 1, -1, 2},
+		{"sorted view into the next bank", "", "n", 0, 1, -1, 3},
+		{"sorted view up into the previous bank", "", "n", 3, -1, -1, 0},
+		{"search skips non-matches", "#loop", "", 0, 1, -1, 3},
+		{"search stays in its matches", "#loop", "", 3, 1, -1, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testModel(3, names...)
+			(*m.files)[0].Tags = []string{"loop"}
+			(*m.files)[3].Tags = []string{"loop"}
+			if tt.sortKey != "" {
+				m.sortFiles(tt.sortKey)
+			}
+			if tt.corrupted >= 0 {
+				(*m.files)[tt.corrupted].Corrupted = true
+			}
+			m.search = tt.query
+			m.cursor = tt.cursor
+			m.moveCursor(tt.direction)
+			if m.cursor != tt.wantCursor {
+				t.Errorf("cursor = %d (%s), want %d (%s)", m.cursor, (*m.files)[m.cursor].Name, tt.wantCursor, (*m.files)[tt.wantCursor].Name)
+			}
+		})
+	}
+}
@@ -23,15 +23,20 @@ func Start(midiChannel chan midi.Message) (func(), error) {
 
 	// Listen for MIDI messages
 	stop, err := midi.ListenTo(in, func(msg midi.Message, timestampms int32) {
-		var channel, note, velocity uint8
+		var channel, note, velocity, controller, value uint8
 
 		switch {
 		case msg.GetNoteOn(&channel, &note, &velocity):
 			midiChannel <- msg
 		case msg.GetNoteOff(&channel, &note, &velocity):
 			midiChannel <- msg
+		case msg.GetControlChange(&channel, &controller, &value):
+			midiChannel <- msg
+		case msg.Is(midi.TimingClockMsg), msg.Is(midi.StartMsg), msg.Is(midi.ContinueMsg), msg.Is(midi.StopMsg):
+			// Clock and transport keep loops in time with an external sequencer
+			midiChannel <- msg
 		}
-	})
+	}, midi.UseTimeCode())
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to listen to MIDI input: %w", err)
@@ -39,6 +44,45 @@ func Start(midiChannel chan midi.Message) (func(), error) {
 	return stop, nil
 }
 
+// StartOut opens a virtual MIDI output port and returns a function that
+// sends messages to it
+func StartOut() (func(midi.Message) error, error) {
+	largestID := FindLargestSmplrMidiOutID()
+
+	driver, err := rtmididrv.New()
+	if err != nil {
+		return nil, fmt.Errorf("can't open MIDI driver: %w", err)
+	}
+	out, err := driver.OpenVirtualOut(fmt.Sprintf("smplr-midi-out-%d", largestID+1))
+	if err != nil {
+		return nil, fmt.Errorf("can't open virtual MIDI output port: %w", err)
+	}
+
+	send, err := midi.SendTo(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send to MIDI output: %w", err)
+	}
+	return send, nil
+}
+
+// FindLargestSmplrMidiOutID returns the largest ID of the smplr output
+// ports already open, which other programs see as input ports
+func FindLargestSmplrMidiOutID() int {
+	inports := midi.GetInPorts()
+	var largestSmplrID int
+	for _, inport := range inports {
+		name := inport.String()
+		var smplrID int
+		n, err := fmt.Sscanf(name, "smplr-midi-out-%d", &smplrID)
+		if err == nil && n == 1 {
+			if smplrID > largestSmplrID {
+				largestSmplrID = smplrID
+			}
+		}
+	}
+	return largestSmplrID
+}
+
 func FindLargestSmplrMidiID() int {
 	outports := midi.GetOutPorts()
 	var largestSmplrID int
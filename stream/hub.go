@@ -0,0 +1,113 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// Event types streamed to clients
+const (
+	EventPlaybackStarted  = "playback-started"
+	EventPlaybackFinished = "playback-finished"
+	EventDecibelLevel     = "decibel-level"
+	EventMarkers          = "markers"
+)
+
+// Event is one state change, sent to clients as a JSON text message
+type Event struct {
+	Type       string    `json:"type"`
+	Time       int64     `json:"time"` // Unix milliseconds, filled in by Publish
+	Sample     string    `json:"sample,omitempty"`
+	Level      *float32  `json:"level,omitempty"`  // dB, the louder side, for decibel-level
+	Levels     []float32 `json:"levels,omitempty"` // dB of the left and right sides, for decibel-level
+	StartFrame *int      `json:"startFrame,omitempty"`
+	EndFrame   *int      `json:"endFrame,omitempty"`
+}
+
+// clientBuffer is the number of events queued per client before events
+// are dropped for it
+const clientBuffer = 64
+
+// writeTimeout bounds how long a stalled client can hold up its writer
+const writeTimeout = 5 * time.Second
+
+// Hub streams events to every connected WebSocket client. Publishing never
+// blocks: a client that falls behind misses events rather than stalling
+// the UI.
+type Hub struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+}
+
+// NewHub creates a hub with no clients
+func NewHub() *Hub {
+	return &Hub{clients: map[chan []byte]struct{}{}}
+}
+
+// Publish sends an event to every client. It is a no-op on a nil hub, so
+// callers needn't check whether streaming is enabled.
+func (h *Hub) Publish(event Event) {
+	if h == nil {
+		return
+	}
+	event.Time = time.Now().UnixMilli()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		select {
+		case client <- payload:
+		default:
+		}
+	}
+}
+
+// ServeHTTP upgrades the request to a WebSocket and streams events to it
+// until the client goes away. Pages served from another origin are
+// refused, so a web page can't listen in.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Accept writes the error response itself
+	c, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer c.CloseNow()
+
+	out := make(chan []byte, clientBuffer)
+	h.mu.Lock()
+	h.clients[out] = struct{}{}
+	h.mu.Unlock()
+	defer h.remove(out)
+
+	// Clients only send control frames, which CloseRead answers. Its
+	// context ends when the client closes the connection.
+	ctx := c.CloseRead(r.Context())
+	for {
+		select {
+		case payload := <-out:
+			writeCtx, cancel := context.WithTimeout(ctx, writeTimeout)
+			err := c.Write(writeCtx, websocket.MessageText, payload)
+			cancel()
+			if err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *Hub) remove(out chan []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, out)
+}
@@ -1,15 +1,23 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"smplr/audio"
 	"smplr/mappings"
+	"smplr/player"
+	"smplr/stream"
 	"smplr/wavfile"
 
 	"github.com/charmbracelet/bubbles/viewport"
@@ -30,27 +38,249 @@ func waitForInterrupt() tea.Cmd {
 }
 
 type model struct {
-	files             *[]wavfile.WavFile
-	cursor            int
-	editing           bool
-	editField         string // "channel", "note", "pitch", or "filename"
-	editValue         string
-	recording         bool
-	recordingFilename string
-	decibelLevel      float32 // current recording level in dB
-	audio             audio.Audio
-	audioDevice       string // audio output device name
-	viewport          viewport.Model
-	ready             bool
-	windowWidth       int
-	markerStepSize    int    // number of frames to move marker with h/l
-	activeMarker      string // "start" or "end"
-	currentError      string // error message to display
-	logger            *log.Logger
-	renamingRecording bool // true when prompting for filename after recording
-}
-
-func initialModel(files *[]wavfile.WavFile, audio audio.Audio, audioDevice string) model {
+	files               *[]wavfile.WavFile
+	cursor              int
+	editing             bool
+	editField           string // "channel", "note", "pitch", "crossfade", or "filename"
+	editValue           string
+	recording           bool
+	recordingFilename   string
+	recordingFormat     string  // container extension for new recordings
+	recordSource        string  // "system", "output" or "input"
+	preRoll             float64 // seconds kept ahead of recordings while armed
+	armed               bool    // capturing the pre-roll until recording starts
+	normalizeRecordings bool    // peak-normalize each recording before naming it
+	recordingLength     recordingLength
+	recordingStarted    time.Time
+	recordingLimit      time.Duration // when the current recording stops by itself, 0 when it doesn't
+	punchTarget         int           // sample the current recording is punched into, -1 for a new file
+	punchMix            bool          // mix the punch-in take with the sample rather than replace it
+	replaceTarget       int           // sample whose file the current recording replaces, -1 for a new file
+	decibelLevels       [2]float32    // current recording level of the left and right sides in dB
+	levelHistory        [2][]float32  // loudest level of each side per levelHistoryInterval, oldest first
+	levelHistoryAt      time.Time     // when the newest history point started
+	audio               audio.Audio
+	audioDevice         string // audio output device name
+	viewport            viewport.Model
+	ready               bool
+	windowWidth         int
+	markerStepSize      int    // number of frames to move marker with h/l
+	activeMarker        string // "start" or "end"
+	currentError        string // error message to display
+	logger              *log.Logger
+	renamingRecording   bool // true when prompting for filename after recording
+	windowHeight        int
+	triggerHistory      *player.TriggerHistory
+	showTriggerHistory  bool
+	midiMonitor         *player.MidiMonitor
+	showMidiMonitor     bool
+	linkSource          int // index of the sample being chained from, -1 when not linking
+	markerCC            int // relative encoder CC that moves the active marker, -1 when disabled
+	markerSelectCC      int // CC that toggles the active marker, -1 when disabled
+	statusMessage       string
+	duplicateGroups     [][]string // duplicates awaiting consolidation
+	splitView           bool
+	otherKit            kitPane // second kit shown in split view
+	kitFocused          bool    // true when the second kit has the cursor
+	roller              *player.Roller
+	clock               *player.Clock // tempo of rolls and time-stretching
+	rollCC              int           // CC that rolls the last trigger, -1 when disabled
+	looper              *player.Looper
+	banks               *player.Banks // the list is shown and played a bank at a time
+	padGrid             bool          // show the bank as a grid of pads instead of a table
+	search              string        // fuzzy filter of the list, "" for none
+	sorting             *sampleSort   // order the list is shown in, nil for its own order
+	recursive           bool          // samples come from subdirectories too
+	importSymlink       bool          // imported samples are linked rather than copied
+	patternBars         int           // length of captured patterns
+	songText            string        // song last entered with ctrl+a, e.g. "1x4 2x2"
+	quantizeStrength    float64       // how far ctrl+q's grid pulls pattern notes, 0 to 1
+	headroomDB          float64
+	headroomVoices      int              // simultaneous hits the gain analysis plans for
+	maxVoices           int              // polyphony limit of the audio engine
+	gainSuggestions     []gainSuggestion // gain changes awaiting confirmation
+	ccMappings          []wavfile.CCMapping
+	ccLearnParam        wavfile.CCParam // parameter the next control change gets mapped to, "" when not learning
+	masterVolume        float32
+	limiter             bool                  // peak limiter on the master output
+	waveZoom            int                   // waveform magnification, 1 shows the whole file
+	waveStart           int                   // first frame the zoomed waveform shows
+	scrub               bool                  // preview the active marker every time it moves
+	stream              *stream.Hub           // WebSocket clients mirroring the state, nil when disabled
+	streamed            map[int]streamedState // state last streamed per sample, by player
+	performance         *player.Performance   // incoming notes, exported as a MIDI file
+	bounceFilename      string                // file the master output is bounced to, "" when not bouncing
+	selectedCue         int                   // cue last jumped to with ( or ), -1 when none
+	abPlayerId          int                   // player of the original A compares against, 0 when none
+	abSource            string                // file abPlayerId plays
+	abOriginal          bool                  // A last played the original rather than the edit
+	showHelp            bool                  // the help overlay covers the screen
+	helpScroll          int                   // rows the help overlay is scrolled down
+	count               int                   // count typed ahead of a marker move, 0 for none
+	layout              *screenLayout         // where the last view drew the list and waveform
+	dragFrame           int                   // frame a waveform drag started at, -1 when not dragging
+	savedSession        *savedSession         // what the session file last had written to it
+}
+
+// savedSession holds the session file contents last written, so saves that
+// change nothing skip the write. The model holds it by pointer since
+// saveSession has a value receiver.
+type savedSession struct {
+	data []byte
+}
+
+// streamedState is the state of a sample that WebSocket clients last saw
+type streamedState struct {
+	playing    bool
+	startFrame int
+	endFrame   int
+}
+
+// recordingNormalizeDB is the peak level --normalize-recordings brings
+// recordings to, leaving a little headroom like `smplr normalize`
+const recordingNormalizeDB = -1.0
+
+// levelHistorySize is the number of points the level sparklines keep, and
+// levelHistoryInterval the time each covers
+const (
+	levelHistorySize     = 24
+	levelHistoryInterval = 250 * time.Millisecond
+)
+
+// recordingTimeoutMsg is sent when a recording reaches --record-length
+type recordingTimeoutMsg struct {
+	filename string // recording the timer was started for
+}
+
+// duplicatesFoundMsg carries the result of a duplicate-content scan
+type duplicatesFoundMsg struct {
+	groups [][]string // groups of WavFile names with identical audio
+}
+
+// findDuplicates fingerprints the loaded files in the background
+func findDuplicates(files []wavfile.WavFile) tea.Cmd {
+	return func() tea.Msg {
+		// Fingerprint the WAV backing each entry, then map back to entry
+		// names. Duplicated slots share a file, which is fingerprinted once.
+		names := map[string]string{}
+		var sources []string
+		for _, file := range files {
+			if file.Metadata == nil || file.Corrupted {
+				continue
+			}
+			if _, seen := names[file.SourceFileName()]; seen {
+				continue
+			}
+			names[file.SourceFileName()] = file.Name
+			sources = append(sources, file.SourceFileName())
+		}
+
+		var groups [][]string
+		for _, group := range wavfile.FindDuplicates(sources) {
+			var named []string
+			for _, source := range group {
+				named = append(named, names[source])
+			}
+			groups = append(groups, named)
+		}
+		return duplicatesFoundMsg{groups: groups}
+	}
+}
+
+// consolidateDuplicates points every entry of each duplicate group at the
+// group's first file, as duplicated slots of it, so the other files are
+// no longer used. Each entry keeps its channel, note, settings and CC
+// mappings.
+func (m *model) consolidateDuplicates() {
+	keptNames := map[string]string{}
+	for _, group := range m.duplicateGroups {
+		for _, name := range group[1:] {
+			keptNames[name] = group[0]
+		}
+	}
+	kept := map[string]wavfile.WavFile{}
+	for _, file := range *m.files {
+		if _, ok := kept[file.Name]; !ok {
+			kept[file.Name] = file
+		}
+	}
+
+	// Every entry of another file becomes a new slot of the kept one, and
+	// chains and CC mappings follow it there
+	type slotKey struct {
+		name string
+		slot int
+	}
+	moved := map[slotKey]slotKey{}
+	nextSlots := map[string]int{}
+	for _, file := range *m.files {
+		name, ok := keptNames[file.Name]
+		if !ok {
+			continue
+		}
+		if _, ok := nextSlots[name]; !ok {
+			nextSlots[name] = wavfile.NextSlot(*m.files, name)
+		}
+		moved[slotKey{file.Name, file.Slot}] = slotKey{name, nextSlots[name]}
+		nextSlots[name]++
+	}
+	for i := range *m.files {
+		file := &(*m.files)[i]
+		if to, ok := moved[slotKey{file.ChainNext, file.ChainNextSlot}]; ok {
+			file.ChainNext, file.ChainNextSlot = to.name, to.slot
+		}
+	}
+	for i, mapping := range m.ccMappings {
+		if to, ok := moved[slotKey{mapping.Sample, mapping.Slot}]; ok {
+			m.ccMappings[i].Sample, m.ccMappings[i].Slot = to.name, to.slot
+		}
+	}
+
+	for i := range *m.files {
+		file := &(*m.files)[i]
+		to, ok := moved[slotKey{file.Name, file.Slot}]
+		if !ok {
+			continue
+		}
+		file.Slot = to.slot
+		if file.PlayerId != 0 {
+			m.audio.DestroyPlayer(file.PlayerId)
+			file.PlayerId = 0
+		}
+		file.PlayingCount = 0
+
+		source := kept[to.name]
+		file.Name = source.Name
+		file.DecodedFileName = source.DecodedFileName
+		file.Metadata = source.Metadata
+		file.Corrupted = source.Corrupted
+		// The audio matches, but keep the markers inside the file
+		if source.Metadata != nil && file.EndFrame >= source.Metadata.NumFrames {
+			file.EndFrame = source.Metadata.NumFrames - 1
+			file.StartFrame = max(min(file.StartFrame, file.EndFrame-1), 0)
+		}
+
+		playerId, err := m.audio.CreatePlayer(file.SourceFileName())
+		if err != nil {
+			file.Corrupted = true
+			m.SetCurrentError(fmt.Sprintf("Failed to load %s: %v", file.Name, err))
+			continue
+		}
+		file.PlayerId = playerId
+		if err := m.applyPlayerSettings(file); err != nil {
+			m.SetCurrentError(fmt.Sprintf("Failed to apply settings: %v", err))
+		}
+	}
+	m.duplicateGroups = nil
+}
+
+// triggerHistorySize is the number of trigger events kept for the history pane
+const triggerHistorySize = 50
+
+// midiMonitorSize is the number of MIDI messages kept for the monitor pane
+const midiMonitorSize = 50
+
+func initialModel(files *[]wavfile.WavFile, audio audio.Audio, settings settings) model {
 	vp := viewport.New(80, 10)
 	vp.YPosition = 0
 
@@ -65,98 +295,402 @@ func initialModel(files *[]wavfile.WavFile, audio audio.Audio, audioDevice strin
 	logger := log.New(logFile, "ERROR: ", log.LstdFlags)
 
 	return model{
-		files:             files,
-		cursor:            0,
-		editing:           false,
-		editField:         "",
-		editValue:         "",
-		recording:         false,
-		recordingFilename: "",
-		audio:             audio,
-		audioDevice:       audioDevice,
-		viewport:          vp,
-		ready:             false,
-		windowWidth:       80,
-		markerStepSize:    1,
-		activeMarker:      "start",
-		logger:            logger,
+		files:               files,
+		cursor:              0,
+		editing:             false,
+		editField:           "",
+		editValue:           "",
+		recording:           false,
+		recordingFilename:   "",
+		recordingFormat:     settings.recordingFormat,
+		recordSource:        settings.recordSource,
+		preRoll:             settings.preRoll,
+		normalizeRecordings: settings.normalizeRecordings,
+		recordingLength:     settings.recordingLength,
+		audio:               audio,
+		audioDevice:         settings.audioDevice,
+		viewport:            vp,
+		ready:               false,
+		windowWidth:         80,
+		markerStepSize:      1,
+		activeMarker:        "start",
+		logger:              logger,
+		triggerHistory:      player.NewTriggerHistory(triggerHistorySize),
+		midiMonitor:         player.NewMidiMonitor(midiMonitorSize),
+		linkSource:          -1,
+		selectedCue:         -1,
+		punchTarget:         -1,
+		replaceTarget:       -1,
+		markerCC:            settings.markerCC,
+		markerSelectCC:      settings.markerSelectCC,
+		roller:              settings.roller,
+		clock:               settings.clock,
+		rollCC:              settings.rollCC,
+		looper:              settings.looper,
+		banks:               settings.banks,
+		recursive:           settings.recursive,
+		importSymlink:       settings.importSymlink,
+		patternBars:         settings.patternBars,
+		quantizeStrength:    settings.quantizeStrength,
+		headroomDB:          settings.headroomDB,
+		headroomVoices:      settings.headroomVoices,
+		maxVoices:           settings.maxVoices,
+		ccMappings:          settings.ccMappings,
+		masterVolume:        1,
+		limiter:             true,
+		waveZoom:            1,
+		stream:              settings.stream,
+		streamed:            map[int]streamedState{},
+		layout:              &screenLayout{waveTop: -1},
+		savedSession:        &savedSession{},
+		dragFrame:           -1,
+		performance:         settings.performance,
+	}
+}
+
+// applyPlayerSettings sends a file's stereo width, gain, envelope and loop
+// mode to its player. Players start at full width, unity gain, no envelope
+// and one-shot, so this must follow every CreatePlayer for a file that
+// changes any of them.
+func (m *model) applyPlayerSettings(file *wavfile.WavFile) error {
+	if file.PlayerId == 0 {
+		return nil
+	}
+	if file.StereoWidth != 100 {
+		if err := m.audio.SetStereoWidth(file.PlayerId, float32(file.StereoWidth)/100); err != nil {
+			return err
+		}
+	}
+	if file.GainDB != 0 {
+		if err := m.audio.SetGain(file.PlayerId, float32(wavfile.DBToAmplitude(file.GainDB))); err != nil {
+			return err
+		}
+	}
+	if file.Pan != 0 {
+		if err := m.audio.SetPan(file.PlayerId, float32(file.Pan)/100); err != nil {
+			return err
+		}
+	}
+	if file.HasEnvelope() {
+		if err := m.audio.SetEnvelope(file.PlayerId, envelopeFor(file)); err != nil {
+			return err
+		}
+	}
+	if file.Reverse {
+		if err := m.audio.SetReverse(file.PlayerId, true); err != nil {
+			return err
+		}
+	}
+	if file.ChannelPair != 0 {
+		if err := m.audio.SetChannelPair(file.PlayerId, file.ChannelPair); err != nil {
+			return err
+		}
+	}
+	if file.Loop {
+		return m.audio.SetLoop(file.PlayerId, true)
 	}
+	return nil
 }
 
-// handlePitchChange handles offline rendering when pitch changes
-func (m *model) handlePitchChange(fileIndex int, newPitch int) error {
-	file := &(*m.files)[fileIndex]
+// setEnvelope parses "attack decay sustain release" (ms, ms, %, ms) and
+// applies it to the selected file
+func (m *model) setEnvelope(value string) {
+	var attack, decay, sustain, release int
+	if n, _ := fmt.Sscanf(value, "%d %d %d %d", &attack, &decay, &sustain, &release); n != 4 {
+		m.SetCurrentError("Envelope needs four values: attack decay sustain release")
+		return
+	}
+	if attack < 0 || decay < 0 || release < 0 || sustain < 0 || sustain > 100 {
+		m.SetCurrentError("Envelope times must be positive and sustain 0-100%")
+		return
+	}
+
+	file := &(*m.files)[m.cursor]
+	file.AttackMs = attack
+	file.DecayMs = decay
+	file.SustainLevel = sustain
+	file.ReleaseMs = release
+	if file.PlayerId != 0 {
+		if err := m.audio.SetEnvelope(file.PlayerId, envelopeFor(file)); err != nil {
+			m.SetCurrentError(fmt.Sprintf("Failed to set envelope: %v", err))
+		}
+	}
+}
 
-	// Check if original file exists
-	if _, err := os.Stat(file.Name); os.IsNotExist(err) {
-		return fmt.Errorf("file does not exist: %s", file.Name)
+// fadeFile applies the fade-in and fade-out lengths in ms given as
+// "in out" to the selected file's region and reloads it
+func (m *model) fadeFile(value string) {
+	var fadeInMs, fadeOutMs int
+	if n, _ := fmt.Sscanf(value, "%d %d", &fadeInMs, &fadeOutMs); n != 2 {
+		m.SetCurrentError("Fade needs two values: fade-in ms and fade-out ms")
+		return
+	}
+	if fadeInMs < 0 || fadeOutMs < 0 {
+		m.SetCurrentError("Fade lengths must be positive")
+		return
 	}
 
-	// Generate pitched filename
-	pitchedFilename := wavfile.GeneratePitchedFilename(file.Name, newPitch)
+	file := &(*m.files)[m.cursor]
+	framesPerMs := float64(file.Metadata.SampleRate) / 1000
+	fadeInFrames := int(float64(fadeInMs) * framesPerMs)
+	fadeOutFrames := int(float64(fadeOutMs) * framesPerMs)
+	if !m.backupFile(file.Name) {
+		return
+	}
+	if err := wavfile.FadeFile(file.Name, file.StartFrame, file.EndFrame, fadeInFrames, fadeOutFrames); err != nil {
+		m.SetCurrentError(fmt.Sprintf("Failed to fade %s: %v", file.Name, err))
+		return
+	}
+	// The length is unchanged, so the markers stay
+	m.reloadEditedFile(m.cursor)
+	m.statusMessage = fmt.Sprintf("Faded %s in over %d ms and out over %d ms", file.Name, fadeInMs, fadeOutMs)
+}
 
-	// If pitch is 0, use original file
-	if newPitch == 0 {
-		file.PitchedFileName = ""
+// cueAtMarker returns the index of the selected file's cue within half a
+// marker step of the active marker, or -1 when there's none
+func (m model) cueAtMarker() int {
+	file := (*m.files)[m.cursor]
+	if file.Metadata == nil {
+		return -1
+	}
+	marker := m.activeMarkerFrame()
+	for i, cue := range file.Metadata.Cues {
+		if max(cue.Frame-marker, marker-cue.Frame) <= m.markerStepSize/2 {
+			return i
+		}
+	}
+	return -1
+}
 
-		// Recreate player with original file
-		if file.PlayerId != 0 {
-			m.audio.DestroyPlayer(file.PlayerId)
+// setCue names the cue under the active marker, adding one when there's
+// none, and removes it when the name is empty
+func (m *model) setCue(label string) {
+	file := &(*m.files)[m.cursor]
+	cues := slices.Clone(file.Metadata.Cues)
+	label = strings.TrimSpace(label)
+	index := m.cueAtMarker()
+	switch {
+	case index < 0:
+		cue := wavfile.CuePoint{Frame: m.activeMarkerFrame(), Label: label}
+		cues = append(cues, cue)
+		if m.writeCues(cues) {
+			m.selectedCue = slices.Index(file.Metadata.Cues, cue)
+			m.statusMessage = fmt.Sprintf("Added cue at frame %d of %s", cue.Frame, file.Name)
 		}
-		playerID, err := m.audio.CreatePlayer(file.Name)
-		if err != nil {
-			return fmt.Errorf("failed to recreate player: %w", err)
+	case label == "":
+		cues = slices.Delete(cues, index, index+1)
+		if m.writeCues(cues) {
+			m.selectedCue = -1
+			m.statusMessage = fmt.Sprintf("Removed cue from %s", file.Name)
+		}
+	default:
+		cues[index].Label = label
+		if m.writeCues(cues) {
+			m.selectedCue = index
+			m.statusMessage = fmt.Sprintf("Renamed cue to %q", label)
 		}
-		file.PlayerId = playerID
-
-		return nil
 	}
+}
 
-	// Check if pitched file already exists
-	if !wavfile.PitchedFileExists(pitchedFilename) {
-		// Render pitched file
-		cents := float32(newPitch * 100)
+// moveCue moves the selected cue to the active marker
+func (m *model) moveCue() {
+	file := &(*m.files)[m.cursor]
+	if m.selectedCue < 0 || m.selectedCue >= len(file.Metadata.Cues) {
+		m.SetCurrentError("Jump to a cue with ( or ) before moving it")
+		return
+	}
+	cues := slices.Clone(file.Metadata.Cues)
+	cues[m.selectedCue].Frame = m.activeMarkerFrame()
+	cue := cues[m.selectedCue]
+	if m.writeCues(cues) {
+		m.selectedCue = slices.Index(file.Metadata.Cues, cue)
+		m.statusMessage = fmt.Sprintf("Moved cue to frame %d", cue.Frame)
+	}
+}
 
-		err := m.audio.RenderPitchedFile(file.Name, pitchedFilename, cents)
+// writeCues writes the selected file's cues back to its cue chunk in
+// frame order, reporting whether it succeeded
+func (m *model) writeCues(cues []wavfile.CuePoint) bool {
+	file := &(*m.files)[m.cursor]
+	slices.SortStableFunc(cues, func(a, b wavfile.CuePoint) int { return a.Frame - b.Frame })
+	if err := wavfile.WriteCues(file.Name, cues); err != nil {
+		m.SetCurrentError(fmt.Sprintf("Failed to write cues to %s: %v", file.Name, err))
+		return false
+	}
+	file.Metadata.Cues = cues
+	return true
+}
 
-		if err != nil {
-			return fmt.Errorf("failed to render pitched file: %w", err)
+// jumpToCue moves the active marker to the selected file's previous
+// (direction -1) or next (direction 1) cue and selects that cue
+func (m *model) jumpToCue(direction int) {
+	file := &(*m.files)[m.cursor]
+	marker := m.activeMarkerFrame()
+	target := -1
+	for i, cue := range file.Metadata.Cues {
+		if direction < 0 && cue.Frame < marker {
+			target = i
+		} else if direction > 0 && cue.Frame > marker {
+			target = i
+			break
 		}
 	}
+	if target < 0 {
+		m.statusMessage = "No more cues"
+		return
+	}
 
-	// Update PitchedFileName
-	file.PitchedFileName = pitchedFilename
+	cue := file.Metadata.Cues[target]
+	file.MoveMarker(m.activeMarker, 1, cue.Frame-marker)
+	m.selectedCue = target
+	m.followMarker()
+	if m.scrub {
+		m.previewMarker()
+	}
+	if cue.Label != "" {
+		m.statusMessage = fmt.Sprintf("Cue %q at frame %d", cue.Label, cue.Frame)
+	} else {
+		m.statusMessage = fmt.Sprintf("Cue at frame %d", cue.Frame)
+	}
+}
 
-	// Recreate player with pitched file
-	if file.PlayerId != 0 {
-		m.audio.DestroyPlayer(file.PlayerId)
+// stretchFile renders the selected file's region time-stretched from its
+// estimated tempo to the target BPM and adds the result to the list
+func (m *model) stretchFile(value string) {
+	targetBPM, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || targetBPM < 20 || targetBPM > 400 {
+		m.SetCurrentError("Target tempo must be 20-400 BPM")
+		return
+	}
+
+	file := (*m.files)[m.cursor]
+	sourceBPM := regionBPM(&file)
+	if sourceBPM <= 0 {
+		m.SetCurrentError(fmt.Sprintf("Can't estimate the tempo of %s", file.Name))
+		return
+	}
+	target := wavfile.StretchedFilename(file.Name, targetBPM)
+	if target == file.Name {
+		m.SetCurrentError(fmt.Sprintf("%s is already at %g BPM", file.Name, targetBPM))
+		return
+	}
+	if _, err := os.Stat(target); err == nil {
+		m.SetCurrentError(fmt.Sprintf("%s already exists", target))
+		return
+	}
+	// The end marker is the last frame of the region
+	if err := m.audio.StretchFile(file.SourceFileName(), target, file.StartFrame, file.EndFrame+1, sourceBPM/targetBPM); err != nil {
+		m.SetCurrentError(fmt.Sprintf("Failed to stretch %s: %v", file.Name, err))
+		return
 	}
+	if err := m.appendFile(target); err != nil {
+		m.SetCurrentError(fmt.Sprintf("Failed to load %s: %v", target, err))
+		return
+	}
+	m.statusMessage = fmt.Sprintf("Stretched %s from %.1f to %.1f BPM", file.Name, sourceBPM, targetBPM)
+}
 
-	playerID, err := m.audio.CreatePlayer(pitchedFilename)
+// detectPitch runs the tuner on a file's region, storing the fundamental
+// on the file. It reports whether a pitch was found.
+func (m *model) detectPitch(file *wavfile.WavFile) bool {
+	if file.Metadata == nil || file.Corrupted {
+		return false
+	}
+	hz, err := wavfile.DetectPitch(file.SourceFileName(), file.StartFrame, file.EndFrame)
 	if err != nil {
-		return fmt.Errorf("failed to create player for pitched file: %w", err)
+		file.DetectedHz = 0
+		m.SetCurrentError(fmt.Sprintf("Failed to detect the pitch of %s: %v", file.Name, err))
+		return false
 	}
-	file.PlayerId = playerID
+	file.DetectedHz = hz
+	return true
+}
 
-	return nil
+// regionBPM estimates the tempo of the loop between a file's markers
+func regionBPM(file *wavfile.WavFile) float64 {
+	return wavfile.EstimateBPM(file.Name, file.EndFrame-file.StartFrame+1, file.Metadata.SampleRate)
+}
+
+// waveformMaxZoom is the deepest waveform zoom
+const waveformMaxZoom = 64
+
+// scrubWindowMs is the length of the preview played around a moved marker
+const scrubWindowMs = 200
+
+// envelopeFor converts a file's envelope settings for the audio engine
+func envelopeFor(file *wavfile.WavFile) audio.Envelope {
+	return audio.Envelope{
+		AttackMs:  file.AttackMs,
+		DecayMs:   file.DecayMs,
+		Sustain:   float32(file.SustainLevel) / 100,
+		ReleaseMs: file.ReleaseMs,
+	}
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	updated, cmd := m.update(msg)
+	next := updated.(model)
+	// Status lines come and go with any message, so the list is resized
+	// to keep the view within the window
+	if next.ready {
+		next.layoutViewport()
+	}
+	next.publishChanges()
+	return next, cmd
+}
+
+// publishChanges streams the playback and marker changes since the last
+// message to WebSocket clients
+func (m model) publishChanges() {
+	if m.stream == nil {
+		return
+	}
+	for _, file := range *m.files {
+		// Keyed by player since duplicated slots share a name; samples
+		// without one can't play yet
+		if file.PlayerId == 0 {
+			continue
+		}
+		playing := file.PlayingCount > 0
+		last, seen := m.streamed[file.PlayerId]
+		if playing != last.playing {
+			eventType := stream.EventPlaybackFinished
+			if playing {
+				eventType = stream.EventPlaybackStarted
+			}
+			m.stream.Publish(stream.Event{Type: eventType, Sample: file.Name})
+		}
+		if seen && (file.StartFrame != last.startFrame || file.EndFrame != last.endFrame) {
+			startFrame, endFrame := file.StartFrame, file.EndFrame
+			m.stream.Publish(stream.Event{Type: stream.EventMarkers, Sample: file.Name, StartFrame: &startFrame, EndFrame: &endFrame})
+		}
+		m.streamed[file.PlayerId] = streamedState{playing: playing, startFrame: file.StartFrame, endFrame: file.EndFrame}
+	}
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case interruptMsg:
+		m.saveSession()
 		// Clean up recording if active
 		if m.recording {
-			m.audio.StopRecording()
+			m.stopCapture()
 			// Remove the partial recording file
 			if m.recordingFilename != "" {
 				os.Remove(m.recordingFilename)
 			}
+		} else if m.armed {
+			m.audio.Disarm()
+		}
+		// Keep the bounce so far
+		if m.bounceFilename != "" {
+			m.audio.StopBounce()
 		}
 		return m, tea.Quit
 
 	case wavfile.PlaybackStartedMsg:
 		for i := range *m.files {
-			if (*m.files)[i].Name == msg.Filename {
+			if (*m.files)[i].PlayerId == msg.PlayerId {
 				(*m.files)[i].PlayingCount++
 				break
 			}
@@ -164,7 +698,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case wavfile.PlaybackFinishedMsg:
 		for i := range *m.files {
-			if (*m.files)[i].Name == msg.Filename {
+			if (*m.files)[i].PlayerId == msg.PlayerId {
 				if (*m.files)[i].PlayingCount > 0 {
 					(*m.files)[i].PlayingCount--
 				}
@@ -172,42 +706,83 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 		return m, nil
+	case recordingTimeoutMsg:
+		// A recording stopped by hand in the meantime leaves a stale timer
+		if m.recording && m.recordingFilename == msg.filename {
+			punching := m.punchTarget >= 0
+			m.stopRecording()
+			if !punching {
+				m.statusMessage = fmt.Sprintf("Recording stopped after %.1fs", m.recordingLimit.Seconds())
+			}
+		}
+		return m, nil
 	case DecibelLevelMsg:
-		m.decibelLevel = msg.Level
+		m.decibelLevels = [2]float32{msg.Left, msg.Right}
+		m.addLevelHistory()
+		level := max(msg.Left, msg.Right)
+		m.stream.Publish(stream.Event{Type: stream.EventDecibelLevel, Level: &level, Levels: m.decibelLevels[:]})
 		return m, nil
 	case wavfile.MetadataLoadedMsg:
-		// Find the WavFile with matching name
+		if msg.Partial {
+			// Show the waveform read so far; the file stays loading
+			for i := range *m.files {
+				if (*m.files)[i].Name == msg.Filename && (*m.files)[i].Loading {
+					(*m.files)[i].PartialMetadata = msg.Metadata
+				}
+			}
+			return m, nil
+		}
+
+		// Find the WavFiles with matching name; duplicated slots share the file
+		// but each gets its own player
 		for i := range *m.files {
 			if (*m.files)[i].Name == msg.Filename {
 				(*m.files)[i].Loading = false
+				(*m.files)[i].PartialMetadata = nil
 
 				if msg.Err != nil {
 					// Mark file as corrupted
 					(*m.files)[i].Corrupted = true
-					break
+					continue
 				}
 
 				// Attach metadata
 				(*m.files)[i].Metadata = msg.Metadata
-				// Set EndFrame to the end of the file
+				(*m.files)[i].DecodedFileName = msg.DecodedFilename
+				// Set EndFrame to the end of the file unless a saved marker still fits
 				if msg.Metadata != nil {
-					(*m.files)[i].EndFrame = msg.Metadata.NumFrames - 1
+					file := &(*m.files)[i]
+					// Files without saved markers start on their smpl loop
+					if loops := msg.Metadata.Loops; file.EndFrame <= 0 && len(loops) > 0 && loops[0].End > loops[0].Start {
+						file.StartFrame, file.EndFrame = loops[0].Start, loops[0].End
+						file.Loop = true
+					}
+					if file.EndFrame <= 0 || file.EndFrame >= msg.Metadata.NumFrames {
+						file.EndFrame = msg.Metadata.NumFrames - 1
+					}
+					if file.StartFrame >= file.EndFrame {
+						file.StartFrame = 0
+					}
 				}
 
 				// Create player and load buffer for low-latency playback
-				playerId, err := m.audio.CreatePlayer((*m.files)[i].Name)
+				playerId, err := m.audio.CreatePlayer((*m.files)[i].SourceFileName())
 				if err != nil {
 					// Mark as corrupted if player creation fails
 					(*m.files)[i].Corrupted = true
-					break
+					continue
 				}
 				(*m.files)[i].PlayerId = playerId
 
+				// Restore saved player settings
+				if err := m.applyPlayerSettings(&(*m.files)[i]); err != nil {
+					m.SetCurrentError(fmt.Sprintf("Failed to restore player settings: %v", err))
+				}
+
 				// Update marker step size if this is the currently selected file
 				if i == m.cursor {
 					m.updateMarkerStepSize()
 				}
-				break
 			}
 		}
 
@@ -245,40 +820,172 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return m, nil
 
-	case tea.WindowSizeMsg:
-		headerHeight := 2    // header line + separator
-		footerHeight := 1    // blank line after viewport
-		recordingHeight := 1 // recording status (if shown)
-		waveformHeight := 8  // blank line + info bar + 4 lines of braille + marker line + frame number
-		reservedHeight := headerHeight + footerHeight + recordingHeight + waveformHeight
+	case player.TriggerMsg:
+		m.triggerHistory.Add(msg)
+		return m, nil
+
+	case player.PlaybackErrorMsg:
+		m.SetCurrentError(fmt.Sprintf("Error playing %s: %v", msg.Sample, msg.Err))
+		return m, nil
+
+	case player.MidiEventMsg:
+		m.midiMonitor.Add(msg)
+		return m, nil
 
-		viewportHeight := msg.Height - reservedHeight
-		if viewportHeight < 3 {
-			viewportHeight = 3 // minimum height
+	case apiRequestMsg:
+		cmd := m.handleAPIRequest(msg)
+		m.saveSession()
+		return m, cmd
+
+	case player.ControlChangeMsg:
+		m.handleControlChange(msg)
+		m.saveSession()
+		return m, nil
+
+	case fileChangedMsg:
+		m.applyFileChange(msg.filename)
+		return m, nil
+
+	case player.BankSelectMsg:
+		if !m.recording && msg.Bank < m.banks.Count(len(*m.files)) {
+			m.selectBank(msg.Bank)
 		}
+		return m, nil
 
-		if !m.ready {
-			m.viewport = viewport.New(msg.Width, viewportHeight)
-			m.viewport.YPosition = 0
-			m.ready = true
-		} else {
-			m.viewport.Width = msg.Width
-			m.viewport.Height = viewportHeight
+	case duplicatesFoundMsg:
+		if len(msg.groups) == 0 {
+			m.statusMessage = "No duplicates found"
+			return m, nil
+		}
+		m.statusMessage = ""
+		m.duplicateGroups = msg.groups
+		m.editing = true
+		m.editField = "consolidate"
+		m.editValue = ""
+		return m, nil
+
+	case gainAnalysisMsg:
+		if len(msg.suggestions) == 0 {
+			m.statusMessage = fmt.Sprintf("Gain staging OK: %d voices fit in %.1f dB of headroom", m.headroomVoices, m.headroomDB)
+			return m, nil
 		}
+		m.statusMessage = ""
+		m.gainSuggestions = msg.suggestions
+		m.editing = true
+		m.editField = "gainstaging"
+		m.editValue = ""
+		return m, nil
+
+	case tea.WindowSizeMsg:
 		m.windowWidth = msg.Width
+		m.windowHeight = msg.Height
+		m.layoutViewport()
 		// Update marker step size when window width changes
 		m.updateMarkerStepSize()
 
+	case tea.MouseMsg:
+		if !m.showHelp {
+			m.handleMouse(msg)
+			// Drags send a motion event per cell, so save once they end
+			if msg.Action == tea.MouseActionRelease {
+				m.saveSession()
+			}
+		}
+		return m, nil
+
 	case tea.KeyMsg:
+		if m.showHelp && msg.String() != "ctrl+c" {
+			m.scrollHelp(msg.String())
+			return m, nil
+		}
 		mapping := mappings.ProcessKey(msg, m.editing)
+		var updated tea.Model
+		var cmd tea.Cmd
 		if m.editing {
-			return m.handleEditingInput(mapping)
+			updated, cmd = m.handleEditingInput(mapping)
+		} else {
+			updated, cmd = m.handleNavigationInput(mapping)
 		}
-		return m.handleNavigationInput(mapping)
+		updated.(model).saveSession()
+		return updated, cmd
 	}
 	return m, nil
 }
 
+// scrollHelp handles a key while the help overlay is open: ?, Esc and q
+// close it and the cursor keys scroll it
+func (m *model) scrollHelp(key string) {
+	page := helpPageHeight(m.windowHeight)
+	last := helpRows(m.windowWidth, m.windowHeight) - page
+	switch key {
+	case "?", "esc", "q":
+		m.showHelp = false
+	case "down", "j":
+		m.helpScroll++
+	case "up", "k":
+		m.helpScroll--
+	case "pgdown", " ":
+		m.helpScroll += page
+	case "pgup":
+		m.helpScroll -= page
+	}
+	m.helpScroll = max(0, min(m.helpScroll, last))
+}
+
+// saveSession writes the session file when anything it keeps has changed
+// since the last save, so handlers can call it after every change without
+// rewriting the file for cursor moves and the like
+func (m model) saveSession() {
+	data, err := wavfile.EncodeSession(*m.files, m.ccMappings, m.recursive)
+	if err != nil {
+		m.logger.Printf("Failed to save session: %v", err)
+		return
+	}
+	if bytes.Equal(data, m.savedSession.data) {
+		return
+	}
+	if err := wavfile.WriteSession(wavfile.SessionFileName, data); err != nil {
+		m.logger.Printf("Failed to save session: %v", err)
+		return
+	}
+	m.savedSession.data = data
+}
+
+// layoutViewport sizes the file list viewport to the space left over by
+// the header, the status lines and panes, and the waveform
+func (m *model) layoutViewport() {
+	headerHeight := 2 // header line + separator
+	footerHeight := 1 // blank line after viewport
+	statusHeight := strings.Count(m.renderStatus(), "\n")
+	waveformHeight := 0
+	if !m.recording && m.cursor >= 0 && m.cursor < len(*m.files) {
+		waveformHeight = 9 // blank line + info bar + 4 lines of braille + marker line + cue names + frame number
+		if (*m.files)[m.cursor].TagLabel() != "" {
+			waveformHeight++
+		}
+	}
+	reservedHeight := headerHeight + footerHeight + statusHeight + waveformHeight
+
+	viewportHeight := m.windowHeight - reservedHeight
+	if viewportHeight < 3 {
+		viewportHeight = 3 // minimum height
+	}
+
+	viewportWidth := m.windowWidth
+	if m.splitView {
+		viewportWidth = m.windowWidth / 2
+	}
+
+	if !m.ready {
+		m.viewport = viewport.New(viewportWidth, viewportHeight)
+		m.viewport.YPosition = 0
+		m.ready = true
+	} else {
+		m.viewport.Width = viewportWidth
+		m.viewport.Height = viewportHeight
+	}
+}
+
 func (m model) Init() tea.Cmd {
 	return waitForInterrupt()
 }
@@ -289,8 +996,14 @@ func (m *model) scrollToSelection() {
 	}
 
 	// Calculate the line number in the viewport content
-	// Since header is outside viewport, cursor line is just the cursor index
-	cursorLine := m.cursor
+	// Since header is outside viewport, the cursor line is its place among
+	// the samples listed
+	m.banks.Select(m.bank())
+	start, _ := m.bankRange()
+	cursorLine := max(slices.Index(m.shownFiles(), m.cursor), 0)
+	if m.padGrid {
+		cursorLine = padRow(m.cursor-start, m.banks.Size()) * padRowHeight
+	}
 
 	// Ensure the cursor is visible in the viewport
 	if cursorLine < m.viewport.YOffset {
@@ -308,6 +1021,134 @@ func (m *model) scrollToSelection() {
 
 	// Update marker step size to move by one character
 	m.updateMarkerStepSize()
+	m.followMarker()
+	m.selectedCue = -1
+}
+
+// bank returns the bank the cursor is in, or the selected bank while
+// nothing is selected
+func (m model) bank() int {
+	if m.cursor >= 0 {
+		return m.cursor / m.banks.Size()
+	}
+	return m.banks.Current()
+}
+
+// bankRange returns the index of the first sample in the cursor's bank and
+// the index past its last
+func (m model) bankRange() (int, int) {
+	start := min(m.bank()*m.banks.Size(), len(*m.files))
+	return start, min(start+m.banks.Size(), len(*m.files))
+}
+
+// selectBank moves the cursor to the same slot in another bank, or the
+// bank's last sample when it is shorter
+func (m *model) selectBank(bank int) {
+	if len(*m.files) == 0 || bank < 0 || bank >= m.banks.Count(len(*m.files)) {
+		return
+	}
+	slot := max(m.cursor, 0) % m.banks.Size()
+	m.cursor = min(bank*m.banks.Size()+slot, len(*m.files)-1)
+	m.scrollToSelection()
+	m.statusMessage = fmt.Sprintf("Bank %s", player.BankLabel(bank))
+}
+
+// appendFile adds a file to the end of the list on the next free MIDI note,
+// loads its metadata and player, and selects it
+func (m *model) appendFile(filename string) error {
+	// Find the largest midi note and add 1
+	maxNote := wavfile.FindMaxMidiNote((*m.files))
+	metadata, decoded, err := wavfile.LoadMetadata(filename, m.audio.ConvertFile, nil)
+	if err != nil {
+		metadata = nil
+	}
+	endFrame := 0
+	if metadata != nil {
+		endFrame = metadata.NumFrames - 1
+	}
+	*m.files = append(*m.files, wavfile.WavFile{
+		Name:            filename,
+		Slot:            wavfile.NextSlot(*m.files, filename),
+		DecodedFileName: decoded,
+		MidiChannel:     1,
+		MidiNote:        maxNote + 1,
+		StereoWidth:     100,
+		SustainLevel:    100,
+		StartFrame:      0,
+		EndFrame:        endFrame,
+		Metadata:        metadata,
+		Loading:         false,
+		Corrupted:       metadata == nil,
+	})
+	// Select the newly added file
+	m.cursor = len(*m.files) - 1
+	m.scrollToSelection() // This will call updateMarkerStepSize()
+
+	if metadata == nil {
+		return fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	playerId, err := m.audio.CreatePlayer((*m.files)[m.cursor].SourceFileName())
+	if err != nil {
+		(*m.files)[m.cursor].Corrupted = true
+		return err
+	}
+	(*m.files)[m.cursor].PlayerId = playerId
+
+	//NOTE: Ensure audio is started in case this is the first file
+	return m.audio.Start(m.audioDevice)
+}
+
+// duplicateFile adds a copy of the selected sample on the next free note
+// and selects it. The copy plays the same file through its own player, so
+// it can have its own markers, pitch and settings.
+func (m *model) duplicateFile() error {
+	file := (*m.files)[m.cursor]
+	file.MidiNote = wavfile.FindMaxMidiNote(*m.files) + 1
+	file.Slot = wavfile.NextSlot(*m.files, file.Name)
+	file.PlayerId = 0
+	file.PlayingCount = 0
+	*m.files = append(*m.files, file)
+	m.cursor = len(*m.files) - 1
+	m.scrollToSelection()
+
+	// A file still loading gets its players when its metadata arrives
+	if file.Loading || file.Corrupted {
+		return nil
+	}
+	playerId, err := m.audio.CreatePlayer(file.SourceFileName())
+	if err != nil {
+		(*m.files)[m.cursor].Corrupted = true
+		return err
+	}
+	(*m.files)[m.cursor].PlayerId = playerId
+	return m.applyPlayerSettings(&(*m.files)[m.cursor])
+}
+
+// copies returns the number of slots in the list that play the named file
+func (m model) copies(name string) int {
+	count := 0
+	for _, file := range *m.files {
+		if file.Name == name {
+			count++
+		}
+	}
+	return count
+}
+
+// deleteFile removes the selected sample and its CC mappings from the list
+// and moves its file to the trash, unless a duplicated slot still plays the
+// file
+func (m *model) deleteFile() error {
+	file := (*m.files)[m.cursor]
+	if m.copies(file.Name) == 1 {
+		if err := wavfile.MoveToTrash(file.Name); err != nil {
+			return err
+		}
+	}
+	m.ccMappings = wavfile.RemoveCCMappings(m.ccMappings, file.Name, file.Slot)
+	m.removeFile(m.cursor)
+	return nil
 }
 
 // adjustCursorToValidFile adjusts the cursor to point to a valid non-corrupted file
@@ -361,9 +1202,10 @@ func (m *model) updateMarkerStepSize() {
 
 	metadata := (*m.files)[m.cursor].Metadata
 
-	// Calculate frames per character: each character position represents NumFrames/width frames
-	// This makes h/l move the marker by one character width
-	framesPerChar := metadata.NumFrames / m.windowWidth
+	// Calculate frames per character: each character position represents the visible frames/width
+	// This makes h/l move the marker by one character width at any zoom
+	_, visibleFrames := m.waveWindow(metadata.NumFrames)
+	framesPerChar := visibleFrames / m.windowWidth
 	if framesPerChar < 1 {
 		framesPerChar = 1
 	}
@@ -371,131 +1213,513 @@ func (m *model) updateMarkerStepSize() {
 	m.markerStepSize = framesPerChar
 }
 
-func (m *model) moveMarker(direction int) {
-	if m.cursor < 0 || m.cursor >= len((*m.files)) {
+// handleControlChange applies MIDI controller input to the marker editor
+func (m *model) handleControlChange(msg player.ControlChangeMsg) {
+	if m.ccLearnParam != "" {
+		m.learnControlChange(msg)
 		return
 	}
 
-	(*m.files)[m.cursor].MoveMarker(m.activeMarker, direction, m.markerStepSize)
-}
-
-func (m model) handleEditingInput(mapping mappings.Mapping) (tea.Model, tea.Cmd) {
-	switch mapping.Command {
-	case mappings.Enter:
-		// Save the edited value
-		if m.editValue != "" {
-			var value int
-			fmt.Sscanf(m.editValue, "%d", &value)
+	// Learned mappings take precedence over the built-in controllers
+	if i := wavfile.FindCCMapping(m.ccMappings, msg.Channel, msg.Controller); i >= 0 {
+		m.applyCCMapping(m.ccMappings[i], msg.Value)
+		return
+	}
+
+	if msg.Controller == m.rollCC {
+		m.rollLastTrigger(player.RollSubdivisionForValue(msg.Value))
+		return
+	}
+
+	if m.recording || m.editing {
+		return
+	}
+
+	switch msg.Controller {
+	case m.markerCC:
+		// Relative encoder: 1-63 turns right, 65-127 turns left (two's complement)
+		steps := msg.Value
+		if steps >= 64 {
+			steps -= 128
+		}
+		if steps != 0 {
+			m.moveMarker(steps)
+		}
+	case m.markerSelectCC:
+		// Toggle on button press, ignore the release
+		if msg.Value >= 64 {
+			if m.activeMarker == "start" {
+				m.activeMarker = "end"
+			} else {
+				m.activeMarker = "start"
+			}
+		}
+	}
+}
+
+// learnControlChange maps the controller that was moved to the parameter
+// being learned
+func (m *model) learnControlChange(msg player.ControlChangeMsg) {
+	mapping := wavfile.CCMapping{
+		Channel:    msg.Channel,
+		Controller: msg.Controller,
+		Param:      m.ccLearnParam,
+	}
+	target := "master volume"
+	if mapping.Param != wavfile.CCParamMasterVolume {
+		if m.cursor < 0 || m.cursor >= len(*m.files) {
+			m.ccLearnParam = ""
+			return
+		}
+		mapping.Sample = (*m.files)[m.cursor].Name
+		mapping.Slot = (*m.files)[m.cursor].Slot
+		target = fmt.Sprintf("%s of %s", mapping.Param, mapping.Sample)
+	}
+
+	m.ccMappings = wavfile.SetCCMapping(m.ccMappings, mapping)
+	m.ccLearnParam = ""
+	m.statusMessage = fmt.Sprintf("Mapped CC %d on channel %d to %s", msg.Controller, msg.Channel, target)
+}
+
+// masterVolumeStepDB is the change of one master volume key press
+const masterVolumeStepDB = 1.0
+
+// setMasterVolume sets the linear master volume, 0 to 1
+func (m *model) setMasterVolume(volume float32) {
+	m.masterVolume = max(0, min(1, volume))
+	if err := m.audio.SetMasterVolume(m.masterVolume); err != nil {
+		m.SetCurrentError(fmt.Sprintf("Failed to set master volume: %v", err))
+	}
+}
+
+// stepMasterVolume moves the master volume by a number of dB steps,
+// reaching silence below -60 dB
+func (m *model) stepMasterVolume(steps int) {
+	db := wavfile.AmplitudeToDB(float64(m.masterVolume))
+	if m.masterVolume == 0 {
+		db = -60 - masterVolumeStepDB
+	}
+	db += float64(steps) * masterVolumeStepDB
+	if db < -60 {
+		m.setMasterVolume(0)
+		return
+	}
+	m.setMasterVolume(float32(wavfile.DBToAmplitude(math.Round(db))))
+}
+
+// applyCCMapping sets the mapped parameter from a controller value
+func (m *model) applyCCMapping(mapping wavfile.CCMapping, value int) {
+	if mapping.Param == wavfile.CCParamMasterVolume {
+		m.setMasterVolume(float32(value) / 127)
+		return
+	}
+
+	for i := range *m.files {
+		file := &(*m.files)[i]
+		if !mapping.Targets(file) {
+			continue
+		}
+		file.ApplyCC(mapping.Param, value)
+		if mapping.Param == wavfile.CCParamGain && file.PlayerId != 0 {
+			if err := m.audio.SetGain(file.PlayerId, float32(wavfile.DBToAmplitude(file.GainDB))); err != nil {
+				m.SetCurrentError(fmt.Sprintf("Failed to set gain: %v", err))
+			}
+		}
+		return
+	}
+}
+
+// countGMDrumMatches returns how many samples are named like a General MIDI drum
+func countGMDrumMatches(files []wavfile.WavFile) int {
+	count := 0
+	for _, file := range files {
+		if _, ok := wavfile.GMDrumNote(file.Name); ok {
+			count++
+		}
+	}
+	return count
+}
+
+// ccLearnKeys maps the keys of the CC learn prompt to parameters
+var ccLearnKeys = map[string]wavfile.CCParam{
+	"g": wavfile.CCParamGain,
+	"p": wavfile.CCParamPitch,
+	"s": wavfile.CCParamStart,
+	"e": wavfile.CCParamEnd,
+	"v": wavfile.CCParamMasterVolume,
+}
+
+// rollLastTrigger rolls the most recently triggered sample, or stops the
+// roll when subdivision is 0
+func (m *model) rollLastTrigger(subdivision int) {
+	if subdivision == 0 {
+		m.roller.Stop()
+		return
+	}
+
+	events := m.triggerHistory.Events()
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Sample != "" {
+			m.roller.Start(events[i].Channel, events[i].Note, events[i].Velocity, subdivision)
+			return
+		}
+	}
+}
+
+// cycleRoll steps the selected sample's roll through the subdivisions and off
+func (m *model) cycleRoll() {
+	current := m.roller.Subdivision()
+	next := 0
+	if current == 0 {
+		next = player.RollSubdivisions[0]
+	} else {
+		for i, subdivision := range player.RollSubdivisions {
+			if subdivision == current && i+1 < len(player.RollSubdivisions) {
+				next = player.RollSubdivisions[i+1]
+			}
+		}
+	}
+
+	if next == 0 {
+		m.roller.Stop()
+		return
+	}
+	file := (*m.files)[m.cursor]
+	m.roller.Start(file.MidiChannel, file.MidiNote, 127, next)
+}
+
+// cycleQuantize steps pattern playback through the quantize grids and off
+func (m *model) cycleQuantize() {
+	current := m.looper.Quantize()
+	next := 0
+	if current == 0 {
+		next = player.QuantizeGrids[0]
+	} else {
+		for i, grid := range player.QuantizeGrids {
+			if grid == current && i+1 < len(player.QuantizeGrids) {
+				next = player.QuantizeGrids[i+1]
+			}
+		}
+	}
+
+	m.looper.SetQuantize(next, m.quantizeStrength)
+	if next == 0 {
+		m.statusMessage = "Pattern quantize off"
+	} else {
+		m.statusMessage = fmt.Sprintf("Pattern quantize %s at %.0f%%", player.QuantizeLabel(next), m.quantizeStrength*100)
+	}
+}
+
+// compareAB alternates between playing the selected sample as it was, from
+// its oldest backup or else the file itself, whole and unpitched, and as it
+// sounds now with its markers, pitch and settings
+func (m *model) compareAB() {
+	file := &(*m.files)[m.cursor]
+	if file.Metadata == nil || file.PlayerId == 0 {
+		return
+	}
+	original, err := wavfile.OriginalBackup(file.Name)
+	if err != nil {
+		original = file.SourceFileName()
+	}
+	// Start over from the original on another sample
+	if original != m.abSource {
+		m.abOriginal = false
+	}
+
+	// Cut whichever is playing so the two never overlap
+	if file.PlayingCount > 0 {
+		m.audio.StopPlayer(file.PlayerId)
+		file.PlayingCount = 0
+	}
+	if m.abPlayerId != 0 {
+		m.audio.StopPlayer(m.abPlayerId)
+	}
+
+	if m.abOriginal {
+		if err := m.audio.PlayRegion(file.PlayerId, file.Name, file.StartFrame, file.EndFrame, file.Cents()); err != nil {
+			m.SetCurrentError("Error playing the edited sample: " + err.Error())
+			return
+		}
+		file.PlayingCount++
+		m.abOriginal = false
+		m.statusMessage = "B: " + file.Name + " as edited"
+		return
+	}
+
+	// A fresh player has none of the sample's settings
+	if original != m.abSource {
+		if m.abPlayerId != 0 {
+			m.audio.DestroyPlayer(m.abPlayerId)
+			m.abPlayerId, m.abSource = 0, ""
+		}
+		playerId, err := m.audio.CreatePlayer(original)
+		if err != nil {
+			m.SetCurrentError("Error loading the original: " + err.Error())
+			return
+		}
+		m.abPlayerId, m.abSource = playerId, original
+	}
+	if err := m.audio.PlayFile(m.abPlayerId, original, 0); err != nil {
+		m.SetCurrentError("Error playing the original: " + err.Error())
+		return
+	}
+	m.abOriginal = true
+	m.statusMessage = "A: " + file.Name + " as original"
+}
+
+// maxCount caps a typed count so a stray run of digits can't overflow
+const maxCount = 9999
+
+// moveMarker moves the active marker by steps marker steps, negative to
+// the left
+func (m *model) moveMarker(steps int) {
+	if m.cursor < 0 || m.cursor >= len((*m.files)) {
+		return
+	}
+
+	(*m.files)[m.cursor].MoveMarker(m.activeMarker, steps, m.markerStepSize)
+	m.followMarker()
+	if m.scrub {
+		m.previewMarker()
+	}
+}
+
+// previewMarker plays a short window around the active marker, so markers
+// can be placed by ear
+func (m *model) previewMarker() {
+	file := &(*m.files)[m.cursor]
+	if file.Metadata == nil || file.PlayerId == 0 {
+		return
+	}
+	frames := int(file.Metadata.SampleRate) * scrubWindowMs / 1000
+	start := max(0, m.activeMarkerFrame()-frames/2)
+	end := min(file.Metadata.NumFrames-1, start+frames)
+	if end <= start {
+		return
+	}
+	// Cut the previous preview so quick moves don't pile up
+	if file.PlayingCount > 0 {
+		m.audio.StopPlayer(file.PlayerId)
+		file.PlayingCount = 0
+	}
+	if err := m.audio.PlayRegion(file.PlayerId, file.Name, start, end, file.Cents()); err != nil {
+		m.SetCurrentError("Error previewing marker: " + err.Error())
+		return
+	}
+	file.PlayingCount++
+}
+
+// waveWindow returns the first frame and the number of frames the waveform
+// shows of a file numFrames long at the current zoom
+func (m model) waveWindow(numFrames int) (int, int) {
+	frames := max(numFrames/m.waveZoom, 1)
+	start := max(0, min(m.waveStart, numFrames-frames))
+	return start, frames
+}
+
+// activeMarkerFrame returns the frame of the selected file's active marker
+func (m model) activeMarkerFrame() int {
+	file := (*m.files)[m.cursor]
+	if m.activeMarker == "end" {
+		return file.EndFrame
+	}
+	return file.StartFrame
+}
+
+// followMarker pans the zoomed waveform to center the active marker once
+// it leaves the visible window
+func (m *model) followMarker() {
+	if m.cursor < 0 || m.cursor >= len(*m.files) || (*m.files)[m.cursor].Metadata == nil {
+		return
+	}
+	marker := m.activeMarkerFrame()
+	start, frames := m.waveWindow((*m.files)[m.cursor].Metadata.NumFrames)
+	if marker < start || marker >= start+frames {
+		m.waveStart = max(0, marker-frames/2)
+	}
+}
+
+// zoomWaveform doubles (direction 1) or halves (direction -1) the waveform
+// zoom, centering the window on the active marker
+func (m *model) zoomWaveform(direction int) {
+	if m.cursor < 0 || m.cursor >= len(*m.files) || (*m.files)[m.cursor].Metadata == nil {
+		return
+	}
+	if direction > 0 {
+		m.waveZoom = min(m.waveZoom*2, waveformMaxZoom)
+	} else {
+		m.waveZoom = max(m.waveZoom/2, 1)
+	}
+	_, frames := m.waveWindow((*m.files)[m.cursor].Metadata.NumFrames)
+	m.waveStart = max(0, m.activeMarkerFrame()-frames/2)
+	m.updateMarkerStepSize()
+}
+
+// panWaveform scrolls the zoomed waveform by a quarter of its width
+func (m *model) panWaveform(direction int) {
+	if m.cursor < 0 || m.cursor >= len(*m.files) || (*m.files)[m.cursor].Metadata == nil {
+		return
+	}
+	start, frames := m.waveWindow((*m.files)[m.cursor].Metadata.NumFrames)
+	m.waveStart = max(0, start+direction*max(frames/4, 1))
+	m.waveStart, _ = m.waveWindow((*m.files)[m.cursor].Metadata.NumFrames)
+}
+
+func (m model) handleEditingInput(mapping mappings.Mapping) (tea.Model, tea.Cmd) {
+	switch mapping.Command {
+	case mappings.Enter:
+		// Save the edited value; an empty cue name removes the cue, and no
+		// tags clears them
+		if m.editField == "cue" {
+			m.setCue(m.editValue)
+		} else if m.editField == "tags" {
+			(*m.files)[m.cursor].Tags = wavfile.ParseTags(m.editValue)
+			if !m.shows(m.cursor) {
+				m.applySearch(m.search)
+			}
+		} else if m.editValue != "" {
+			var value int
+			fmt.Sscanf(m.editValue, "%d", &value)
 
 			if m.editField == "channel" && value >= 1 && value <= 16 {
 				(*m.files)[m.cursor].MidiChannel = value
-			} else if m.editField == "note" && value >= 0 && value <= 127 {
-				(*m.files)[m.cursor].MidiNote = value
+			} else if m.editField == "note" {
+				if note, err := wavfile.ParseNote(m.editValue); err != nil {
+					m.SetCurrentError(err.Error())
+				} else {
+					(*m.files)[m.cursor].MidiNote = note
+				}
+			} else if m.editField == "chop" {
+				if root, err := wavfile.ParseNote(m.editValue); err != nil {
+					m.SetCurrentError(err.Error())
+				} else {
+					file := (*m.files)[m.cursor]
+					base := wavfile.SliceBase(file.Name)
+					count, unplaced := wavfile.ChopSlices(*m.files, base, file.MidiChannel, root)
+					m.statusMessage = fmt.Sprintf("Mapped %d slices of %s from note %s on channel %d", count, filepath.Base(base), wavfile.NoteName(root), file.MidiChannel)
+					if unplaced > 0 {
+						m.statusMessage += fmt.Sprintf("; %d slices or samples found no note up to 127 and kept their mapping", unplaced)
+					}
+				}
+			} else if m.editField == "exportregion" {
+				file := (*m.files)[m.cursor]
+				name := strings.TrimSpace(m.editValue)
+				target := name + ".wav"
+				// The end marker is the last frame of the region
+				endFrame := file.EndFrame + 1
+				if name == "" {
+					m.SetCurrentError("Enter a name for the exported region")
+				} else if slices.ContainsFunc(*m.files, func(other wavfile.WavFile) bool { return other.Name == target }) {
+					m.SetCurrentError(fmt.Sprintf("%s is already in the list; pick another name", target))
+				} else if err := wavfile.WriteRegion(file.SourceFileName(), target, file.StartFrame, endFrame, file.Loop); err != nil {
+					m.SetCurrentError(fmt.Sprintf("Failed to export region: %v", err))
+				} else if err := m.appendFile(target); err != nil {
+					m.SetCurrentError(fmt.Sprintf("Failed to load %s: %v", target, err))
+				} else {
+					m.statusMessage = fmt.Sprintf("Exported the region of %s to %s", file.Name, target)
+				}
+			} else if m.editField == "stretch" {
+				m.stretchFile(m.editValue)
 			} else if m.editField == "pitch" && value >= -12 && value <= 12 {
-				// Handle offline rendering for pitch change
-				err := m.handlePitchChange(m.cursor, value)
-				if err != nil {
-					m.SetCurrentError(fmt.Sprintf("Failed to change pitch: %v", err))
-
-					// If file doesn't exist, remove it from the list
-					if _, statErr := os.Stat((*m.files)[m.cursor].Name); os.IsNotExist(statErr) {
-						fileToRemove := m.cursor
-						*m.files = append((*m.files)[:fileToRemove], (*m.files)[fileToRemove+1:]...)
-
-						// Adjust cursor to valid non-corrupted file
-						m.adjustCursorToValidFile()
+				// Pitch is applied by the engine on the next trigger
+				(*m.files)[m.cursor].Pitch = value
+			} else if m.editField == "crossfade" {
+				(*m.files)[m.cursor].ChainCrossfade = max(0, min(value, wavfile.MaxChainCrossfade))
+			} else if m.editField == "gain" && value >= -60 && value <= 0 {
+				file := &(*m.files)[m.cursor]
+				file.GainDB = float64(value)
+				if file.PlayerId != 0 {
+					if err := m.audio.SetGain(file.PlayerId, float32(wavfile.DBToAmplitude(file.GainDB))); err != nil {
+						m.SetCurrentError(fmt.Sprintf("Failed to set gain: %v", err))
+					}
+				}
+			} else if m.editField == "swing" && value >= player.SwingStraight && value <= player.SwingMax {
+				m.clock.SetSwing(value)
+			} else if m.editField == "choke" && value >= 0 && value <= 16 {
+				(*m.files)[m.cursor].ChokeGroup = value
+			} else if m.editField == "envelope" {
+				m.setEnvelope(m.editValue)
+			} else if m.editField == "fade" {
+				m.fadeFile(m.editValue)
+			} else if m.editField == "width" && value >= 0 && value <= 100 {
+				file := &(*m.files)[m.cursor]
+				file.StereoWidth = value
+				if file.PlayerId != 0 {
+					if err := m.audio.SetStereoWidth(file.PlayerId, float32(value)/100); err != nil {
+						m.SetCurrentError(fmt.Sprintf("Failed to set stereo width: %v", err))
 					}
+				}
+			} else if m.editField == "pan" && value >= -100 && value <= 100 {
+				file := &(*m.files)[m.cursor]
+				file.Pan = value
+				if file.PlayerId != 0 {
+					if err := m.audio.SetPan(file.PlayerId, float32(value)/100); err != nil {
+						m.SetCurrentError(fmt.Sprintf("Failed to set pan: %v", err))
+					}
+				}
+			} else if m.editField == "song" {
+				if steps, err := player.ParseSong(m.editValue); err != nil {
+					m.SetCurrentError(err.Error())
+				} else if err := m.looper.PlaySong(steps); err != nil {
+					m.SetCurrentError(fmt.Sprintf("Failed to play song: %v", err))
+				} else {
+					m.songText = m.editValue
+				}
+			} else if m.editField == "import" {
+				if name, err := m.importFile(m.editValue); err != nil {
+					m.SetCurrentError(fmt.Sprintf("Failed to import sample: %v", err))
+				} else {
+					m.statusMessage = "Imported " + name
+				}
+			} else if m.editField == "kitdir" {
+				kit, err := loadKitPane(m.editValue)
+				if err != nil {
+					m.SetCurrentError(fmt.Sprintf("Failed to open kit: %v", err))
 				} else {
-					// Only set pitch if successful
-					(*m.files)[m.cursor].Pitch = value
+					m.otherKit = kit
+					m.splitView = true
+					m.kitFocused = false
+					m.layoutViewport()
 				}
 			} else if m.editField == "filename" && m.renamingRecording {
 				// Handle recording filename rename
-				newFilename := m.editValue + ".wav"
+				newFilename := m.editValue + filepath.Ext(m.recordingFilename)
 
 				// Rename the file
 				err := os.Rename(m.recordingFilename, newFilename)
 				if err != nil {
 					m.SetCurrentError(fmt.Sprintf("Failed to rename file: %v", err))
-				} else {
-					// Find the largest midi note and add 1
-					maxNote := wavfile.FindMaxMidiNote((*m.files))
-					// Load metadata for the new recording
-					metadata, err := wavfile.ReadMetadata(newFilename)
-					if err != nil {
-						metadata = nil
-					}
-					endFrame := 0
-					if metadata != nil {
-						endFrame = metadata.NumFrames - 1
-					}
-					*m.files = append(*m.files, wavfile.WavFile{
-						Name:        newFilename,
-						MidiChannel: 1,
-						MidiNote:    maxNote + 1,
-						StartFrame:  0,
-						EndFrame:    endFrame,
-						Metadata:    metadata,
-						Loading:     false,
-					})
-					playerId, err := m.audio.CreatePlayer(newFilename)
-					if err != nil {
-						panic(err)
-					}
-					if len(*m.files) == 1 {
-						//NOTE: Ensure audio is started in case this is the first file
-						err = m.audio.Start(m.audioDevice)
-						if err != nil {
-							panic(err)
-						}
-					}
-					(*m.files)[len(*m.files)-1].PlayerId = playerId
-					// Select the newly added file
-					m.cursor = len(*m.files) - 1
-					m.scrollToSelection() // This will call updateMarkerStepSize()
+				} else if err := m.appendFile(newFilename); err != nil {
+					m.SetCurrentError(fmt.Sprintf("Failed to load recording: %v", err))
 				}
 
 				m.recordingFilename = ""
 				m.renamingRecording = false
 			}
 		}
+		m.duplicateGroups = nil
+		m.gainSuggestions = nil
 		m.editing = false
 		m.editValue = ""
 		m.editField = ""
 
 	case mappings.Escape:
 		// Cancel editing
+		m.duplicateGroups = nil
+		m.gainSuggestions = nil
 		if m.editField == "filename" && m.renamingRecording {
 			// Keep the timestamp-based filename
-			maxNote := wavfile.FindMaxMidiNote((*m.files))
-			metadata, err := wavfile.ReadMetadata(m.recordingFilename)
-			if err != nil {
-				metadata = nil
-			}
-			endFrame := 0
-			if metadata != nil {
-				endFrame = metadata.NumFrames - 1
-			}
-			*m.files = append(*m.files, wavfile.WavFile{
-				Name:        m.recordingFilename,
-				MidiChannel: 1,
-				MidiNote:    maxNote + 1,
-				StartFrame:  0,
-				EndFrame:    endFrame,
-				Metadata:    metadata,
-				Loading:     false,
-			})
-			playerId, err := m.audio.CreatePlayer(m.recordingFilename)
-			if err != nil {
-				panic(err)
+			if err := m.appendFile(m.recordingFilename); err != nil {
+				m.SetCurrentError(fmt.Sprintf("Failed to load recording: %v", err))
 			}
-			(*m.files)[len(*m.files)-1].PlayerId = playerId
-			m.cursor = len(*m.files) - 1
-			m.scrollToSelection()
 
 			m.recordingFilename = ""
 			m.renamingRecording = false
 		}
+		if m.editField == "search" {
+			m.applySearch("")
+		}
 		m.editing = false
 		m.editValue = ""
 		m.editField = ""
@@ -504,21 +1728,148 @@ func (m model) handleEditingInput(mapping mappings.Mapping) (tea.Model, tea.Cmd)
 		if len(m.editValue) > 0 {
 			m.editValue = m.editValue[:len(m.editValue)-1]
 		}
+		if m.editField == "search" {
+			m.applySearch(m.editValue)
+		}
 
 	case mappings.NumberInput:
 		m.editValue += mapping.LastValue
+		if m.editField == "search" {
+			m.applySearch(m.editValue)
+		}
 
 	case mappings.TextInput:
+		if m.editField == "punch" {
+			m.editing = false
+			m.editField = ""
+			m.editValue = ""
+			switch mapping.LastValue {
+			case "r", "m":
+				// The pre-roll would shift the take ahead of the start marker
+				if m.armed {
+					m.SetCurrentError("Disarm recording (ctrl+r) before punching in")
+					return m, nil
+				}
+				return m, m.startPunchIn(mapping.LastValue == "m")
+			case "f":
+				return m, m.startSlotRecording()
+			}
+			return m, nil
+		}
+		if m.editField == "sort" {
+			m.editing = false
+			m.editField = ""
+			m.editValue = ""
+			m.sortFiles(mapping.LastValue)
+			return m, nil
+		}
+		if m.editField == "consolidate" {
+			if mapping.LastValue == "y" {
+				m.consolidateDuplicates()
+			}
+			m.duplicateGroups = nil
+			m.editing = false
+			m.editField = ""
+			m.editValue = ""
+			return m, nil
+		}
+		if m.editField == "cclearn" {
+			if param, ok := ccLearnKeys[mapping.LastValue]; ok {
+				m.ccLearnParam = param
+				m.statusMessage = fmt.Sprintf("Move a controller to map it to %s (any key cancels)", param)
+			} else if mapping.LastValue == "x" {
+				file := (*m.files)[m.cursor]
+				m.ccMappings = wavfile.RemoveCCMappings(m.ccMappings, file.Name, file.Slot)
+				m.statusMessage = "Cleared CC mappings of " + file.Name
+			}
+			m.editing = false
+			m.editField = ""
+			m.editValue = ""
+			return m, nil
+		}
+		if m.editField == "restore" {
+			if mapping.LastValue == "y" {
+				m.restoreOriginal()
+			}
+			m.editing = false
+			m.editField = ""
+			m.editValue = ""
+			return m, nil
+		}
+		if m.editField == "delete" {
+			if mapping.LastValue == "y" {
+				name := (*m.files)[m.cursor].Name
+				if err := m.deleteFile(); err != nil {
+					m.SetCurrentError(fmt.Sprintf("Failed to delete %s: %v", name, err))
+				} else {
+					m.statusMessage = "Deleted " + name
+				}
+			}
+			m.editing = false
+			m.editField = ""
+			m.editValue = ""
+			return m, nil
+		}
+		if m.editField == "committrim" {
+			if mapping.LastValue == "y" {
+				m.commitTrim()
+			}
+			m.editing = false
+			m.editField = ""
+			m.editValue = ""
+			return m, nil
+		}
+		if m.editField == "gmdrums" {
+			if mapping.LastValue == "y" {
+				count, unplaced := wavfile.AssignGMDrumNotes(*m.files)
+				m.statusMessage = fmt.Sprintf("Assigned General MIDI drum notes to %d samples", count)
+				if unplaced > 0 {
+					m.statusMessage += fmt.Sprintf("; %d samples found no free note up to 127 and kept their note", unplaced)
+				}
+			}
+			m.editing = false
+			m.editField = ""
+			m.editValue = ""
+			return m, nil
+		}
+		if m.editField == "gainstaging" {
+			if mapping.LastValue == "y" {
+				m.applyGainSuggestions()
+			}
+			m.gainSuggestions = nil
+			m.editing = false
+			m.editField = ""
+			m.editValue = ""
+			return m, nil
+		}
 		m.editValue += mapping.LastValue
+		if m.editField == "search" {
+			// Filter as the query is typed
+			m.applySearch(m.editValue)
+		}
 	}
 	return m, nil
 }
 
 func (m model) handleNavigationInput(mapping mappings.Mapping) (tea.Model, tea.Cmd) {
-	// Clear any error on key press
+	// Clear any error or status on key press, and cancel CC learning
 	m.currentError = ""
+	m.statusMessage = ""
+	m.ccLearnParam = ""
+	// A count applies to the key right after it
+	count := max(m.count, 1)
+	if mapping.Command != mappings.Count {
+		m.count = 0
+	}
 
 	switch mapping.Command {
+	case mappings.Count:
+		// A leading 0 isn't a count
+		if digit := int(mapping.LastValue[0] - '0'); m.count > 0 || digit > 0 {
+			m.count = min(m.count*10+digit, maxCount)
+			m.statusMessage = fmt.Sprintf("Count: %d", m.count)
+		}
+
 	case mappings.Quit:
 		// Clean up recording if active
 		if m.recording {
@@ -528,35 +1879,75 @@ func (m model) handleNavigationInput(mapping mappings.Mapping) (tea.Model, tea.C
 				os.Remove(m.recordingFilename)
 			}
 		}
+		// Keep the bounce so far
+		if m.bounceFilename != "" {
+			m.audio.StopBounce()
+		}
 		return m, tea.Quit
 
 	case mappings.CursorUp:
-		if !m.recording && m.cursor > 0 {
-			// Move up, skipping corrupted files
-			for newCursor := m.cursor - 1; newCursor >= 0; newCursor-- {
-				if !(*m.files)[newCursor].Corrupted {
-					m.cursor = newCursor
-					m.scrollToSelection()
-					break
-				}
+		if m.splitView && m.kitFocused {
+			if m.otherKit.cursor > 0 {
+				m.otherKit.cursor--
 			}
+		} else if !m.recording {
+			m.moveCursor(-1)
 		}
 
 	case mappings.CursorDown:
-		if !m.recording && m.cursor < len((*m.files))-1 {
-			// Move down, skipping corrupted files
-			for newCursor := m.cursor + 1; newCursor < len(*m.files); newCursor++ {
-				if !(*m.files)[newCursor].Corrupted {
-					m.cursor = newCursor
-					m.scrollToSelection()
-					break
-				}
+		if m.splitView && m.kitFocused {
+			if m.otherKit.cursor < len(m.otherKit.files)-1 {
+				m.otherKit.cursor++
 			}
+		} else if !m.recording {
+			m.moveCursor(1)
+		}
+
+	case mappings.TogglePadGrid:
+		m.padGrid = !m.padGrid
+		m.scrollToSelection()
+
+	case mappings.MoveFileUp:
+		if m.sorting != nil {
+			m.statusMessage = "Show the list order (Ctrl+T l) to move samples"
+		} else if !m.recording && !(m.splitView && m.kitFocused) {
+			m.moveFile(-1)
+		}
+
+	case mappings.MoveFileDown:
+		if m.sorting != nil {
+			m.statusMessage = "Show the list order (Ctrl+T l) to move samples"
+		} else if !m.recording && !(m.splitView && m.kitFocused) {
+			m.moveFile(1)
+		}
+
+	case mappings.SortFiles:
+		if !m.recording && len(*m.files) > 1 {
+			m.editing = true
+			m.editField = "sort"
+			m.editValue = ""
+		}
+
+	case mappings.Search:
+		if !m.recording && !(m.splitView && m.kitFocused) {
+			m.editing = true
+			m.editField = "search"
+			m.editValue = m.search
+		}
+
+	case mappings.PrevBank:
+		if !m.recording && !(m.splitView && m.kitFocused) && m.search == "" {
+			m.selectBank(m.bank() - 1)
+		}
+
+	case mappings.NextBank:
+		if !m.recording && !(m.splitView && m.kitFocused) && m.search == "" {
+			m.selectBank(m.bank() + 1)
 		}
 
 	case mappings.EditChannel:
 		// Edit channel
-		if len((*m.files)) > 0 {
+		if len((*m.files)) > 0 && m.cursor >= 0 {
 			m.editing = true
 			m.editField = "channel"
 			m.editValue = ""
@@ -564,7 +1955,7 @@ func (m model) handleNavigationInput(mapping mappings.Mapping) (tea.Model, tea.C
 
 	case mappings.EditNote:
 		// Edit note
-		if len((*m.files)) > 0 {
+		if len((*m.files)) > 0 && m.cursor >= 0 {
 			m.editing = true
 			m.editField = "note"
 			m.editValue = ""
@@ -572,62 +1963,522 @@ func (m model) handleNavigationInput(mapping mappings.Mapping) (tea.Model, tea.C
 
 	case mappings.EditPitch:
 		// Edit pitch
-		if len((*m.files)) > 0 {
+		if len((*m.files)) > 0 && m.cursor >= 0 {
 			m.editing = true
 			m.editField = "pitch"
 			m.editValue = ""
 		}
 
-	case mappings.Recording:
-		if !m.recording {
-			// Start recording with timestamp-based filename
-			m.recording = true
-			m.cursor = -1 // Deselect all files while recording
-			m.recordingFilename = fmt.Sprintf("recording_%s.wav", time.Now().Format("20060102_150405"))
-			m.audio.Record(m.recordingFilename)
-		} else {
-			// Stop recording and prompt for filename
-			m.recording = false
-			m.audio.StopRecording()
-			if m.recordingFilename != "" {
-				// Enter renaming mode to prompt user for new filename
-				m.renamingRecording = true
-				m.editing = true
-				m.editField = "filename"
-				// Pre-fill with base name without extension and timestamp
-				m.editValue = "recording"
-			}
+	case mappings.EditCrossfade:
+		// Edit crossfade into the next chained sample
+		if len((*m.files)) > 0 && m.cursor >= 0 {
+			m.editing = true
+			m.editField = "crossfade"
+			m.editValue = ""
 		}
 
-	case mappings.MarkerLeft:
-		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
-			m.moveMarker(-1)
+	case mappings.ToggleSplitView:
+		if m.splitView {
+			m.splitView = false
+			m.kitFocused = false
+			m.layoutViewport()
+		} else if !m.recording {
+			// Prompt for the directory of the second kit
+			m.editing = true
+			m.editField = "kitdir"
+			m.editValue = ""
 		}
 
-	case mappings.MarkerRight:
-		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
-			m.moveMarker(1)
+	case mappings.SwitchPane:
+		if m.splitView {
+			m.kitFocused = !m.kitFocused
 		}
 
-	case mappings.MarkerStepIncrease:
-		// Double the step size
-		m.markerStepSize *= 2
-		if m.markerStepSize > 1000000 {
-			m.markerStepSize = 1000000 // Cap at 1 million frames
+	case mappings.ToggleHelp:
+		m.showHelp = true
+		m.helpScroll = 0
+
+	case mappings.CompareAB:
+		if !m.recording && m.cursor >= 0 && m.cursor < len(*m.files) {
+			m.compareAB()
 		}
 
-	case mappings.MarkerStepDecrease:
-		// Halve the step size
-		m.markerStepSize /= 2
-		if m.markerStepSize < 1 {
-			m.markerStepSize = 1 // Minimum 1 frame
+	case mappings.ToggleFavorite:
+		if m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := &(*m.files)[m.cursor]
+			file.Favorite = !file.Favorite
+			// A favorites search lets go of the sample
+			if !m.shows(m.cursor) {
+				m.applySearch(m.search)
+			}
 		}
 
-	case mappings.SelectStartMarker:
-		m.activeMarker = "start"
+	case mappings.EditTags:
+		// Prompt for the tags, starting from the current ones
+		if !m.recording && m.cursor >= 0 && m.cursor < len(*m.files) {
+			m.editing = true
+			m.editField = "tags"
+			m.editValue = strings.Join((*m.files)[m.cursor].Tags, " ")
+		}
 
-	case mappings.SelectEndMarker:
-		m.activeMarker = "end"
+	case mappings.DeleteFile:
+		if !m.recording && m.cursor >= 0 && m.cursor < len(*m.files) {
+			m.editing = true
+			m.editField = "delete"
+			m.editValue = ""
+		}
+
+	case mappings.DuplicateFile:
+		if !m.recording && m.cursor >= 0 && m.cursor < len(*m.files) {
+			name := (*m.files)[m.cursor].Name
+			if err := m.duplicateFile(); err != nil {
+				m.SetCurrentError(fmt.Sprintf("Failed to duplicate %s: %v", name, err))
+			} else {
+				m.statusMessage = fmt.Sprintf("Duplicated %s onto %s", name, wavfile.NoteName((*m.files)[m.cursor].MidiNote))
+			}
+		}
+
+	case mappings.ImportFile:
+		// Prompt for the path of a sample to bring into the kit
+		if !m.recording {
+			m.editing = true
+			m.editField = "import"
+			m.editValue = ""
+		}
+
+	case mappings.CopyToOtherKit:
+		if m.splitView && !m.recording {
+			if err := m.copyToOtherKit(); err != nil {
+				m.SetCurrentError(fmt.Sprintf("Failed to copy sample: %v", err))
+			} else {
+				m.statusMessage = "Sample copied with its mappings"
+			}
+		}
+
+	case mappings.EditStereoWidth:
+		// Edit stereo width
+		if len((*m.files)) > 0 && m.cursor >= 0 {
+			m.editing = true
+			m.editField = "width"
+			m.editValue = ""
+		}
+
+	case mappings.MasterVolumeDown:
+		m.stepMasterVolume(-1)
+
+	case mappings.MasterVolumeUp:
+		m.stepMasterVolume(1)
+
+	case mappings.ToggleLimiter:
+		m.limiter = !m.limiter
+		if err := m.audio.SetLimiter(m.limiter); err != nil {
+			m.SetCurrentError(fmt.Sprintf("Failed to set limiter: %v", err))
+		}
+
+	case mappings.FadeFile:
+		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := (*m.files)[m.cursor]
+			if file.DecodedFileName != "" {
+				m.SetCurrentError("Cannot fade non-WAV file. Only WAV files can be edited.")
+				return m, nil
+			}
+			if file.Metadata == nil || file.Corrupted {
+				return m, nil
+			}
+			m.editing = true
+			m.editField = "fade"
+			m.editValue = ""
+		}
+
+	case mappings.PunchIn:
+		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := (*m.files)[m.cursor]
+			if file.DecodedFileName != "" {
+				m.SetCurrentError("Cannot punch into non-WAV file. Only WAV files can be edited.")
+				return m, nil
+			}
+			if file.Metadata == nil || file.Corrupted {
+				return m, nil
+			}
+			m.editing = true
+			m.editField = "punch"
+			m.editValue = ""
+		}
+
+	case mappings.EditPan:
+		if len((*m.files)) > 0 && m.cursor >= 0 {
+			m.editing = true
+			m.editField = "pan"
+			m.editValue = ""
+		}
+
+	case mappings.Panic:
+		// Silence everything, including rolls that would retrigger
+		m.roller.Stop()
+		if err := m.audio.StopAll(); err != nil {
+			m.SetCurrentError(fmt.Sprintf("Failed to stop all voices: %v", err))
+		}
+		for i := range *m.files {
+			(*m.files)[i].PlayingCount = 0
+		}
+		m.statusMessage = "All voices off"
+
+	case mappings.Roll:
+		if len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			m.cycleRoll()
+		}
+
+	case mappings.CapturePattern:
+		if m.looper.State() == player.LooperOff {
+			m.looper.Capture(m.patternBars)
+			m.statusMessage = fmt.Sprintf("Capturing a %d-bar pattern from the next note", m.patternBars)
+		} else {
+			m.looper.Stop()
+			m.statusMessage = "Pattern stopped"
+		}
+
+	case mappings.CycleQuantize:
+		m.cycleQuantize()
+
+	case mappings.PlaySong:
+		if m.looper.State() == player.LooperSong {
+			m.looper.Stop()
+			m.statusMessage = "Song stopped"
+		} else if m.looper.Patterns() == 0 {
+			m.SetCurrentError("Capture a pattern with ctrl+p before arranging a song")
+		} else {
+			// Start from the last song so it can be replayed or tweaked
+			m.editing = true
+			m.editField = "song"
+			m.editValue = m.songText
+		}
+
+	case mappings.EditSwing:
+		m.editing = true
+		m.editField = "swing"
+		m.editValue = ""
+
+	case mappings.ToggleChromatic:
+		// Toggle keyboard mode, rooted at the sample's note
+		if len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := &(*m.files)[m.cursor]
+			file.Chromatic = !file.Chromatic
+			if file.Chromatic {
+				m.statusMessage = fmt.Sprintf("Keyboard mode: channel %d, root note %s", file.MidiChannel, wavfile.NoteName(file.MidiNote))
+			}
+		}
+
+	case mappings.LearnCC:
+		// Pick the parameter a controller should be mapped to
+		if len((*m.files)) > 0 && m.cursor >= 0 {
+			m.editing = true
+			m.editField = "cclearn"
+			m.editValue = ""
+		}
+
+	case mappings.EditChokeGroup:
+		// Edit choke group
+		if len((*m.files)) > 0 && m.cursor >= 0 {
+			m.editing = true
+			m.editField = "choke"
+			m.editValue = ""
+		}
+
+	case mappings.TogglePlayMode:
+		if len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := &(*m.files)[m.cursor]
+			if file.PlayMode == wavfile.PlayModeGate {
+				file.PlayMode = wavfile.PlayModeOneShot
+			} else {
+				file.PlayMode = wavfile.PlayModeGate
+			}
+			m.statusMessage = "Play mode: " + file.PlayMode.String()
+		}
+
+	case mappings.ToggleLoop:
+		if len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := &(*m.files)[m.cursor]
+			file.Loop = !file.Loop
+			if file.PlayerId != 0 {
+				if err := m.audio.SetLoop(file.PlayerId, file.Loop); err != nil {
+					m.SetCurrentError(fmt.Sprintf("Failed to set loop: %v", err))
+				}
+			}
+		}
+
+	case mappings.ToggleReverse:
+		if len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := &(*m.files)[m.cursor]
+			file.Reverse = !file.Reverse
+			if file.PlayerId != 0 {
+				if err := m.audio.SetReverse(file.PlayerId, file.Reverse); err != nil {
+					m.SetCurrentError(fmt.Sprintf("Failed to set reverse: %v", err))
+				}
+			}
+		}
+
+	case mappings.CycleChannelPair:
+		if len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := &(*m.files)[m.cursor]
+			pairs := file.ChannelPairs()
+			if pairs == 0 {
+				m.SetCurrentError("Channel pairs only apply to files with more than two channels")
+				return m, nil
+			}
+			file.ChannelPair = (file.ChannelPair + 1) % (pairs + 1)
+			if file.PlayerId != 0 {
+				if err := m.audio.SetChannelPair(file.PlayerId, file.ChannelPair); err != nil {
+					m.SetCurrentError(fmt.Sprintf("Failed to set channel pair: %v", err))
+					return m, nil
+				}
+			}
+			m.statusMessage = fmt.Sprintf("Playing channels %s of %s", file.ChannelPairLabel(), file.Name)
+		}
+
+	case mappings.EditCue, mappings.PrevCue, mappings.NextCue, mappings.MoveCue:
+		if len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := &(*m.files)[m.cursor]
+			if file.DecodedFileName != "" {
+				m.SetCurrentError("Cannot edit cues of non-WAV file. Only WAV files can be edited.")
+				return m, nil
+			}
+			if file.Metadata == nil || file.Corrupted {
+				return m, nil
+			}
+			switch mapping.Command {
+			case mappings.EditCue:
+				m.editing = true
+				m.editField = "cue"
+				m.editValue = ""
+				if index := m.cueAtMarker(); index >= 0 {
+					m.editValue = file.Metadata.Cues[index].Label
+				}
+			case mappings.PrevCue:
+				m.jumpToCue(-1)
+			case mappings.NextCue:
+				m.jumpToCue(1)
+			case mappings.MoveCue:
+				m.moveCue()
+			}
+		}
+
+	case mappings.ReverseFile:
+		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := &(*m.files)[m.cursor]
+			if file.DecodedFileName != "" {
+				m.SetCurrentError("Cannot reverse non-WAV file. Only WAV files can be edited.")
+				return m, nil
+			}
+			if file.Metadata == nil || file.Corrupted {
+				return m, nil
+			}
+			if !m.backupFile(file.Name) {
+				return m, nil
+			}
+			if err := wavfile.ReverseFile(file.Name); err != nil {
+				m.SetCurrentError(fmt.Sprintf("Failed to reverse %s: %v", file.Name, err))
+				return m, nil
+			}
+			// Mirror the markers so they keep framing the same audio
+			lastFrame := file.Metadata.NumFrames - 1
+			file.StartFrame, file.EndFrame = lastFrame-file.EndFrame, lastFrame-file.StartFrame
+			m.reloadEditedFile(m.cursor)
+			m.statusMessage = fmt.Sprintf("Reversed %s", file.Name)
+		}
+
+	case mappings.EditEnvelope:
+		// Edit envelope
+		if len((*m.files)) > 0 && m.cursor >= 0 {
+			m.editing = true
+			m.editField = "envelope"
+			m.editValue = ""
+		}
+
+	case mappings.EditGain:
+		// Edit gain
+		if len((*m.files)) > 0 && m.cursor >= 0 {
+			m.editing = true
+			m.editField = "gain"
+			m.editValue = ""
+		}
+
+	case mappings.AnalyzeGain:
+		if !m.recording {
+			m.statusMessage = "Analyzing gain staging..."
+			return m, analyzeGainStaging(*m.files, m.headroomDB, m.headroomVoices)
+		}
+
+	case mappings.AssignGMDrums:
+		if !m.recording {
+			if countGMDrumMatches(*m.files) == 0 {
+				m.statusMessage = "No sample names match the General MIDI drum map"
+				return m, nil
+			}
+			m.editing = true
+			m.editField = "gmdrums"
+			m.editValue = ""
+		}
+
+	case mappings.ToggleBounce:
+		if m.recording && m.recordSource == "output" {
+			m.SetCurrentError("The output is already being recorded")
+		} else if m.bounceFilename == "" {
+			filename := fmt.Sprintf("bounce_%s.wav", time.Now().Format("20060102_150405"))
+			if err := m.audio.StartBounce(filename); err != nil {
+				m.SetCurrentError(fmt.Sprintf("Error starting bounce: %v", err))
+			} else {
+				m.bounceFilename = filename
+			}
+		} else {
+			if err := m.audio.StopBounce(); err != nil {
+				m.SetCurrentError(fmt.Sprintf("Error stopping bounce: %v", err))
+			} else {
+				m.statusMessage = "Bounced master output to " + m.bounceFilename
+			}
+			m.bounceFilename = ""
+		}
+
+	case mappings.ExportSFZ:
+		filename := kitName() + ".sfz"
+		if err := wavfile.WriteSFZ(filename, *m.files); err != nil {
+			m.SetCurrentError(fmt.Sprintf("Error exporting SFZ: %v", err))
+		} else {
+			m.statusMessage = "Exported kit to " + filename
+		}
+
+	case mappings.ExportSF2:
+		filename := kitName() + ".sf2"
+		if err := wavfile.WriteSF2(filename, kitName(), *m.files); err != nil {
+			m.SetCurrentError(fmt.Sprintf("Error exporting SoundFont: %v", err))
+		} else {
+			m.statusMessage = "Exported kit to " + filename
+		}
+
+	case mappings.ExportMidi:
+		if m.performance == nil || m.performance.Notes() == 0 {
+			m.statusMessage = "No MIDI notes played yet"
+		} else {
+			filename := fmt.Sprintf("performance_%s.mid", time.Now().Format("20060102_150405"))
+			if err := m.performance.WriteFile(filename); err != nil {
+				m.SetCurrentError(fmt.Sprintf("Error exporting performance: %v", err))
+			} else {
+				m.statusMessage = fmt.Sprintf("Exported %d notes to %s", m.performance.Notes(), filename)
+			}
+		}
+
+	case mappings.FindDuplicates:
+		if !m.recording {
+			m.statusMessage = "Scanning for duplicates..."
+			return m, findDuplicates(*m.files)
+		}
+
+	case mappings.LinkChain:
+		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			if m.linkSource < 0 || m.linkSource >= len(*m.files) {
+				// First press picks the sample to chain from
+				m.linkSource = m.cursor
+			} else {
+				// Second press picks the sample that follows it; linking a
+				// sample to itself clears its chain
+				source := &(*m.files)[m.linkSource]
+				if m.linkSource == m.cursor {
+					source.ChainNext, source.ChainNextSlot = "", 0
+				} else {
+					source.ChainNext, source.ChainNextSlot = (*m.files)[m.cursor].Name, (*m.files)[m.cursor].Slot
+				}
+				m.linkSource = -1
+			}
+		}
+
+	case mappings.Recording:
+		if !m.recording {
+			if err := m.startRecording(); err != nil {
+				m.SetCurrentError(fmt.Sprintf("Failed to start recording: %v", err))
+				return m, nil
+			}
+			return m, m.recordingTimer()
+		}
+		m.stopRecording()
+
+	case mappings.ArmRecording:
+		if m.recording {
+			return m, nil
+		}
+		if m.recordSource != "system" {
+			m.SetCurrentError("Pre-roll only works when recording system audio (--record-source system)")
+			return m, nil
+		}
+		if m.armed {
+			if err := m.audio.Disarm(); err != nil {
+				m.SetCurrentError(fmt.Sprintf("Failed to disarm recording: %v", err))
+			}
+			m.armed = false
+			m.statusMessage = "Recording disarmed"
+		} else if err := m.audio.Arm(m.preRoll); err != nil {
+			m.SetCurrentError(fmt.Sprintf("Failed to arm recording: %v", err))
+		} else {
+			m.armed = true
+			m.statusMessage = fmt.Sprintf("Armed: recordings start %.1fs before r is pressed", m.preRoll)
+		}
+		m.layoutViewport()
+
+	case mappings.MarkerLeft:
+		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			m.moveMarker(-count)
+		}
+
+	case mappings.MarkerRight:
+		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			m.moveMarker(count)
+		}
+
+	case mappings.ToggleScrub:
+		m.scrub = !m.scrub
+		if m.scrub && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			m.previewMarker()
+		}
+
+	case mappings.ZoomIn:
+		m.zoomWaveform(1)
+
+	case mappings.ZoomOut:
+		m.zoomWaveform(-1)
+
+	case mappings.PanLeft:
+		m.panWaveform(-1)
+
+	case mappings.PanRight:
+		m.panWaveform(1)
+
+	case mappings.MarkerStepIncrease:
+		// Double the step size
+		m.markerStepSize *= 2
+		if m.markerStepSize > 1000000 {
+			m.markerStepSize = 1000000 // Cap at 1 million frames
+		}
+
+	case mappings.MarkerStepDecrease:
+		// Halve the step size
+		m.markerStepSize /= 2
+		if m.markerStepSize < 1 {
+			m.markerStepSize = 1 // Minimum 1 frame
+		}
+
+	case mappings.ToggleTriggerHistory:
+		m.showTriggerHistory = !m.showTriggerHistory
+		m.layoutViewport()
+
+	case mappings.ToggleMidiMonitor:
+		m.showMidiMonitor = !m.showMidiMonitor
+		m.layoutViewport()
+
+	case mappings.SelectStartMarker:
+		m.activeMarker = "start"
+		m.followMarker()
+
+	case mappings.SelectEndMarker:
+		m.activeMarker = "end"
+		m.followMarker()
 
 	case mappings.PlayFile:
 		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
@@ -640,17 +2491,14 @@ func (m model) handleNavigationInput(mapping mappings.Mapping) (tea.Model, tea.C
 				(*m.files)[m.cursor].PlayingCount = 0
 				return m, nil
 			}
-			// Use pitched file if it exists, otherwise use original
-			filename := (*m.files)[m.cursor].Name
-			if (*m.files)[m.cursor].PitchedFileName != "" {
-				filename = (*m.files)[m.cursor].PitchedFileName
-			}
-			// No real-time pitch shifting - files are pre-rendered
-			err := m.audio.PlayFile((*m.files)[m.cursor].PlayerId, filename, 0)
+			// Markers trim playback without touching the file
+			file := (*m.files)[m.cursor]
+			err := m.audio.PlayRegion(file.PlayerId, file.Name, file.StartFrame, file.EndFrame, file.Cents())
 			if err != nil {
 				m.SetCurrentError("Error playing file: " + err.Error())
 			} else {
 				(*m.files)[m.cursor].PlayingCount++
+				m.recordKeyboardTrigger()
 			}
 		}
 
@@ -658,91 +2506,566 @@ func (m model) handleNavigationInput(mapping mappings.Mapping) (tea.Model, tea.C
 		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
 			// Stop if currently playing
 			if (*m.files)[m.cursor].PlayingCount > 0 {
-				err := m.audio.StopPlayer((*m.files)[m.cursor].PlayerId)
-				if err != nil {
-					panic("Error stopping file from update")
+				for _, index := range wavfile.ChainIndices(*m.files, m.cursor) {
+					err := m.audio.StopPlayer((*m.files)[index].PlayerId)
+					if err != nil {
+						panic("Error stopping file from update")
+					}
+					(*m.files)[index].PlayingCount = 0
 				}
-				(*m.files)[m.cursor].PlayingCount = 0
 				return m, nil
 			}
-			// Use pitched file if it exists, otherwise use original
-			filename := (*m.files)[m.cursor].Name
-			if (*m.files)[m.cursor].PitchedFileName != "" {
-				filename = (*m.files)[m.cursor].PitchedFileName
-			}
-			// No real-time pitch shifting - files are pre-rendered
-			err := m.audio.PlayRegion(
-				(*m.files)[m.cursor].PlayerId,
-				filename,
-				(*m.files)[m.cursor].StartFrame,
-				(*m.files)[m.cursor].EndFrame,
-				0,
-			)
-			if err != nil {
-				panic("Error playing region from update: " + err.Error())
+			if err := m.playSample(m.cursor, player.SourceKeyboard); err != nil {
+				m.SetCurrentError(err.Error())
 			}
-			(*m.files)[m.cursor].PlayingCount++
 		}
 
 	case mappings.TrimFile:
+		// Markers already trim playback; cutting the file needs confirmation
 		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
-			if (*m.files)[m.cursor].Pitch != 0 {
-				m.SetCurrentError("Cannot trim file with non-zero pitch. Reset pitch to 0 first.")
+			if (*m.files)[m.cursor].DecodedFileName != "" {
+				m.SetCurrentError("Cannot trim non-WAV file. Only WAV files can be edited.")
 				return m, nil
 			}
-			// Check if file exists before trimming
-			if _, err := os.Stat((*m.files)[m.cursor].Name); os.IsNotExist(err) {
-				m.SetCurrentError(fmt.Sprintf("File does not exist: %s", (*m.files)[m.cursor].Name))
+			m.editing = true
+			m.editField = "committrim"
+			m.editValue = ""
+		}
 
-				// Remove file from the list
-				fileToRemove := m.cursor
-				*m.files = append((*m.files)[:fileToRemove], (*m.files)[fileToRemove+1:]...)
+	case mappings.ExportRegion:
+		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := (*m.files)[m.cursor]
+			if file.Metadata == nil || file.Corrupted {
+				return m, nil
+			}
+			m.editing = true
+			m.editField = "exportregion"
+			m.editValue = ""
+		}
 
-				// Adjust cursor to valid non-corrupted file
-				m.adjustCursorToValidFile()
+	case mappings.RestoreOriginal:
+		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			if _, err := wavfile.OriginalBackup((*m.files)[m.cursor].Name); err != nil {
+				m.statusMessage = "No backup of this sample; it hasn't been edited"
+				return m, nil
+			}
+			m.editing = true
+			m.editField = "restore"
+			m.editValue = ""
+		}
 
+	case mappings.TrimSilence:
+		if len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := &(*m.files)[m.cursor]
+			if file.Metadata == nil || file.Corrupted {
 				return m, nil
 			}
-			err := m.audio.TrimFile(
-				(*m.files)[m.cursor].Name,
-				(*m.files)[m.cursor].StartFrame,
-				(*m.files)[m.cursor].EndFrame,
-			)
-			if err == nil {
-				// Remove all pitched versions of this file
-				if err := wavfile.RemoveAllPitchedVersions((*m.files)[m.cursor].Name); err != nil {
-					m.SetCurrentError(fmt.Sprintf("Warning: failed to remove pitched versions: %v", err))
-				}
+			startFrame, endFrame, err := wavfile.FindSound(file.SourceFileName(), wavfile.DefaultSilenceDB)
+			if err != nil {
+				m.SetCurrentError(fmt.Sprintf("Failed to find silence in %s: %v", file.Name, err))
+				return m, nil
+			}
+			// Markers only move; t trims the file to them
+			file.StartFrame = startFrame
+			file.EndFrame = endFrame
+			m.statusMessage = fmt.Sprintf("Markers moved past silence below %.0f dBFS (t commits the trim)", wavfile.DefaultSilenceDB)
+		}
 
-				// Destroy the old player and create a new one
-				if err := m.audio.DestroyPlayer((*m.files)[m.cursor].PlayerId); err != nil {
-					m.SetCurrentError(fmt.Sprintf("Warning: failed to destroy player: %v", err))
-				}
-				newPlayerID, err := m.audio.CreatePlayer((*m.files)[m.cursor].Name)
-				if err != nil {
-					m.SetCurrentError(fmt.Sprintf("Failed to create new player: %v", err))
-				} else {
-					(*m.files)[m.cursor].PlayerId = newPlayerID
-				}
+	case mappings.RemoveDCOffset:
+		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := &(*m.files)[m.cursor]
+			if file.DecodedFileName != "" {
+				m.SetCurrentError("Cannot remove DC offset from non-WAV file. Only WAV files can be edited.")
+				return m, nil
+			}
+			if file.Metadata == nil || file.Corrupted {
+				return m, nil
+			}
+			offsets, err := wavfile.DCOffset(file.Name)
+			if err != nil {
+				m.SetCurrentError(fmt.Sprintf("Failed to analyze %s: %v", file.Name, err))
+				return m, nil
+			}
+			// Below one 16-bit step there is nothing to remove
+			if wavfile.MaxDCOffset(offsets) < 1.0/32768 {
+				m.statusMessage = fmt.Sprintf("%s has no DC offset", file.Name)
+				return m, nil
+			}
+			if !m.backupFile(file.Name) {
+				return m, nil
+			}
+			if _, err := wavfile.RemoveDCOffset(file.Name); err != nil {
+				m.SetCurrentError(fmt.Sprintf("Failed to remove DC offset from %s: %v", file.Name, err))
+				return m, nil
+			}
+			m.reloadEditedFile(m.cursor)
+			m.statusMessage = fmt.Sprintf("Removed a DC offset of %.3f%% from %s", wavfile.MaxDCOffset(offsets)*100, file.Name)
+		}
 
-				// Reload metadata after trimming
-				metadata, err := wavfile.ReadMetadata((*m.files)[m.cursor].Name)
-				if err == nil {
-					(*m.files)[m.cursor].Metadata = metadata
-					// Reset markers to the start and end of the new file
-					(*m.files)[m.cursor].StartFrame = 0
-					(*m.files)[m.cursor].EndFrame = metadata.NumFrames - 1
-					// Update marker step size for the new file length
-					m.updateMarkerStepSize()
-				} else {
-					m.SetCurrentError(fmt.Sprintf("Warning: failed to reload metadata: %v", err))
+	case mappings.SliceFile:
+		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := (*m.files)[m.cursor]
+			if file.Metadata == nil || file.Corrupted {
+				return m, nil
+			}
+			// The end marker is the last frame of the region
+			cuts := []int{file.StartFrame, file.EndFrame + 1}
+			names, err := wavfile.SliceFile(file.SourceFileName(), file.Name, cuts)
+			for _, name := range names {
+				// Each slice takes the next note, so they play in order
+				if err := m.appendFile(name); err != nil {
+					m.SetCurrentError(fmt.Sprintf("Failed to load slice: %v", err))
 				}
 			}
+			if err != nil {
+				m.SetCurrentError(fmt.Sprintf("Failed to slice %s: %v", file.Name, err))
+				return m, nil
+			}
+			m.statusMessage = fmt.Sprintf("Sliced %s into %d samples", file.Name, len(names))
+		}
+
+	case mappings.ChopSlices:
+		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			base := wavfile.SliceBase((*m.files)[m.cursor].Name)
+			if !wavfile.HasSlices(*m.files, base) {
+				m.statusMessage = "No slices of this sample; press T to slice it first"
+				return m, nil
+			}
+			m.editing = true
+			m.editField = "chop"
+			m.editValue = ""
+		}
+
+	case mappings.StretchFile:
+		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := (*m.files)[m.cursor]
+			if file.Metadata == nil || file.Corrupted {
+				return m, nil
+			}
+			m.editing = true
+			m.editField = "stretch"
+			m.editValue = ""
+		}
+
+	case mappings.DetectPitch:
+		if len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			m.detectPitch(&(*m.files)[m.cursor])
+		}
+
+	case mappings.TuneToC:
+		if len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := &(*m.files)[m.cursor]
+			if file.DetectedHz == 0 && !m.detectPitch(file) {
+				return m, nil
+			}
+			file.Pitch, file.FineTune = wavfile.TuneToC(file.DetectedHz)
+			m.statusMessage = fmt.Sprintf("Tuned %s to C: %+d semitones %+d cents", file.Name, file.Pitch, file.FineTune)
+		}
+
+	case mappings.NormalizeFile:
+		if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+			file := &(*m.files)[m.cursor]
+			if file.DecodedFileName != "" {
+				m.SetCurrentError("Cannot normalize non-WAV file. Only WAV files can be edited.")
+				return m, nil
+			}
+			if file.Metadata == nil || file.Corrupted {
+				return m, nil
+			}
+			if !m.backupFile(file.Name) {
+				return m, nil
+			}
+			gainDB, err := wavfile.NormalizeFile(file.Name, 0)
+			if err != nil {
+				m.SetCurrentError(fmt.Sprintf("Failed to normalize %s: %v", file.Name, err))
+				return m, nil
+			}
+			// The length is unchanged, so the markers stay
+			m.reloadEditedFile(m.cursor)
+			m.statusMessage = fmt.Sprintf("Normalized %s to 0 dBFS (%+.1f dB)", file.Name, gainDB)
 		}
 	}
 	return m, nil
 }
 
+// backupFile copies a file into .smplr/backups before a destructive edit,
+// reporting whether the edit may go ahead
+func (m *model) backupFile(filename string) bool {
+	if _, err := wavfile.BackupFile(filename); err != nil {
+		m.SetCurrentError(fmt.Sprintf("Edit cancelled: %v", err))
+		return false
+	}
+	return true
+}
+
+// restoreOriginal puts back the selected file as it was before its first
+// destructive edit
+func (m *model) restoreOriginal() {
+	file := &(*m.files)[m.cursor]
+	if err := wavfile.RestoreOriginal(file.Name); err != nil {
+		m.SetCurrentError(fmt.Sprintf("Failed to restore %s: %v", file.Name, err))
+		return
+	}
+	if metadata := m.reloadEditedFile(m.cursor); metadata != nil {
+		file.StartFrame = 0
+		file.EndFrame = metadata.NumFrames - 1
+		m.updateMarkerStepSize()
+	}
+	m.statusMessage = "Restored the original " + file.Name
+}
+
+// commitTrim cuts the selected file down to the region between its
+// markers, discarding the audio outside them
+func (m *model) commitTrim() {
+	// Check if file exists before trimming
+	if _, err := os.Stat((*m.files)[m.cursor].Name); os.IsNotExist(err) {
+		m.SetCurrentError(fmt.Sprintf("File does not exist: %s", (*m.files)[m.cursor].Name))
+
+		// Remove file from the list
+		fileToRemove := m.cursor
+		*m.files = append((*m.files)[:fileToRemove], (*m.files)[fileToRemove+1:]...)
+
+		// Adjust cursor to valid non-corrupted file
+		m.adjustCursorToValidFile()
+		return
+	}
+	if !m.backupFile((*m.files)[m.cursor].Name) {
+		return
+	}
+	err := m.audio.TrimFile(
+		(*m.files)[m.cursor].Name,
+		(*m.files)[m.cursor].StartFrame,
+		(*m.files)[m.cursor].EndFrame,
+	)
+	if err != nil {
+		m.SetCurrentError(fmt.Sprintf("Failed to trim %s: %v", (*m.files)[m.cursor].Name, err))
+		return
+	}
+	if metadata := m.reloadEditedFile(m.cursor); metadata != nil {
+		// Reset markers to the start and end of the new file
+		(*m.files)[m.cursor].StartFrame = 0
+		(*m.files)[m.cursor].EndFrame = metadata.NumFrames - 1
+		// Update marker step size for the new file length
+		m.updateMarkerStepSize()
+	}
+}
+
+// reloadEditedFile recreates the player of a file rewritten on disk and
+// rereads its metadata, so playback and the waveform pick up the new audio.
+// It returns the new metadata, or nil if it couldn't be read.
+func (m *model) reloadEditedFile(index int) *wavfile.Metadata {
+	file := &(*m.files)[index]
+	// The audio changed, so an earlier tuner reading no longer holds
+	file.DetectedHz = 0
+
+	// Remove all pitched versions of this file
+	if err := wavfile.RemoveAllPitchedVersions(file.Name); err != nil {
+		m.SetCurrentError(fmt.Sprintf("Warning: failed to remove pitched versions: %v", err))
+	}
+
+	// Destroy the old player and create a new one
+	if err := m.audio.DestroyPlayer(file.PlayerId); err != nil {
+		m.SetCurrentError(fmt.Sprintf("Warning: failed to destroy player: %v", err))
+	}
+	newPlayerID, err := m.audio.CreatePlayer(file.Name)
+	if err != nil {
+		m.SetCurrentError(fmt.Sprintf("Failed to create new player: %v", err))
+	} else {
+		file.PlayerId = newPlayerID
+		if err := m.applyPlayerSettings(file); err != nil {
+			m.SetCurrentError(fmt.Sprintf("Warning: failed to restore player settings: %v", err))
+		}
+	}
+
+	// Reload metadata after editing
+	metadata, err := wavfile.CachedMetadata(file.Name, nil)
+	if err != nil {
+		m.SetCurrentError(fmt.Sprintf("Warning: failed to reload metadata: %v", err))
+		return nil
+	}
+	file.Metadata = metadata
+	return metadata
+}
+
+// startRecording starts recording to a timestamp-based filename
+func (m *model) startRecording() error {
+	filename := fmt.Sprintf("recording_%s.%s", time.Now().Format("20060102_150405"), m.recordingFormat)
+	if err := m.startCapture(filename); err != nil {
+		return err
+	}
+	m.recording = true
+	m.cursor = -1 // Deselect all files while recording
+	m.recordingFilename = filename
+	m.recordingStarted = time.Now()
+	m.recordingLimit = m.recordingLength.duration(m.clock.BPM())
+	return nil
+}
+
+// startCapture starts recording the --record-source to filename: system
+// audio, smplr's own output, or the default input
+func (m *model) startCapture(filename string) error {
+	switch m.recordSource {
+	case "output":
+		if m.bounceFilename != "" {
+			return fmt.Errorf("stop bouncing (B) before recording the output")
+		}
+		return m.audio.StartBounce(filename)
+	case "input":
+		return m.audio.RecordInput(filename, "")
+	default:
+		return m.audio.Record(filename)
+	}
+}
+
+// stopCapture stops the recording startCapture started
+func (m *model) stopCapture() error {
+	if m.recordSource == "output" {
+		return m.audio.StopBounce()
+	}
+	return m.audio.StopRecording()
+}
+
+// addLevelHistory adds the current levels to the level history, keeping
+// the loudest reading of each interval
+func (m *model) addLevelHistory() {
+	now := time.Now()
+	newPoint := now.Sub(m.levelHistoryAt) >= levelHistoryInterval
+	if newPoint {
+		m.levelHistoryAt = now
+	}
+	for side, level := range m.decibelLevels {
+		history := m.levelHistory[side]
+		if newPoint || len(history) == 0 {
+			history = append(history, level)
+			if len(history) > levelHistorySize {
+				history = history[1:]
+			}
+		} else {
+			history[len(history)-1] = max(history[len(history)-1], level)
+		}
+		m.levelHistory[side] = history
+	}
+}
+
+// recordingTimer returns a command that stops the current recording once
+// it reaches its limit, or nil when it has none
+func (m model) recordingTimer() tea.Cmd {
+	if m.recordingLimit <= 0 {
+		return nil
+	}
+	filename := m.recordingFilename
+	return tea.Tick(m.recordingLimit, func(time.Time) tea.Msg {
+		return recordingTimeoutMsg{filename: filename}
+	})
+}
+
+// startPunchIn records a take into the selected sample from its start
+// marker, punching out at its end marker
+func (m *model) startPunchIn(mix bool) tea.Cmd {
+	file := (*m.files)[m.cursor]
+	m.punchTarget = m.cursor
+	m.punchMix = mix
+	m.recording = true
+	m.cursor = -1 // Deselect all files while recording
+	// Takes are read back, so they're always WAV
+	m.recordingFilename = fmt.Sprintf("punch_%s.wav", time.Now().Format("20060102_150405"))
+	m.recordingStarted = time.Now()
+	regionFrames := file.EndFrame - file.StartFrame + 1
+	m.recordingLimit = time.Duration(float64(regionFrames) / float64(file.Metadata.SampleRate) * float64(time.Second))
+	if err := m.startCapture(m.recordingFilename); err != nil {
+		m.recording = false
+		m.cursor = m.punchTarget
+		m.punchTarget = -1
+		m.recordingFilename = ""
+		m.SetCurrentError(fmt.Sprintf("Failed to start punch-in: %v", err))
+		return nil
+	}
+	return m.recordingTimer()
+}
+
+// startSlotRecording records a take that replaces the selected sample's
+// file, keeping its mapping and settings
+func (m *model) startSlotRecording() tea.Cmd {
+	// Takes replace a WAV, so they're always WAV
+	filename := fmt.Sprintf("take_%s.wav", time.Now().Format("20060102_150405"))
+	if err := m.startCapture(filename); err != nil {
+		m.SetCurrentError(fmt.Sprintf("Failed to start recording: %v", err))
+		return nil
+	}
+	m.replaceTarget = m.cursor
+	m.recording = true
+	m.cursor = -1 // Deselect all files while recording
+	m.recordingFilename = filename
+	m.recordingStarted = time.Now()
+	m.recordingLimit = m.recordingLength.duration(m.clock.BPM())
+	return m.recordingTimer()
+}
+
+// finishSlotRecording moves a take over the file of the sample it
+// replaces, backing the file up first
+func (m *model) finishSlotRecording() {
+	index := m.replaceTarget
+	take := m.recordingFilename
+	m.replaceTarget = -1
+	m.recordingFilename = ""
+	m.cursor = index
+
+	file := &(*m.files)[index]
+	if !m.backupFile(file.Name) {
+		os.Remove(take)
+		return
+	}
+	// Markers around the whole old file go around the whole take
+	wholeFile := file.Metadata != nil && file.StartFrame == 0 && file.EndFrame == file.Metadata.NumFrames-1
+	if err := os.Rename(take, file.Name); err != nil {
+		m.SetCurrentError(fmt.Sprintf("Failed to replace %s, the take is in %s: %v", file.Name, take, err))
+		return
+	}
+	if metadata := m.reloadEditedFile(index); metadata != nil {
+		if wholeFile || file.EndFrame >= metadata.NumFrames {
+			file.EndFrame = metadata.NumFrames - 1
+		}
+		file.StartFrame = min(file.StartFrame, file.EndFrame)
+		m.updateMarkerStepSize()
+	}
+	m.statusMessage = fmt.Sprintf("Recorded over %s", file.Name)
+}
+
+// finishPunchIn writes the punch-in take into its sample and removes the
+// take
+func (m *model) finishPunchIn() {
+	index := m.punchTarget
+	take := m.recordingFilename
+	m.punchTarget = -1
+	m.recordingFilename = ""
+	m.cursor = index
+	defer os.Remove(take)
+
+	file := &(*m.files)[index]
+	// The take is at the capture rate, which may not be the sample's
+	if metadata, err := wavfile.ReadMetadata(take); err != nil {
+		m.SetCurrentError(fmt.Sprintf("Failed to read take: %v", err))
+		return
+	} else if metadata.SampleRate != file.Metadata.SampleRate {
+		resampled := strings.TrimSuffix(take, ".wav") + "_resampled.wav"
+		if err := m.audio.ResampleFile(take, resampled, int(file.Metadata.SampleRate), file.Metadata.BitsPerSample); err != nil {
+			m.SetCurrentError(fmt.Sprintf("Failed to resample take: %v", err))
+			return
+		}
+		defer os.Remove(resampled)
+		take = resampled
+	}
+
+	if !m.backupFile(file.Name) {
+		return
+	}
+	if err := wavfile.PunchIn(file.Name, take, file.StartFrame, file.EndFrame, m.punchMix); err != nil {
+		m.SetCurrentError(fmt.Sprintf("Failed to punch into %s: %v", file.Name, err))
+		return
+	}
+	// The length is unchanged, so the markers stay
+	m.reloadEditedFile(index)
+	if m.punchMix {
+		m.statusMessage = fmt.Sprintf("Mixed the take into %s", file.Name)
+	} else {
+		m.statusMessage = fmt.Sprintf("Replaced the region of %s with the take", file.Name)
+	}
+}
+
+// stopRecording stops the current recording and prompts for its filename,
+// or writes the take into the sample it was recorded for
+func (m *model) stopRecording() {
+	m.recording = false
+	if err := m.stopCapture(); err != nil {
+		m.SetCurrentError(fmt.Sprintf("Failed to stop recording: %v", err))
+	}
+	// Stay armed for the next take
+	if m.armed {
+		if err := m.audio.Arm(m.preRoll); err != nil {
+			m.armed = false
+			m.layoutViewport()
+			m.SetCurrentError(fmt.Sprintf("Failed to re-arm recording: %v", err))
+		}
+	}
+	if m.punchTarget >= 0 {
+		m.finishPunchIn()
+		return
+	}
+	if m.normalizeRecordings && m.recordingFilename != "" {
+		if filepath.Ext(m.recordingFilename) != ".wav" {
+			m.SetCurrentError("Only WAV recordings can be normalized")
+		} else if gainDB, err := wavfile.NormalizeFile(m.recordingFilename, recordingNormalizeDB); err != nil {
+			m.SetCurrentError(fmt.Sprintf("Failed to normalize recording: %v", err))
+		} else {
+			m.statusMessage = fmt.Sprintf("Normalized recording by %+.1f dB", gainDB)
+		}
+	}
+	if m.replaceTarget >= 0 {
+		m.finishSlotRecording()
+		return
+	}
+	if m.recordingFilename != "" {
+		// Enter renaming mode to prompt user for new filename
+		m.renamingRecording = true
+		m.editing = true
+		m.editField = "filename"
+		// Pre-fill with base name without extension and timestamp
+		m.editValue = "recording"
+	}
+}
+
+// playSample plays the region of files[index], or its whole chain, choking
+// its group, and records the trigger from the given source
+func (m *model) playSample(index int, source string) error {
+	player.Choke(*m.files, m.audio, index)
+	if (*m.files)[index].ChainNext != "" {
+		chain := wavfile.ChainIndices(*m.files, index)
+		if err := m.audio.PlayChain(player.ChainLinks(*m.files, chain, 0)); err != nil {
+			return fmt.Errorf("Error playing chain: %w", err)
+		}
+		for _, i := range chain {
+			(*m.files)[i].PlayingCount++
+		}
+		m.recordTrigger(index, source)
+		return nil
+	}
+
+	file := &(*m.files)[index]
+	if err := m.audio.PlayRegion(file.PlayerId, file.Name, file.StartFrame, file.EndFrame, file.Cents()); err != nil {
+		return fmt.Errorf("Error playing region: %w", err)
+	}
+	file.PlayingCount++
+	m.recordTrigger(index, source)
+	return nil
+}
+
+// recordKeyboardTrigger adds a trigger history entry for the selected file
+func (m *model) recordKeyboardTrigger() {
+	m.recordTrigger(m.cursor, player.SourceKeyboard)
+}
+
+// recordTrigger adds a trigger of files[index] that didn't come through the
+// player to the trigger history
+func (m *model) recordTrigger(index int, source string) {
+	file := (*m.files)[index]
+	m.triggerHistory.Add(player.TriggerMsg{
+		Time:     time.Now(),
+		Source:   source,
+		Channel:  file.MidiChannel,
+		Note:     file.MidiNote,
+		Velocity: 127,
+		Sample:   file.Name,
+	})
+}
+
+// kitName names exports after the kit directory
+func kitName() string {
+	if dir, err := os.Getwd(); err == nil && filepath.Base(dir) != string(filepath.Separator) {
+		return filepath.Base(dir)
+	}
+	return "kit"
+}
+
 func (m *model) SetCurrentError(errMsg string) {
 	// Set current error message
 	m.currentError = errMsg
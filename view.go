@@ -2,12 +2,21 @@ package main
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
+	"time"
+
+	"smplr/player"
+	"smplr/wavfile"
 
 	"github.com/charmbracelet/lipgloss"
 )
 
 func (m model) View() string {
+	if m.showHelp {
+		return renderHelp(m.windowWidth, m.windowHeight, m.helpScroll)
+	}
+
 	var b strings.Builder
 	var listContent strings.Builder
 
@@ -25,16 +34,38 @@ func (m model) View() string {
 
 	// Header row (outside viewport, always visible)
 	header := fmt.Sprintf("%-40s  %-7s  %-5s  %-5s", "Name", "Channel", "Note", "Pitch")
+	if m.padGrid {
+		header = fmt.Sprintf("%-64s", "Pads")
+	}
 	b.WriteString(headerStyle.Render(header))
+	bankStart, bankEnd := m.bankRange()
+	shown := m.shownFiles()
+	bankStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("214")).
+		Bold(true)
+	if m.search != "" {
+		b.WriteString("  " + bankStyle.Render(fmt.Sprintf("/%s: %d of %d", m.search, len(shown), len(*m.files))))
+	} else if banks := m.banks.Count(len(*m.files)); banks > 1 {
+		b.WriteString("  " + bankStyle.Render(fmt.Sprintf("Bank %s (%d/%d): %d-%d of %d",
+			player.BankLabel(m.bank()), m.bank()+1, banks, bankStart+1, bankEnd, len(*m.files))))
+	}
+	if m.sorting != nil && !m.padGrid {
+		b.WriteString("  " + bankStyle.Render("by "+m.sorting.name))
+	}
 	b.WriteString("\n")
 	b.WriteString(headerStyle.Render(strings.Repeat("-", 64)))
 	b.WriteString("\n")
+	m.layout.listTop = strings.Count(b.String(), "\n")
+	m.layout.waveTop = -1
 
 	if len(*m.files) == 0 {
-		listContent.WriteString("No .wav files found in current directory.\n")
+		listContent.WriteString("No audio files found in current directory.\n")
+	} else if m.padGrid {
+		listContent.WriteString(renderPadGrid(*m.files, bankStart, m.banks.Size(), m.cursor, m.shows))
 	} else {
-		// File rows (inside viewport)
-		for i, file := range *m.files {
+		// File rows of the search matches or the selected bank (inside viewport)
+		for _, i := range shown {
+			file := (*m.files)[i]
 			cursor := "  "
 			if m.cursor == i && !m.recording {
 				cursor = "> "
@@ -55,8 +86,11 @@ func (m model) View() string {
 			}
 
 			channelStr := fmt.Sprintf("%d", file.MidiChannel)
-			noteStr := fmt.Sprintf("%d", file.MidiNote)
+			noteStr := wavfile.NoteName(file.MidiNote)
 			pitchStr := fmt.Sprintf("%d", file.Pitch)
+			if file.FineTune != 0 {
+				pitchStr += fmt.Sprintf("%+d¢", file.FineTune)
+			}
 
 			// Highlight field being edited
 			if m.cursor == i && m.editing && !m.recording {
@@ -80,6 +114,41 @@ func (m model) View() string {
 			if file.Loading {
 				loadingIcon = "↻ "
 			}
+			if file.Favorite {
+				loadingIcon += "★ "
+			}
+			if file.ChainNext != "" {
+				loadingIcon += "⇢ "
+			}
+			if file.Chromatic {
+				loadingIcon += "♪ "
+			}
+			if file.Loop {
+				loadingIcon += "∞ "
+			}
+			if file.Reverse {
+				loadingIcon += "◀ "
+			}
+			if file.PlayMode == wavfile.PlayModeGate {
+				loadingIcon += "⊓ "
+			}
+			if file.ChokeGroup != 0 {
+				loadingIcon += fmt.Sprintf("✕%d ", file.ChokeGroup)
+			}
+			if file.GainDB != 0 {
+				loadingIcon += fmt.Sprintf("%+.1fdB ", file.GainDB)
+			}
+			if file.StereoWidth < 100 {
+				loadingIcon += fmt.Sprintf("↔%d%% ", file.StereoWidth)
+			}
+			if file.Pan < 0 {
+				loadingIcon += fmt.Sprintf("L%d ", -file.Pan)
+			} else if file.Pan > 0 {
+				loadingIcon += fmt.Sprintf("R%d ", file.Pan)
+			}
+			if file.ChannelPairs() > 0 {
+				loadingIcon += fmt.Sprintf("⧉%s ", file.ChannelPairLabel())
+			}
 			nameWithIcon := loadingIcon + name
 			if len(nameWithIcon) > 38 {
 				nameWithIcon = nameWithIcon[:35] + "..."
@@ -99,15 +168,137 @@ func (m model) View() string {
 	m.viewport.SetContent(listContent.String())
 
 	// Build final view (viewport after header)
-	b.WriteString(m.viewport.View())
+	if m.splitView {
+		paneWidth := m.windowWidth / 2
+		left := lipgloss.NewStyle().Width(paneWidth).MaxWidth(paneWidth).Render(m.viewport.View())
+		right := renderKitPane(m.otherKit, m.windowWidth-paneWidth, m.viewport.Height, m.kitFocused)
+		b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, left, right))
+	} else {
+		b.WriteString(m.viewport.View())
+	}
 	b.WriteString("\n")
 
+	b.WriteString(m.renderStatus())
+
+	// Display waveform for the selected file (not while recording)
+	if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
+		b.WriteString("\n")
+		file := (*m.files)[m.cursor]
+		if tags := file.TagLabel(); tags != "" {
+			tagStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("33"))
+			b.WriteString(tagStyle.Render(tags) + "\n")
+		}
+		// Long files fill in left to right while loading
+		metadata, endFrame := file.Metadata, file.EndFrame
+		if metadata == nil && file.PartialMetadata != nil {
+			metadata, endFrame = file.PartialMetadata, file.PartialMetadata.NumFrames-1
+		}
+		viewStart, viewFrames := 0, 0
+		if metadata != nil {
+			viewStart, viewFrames = m.waveWindow(metadata.NumFrames)
+		}
+		m.layout.waveTop = strings.Count(b.String(), "\n")
+		waveform := RenderWaveformForFile(
+			metadata,
+			m.windowWidth,
+			viewStart,
+			viewFrames,
+			file.StartFrame,
+			endFrame,
+			file.ChannelPair,
+			m.selectedCue,
+			m.activeMarker,
+			m.markerStepSize,
+		)
+		b.WriteString(waveform)
+	}
+
+	m.layout.lines = strings.Count(b.String(), "\n") + 1
+	return b.String()
+}
+
+// renderStatus renders everything drawn between the list and the waveform:
+// recording and transport status, prompts, messages and the open panes.
+// layoutViewport counts its lines to size the list.
+func (m model) renderStatus() string {
+	var b strings.Builder
+
+	editingStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color("62")).
+		Foreground(lipgloss.Color("230"))
+
 	if m.recording {
 		recordingStyle := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("196")).
 			Bold(true)
-		b.WriteString(recordingStyle.Render("● RECORDING") + "\n")
-		b.WriteString(renderLevelMeter(m.decibelLevel, 50) + "\n")
+		status := "● RECORDING"
+		if m.punchTarget >= 0 && m.punchTarget < len(*m.files) {
+			status = "● PUNCHING IN to " + (*m.files)[m.punchTarget].Name
+		}
+		if m.replaceTarget >= 0 && m.replaceTarget < len(*m.files) {
+			status = "● RECORDING OVER " + (*m.files)[m.replaceTarget].Name
+		}
+		if m.recordingLimit > 0 {
+			status += fmt.Sprintf(" %.1fs / %.1fs", time.Since(m.recordingStarted).Seconds(), m.recordingLimit.Seconds())
+		}
+		b.WriteString(recordingStyle.Render(status) + "\n")
+		b.WriteString("L " + renderLevelMeter(m.decibelLevels[0], 50) + " " + renderSparkline(m.levelHistory[0]) + "\n")
+		b.WriteString("R " + renderLevelMeter(m.decibelLevels[1], 50) + " " + renderSparkline(m.levelHistory[1]) + "\n")
+	}
+
+	if m.armed && !m.recording {
+		armedStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Bold(true)
+		// Histories on one line, meters on the next, both sides side by side
+		b.WriteString(armedStyle.Render(fmt.Sprintf("○ ARMED %.1fs ", m.preRoll)))
+		b.WriteString(renderSparkline(m.levelHistory[0]) + " " + renderSparkline(m.levelHistory[1]) + "\n")
+		b.WriteString("L " + renderLevelMeter(m.decibelLevels[0], 20) + " R " + renderLevelMeter(m.decibelLevels[1], 20) + "\n")
+	}
+
+	if m.bounceFilename != "" {
+		bounceStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Bold(true)
+		b.WriteString(bounceStyle.Render("● BOUNCING to "+m.bounceFilename) + "\n")
+	}
+
+	b.WriteString(renderMaster(m.masterVolume, m.limiter) + "\n")
+
+	if m.cursor >= 0 && m.cursor < len(*m.files) && (*m.files)[m.cursor].DetectedHz > 0 {
+		hz := (*m.files)[m.cursor].DetectedHz
+		note, cents := wavfile.FrequencyToNote(hz)
+		tunerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("33"))
+		b.WriteString(tunerStyle.Render(fmt.Sprintf("♩ %s %+.0f cents (%.1f Hz)", wavfile.NoteName(note), cents, hz)) + "\n")
+	}
+
+	if voices := m.audio.ActiveVoices(); voices > 0 {
+		voiceColor := "70"
+		if voices >= m.maxVoices {
+			// Further hits steal voices
+			voiceColor = "196"
+		}
+		voiceStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(voiceColor))
+		b.WriteString(voiceStyle.Render(fmt.Sprintf("♫ %d/%d voices", voices, m.maxVoices)) + "\n")
+	}
+
+	if m.scrub {
+		scrubStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+		b.WriteString(scrubStyle.Render("◆ SCRUB: markers preview as they move") + "\n")
+	}
+
+	if subdivision := m.roller.Subdivision(); subdivision > 0 {
+		rollStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Bold(true)
+		b.WriteString(rollStyle.Render(fmt.Sprintf("⟳ ROLL 1/%d", subdivision)) + "\n")
+	}
+
+	if transport := m.transportBar(); transport != "" {
+		transportStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Bold(true)
+		b.WriteString(transportStyle.Render(transport) + "\n")
 	}
 
 	// Display filename input prompt when renaming recording
@@ -117,10 +308,293 @@ func (m model) View() string {
 			Bold(true)
 		b.WriteString(promptStyle.Render("Enter filename: "))
 		b.WriteString(editingStyle.Render(m.editValue + "_"))
-		b.WriteString(".wav\n")
+		b.WriteString(filepath.Ext(m.recordingFilename) + "\n")
 		b.WriteString("(Press Enter to save, Esc to keep timestamp)\n")
 	}
 
+	// Display region export filename prompt
+	if m.editing && m.editField == "exportregion" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render("Export region as: "))
+		b.WriteString(editingStyle.Render(m.editValue + "_"))
+		b.WriteString(".wav\n")
+		b.WriteString("(Press Enter to save, Esc to cancel)\n")
+	}
+
+	// Display song prompt
+	if m.editing && m.editField == "song" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render(fmt.Sprintf("Song of patterns 1-%d, each with an optional repeat count (1x4 2x2 1): ", m.looper.Patterns())))
+		b.WriteString(editingStyle.Render(m.editValue+"_") + "\n")
+		b.WriteString("(Press Enter to play, Esc to cancel)\n")
+	}
+
+	// Display search prompt
+	if m.editing && m.editField == "search" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render("/"))
+		b.WriteString(editingStyle.Render(m.editValue+"_") + "\n")
+		b.WriteString("(Press Enter to keep the filter, Esc to clear it)\n")
+	}
+
+	// Display tags prompt
+	if m.editing && m.editField == "tags" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render("Tags: "))
+		b.WriteString(editingStyle.Render(m.editValue+"_") + "\n")
+		b.WriteString("(Separate tags with spaces, e.g. kick 808 dry; Enter to save, Esc to cancel)\n")
+	}
+
+	// Display kit directory prompt
+	if m.editing && m.editField == "kitdir" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render("Open kit directory: "))
+		b.WriteString(editingStyle.Render(m.editValue+"_") + "\n")
+		b.WriteString("(Press Enter to open, Esc to cancel)\n")
+	}
+
+	// Display import prompt
+	if m.editing && m.editField == "import" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render("Import sample from: "))
+		b.WriteString(editingStyle.Render(m.editValue+"_") + "\n")
+		b.WriteString("(Press Enter to import, Esc to cancel)\n")
+	}
+
+	// Display crossfade input prompt
+	if m.editing && m.editField == "crossfade" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render("Crossfade into next sample (ms): "))
+		b.WriteString(editingStyle.Render(m.editValue + "_"))
+		b.WriteString("\n")
+	}
+
+	// Display stereo width input prompt
+	if m.editing && m.editField == "width" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render("Stereo width (0-100%, 0 = mono): "))
+		b.WriteString(editingStyle.Render(m.editValue + "_"))
+		b.WriteString("\n")
+	}
+
+	// Display pan input prompt
+	if m.editing && m.editField == "pan" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render("Pan (-100 left to 100 right, 0 = center): "))
+		b.WriteString(editingStyle.Render(m.editValue + "_"))
+		b.WriteString("\n")
+	}
+
+	// Display envelope input prompt
+	if m.editing && m.editField == "envelope" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		file := (*m.files)[m.cursor]
+		b.WriteString(promptStyle.Render("Envelope (attack ms, decay ms, sustain %, release ms): "))
+		b.WriteString(editingStyle.Render(m.editValue + "_"))
+		b.WriteString(fmt.Sprintf("  (now %d %d %d %d)\n", file.AttackMs, file.DecayMs, file.SustainLevel, file.ReleaseMs))
+	}
+
+	// Display fade input prompt
+	if m.editing && m.editField == "fade" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render("Fade (fade-in ms from start marker, fade-out ms to end marker): "))
+		b.WriteString(editingStyle.Render(m.editValue + "_"))
+		b.WriteString("\n")
+	}
+
+	// Display punch-in prompt
+	if m.editing && m.editField == "punch" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Bold(true)
+		b.WriteString(promptStyle.Render("Record into this sample: (r)eplace or (m)ix the region between the markers, or replace the whole (f)ile"))
+		b.WriteString("\n")
+	}
+
+	// Display cue name prompt
+	if m.editing && m.editField == "cue" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render("Cue name at marker (empty removes an existing cue): "))
+		b.WriteString(editingStyle.Render(m.editValue + "_"))
+		b.WriteString("\n")
+	}
+
+	// Display CC learn prompt
+	if m.editing && m.editField == "cclearn" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render("Learn CC for (g)ain, (p)itch, (s)tart, (e)nd, master (v)olume, or (x) clear this sample's mappings"))
+		b.WriteString("\n")
+	}
+
+	// Display swing input prompt
+	if m.editing && m.editField == "swing" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render(fmt.Sprintf("Swing (%d-%d%%, %d = straight): ", player.SwingStraight, player.SwingMax, player.SwingStraight)))
+		b.WriteString(editingStyle.Render(m.editValue + "_"))
+		b.WriteString("\n")
+	}
+
+	// Display choke group input prompt
+	if m.editing && m.editField == "choke" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render("Choke group (1-16, 0 = none): "))
+		b.WriteString(editingStyle.Render(m.editValue + "_"))
+		b.WriteString("\n")
+	}
+
+	// Display gain input prompt
+	if m.editing && m.editField == "gain" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render("Gain (-60 to 0 dB): "))
+		b.WriteString(editingStyle.Render(m.editValue + "_"))
+		b.WriteString("\n")
+	}
+
+	// Display gain staging suggestions
+	if m.editing && m.editField == "gainstaging" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		for _, suggestion := range m.gainSuggestions {
+			b.WriteString(fmt.Sprintf("%-30s peak %6.1f dB  rms %6.1f dB  gain %+5.1f -> %+5.1f dB\n",
+				suggestion.name, suggestion.peakDB, suggestion.rmsDB, suggestion.currentDB, suggestion.suggestedDB))
+		}
+		b.WriteString(promptStyle.Render(fmt.Sprintf("Apply gains for %.1f dB headroom with %d pads hit together? (y/n)", m.headroomDB, m.headroomVoices)))
+		b.WriteString("\n")
+	}
+
+	// Display time-stretch prompt
+	if m.editing && m.editField == "stretch" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		file := (*m.files)[m.cursor]
+		b.WriteString(promptStyle.Render(fmt.Sprintf("Stretch from %.1f BPM to (tempo is %.1f): ", regionBPM(&file), m.clock.BPM())))
+		b.WriteString(editingStyle.Render(m.editValue + "_"))
+		b.WriteString("\n")
+	}
+
+	// Display chop prompt
+	if m.editing && m.editField == "chop" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		file := (*m.files)[m.cursor]
+		b.WriteString(promptStyle.Render(fmt.Sprintf("Chop: first note for the slices on channel %d (e.g. 36 or C1): ", file.MidiChannel)))
+		b.WriteString(editingStyle.Render(m.editValue + "_"))
+		b.WriteString("\n")
+	}
+
+	// Display commit trim prompt
+	if m.editing && m.editField == "committrim" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Bold(true)
+		file := (*m.files)[m.cursor]
+		b.WriteString(promptStyle.Render(fmt.Sprintf("Commit trim: permanently cut %s to frames %d-%d? (y/n)", file.Name, file.StartFrame, file.EndFrame)))
+		b.WriteString("\n")
+	}
+
+	// Display delete confirmation
+	if m.editing && m.editField == "delete" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Bold(true)
+		name := (*m.files)[m.cursor].Name
+		if m.copies(name) > 1 {
+			b.WriteString(promptStyle.Render(fmt.Sprintf("Remove this copy of %s? The file stays for the others (y/n)", name)))
+		} else {
+			b.WriteString(promptStyle.Render(fmt.Sprintf("Delete %s and move it to the trash? (y/n)", name)))
+		}
+		b.WriteString("\n")
+	}
+
+	// Display restore prompt
+	if m.editing && m.editField == "restore" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Bold(true)
+		b.WriteString(promptStyle.Render(fmt.Sprintf("Restore %s as it was before its first edit? (y/n)", (*m.files)[m.cursor].Name)))
+		b.WriteString("\n")
+	}
+
+	// Display GM drum map prompt
+	if m.editing && m.editField == "gmdrums" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render(fmt.Sprintf("Map %d samples to General MIDI drum notes by name? (y/n)", countGMDrumMatches(*m.files))))
+		b.WriteString("\n")
+	}
+
+	if m.editing && m.editField == "sort" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render("Sort by (n)ame, (d)uration, (m)IDI note or (r)ecently added, or show the (l)ist order"))
+		b.WriteString("\n")
+	}
+
+	// Display duplicate consolidation prompt
+	if m.editing && m.editField == "consolidate" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		for _, group := range m.duplicateGroups {
+			b.WriteString("Duplicates: " + strings.Join(group, " = ") + "\n")
+		}
+		b.WriteString(promptStyle.Render("Play every entry from the first file of its group, keeping its channel, note, settings and CC mappings? (y/n)"))
+		b.WriteString("\n")
+	}
+
+	// Display status message if present
+	if m.statusMessage != "" {
+		statusStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33"))
+		b.WriteString(statusStyle.Render(m.statusMessage) + "\n")
+	}
+
+	// Display chain linking status
+	if m.linkSource >= 0 && m.linkSource < len(*m.files) {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Bold(true)
+		b.WriteString(promptStyle.Render(fmt.Sprintf("Chaining from %s: ", (*m.files)[m.linkSource].Name)))
+		b.WriteString("select the next sample and press L (L on the same sample clears the chain)\n")
+	}
+
 	// Display error message if present
 	if m.currentError != "" {
 		errorStyle := lipgloss.NewStyle().
@@ -129,23 +603,34 @@ func (m model) View() string {
 		b.WriteString(errorStyle.Render("ERROR: "+m.currentError) + "\n")
 	}
 
-	// Display waveform for the selected file (not while recording)
-	if !m.recording && len(*m.files) > 0 && m.cursor >= 0 && m.cursor < len(*m.files) {
-		b.WriteString("\n")
-		waveform := RenderWaveformForFile(
-			(*m.files)[m.cursor].Metadata,
-			m.windowWidth,
-			(*m.files)[m.cursor].StartFrame,
-			(*m.files)[m.cursor].EndFrame,
-			m.activeMarker,
-			m.markerStepSize,
-		)
-		b.WriteString(waveform)
+	if m.showTriggerHistory {
+		b.WriteString(renderTriggerHistory(m.triggerHistory, triggerHistoryPaneHeight))
+	}
+
+	if m.showMidiMonitor {
+		b.WriteString(renderMidiMonitor(m.midiMonitor, midiMonitorPaneHeight))
 	}
 
 	return b.String()
 }
 
+// renderMaster renders the master volume and limiter state
+func renderMaster(volume float32, limiter bool) string {
+	level := "-∞ dB"
+	if volume > 0 {
+		level = fmt.Sprintf("%.1f dB", wavfile.AmplitudeToDB(float64(volume)))
+	}
+	limiterState := "limiter on"
+	if !limiter {
+		limiterState = "limiter off"
+	}
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	if !limiter {
+		style = style.Foreground(lipgloss.Color("214"))
+	}
+	return style.Render(fmt.Sprintf("Master %s, %s", level, limiterState))
+}
+
 // renderLevelMeter renders a horizontal level meter for audio decibel levels
 func renderLevelMeter(db float32, width int) string {
 	// Decibel range: -60 dB (quiet) to 0 dB (max)
@@ -195,3 +680,131 @@ func renderLevelMeter(db float32, width int) string {
 
 	return meter.String()
 }
+
+// transportBar describes the internal sequencer: the pattern, tempo and
+// swing. It is empty while there's no pattern and the swing is straight.
+func (m model) transportBar() string {
+	state := m.looper.State()
+	swing := m.clock.Swing()
+	if state == player.LooperOff && swing == player.SwingStraight {
+		return ""
+	}
+
+	var pattern string
+	switch state {
+	case player.LooperOff:
+		pattern = "■ PATTERN off"
+	case player.LooperWaiting:
+		pattern = fmt.Sprintf("○ PATTERN %d bars: waiting for the first note", m.patternBars)
+	case player.LooperCapturing:
+		pattern = fmt.Sprintf("● PATTERN %d bars: capturing", m.patternBars)
+	case player.LooperPlaying:
+		// A loop is always the latest capture
+		current := m.looper.Pattern()
+		pattern = fmt.Sprintf("⟳ PATTERN %d, %d bars: looping %d notes", m.looper.Patterns(), current.Bars, current.NoteOns())
+	case player.LooperSong:
+		song, step, repeat := m.looper.SongPosition()
+		if step < len(song) {
+			pattern = fmt.Sprintf("♫ SONG step %d/%d: pattern %d, pass %d/%d", step+1, len(song), song[step].Pattern+1, repeat, song[step].Repeats)
+		}
+	}
+	if grid := m.looper.Quantize(); grid > 0 && (state == player.LooperPlaying || state == player.LooperSong) {
+		pattern += fmt.Sprintf(", quantized to %s at %.0f%%", player.QuantizeLabel(grid), m.quantizeStrength*100)
+	}
+	return fmt.Sprintf("%s | %.1f BPM | Swing %d%%", pattern, m.clock.BPM(), swing)
+}
+
+// sparkBlocks are the sparkline characters from quietest to loudest
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// renderSparkline renders a level history over the meter's -60 to 0 dB
+// range, one character per point, padded so it doesn't shift as it fills
+func renderSparkline(history []float32) string {
+	var b strings.Builder
+	b.WriteString(strings.Repeat(" ", max(levelHistorySize-len(history), 0)))
+	for _, db := range history {
+		level := int((min(max(db, -60), 0) + 60) / 60 * float32(len(sparkBlocks)-1))
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
+
+// triggerHistoryPaneHeight is the number of lines used by the trigger history pane
+const triggerHistoryPaneHeight = 10
+
+// renderTriggerHistory renders the most recent trigger events, newest last
+func renderTriggerHistory(history *player.TriggerHistory, height int) string {
+	var b strings.Builder
+
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("33"))
+	unmatchedStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240"))
+
+	b.WriteString(headerStyle.Render(fmt.Sprintf("%-12s  %-8s  %-4s  %-4s  %-3s  %s", "Time", "Source", "Ch", "Note", "Vel", "Sample")))
+	b.WriteString("\n")
+
+	rows := height - 1
+	events := history.Events()
+	if len(events) > rows {
+		events = events[len(events)-rows:]
+	}
+
+	for _, event := range events {
+		sample := event.Sample
+		if sample == "" {
+			sample = "(no match)"
+		}
+		line := fmt.Sprintf("%-12s  %-8s  %-4d  %-4d  %-3d  %s",
+			event.Time.Format("15:04:05.000"), event.Source, event.Channel, event.Note, event.Velocity, sample)
+		if event.Sample == "" {
+			line = unmatchedStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	// Pad so the pane keeps a fixed height
+	for i := len(events); i < rows; i++ {
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// midiMonitorPaneHeight is the number of lines used by the MIDI monitor pane
+const midiMonitorPaneHeight = 10
+
+// renderMidiMonitor renders the most recent incoming MIDI messages, newest last
+func renderMidiMonitor(monitor *player.MidiMonitor, height int) string {
+	var b strings.Builder
+
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("33"))
+
+	b.WriteString(headerStyle.Render(fmt.Sprintf("%-12s  %-8s  %-4s  %s", "Time", "Message", "Ch", "Data")))
+	b.WriteString("\n")
+
+	rows := height - 1
+	events := monitor.Events()
+	if len(events) > rows {
+		events = events[len(events)-rows:]
+	}
+
+	for _, event := range events {
+		channel := "-"
+		if event.Channel > 0 {
+			channel = fmt.Sprintf("%d", event.Channel)
+		}
+		b.WriteString(fmt.Sprintf("%-12s  %-8s  %-4s  %s\n",
+			event.Time.Format("15:04:05.000"), event.Kind, channel, event.Data))
+	}
+
+	// Pad so the pane keeps a fixed height
+	for i := len(events); i < rows; i++ {
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"smplr/mappings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// helpColumnWidth is the width of one column of the help overlay
+const helpColumnWidth = 48
+
+// helpKeyNames are the help overlay's names for keys whose key string
+// doesn't read well
+var helpKeyNames = map[string]string{
+	" ":          "space",
+	"up":         "↑",
+	"down":       "↓",
+	"shift+up":   "shift+↑",
+	"shift+down": "shift+↓",
+}
+
+// helpKeyLabel names the keys of a binding, as a range such as 0-9 when
+// there are too many to list
+func helpKeyLabel(keys []string) string {
+	if len(keys) > 3 {
+		return keys[0] + "-" + keys[len(keys)-1]
+	}
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key
+		if name, ok := helpKeyNames[key]; ok {
+			names[i] = name
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+// helpLines returns the lines of the help overlay: each group's title
+// followed by its keys and what they do, with a blank line between groups
+func helpLines() []string {
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("33")).
+		Bold(true)
+	keyStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("214"))
+
+	var lines []string
+	for i, group := range mappings.Bindings {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, titleStyle.Render(group.Title))
+		for _, binding := range group.Bindings {
+			lines = append(lines, keyStyle.Render(fmt.Sprintf("%-17s", helpKeyLabel(binding.Keys)))+binding.Description)
+		}
+	}
+	return lines
+}
+
+// helpPageHeight returns the rows of keys the help overlay shows at once
+func helpPageHeight(windowHeight int) int {
+	return max(windowHeight-3, 1)
+}
+
+// helpRows returns the number of rows the help takes laid out in as many
+// columns as fit the window
+func helpRows(windowWidth int, windowHeight int) int {
+	columns := max(windowWidth/helpColumnWidth, 1)
+	lines := len(helpLines())
+	return max((lines+columns-1)/columns, helpPageHeight(windowHeight))
+}
+
+// renderHelp renders the full-screen help overlay listing every key, laid
+// out in columns and scrolled down by scroll rows
+func renderHelp(windowWidth int, windowHeight int, scroll int) string {
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("33"))
+
+	lines := helpLines()
+	columns := max(windowWidth/helpColumnWidth, 1)
+	rows := helpRows(windowWidth, windowHeight)
+	page := helpPageHeight(windowHeight)
+
+	var b strings.Builder
+	b.WriteString(headerStyle.Render("Keys") + "\n")
+	for row := scroll; row < min(scroll+page, rows); row++ {
+		for column := range columns {
+			index := column*rows + row
+			if index >= len(lines) {
+				break
+			}
+			b.WriteString(lipgloss.NewStyle().Width(helpColumnWidth).Render(lines[index]))
+		}
+		b.WriteString("\n")
+	}
+	footer := "(? or Esc to close)"
+	if rows > page {
+		footer = "(j/k to scroll, ? or Esc to close)"
+	}
+	b.WriteString(footer)
+	return b.String()
+}
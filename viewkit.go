@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderKitPane renders the file list of the second kit in split view
+func renderKitPane(kit kitPane, width int, height int, focused bool) string {
+	var b strings.Builder
+
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("33"))
+	if focused {
+		headerStyle = headerStyle.Reverse(true)
+	}
+	selectedStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("170")).
+		Bold(true)
+
+	b.WriteString(headerStyle.Render(truncate(kit.dir, width)))
+	b.WriteString("\n")
+
+	if len(kit.files) == 0 {
+		b.WriteString("No audio files found.\n")
+		return b.String()
+	}
+
+	// Keep the cursor visible
+	offset := 0
+	if kit.cursor >= height-1 {
+		offset = kit.cursor - (height - 2)
+	}
+
+	for i := offset; i < len(kit.files) && i-offset < height-1; i++ {
+		line := "  " + kit.files[i]
+		if i == kit.cursor {
+			line = "> " + kit.files[i]
+		}
+		line = truncate(line, width)
+		if i == kit.cursor && focused {
+			line = selectedStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	return b.String()
+}
+
+// truncate shortens s to width, marking the cut with an ellipsis
+func truncate(s string, width int) string {
+	if width <= 3 || len(s) <= width {
+		return s
+	}
+	return fmt.Sprintf("%s...", s[:width-3])
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"smplr/wavfile"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// padCellWidth is the width of one pad in the grid
+const padCellWidth = 12
+
+// padRowHeight is the lines each row of pads takes, the gap included
+const padRowHeight = 3
+
+// padColumns returns the width of the pad grid: 8 across for banks of 64
+// or more, like a Launchpad, and 4 across otherwise, like an MPC
+func padColumns(bankSize int) int {
+	if bankSize >= 64 {
+		return 8
+	}
+	return 4
+}
+
+// padRow returns the row of the grid a slot of the bank is drawn on,
+// counting from the top
+func padRow(slot int, bankSize int) int {
+	columns := padColumns(bankSize)
+	rows := (bankSize + columns - 1) / columns
+	return rows - 1 - slot/columns
+}
+
+// renderPadGrid renders a bank of samples as a grid of pads with the first
+// slot at the bottom left, as controller profiles count them. Playing
+// samples light their pad, the cursor's pad is highlighted and samples
+// shows rejects, such as search misses, are dimmed.
+func renderPadGrid(files []wavfile.WavFile, start int, bankSize int, cursor int, shows func(int) bool) string {
+	padStyle := lipgloss.NewStyle().
+		Width(padCellWidth).
+		Background(lipgloss.Color("236")).
+		Foreground(lipgloss.Color("252"))
+	litStyle := padStyle.
+		Background(lipgloss.Color("46")).
+		Foreground(lipgloss.Color("16"))
+	selectedStyle := padStyle.
+		Background(lipgloss.Color("170")).
+		Foreground(lipgloss.Color("16")).
+		Bold(true)
+	emptyStyle := padStyle.
+		Background(lipgloss.Color("234")).
+		Foreground(lipgloss.Color("240"))
+
+	var b strings.Builder
+	columns := padColumns(bankSize)
+	rows := (bankSize + columns - 1) / columns
+	for row := rows - 1; row >= 0; row-- {
+		var names, notes []string
+		for column := range columns {
+			slot := row*columns + column
+			index := start + slot
+			if slot >= bankSize || index >= len(files) {
+				names = append(names, emptyStyle.Render(fmt.Sprintf("%2d", slot+1)))
+				notes = append(notes, emptyStyle.Render(""))
+				continue
+			}
+
+			file := files[index]
+			style := padStyle
+			switch {
+			case file.PlayingCount > 0:
+				style = litStyle
+			case index == cursor:
+				style = selectedStyle
+			case file.Corrupted, !shows(index):
+				style = emptyStyle
+			}
+			name := truncate(file.Name, padCellWidth-3)
+			names = append(names, style.Render(fmt.Sprintf("%2d %s", slot+1, name)))
+			notes = append(notes, style.Render(fmt.Sprintf("   %s ch%d", wavfile.NoteName(file.MidiNote), file.MidiChannel)))
+		}
+		b.WriteString(strings.Join(names, " ") + "\n")
+		b.WriteString(strings.Join(notes, " ") + "\n")
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -6,9 +6,20 @@ import (
 	"strings"
 
 	"smplr/wavfile"
+
+	"github.com/charmbracelet/lipgloss"
 )
 
-func renderBrailleWaveform(peaks []float64, width int) string {
+// peakStyle dims the transient peaks outside the solid RMS band
+var peakStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+// waveformHeight is the number of braille rows the waveform takes
+const waveformHeight = 4
+
+// renderBrailleWaveform draws height rows of braille in two layers: a
+// solid inner band up to the RMS level, for loudness, and sparse dimmed
+// dots up to the peak, for transients
+func renderBrailleWaveform(peaks []float64, rms []float64, width int, height int) string {
 	if len(peaks) == 0 {
 		return ""
 	}
@@ -26,14 +37,12 @@ func renderBrailleWaveform(peaks []float64, width int) string {
 	dotPattern := []int{0x01, 0x02, 0x04, 0x40, 0x08, 0x10, 0x20, 0x80}
 
 	// Multiple rows of braille for more vertical depth
-	brailleHeight := 4
+	brailleHeight := height
 	totalLevels := brailleHeight * 4 // 4 dots per column per character
 
-	// Each braille char shows 2 columns of waveform
+	// Each braille char shows 2 columns of waveform; zoomed in, a peak
+	// can span several columns
 	peaksPerColumn := float64(len(peaks)) / float64((width * 2))
-	if peaksPerColumn < 1 {
-		peaksPerColumn = 1
-	}
 
 	// Create grid of braille characters
 	grid := make([][]rune, brailleHeight)
@@ -44,6 +53,12 @@ func renderBrailleWaveform(peaks []float64, width int) string {
 		}
 	}
 
+	// Track the highest RMS level per character column for shading
+	rmsLevels := make([]int, width)
+	for i := range rmsLevels {
+		rmsLevels[i] = -1
+	}
+
 	for brailleCol := range width {
 		// Process 2 columns (left and right dots)
 		for subCol := range 2 {
@@ -59,6 +74,10 @@ func renderBrailleWaveform(peaks []float64, width int) string {
 				maxAbs := 0.0
 				loopStart := int(math.Round(start))
 				loopEnd := int(math.Round(end))
+				if loopEnd <= loopStart {
+					loopStart = int(start)
+					loopEnd = loopStart + 1
+				}
 				for i := loopStart; i < loopEnd; i++ {
 					if peaks[i] > maxAbs {
 						maxAbs = peaks[i]
@@ -71,8 +90,28 @@ func renderBrailleWaveform(peaks []float64, width int) string {
 					level = totalLevels - 1
 				}
 
-				// Fill dots from bottom up
+				// RMS across the same range of segments
+				rmsLevel := level
+				if len(rms) == len(peaks) && loopEnd > loopStart {
+					sumSquares := 0.0
+					for i := loopStart; i < loopEnd; i++ {
+						sumSquares += rms[i] * rms[i]
+					}
+					rmsLevel = int(math.Sqrt(sumSquares/float64(loopEnd-loopStart)) * float64(totalLevels-1))
+					if rmsLevel > level {
+						rmsLevel = level
+					}
+					if rmsLevel > rmsLevels[brailleCol] {
+						rmsLevels[brailleCol] = rmsLevel
+					}
+				}
+
+				// Fill dots from bottom up, solid to the RMS level and every
+				// other dot above it, always marking the peak itself
 				for l := 0; l <= level; l++ {
+					if l > rmsLevel && l < level && (l-rmsLevel)%2 == 1 {
+						continue
+					}
 					row := brailleHeight - 1 - (l / 4)
 					dotInChar := 3 - (l % 4)
 					dotIndex := subCol*4 + dotInChar
@@ -85,29 +124,89 @@ func renderBrailleWaveform(peaks []float64, width int) string {
 		}
 	}
 
-	// Build braille grid output
-	for _, row := range grid {
-		b.WriteString(string(row))
+	// Build braille grid output, dimming cells that sit above the RMS band
+	for rowIndex, row := range grid {
+		rowBottomLevel := (brailleHeight - 1 - rowIndex) * 4
+		var run []rune
+		runIsRMS := false
+		for col, ch := range row {
+			isRMS := rmsLevels[col] >= rowBottomLevel
+			if isRMS != runIsRMS && len(run) > 0 {
+				writeWaveformRun(&b, run, runIsRMS)
+				run = run[:0]
+			}
+			runIsRMS = isRMS
+			run = append(run, ch)
+		}
+		writeWaveformRun(&b, run, runIsRMS)
 		b.WriteString("\n")
 	}
 
 	return b.String()
 }
 
-// RenderWaveformForFile renders a waveform in braille with metadata
-func RenderWaveformForFile(metadata *wavfile.Metadata, width int, startFrame int, endFrame int, activeMarker string, markerStepSize int) string {
-	if metadata == nil || len(metadata.WaveformData.Peaks) == 0 {
+// writeWaveformRun writes a run of braille characters, dimmed unless inside the RMS band
+func writeWaveformRun(b *strings.Builder, run []rune, isRMS bool) {
+	if len(run) == 0 {
+		return
+	}
+	if isRMS {
+		b.WriteString(string(run))
+	} else {
+		b.WriteString(peakStyle.Render(string(run)))
+	}
+}
+
+// RenderWaveformForFile renders a waveform in braille with metadata,
+// showing viewFrames frames from viewStart. Multichannel files show the
+// mix, or channelPair when one is chosen. Cue points show as ◇ on the
+// marker line, ◆ for selectedCue, with their names underneath.
+func RenderWaveformForFile(metadata *wavfile.Metadata, width int, viewStart int, viewFrames int, startFrame int, endFrame int, channelPair int, selectedCue int, activeMarker string, markerStepSize int) string {
+	if metadata == nil || metadata.WaveformData.Mix == nil || metadata.NumFrames == 0 {
 		return "Loading waveform... ↻"
 	}
 
 	var b strings.Builder
 
 	// Info bar
-	b.WriteString(fmt.Sprintf("Duration: %.2fs | Frames: %d | Sample Rate: %d Hz | Step: %d frames\n",
+	b.WriteString(fmt.Sprintf("Duration: %.2fs | Frames: %d | Sample Rate: %d Hz | Step: %d frames",
 		metadata.Duration, metadata.NumFrames, metadata.SampleRate, markerStepSize))
+	channels := metadata.WaveformData.Channels
+	var left, right *wavfile.PeakPyramid
+	switch {
+	case len(channels) == 2:
+		left, right = channels[0], channels[1]
+		b.WriteString(" | L/R")
+	case len(channels) > 2 && channelPair > 0:
+		first := min(channelPair*2-2, len(channels)-1)
+		if first+1 < len(channels) {
+			left, right = channels[first], channels[first+1]
+			b.WriteString(fmt.Sprintf(" | Ch %d-%d of %d", first+1, first+2, len(channels)))
+		} else {
+			// A lone last channel plays on both sides
+			left, right = channels[first], channels[first]
+			b.WriteString(fmt.Sprintf(" | Ch %d of %d", first+1, len(channels)))
+		}
+	case len(channels) > 2:
+		b.WriteString(fmt.Sprintf(" | %dch mix", len(channels)))
+	}
+	if viewFrames < metadata.NumFrames {
+		b.WriteString(fmt.Sprintf(" | View: %d-%d (%dx)", viewStart, viewStart+viewFrames-1, metadata.NumFrames/viewFrames))
+	}
+	b.WriteString("\n")
 
-	// Waveform
-	b.WriteString(renderBrailleWaveform(metadata.WaveformData.Peaks, width))
+	// Waveform of the frames in view, two columns per character; stereo
+	// pairs stack left over right at half height each
+	renderWindow := func(pyramid *wavfile.PeakPyramid, height int) {
+		peaks, rms := pyramid.Window(viewStart, viewFrames, width*2)
+		b.WriteString(renderBrailleWaveform(peaks, rms, width, height))
+	}
+	if left != nil {
+		renderWindow(left, waveformHeight/2)
+		renderWindow(right, waveformHeight/2)
+	} else {
+		renderWindow(metadata.WaveformData.Mix, waveformHeight)
+	}
 
 	// Build marker line showing both start and end markers
 	markerLine := make([]rune, width)
@@ -115,11 +214,39 @@ func RenderWaveformForFile(metadata *wavfile.Metadata, width int, startFrame int
 		markerLine[i] = ' '
 	}
 
+	// Cues go down first so the start and end markers draw over them
+	cueLine := make([]rune, width)
+	for i := range cueLine {
+		cueLine[i] = ' '
+	}
+	nextLabelPos := 0
+	for i, cue := range metadata.Cues {
+		charPos := int(float64(cue.Frame-viewStart)/float64(viewFrames)*float64(width*2)) / 2
+		if charPos < 0 || charPos >= width {
+			continue
+		}
+		if i == selectedCue {
+			markerLine[charPos] = '◆'
+		} else {
+			markerLine[charPos] = '◇'
+		}
+		// Names that would run into the previous one are left out
+		if cue.Label != "" && charPos >= nextLabelPos {
+			for j, ch := range []rune(cue.Label) {
+				if charPos+j >= width {
+					break
+				}
+				cueLine[charPos+j] = ch
+			}
+			nextLabelPos = charPos + len([]rune(cue.Label)) + 1
+		}
+	}
+
 	// Calculate positions for start and end markers
-	startPos := int(float64(startFrame) / float64(metadata.NumFrames) * float64(width*2))
+	startPos := int(float64(startFrame-viewStart) / float64(viewFrames) * float64(width*2))
 	startCharPos := startPos / 2
 
-	endPos := int(float64(endFrame) / float64(metadata.NumFrames) * float64(width*2))
+	endPos := int(float64(endFrame-viewStart) / float64(viewFrames) * float64(width*2))
 	endCharPos := endPos / 2
 
 	// Place markers (active marker uses ▲, inactive uses ▽)
@@ -140,6 +267,9 @@ func RenderWaveformForFile(metadata *wavfile.Metadata, width int, startFrame int
 	}
 
 	b.WriteString(string(markerLine) + "\n")
+	if len(metadata.Cues) > 0 {
+		b.WriteString(string(cueLine) + "\n")
+	}
 
 	// Display frame numbers under their respective markers
 	infoLine := make([]rune, width)
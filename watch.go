@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"smplr/wavfile"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchSettle is how long a file must go unchanged before it is reloaded,
+// so a file still being copied or exported is picked up once, when done
+const watchSettle = 500 * time.Millisecond
+
+// fileChangedMsg reports a sample file added, removed or rewritten by
+// another program
+type fileChangedMsg struct {
+	filename string
+}
+
+// watchDir watches the working directory, and its subdirectories when
+// recursive is set, sending a fileChangedMsg for each audio file that
+// changes. It returns a function that stops watching.
+func watchDir(send func(tea.Msg), recursive bool) (func(), error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add("."); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	if recursive {
+		addSubdirs(watcher, ".")
+	}
+
+	go func() {
+		var mu sync.Mutex
+		pending := map[string]*time.Timer{}
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				name := filepath.Clean(event.Name)
+				if recursive && event.Has(fsnotify.Create) {
+					if info, err := os.Stat(name); err == nil && info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+						watcher.Add(name)
+						addSubdirs(watcher, name)
+						continue
+					}
+				}
+				if !wavfile.IsSampleFile(name) || event.Op == fsnotify.Chmod {
+					continue
+				}
+				// Restart the wait on every write
+				mu.Lock()
+				if timer, ok := pending[name]; ok {
+					timer.Stop()
+				}
+				pending[name] = time.AfterFunc(watchSettle, func() {
+					mu.Lock()
+					delete(pending, name)
+					mu.Unlock()
+					send(fileChangedMsg{filename: name})
+				})
+				mu.Unlock()
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+
+	return func() { watcher.Close() }, nil
+}
+
+// addSubdirs watches every directory below dir, skipping hidden ones such
+// as .smplr
+func addSubdirs(watcher *fsnotify.Watcher, dir string) {
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() || path == dir {
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		watcher.Add(path)
+		return nil
+	})
+}
+
+// applyFileChange brings the list in line with a file changed on disk:
+// new files are appended on the next free note, removed ones dropped, and
+// rewritten ones reloaded keeping their mappings and settings
+func (m *model) applyFileChange(filename string) {
+	// smplr's own takes and bounces are added when they are done
+	if filename == m.recordingFilename || filename == m.bounceFilename {
+		return
+	}
+	index := slices.IndexFunc(*m.files, func(file wavfile.WavFile) bool { return file.Name == filename })
+	_, err := os.Stat(filename)
+	switch {
+	case err == nil && index < 0:
+		selected := m.cursor
+		if err := m.appendFile(filename); err != nil {
+			m.SetCurrentError(fmt.Sprintf("Failed to load new file %s: %v", filename, err))
+		} else {
+			m.statusMessage = "Added " + filename
+		}
+		// Only an empty list moves to the new file
+		if selected >= 0 {
+			m.cursor = selected
+			m.scrollToSelection()
+		}
+	case err == nil:
+		if (*m.files)[index].Loading {
+			return
+		}
+		// Duplicated slots of the file are reloaded too
+		for i := index; i < len(*m.files); i++ {
+			if (*m.files)[i].Name == filename {
+				m.reloadChangedFile(i)
+			}
+		}
+		m.statusMessage = "Reloaded " + filename
+	case os.IsNotExist(err) && index >= 0:
+		// Removing shifts the samples a take is headed for
+		if m.recording {
+			return
+		}
+		for i := len(*m.files) - 1; i >= index; i-- {
+			if (*m.files)[i].Name == filename {
+				m.removeFile(i)
+			}
+		}
+		m.statusMessage = "Removed " + filename
+	default:
+		return
+	}
+	m.saveSession()
+}
+
+// reloadChangedFile rereads a file rewritten by another program and
+// recreates its player. Markers around the whole file grow or shrink with
+// it; others are kept where they still fit.
+func (m *model) reloadChangedFile(index int) {
+	file := &(*m.files)[index]
+	wholeFile := file.Metadata != nil && file.StartFrame == 0 && file.EndFrame == file.Metadata.NumFrames-1
+	// The audio changed, so an earlier tuner reading no longer holds
+	file.DetectedHz = 0
+
+	if !file.Corrupted {
+		if err := m.audio.DestroyPlayer(file.PlayerId); err != nil {
+			m.SetCurrentError(fmt.Sprintf("Warning: failed to destroy player: %v", err))
+		}
+	}
+	metadata, decoded, err := wavfile.LoadMetadata(file.Name, m.audio.ConvertFile, nil)
+	if err != nil {
+		file.Corrupted = true
+		m.SetCurrentError(fmt.Sprintf("Failed to reload %s: %v", file.Name, err))
+		return
+	}
+	file.Metadata = metadata
+	file.DecodedFileName = decoded
+	file.Corrupted = false
+	if wholeFile || file.EndFrame <= 0 || file.EndFrame >= metadata.NumFrames {
+		file.EndFrame = metadata.NumFrames - 1
+	}
+	file.StartFrame = min(file.StartFrame, file.EndFrame)
+
+	playerId, err := m.audio.CreatePlayer(file.SourceFileName())
+	if err != nil {
+		file.Corrupted = true
+		m.SetCurrentError(fmt.Sprintf("Failed to create new player: %v", err))
+		return
+	}
+	file.PlayerId = playerId
+	if err := m.applyPlayerSettings(file); err != nil {
+		m.SetCurrentError(fmt.Sprintf("Warning: failed to restore player settings: %v", err))
+	}
+	if index == m.cursor {
+		m.updateMarkerStepSize()
+	}
+}
+
+// removeFile drops a sample whose file is gone, keeping the cursor and any
+// chain being linked on the samples they were on
+func (m *model) removeFile(index int) {
+	file := (*m.files)[index]
+	if !file.Loading && !file.Corrupted {
+		if err := m.audio.DestroyPlayer(file.PlayerId); err != nil {
+			m.SetCurrentError(fmt.Sprintf("Warning: failed to destroy player: %v", err))
+		}
+	}
+	*m.files = slices.Delete(*m.files, index, index+1)
+
+	if m.cursor > index {
+		m.cursor--
+	}
+	switch {
+	case m.linkSource == index:
+		m.linkSource = -1
+	case m.linkSource > index:
+		m.linkSource--
+	}
+	m.adjustCursorToValidFile()
+	m.scrollToSelection()
+}
@@ -0,0 +1,103 @@
+package wavfile
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupDir holds copies of files taken before destructive edits
+const backupDir = ".smplr/backups"
+
+// backupTimeLayout is the timestamp that ends the name of a backup
+const backupTimeLayout = "20060102_150405.000"
+
+// backups returns the paths of every backup of a file, oldest first.
+// Only names of exactly the file's name and a timestamp count, so the
+// backups of kick.wav.old aren't taken for those of kick.wav.
+func backups(filename string) ([]string, error) {
+	entries, err := os.ReadDir(backupDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	prefix := flatName(filename) + "."
+	var matches []string
+	for _, entry := range entries {
+		stamp, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok {
+			continue
+		}
+		if _, err := time.Parse(backupTimeLayout, stamp); err == nil {
+			matches = append(matches, filepath.Join(backupDir, entry.Name()))
+		}
+	}
+	// Timestamps sort chronologically
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// BackupFile copies a file into .smplr/backups as <name>.<timestamp> before
+// it is edited in place, returning the backup's path
+func BackupFile(filename string) (string, error) {
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	backup := filepath.Join(backupDir, flatName(filename)+"."+time.Now().Format(backupTimeLayout))
+	if err := copyFile(filename, backup); err != nil {
+		return "", fmt.Errorf("failed to back up %s: %w", filename, err)
+	}
+	return backup, nil
+}
+
+// OriginalBackup returns the oldest backup of a file, which holds the file
+// as it was before smplr first edited it
+func OriginalBackup(filename string) (string, error) {
+	matches, err := backups(filename)
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no backup of %s", filename)
+	}
+	return matches[0], nil
+}
+
+// RestoreOriginal replaces a file with its oldest backup. The current
+// version is backed up first, so restoring can itself be undone.
+func RestoreOriginal(filename string) error {
+	original, err := OriginalBackup(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := BackupFile(filename); err != nil {
+		return err
+	}
+	return copyFile(original, filename)
+}
+
+// copyFile copies src to dst, replacing dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,71 @@
+package wavfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Offsets of the bext chunk fields smplr reads; the text fields are
+// fixed-width ASCII, padded with zeros
+const (
+	bextOriginatorOffset          = 256
+	bextOriginatorReferenceOffset = 288
+	bextOriginationDateOffset     = 320
+	bextOriginationTimeOffset     = 330
+	bextTimeReferenceOffset       = 338
+	bextMinSize                   = 346
+)
+
+// BroadcastInfo holds the bext chunk of a Broadcast WAV file
+type BroadcastInfo struct {
+	Description         string `json:"description,omitempty"`
+	Originator          string `json:"originator,omitempty"`
+	OriginatorReference string `json:"originatorReference,omitempty"`
+	OriginationDate     string `json:"originationDate,omitempty"` // yyyy-mm-dd
+	OriginationTime     string `json:"originationTime,omitempty"` // hh:mm:ss
+	TimeReference       uint64 `json:"timeReference"`             // Frames since midnight at the first frame
+}
+
+// ReadBroadcastInfo returns the bext chunk of a WAV file, or nil when it
+// has none
+func ReadBroadcastInfo(filename string) (*BroadcastInfo, error) {
+	var bext []byte
+	err := scanChunks(filename, func(id string, body []byte) {
+		if id == "bext" && bext == nil {
+			bext = body
+		}
+	})
+	if err != nil || bext == nil {
+		return nil, err
+	}
+	return parseBroadcastInfo(bext)
+}
+
+// parseBroadcastInfo decodes the body of a bext chunk
+func parseBroadcastInfo(data []byte) (*BroadcastInfo, error) {
+	if len(data) < bextMinSize {
+		return nil, fmt.Errorf("bext chunk too short")
+	}
+	text := func(start, end int) string {
+		return strings.TrimSpace(strings.TrimRight(string(data[start:end]), "\x00"))
+	}
+	return &BroadcastInfo{
+		Description:         text(0, bextOriginatorOffset),
+		Originator:          text(bextOriginatorOffset, bextOriginatorReferenceOffset),
+		OriginatorReference: text(bextOriginatorReferenceOffset, bextOriginationDateOffset),
+		OriginationDate:     text(bextOriginationDateOffset, bextOriginationTimeOffset),
+		OriginationTime:     text(bextOriginationTimeOffset, bextTimeReferenceOffset),
+		TimeReference:       binary.LittleEndian.Uint64(data[bextTimeReferenceOffset:]),
+	}, nil
+}
+
+// TimeOfDay formats the time reference as the hh:mm:ss.mmm time of day the
+// recording starts at, given its sample rate
+func (b *BroadcastInfo) TimeOfDay(sampleRate uint32) string {
+	if sampleRate == 0 {
+		return ""
+	}
+	ms := b.TimeReference * 1000 / uint64(sampleRate)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,51 @@
+package wavfile
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// bpmPattern matches a tempo written in a filename, as in "break_95bpm.wav"
+// or "loop 120 BPM.wav"
+var bpmPattern = regexp.MustCompile(`(?i)(\d{2,3}(?:\.\d+)?)[ _-]?bpm`)
+
+// EstimateBPM guesses the tempo of a loop of numFrames frames. A tempo in
+// the filename wins; otherwise the loop is taken to span a power-of-two
+// number of beats, picking the count that puts the tempo in 80-160 BPM.
+func EstimateBPM(filename string, numFrames int, sampleRate uint32) float64 {
+	if match := bpmPattern.FindStringSubmatch(filepath.Base(filename)); match != nil {
+		if bpm, err := strconv.ParseFloat(match[1], 64); err == nil && bpm > 0 {
+			return bpm
+		}
+	}
+
+	if numFrames <= 0 || sampleRate == 0 {
+		return 0
+	}
+	seconds := float64(numFrames) / float64(sampleRate)
+	beats := 1.0
+	bpm := beats * 60 / seconds
+	for bpm < 80 && beats < 1024 {
+		beats *= 2
+		bpm = beats * 60 / seconds
+	}
+	for bpm >= 160 {
+		beats /= 2
+		bpm = beats * 60 / seconds
+	}
+	return bpm
+}
+
+// StretchedFilename returns the name of a file's variant rendered at bpm,
+// as in "break_120bpm.wav" from "break_95bpm.wav". A tempo already in the
+// name is replaced, so EstimateBPM reads the new one back.
+func StretchedFilename(filename string, bpm float64) string {
+	ext := filepath.Ext(filename)
+	dir, base := filepath.Split(filename[:len(filename)-len(ext)])
+	tempo := strconv.FormatFloat(bpm, 'f', -1, 64) + "bpm"
+	if match := bpmPattern.FindStringIndex(base); match != nil {
+		return dir + base[:match[0]] + tempo + base[match[1]:] + ".wav"
+	}
+	return dir + base + "_" + tempo + ".wav"
+}
@@ -0,0 +1,59 @@
+package wavfile
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimateBPM(t *testing.T) {
+	tests := []struct {
+		name       string
+		filename   string
+		numFrames  int
+		sampleRate uint32
+		want       float64
+	}{
+		{"tempo in the name", "break_95bpm.wav", 44100, 44100, 95},
+		{"tempo with a space and capitals", "loops/Loop 120 BPM.wav", 44100, 44100, 120},
+		{"fractional tempo", "groove-87.5bpm.wav", 44100, 44100, 87.5},
+		{"directory tempo is ignored", "90bpm/loop.wav", 4 * 44100, 44100, 120},
+		{"two seconds is four beats at 120", "loop.wav", 2 * 44100, 44100, 120},
+		{"one bar at 100", "loop.wav", int(2.4 * 48000), 48000, 100},
+		{"long loop halves into range", "loop.wav", 16 * 44100, 44100, 120},
+		{"short hit doubles down into range", "hit.wav", 44100 / 4, 44100, 120},
+		{"empty file", "loop.wav", 0, 44100, 0},
+		{"no sample rate", "loop.wav", 44100, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EstimateBPM(tt.filename, tt.numFrames, tt.sampleRate)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("EstimateBPM = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStretchedFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		bpm      float64
+		want     string
+	}{
+		{"break.wav", 120, "break_120bpm.wav"},
+		{"break_95bpm.wav", 120, "break_120bpm.wav"},
+		{"Loop 90 BPM take2.aif", 100, "Loop 100bpm take2.wav"},
+		{"loops/90bpm/break.wav", 87.5, "loops/90bpm/break_87.5bpm.wav"},
+	}
+
+	for _, tt := range tests {
+		got := StretchedFilename(tt.filename, tt.bpm)
+		if got != tt.want {
+			t.Errorf("StretchedFilename(%q, %g) = %q, want %q", tt.filename, tt.bpm, got, tt.want)
+		}
+		if read := EstimateBPM(got, 0, 0); read != tt.bpm {
+			t.Errorf("EstimateBPM(%q) = %g, want %g", got, read, tt.bpm)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package wavfile
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// cacheDir holds computed metadata and peaks so restarts needn't re-read
+// every file
+const cacheDir = ".smplr/cache"
+
+// metadataCacheVersion invalidates every cache entry when the cached
+// layout changes
+const metadataCacheVersion = 5
+
+// metadataCacheEntry is the on-disk form of a cached file's metadata
+type metadataCacheEntry struct {
+	Version  int
+	Size     int64
+	ModTime  int64
+	Metadata *Metadata
+}
+
+// metadataCachePath returns the cache file for a file, named by a hash of
+// its absolute path
+func metadataCachePath(filename string) string {
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+	sum := sha256.Sum256([]byte(filename))
+	return filepath.Join(cacheDir, hex.EncodeToString(sum[:16])+".peaks")
+}
+
+// CachedMetadata returns a WAV file's metadata from .smplr/cache when the
+// file's size and modification time still match, and otherwise reads the
+// file with StreamMetadata and caches the result. Failing to write the
+// cache isn't an error.
+func CachedMetadata(filename string, progress func(*Metadata)) (*Metadata, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	cachePath := metadataCachePath(filename)
+	if entry, err := readMetadataCache(cachePath); err == nil &&
+		entry.Version == metadataCacheVersion &&
+		entry.Size == info.Size() &&
+		entry.ModTime == info.ModTime().UnixNano() {
+		return entry.Metadata, nil
+	}
+
+	metadata, err := StreamMetadata(filename, progress)
+	if err != nil {
+		return nil, err
+	}
+	writeMetadataCache(cachePath, metadataCacheEntry{
+		Version:  metadataCacheVersion,
+		Size:     info.Size(),
+		ModTime:  info.ModTime().UnixNano(),
+		Metadata: metadata,
+	})
+	return metadata, nil
+}
+
+// readMetadataCache decodes a cache entry
+func readMetadataCache(cachePath string) (*metadataCacheEntry, error) {
+	file, err := os.Open(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entry metadataCacheEntry
+	if err := gob.NewDecoder(file).Decode(&entry); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", cachePath, err)
+	}
+	if entry.Metadata == nil {
+		return nil, fmt.Errorf("empty cache entry %s", cachePath)
+	}
+	return &entry, nil
+}
+
+// writeMetadataCache encodes a cache entry, replacing it atomically so a
+// concurrent load never sees half of one
+func writeMetadataCache(cachePath string, entry metadataCacheEntry) error {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+
+	tempFilename := cachePath + ".tmp"
+	file, err := os.Create(tempFilename)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(file).Encode(entry); err != nil {
+		file.Close()
+		os.Remove(tempFilename)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tempFilename)
+		return err
+	}
+	return os.Rename(tempFilename, cachePath)
+}
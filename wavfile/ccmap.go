@@ -0,0 +1,89 @@
+package wavfile
+
+import "math"
+
+// CCParam is a sampler parameter that a MIDI controller can drive
+type CCParam string
+
+const (
+	CCParamGain         CCParam = "gain"
+	CCParamPitch        CCParam = "pitch"
+	CCParamStart        CCParam = "start"
+	CCParamEnd          CCParam = "end"
+	CCParamMasterVolume CCParam = "master"
+)
+
+// CCMapping routes a control change to a parameter of one sample slot, or
+// to the master volume when Sample is empty
+type CCMapping struct {
+	Channel    int     `json:"channel"` // 1-based MIDI channel
+	Controller int     `json:"controller"`
+	Param      CCParam `json:"param"`
+	Sample     string  `json:"sample,omitempty"`
+	Slot       int     `json:"slot,omitempty"` // Which duplicated slot of Sample
+}
+
+// Targets reports whether the mapping drives the given sample slot
+func (c CCMapping) Targets(file *WavFile) bool {
+	return c.Sample == file.Name && c.Slot == file.Slot
+}
+
+// FindCCMapping returns the index of the mapping for a channel and
+// controller, or -1 if the controller isn't mapped
+func FindCCMapping(mappings []CCMapping, channel int, controller int) int {
+	for i, mapping := range mappings {
+		if mapping.Channel == channel && mapping.Controller == controller {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetCCMapping adds a mapping, replacing any other mapping of the same
+// channel and controller
+func SetCCMapping(mappings []CCMapping, mapping CCMapping) []CCMapping {
+	if i := FindCCMapping(mappings, mapping.Channel, mapping.Controller); i >= 0 {
+		mappings[i] = mapping
+		return mappings
+	}
+	return append(mappings, mapping)
+}
+
+// RemoveCCMappings drops every mapping that targets the given slot of the
+// named sample
+func RemoveCCMappings(mappings []CCMapping, sample string, slot int) []CCMapping {
+	kept := mappings[:0]
+	for _, mapping := range mappings {
+		if mapping.Sample != sample || mapping.Slot != slot {
+			kept = append(kept, mapping)
+		}
+	}
+	return kept
+}
+
+// ApplyCC sets a sample parameter from a 0-127 controller value, spreading
+// the controller over the parameter's whole range: -60 to 0 dB of gain,
+// -12 to +12 semitones of pitch, or the length of the file for markers.
+// The master volume isn't a sample parameter and is ignored.
+func (w *WavFile) ApplyCC(param CCParam, value int) {
+	position := float64(value) / 127
+
+	switch param {
+	case CCParamGain:
+		w.GainDB = math.Round(position*60) - 60
+	case CCParamPitch:
+		w.Pitch = int(math.Round(position*24)) - 12
+	case CCParamStart, CCParamEnd:
+		if w.Metadata == nil || w.Metadata.NumFrames == 0 {
+			return
+		}
+		frame := int(position * float64(w.Metadata.NumFrames-1))
+		// Markers stay at least one frame apart, since an empty region
+		// can't be played
+		if param == CCParamStart {
+			w.StartFrame = max(min(frame, w.EndFrame-1), 0)
+		} else {
+			w.EndFrame = max(frame, w.StartFrame+1)
+		}
+	}
+}
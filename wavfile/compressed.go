@@ -0,0 +1,205 @@
+package wavfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Compressed WAV format codes found on older sample CDs
+const (
+	wavFormatALaw     = 6
+	wavFormatMuLaw    = 7
+	wavFormatIMAADPCM = 0x11
+)
+
+// imaIndexTable adjusts the step index after each IMA ADPCM nibble
+var imaIndexTable = [16]int{-1, -1, -1, -1, 2, 4, 6, 8, -1, -1, -1, -1, 2, 4, 6, 8}
+
+// imaStepTable holds the IMA ADPCM quantizer step sizes
+var imaStepTable = [89]int{
+	7, 8, 9, 10, 11, 12, 13, 14, 16, 17, 19, 21, 23, 25, 28, 31, 34, 37, 41, 45,
+	50, 55, 60, 66, 73, 80, 88, 97, 107, 118, 130, 143, 157, 173, 190, 209, 230,
+	253, 279, 307, 337, 371, 408, 449, 494, 544, 598, 658, 724, 796, 876, 963,
+	1060, 1166, 1282, 1411, 1552, 1707, 1878, 2066, 2272, 2499, 2749, 3024, 3327,
+	3660, 4026, 4428, 4871, 5358, 5894, 6484, 7132, 7845, 8630, 9493, 10442,
+	11487, 12635, 13899, 15289, 16818, 18500, 20350, 22385, 24623, 27086, 29794,
+	32767,
+}
+
+// IsCompressedWAV checks if a WAV file holds IMA ADPCM, µ-law or A-law
+// audio, which has to be decoded to PCM before it can be used
+func IsCompressedWAV(filename string) bool {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	header, _, err := readWavHeader(file)
+	if err != nil {
+		return false
+	}
+	switch header.AudioFormat {
+	case wavFormatALaw, wavFormatMuLaw, wavFormatIMAADPCM:
+		return true
+	}
+	return false
+}
+
+// DecodeCompressedWAV decodes a compressed WAV file to 16-bit PCM at target.
+// It is a Decoder, so compressed WAVs load like any other non-PCM source.
+func DecodeCompressedWAV(source string, target string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header, dataSize, err := readWavHeader(file)
+	if err != nil {
+		return err
+	}
+
+	data := make([]byte, dataSize)
+	n, err := io.ReadFull(file, data)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("error reading samples: %w", err)
+	}
+	data = data[:n]
+
+	numChannels := int(header.NumChannels)
+	if numChannels == 0 {
+		return fmt.Errorf("no channels in %s", source)
+	}
+
+	var samples []float64
+	switch header.AudioFormat {
+	case wavFormatMuLaw:
+		samples = make([]float64, len(data))
+		for i, b := range data {
+			samples[i] = float64(decodeMuLaw(b)) / 32768.0
+		}
+	case wavFormatALaw:
+		samples = make([]float64, len(data))
+		for i, b := range data {
+			samples[i] = float64(decodeALaw(b)) / 32768.0
+		}
+	case wavFormatIMAADPCM:
+		samples, err = decodeIMAADPCM(data, numChannels, int(header.BlockAlign))
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported audio format: %d", header.AudioFormat)
+	}
+
+	// Drop a trailing partial frame
+	samples = samples[:len(samples)-len(samples)%numChannels]
+
+	return WritePCM(target, &PCM{
+		AudioFormat:   wavFormatPCM,
+		NumChannels:   numChannels,
+		SampleRate:    header.SampleRate,
+		BitsPerSample: 16,
+		Samples:       samples,
+	})
+}
+
+// decodeMuLaw expands a G.711 µ-law byte to a 16-bit sample
+func decodeMuLaw(b byte) int16 {
+	b = ^b
+	exponent := (b >> 4) & 0x07
+	mantissa := int(b & 0x0F)
+	sample := ((mantissa << 3) + 0x84) << exponent
+	sample -= 0x84
+	if b&0x80 != 0 {
+		return int16(-sample)
+	}
+	return int16(sample)
+}
+
+// decodeALaw expands a G.711 A-law byte to a 16-bit sample
+func decodeALaw(b byte) int16 {
+	b ^= 0x55
+	exponent := (b >> 4) & 0x07
+	mantissa := int(b & 0x0F)
+	sample := (mantissa << 4) + 8
+	if exponent > 0 {
+		sample = ((mantissa << 4) + 0x108) << (exponent - 1)
+	}
+	if b&0x80 != 0 {
+		return int16(sample)
+	}
+	return int16(-sample)
+}
+
+// decodeIMAADPCM decodes WAV IMA ADPCM blocks to interleaved samples. Each
+// block starts with a predictor and step index per channel, followed by
+// 4-byte groups of eight nibbles per channel in turn.
+func decodeIMAADPCM(data []byte, numChannels int, blockAlign int) ([]float64, error) {
+	headerBytes := 4 * numChannels
+	if blockAlign <= headerBytes {
+		return nil, fmt.Errorf("invalid IMA ADPCM block size: %d", blockAlign)
+	}
+
+	var samples []float64
+	predictors := make([]int, numChannels)
+	indices := make([]int, numChannels)
+	for len(data) > headerBytes {
+		block := data[:min(blockAlign, len(data))]
+		data = data[len(block):]
+
+		for ch := range numChannels {
+			predictors[ch] = int(int16(binary.LittleEndian.Uint16(block[ch*4:])))
+			indices[ch] = max(0, min(int(block[ch*4+2]), len(imaStepTable)-1))
+		}
+		// The header predictor is the block's first frame
+		for ch := range numChannels {
+			samples = append(samples, float64(predictors[ch])/32768.0)
+		}
+
+		// Each group holds 8 frames: 4 bytes per channel, low nibble first
+		groupBytes := 4 * numChannels
+		body := block[headerBytes:]
+		for len(body) >= groupBytes {
+			frames := make([]float64, 8*numChannels)
+			for ch := range numChannels {
+				for i, b := range body[ch*4 : ch*4+4] {
+					for half, nibble := range [2]byte{b & 0x0F, b >> 4} {
+						sample := decodeIMANibble(nibble, &predictors[ch], &indices[ch])
+						frames[(i*2+half)*numChannels+ch] = float64(sample) / 32768.0
+					}
+				}
+			}
+			samples = append(samples, frames...)
+			body = body[groupBytes:]
+		}
+	}
+	return samples, nil
+}
+
+// decodeIMANibble applies one ADPCM nibble to a channel's predictor and
+// step index, returning the new sample
+func decodeIMANibble(nibble byte, predictor *int, index *int) int16 {
+	step := imaStepTable[*index]
+	diff := step >> 3
+	if nibble&1 != 0 {
+		diff += step >> 2
+	}
+	if nibble&2 != 0 {
+		diff += step >> 1
+	}
+	if nibble&4 != 0 {
+		diff += step
+	}
+	if nibble&8 != 0 {
+		*predictor -= diff
+	} else {
+		*predictor += diff
+	}
+	*predictor = max(-32768, min(32767, *predictor))
+	*index = max(0, min(*index+imaIndexTable[nibble], len(imaStepTable)-1))
+	return int16(*predictor)
+}
@@ -0,0 +1,181 @@
+package wavfile
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecodeMuLaw(t *testing.T) {
+	tests := []struct {
+		in   byte
+		want int16
+	}{
+		{0xFF, 0},
+		{0x7F, 0},
+		{0x80, 32124},
+		{0x00, -32124},
+		{0xF0, 120},
+		{0x70, -120},
+	}
+
+	for _, tt := range tests {
+		if got := decodeMuLaw(tt.in); got != tt.want {
+			t.Errorf("decodeMuLaw(%#02x) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeALaw(t *testing.T) {
+	tests := []struct {
+		in   byte
+		want int16
+	}{
+		{0xD5, 8},
+		{0x55, -8},
+		{0xAA, 32256},
+		{0x2A, -32256},
+		{0xC5, 264},
+		{0x45, -264},
+	}
+
+	for _, tt := range tests {
+		if got := decodeALaw(tt.in); got != tt.want {
+			t.Errorf("decodeALaw(%#02x) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeIMAADPCM(t *testing.T) {
+	// Mono block: predictor 1000 at step index 0, then eight zero nibbles
+	silent := []byte{0xE8, 0x03, 0, 0, 0, 0, 0, 0}
+	// Stereo block: predictors 100 and -100, then eight frames of the
+	// largest step up on the left and down on the right
+	stereo := []byte{100, 0, 0, 0, 0x9C, 0xFF, 0, 0, 0x77, 0x77, 0x77, 0x77, 0xFF, 0xFF, 0xFF, 0xFF}
+
+	tests := []struct {
+		name        string
+		data        []byte
+		numChannels int
+		blockAlign  int
+		wantLen     int
+		wantErr     bool
+		check       func(*testing.T, []float64)
+	}{
+		{
+			name: "holds the predictor", data: silent, numChannels: 1, blockAlign: 8, wantLen: 9,
+			check: func(t *testing.T, samples []float64) {
+				for i, sample := range samples {
+					if sample != 1000.0/32768 {
+						t.Errorf("sample %d = %g, want %g", i, sample, 1000.0/32768)
+					}
+				}
+			},
+		},
+		{
+			name: "two blocks", data: append(append([]byte{}, silent...), silent...), numChannels: 1, blockAlign: 8, wantLen: 18,
+		},
+		{
+			name: "partial group is dropped", data: silent[:6], numChannels: 1, blockAlign: 8, wantLen: 1,
+		},
+		{
+			name: "channels interleave", data: stereo, numChannels: 2, blockAlign: 16, wantLen: 18,
+			check: func(t *testing.T, samples []float64) {
+				for frame := 1; frame < 9; frame++ {
+					left, right := samples[frame*2], samples[frame*2+1]
+					if left <= samples[frame*2-2] || right >= samples[frame*2-1] {
+						t.Fatalf("frame %d = %g, %g, want left rising and right falling", frame, left, right)
+					}
+				}
+			},
+		},
+		{
+			name: "block no bigger than its header", data: silent, numChannels: 2, blockAlign: 8, wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples, err := decodeIMAADPCM(tt.data, tt.numChannels, tt.blockAlign)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeIMAADPCM error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(samples) != tt.wantLen {
+				t.Fatalf("decodeIMAADPCM returned %d samples, want %d", len(samples), tt.wantLen)
+			}
+			if tt.check != nil {
+				tt.check(t, samples)
+			}
+		})
+	}
+}
+
+// compressedWAV writes a WAV file of the given format code and raw data
+func compressedWAV(t *testing.T, format uint16, numChannels int, blockAlign int, bitsPerSample int, data []byte) string {
+	t.Helper()
+	fmtChunk := binary.LittleEndian.AppendUint16(nil, format)
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(numChannels))
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, 8000)
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, uint32(8000*blockAlign))
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(blockAlign))
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(bitsPerSample))
+
+	body := append([]byte("WAVE"), riffChunk("fmt ", fmtChunk)...)
+	body = append(body, riffChunk("data", data)...)
+	filename := filepath.Join(t.TempDir(), "compressed.wav")
+	if err := os.WriteFile(filename, riffChunk("RIFF", body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestDecodeCompressedWAV(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      uint16
+		numChannels int
+		blockAlign  int
+		bits        int
+		data        []byte
+		want        []int16
+	}{
+		{"µ-law", wavFormatMuLaw, 1, 1, 8, []byte{0xFF, 0x80, 0x00}, []int16{0, 32124, -32124}},
+		{"A-law stereo", wavFormatALaw, 2, 2, 8, []byte{0xD5, 0x55, 0xAA, 0x2A}, []int16{8, -8, 32256, -32256}},
+		{"A-law partial frame", wavFormatALaw, 2, 2, 8, []byte{0xD5, 0x55, 0xAA}, []int16{8, -8}},
+		{"IMA ADPCM", wavFormatIMAADPCM, 1, 8, 4, []byte{0xE8, 0x03, 0, 0, 0, 0, 0, 0}, []int16{1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := compressedWAV(t, tt.format, tt.numChannels, tt.blockAlign, tt.bits, tt.data)
+			if !IsCompressedWAV(source) {
+				t.Fatal("IsCompressedWAV = false")
+			}
+			target := filepath.Join(t.TempDir(), "decoded.wav")
+			if err := DecodeCompressedWAV(source, target); err != nil {
+				t.Fatal(err)
+			}
+			if IsCompressedWAV(target) {
+				t.Error("decoded file is still compressed")
+			}
+
+			pcm, err := ReadPCM(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pcm.NumChannels != tt.numChannels || pcm.BitsPerSample != 16 || pcm.SampleRate != 8000 {
+				t.Errorf("decoded to %dch %d-bit %dHz", pcm.NumChannels, pcm.BitsPerSample, pcm.SampleRate)
+			}
+			if len(pcm.Samples) != len(tt.want) {
+				t.Fatalf("decoded %d samples, want %d", len(pcm.Samples), len(tt.want))
+			}
+			for i, sample := range pcm.Samples {
+				if got := int16(math.Round(sample * 32768)); got != tt.want[i] {
+					t.Errorf("sample %d = %d, want %d", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
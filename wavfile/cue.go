@@ -0,0 +1,114 @@
+package wavfile
+
+import (
+	"encoding/binary"
+	"sort"
+	"strings"
+)
+
+// cuePointSize is the byte size of one cue point record
+const cuePointSize = 24
+
+// CuePoint is a named marker from a WAV file's cue chunk
+type CuePoint struct {
+	Frame int
+	Label string // From the matching labl chunk in LIST adtl, "" when unnamed
+}
+
+// isCueChunk reports whether a chunk holds cue points or their labels
+func isCueChunk(id string, body []byte) bool {
+	return id == "cue " || (id == "LIST" && len(body) >= 4 && string(body[0:4]) == "adtl")
+}
+
+// ReadCues returns the cue points of a WAV file in frame order, with the
+// labels of any LIST adtl chunk
+func ReadCues(filename string) ([]CuePoint, error) {
+	frames := map[uint32]int{}
+	labels := map[uint32]string{}
+	var ids []uint32
+	err := scanChunks(filename, func(id string, body []byte) {
+		switch {
+		case id == "cue " && len(body) >= 4:
+			count := int(binary.LittleEndian.Uint32(body))
+			for i := range count {
+				if 4+(i+1)*cuePointSize > len(body) {
+					break
+				}
+				record := body[4+i*cuePointSize:]
+				cueID := binary.LittleEndian.Uint32(record)
+				frames[cueID] = int(binary.LittleEndian.Uint32(record[20:]))
+				ids = append(ids, cueID)
+			}
+		case id == "LIST" && isCueChunk(id, body):
+			// labl sub-chunks: cue ID followed by a zero-terminated name
+			for sub := body[4:]; len(sub) >= 8; {
+				size := int(binary.LittleEndian.Uint32(sub[4:]))
+				end := min(8+size, len(sub))
+				if string(sub[0:4]) == "labl" && len(sub) >= 12 && end >= 12 {
+					labels[binary.LittleEndian.Uint32(sub[8:])] = strings.TrimRight(string(sub[12:end]), "\x00")
+				}
+				sub = sub[min(end+size&1, len(sub)):]
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var cues []CuePoint
+	for _, cueID := range ids {
+		cues = append(cues, CuePoint{Frame: frames[cueID], Label: labels[cueID]})
+	}
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].Frame < cues[j].Frame })
+	return cues, nil
+}
+
+// cueChunks encodes cue points as a cue chunk and a LIST adtl chunk of
+// their labels, numbering them from 1 in order
+func cueChunks(cues []CuePoint) []byte {
+	if len(cues) == 0 {
+		return nil
+	}
+
+	body := make([]byte, 4+len(cues)*cuePointSize)
+	binary.LittleEndian.PutUint32(body, uint32(len(cues)))
+	var labels [][]byte
+	for i, cue := range cues {
+		record := body[4+i*cuePointSize:]
+		binary.LittleEndian.PutUint32(record[0:], uint32(i+1))
+		binary.LittleEndian.PutUint32(record[4:], uint32(cue.Frame))
+		copy(record[8:], "data")
+		binary.LittleEndian.PutUint32(record[20:], uint32(cue.Frame))
+		if cue.Label != "" {
+			labl := binary.LittleEndian.AppendUint32(nil, uint32(i+1))
+			labl = append(append(labl, cue.Label...), 0)
+			labels = append(labels, riffChunk("labl", labl))
+		}
+	}
+
+	chunks := riffChunk("cue ", body)
+	if len(labels) > 0 {
+		chunks = append(chunks, riffList("adtl", labels...)...)
+	}
+	return chunks
+}
+
+// WriteCues replaces the cue points of a WAV file, leaving its audio and
+// other chunks as they are
+func WriteCues(filename string, cues []CuePoint) error {
+	return rewriteChunks(filename, func(id string, body []byte) bool {
+		return !isCueChunk(id, body)
+	}, cueChunks(cues))
+}
+
+// regionCues returns the cue points inside the frames from startFrame up
+// to endFrame, shifted to match
+func regionCues(cues []CuePoint, startFrame, endFrame int) []CuePoint {
+	var region []CuePoint
+	for _, cue := range cues {
+		if cue.Frame >= startFrame && cue.Frame < endFrame {
+			region = append(region, CuePoint{Frame: cue.Frame - startFrame, Label: cue.Label})
+		}
+	}
+	return region
+}
@@ -0,0 +1,121 @@
+package wavfile
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCueRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		cues []CuePoint
+		want []CuePoint
+	}{
+		{"none", nil, nil},
+		{"unnamed", []CuePoint{{Frame: 10}}, []CuePoint{{Frame: 10}}},
+		{
+			name: "sorted by frame",
+			cues: []CuePoint{{Frame: 40, Label: "chorus"}, {Frame: 0, Label: "intro"}, {Frame: 20}},
+			want: []CuePoint{{Frame: 0, Label: "intro"}, {Frame: 20}, {Frame: 40, Label: "chorus"}},
+		},
+		{"odd label length is padded", []CuePoint{{Frame: 7, Label: "ab"}}, []CuePoint{{Frame: 7, Label: "ab"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := writeTestWAV(t, "test.wav", &PCM{NumChannels: 1, SampleRate: 44100, BitsPerSample: 16, Samples: ramp(50, 1)})
+			// Written twice to check old cues are replaced rather than added to
+			for range 2 {
+				if err := WriteCues(filename, tt.cues); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := ReadCues(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ReadCues = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// cueFile writes a WAV file with no audio and the given chunks
+func cueFile(t *testing.T, chunks ...[]byte) string {
+	t.Helper()
+	data := []byte("RIFF\x00\x00\x00\x00WAVE")
+	for _, chunk := range chunks {
+		data = append(data, chunk...)
+	}
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+	filename := filepath.Join(t.TempDir(), "cues.wav")
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestReadCuesMalformed(t *testing.T) {
+	oneCue := make([]byte, 4+cuePointSize)
+	binary.LittleEndian.PutUint32(oneCue, 1)
+	binary.LittleEndian.PutUint32(oneCue[4:], 1)
+	binary.LittleEndian.PutUint32(oneCue[24:], 30)
+
+	// A labl sub-chunk whose size covers the cue ID, cut short by the list
+	shortLabl := append([]byte("adtllabl"), 4, 0, 0, 0, 1, 0)
+
+	overCount := make([]byte, 4+cuePointSize)
+	binary.LittleEndian.PutUint32(overCount, 1000)
+	binary.LittleEndian.PutUint32(overCount[4:], 1)
+	binary.LittleEndian.PutUint32(overCount[24:], 12)
+
+	tests := []struct {
+		name   string
+		chunks [][]byte
+		want   []CuePoint
+	}{
+		{
+			name:   "count beyond the chunk",
+			chunks: [][]byte{riffChunk("cue ", overCount)},
+			want:   []CuePoint{{Frame: 12}},
+		},
+		{
+			name:   "truncated labl",
+			chunks: [][]byte{riffChunk("cue ", oneCue), riffChunk("LIST", shortLabl)},
+			want:   []CuePoint{{Frame: 30}},
+		},
+		{
+			name:   "labl size beyond the list",
+			chunks: [][]byte{riffChunk("cue ", oneCue), riffChunk("LIST", append([]byte("adtllabl\xff\x00\x00\x00"), 1, 0, 0, 0, 'x'))},
+			want:   []CuePoint{{Frame: 30, Label: "x"}},
+		},
+		{
+			name:   "empty cue chunk",
+			chunks: [][]byte{riffChunk("cue ", nil)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadCues(cueFile(t, tt.chunks...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ReadCues = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegionCues(t *testing.T) {
+	cues := []CuePoint{{Frame: 5}, {Frame: 10, Label: "a"}, {Frame: 19, Label: "b"}, {Frame: 20}}
+	want := []CuePoint{{Frame: 0, Label: "a"}, {Frame: 9, Label: "b"}}
+	if got := regionCues(cues, 10, 20); !slices.Equal(got, want) {
+		t.Errorf("regionCues = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,58 @@
+package wavfile
+
+import "math"
+
+// DCOffset returns the mean of each channel of a WAV file as a fraction of
+// full scale. A nonzero mean shifts the waveform off center, which clicks
+// when playback starts mid-signal.
+func DCOffset(filename string) ([]float64, error) {
+	pcm, err := ReadPCM(filename)
+	if err != nil {
+		return nil, err
+	}
+	return channelMeans(pcm), nil
+}
+
+// RemoveDCOffset subtracts each channel's mean from its samples, rewriting
+// the WAV file, and returns the offsets it removed
+func RemoveDCOffset(filename string) ([]float64, error) {
+	pcm, err := ReadPCM(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := channelMeans(pcm)
+	for i := range pcm.Samples {
+		pcm.Samples[i] -= offsets[i%pcm.NumChannels]
+	}
+
+	if err := WritePCM(filename, pcm); err != nil {
+		return nil, err
+	}
+	return offsets, nil
+}
+
+// MaxDCOffset returns the largest absolute offset across channels
+func MaxDCOffset(offsets []float64) float64 {
+	largest := 0.0
+	for _, offset := range offsets {
+		largest = math.Max(largest, math.Abs(offset))
+	}
+	return largest
+}
+
+// channelMeans averages the samples of each channel
+func channelMeans(pcm *PCM) []float64 {
+	means := make([]float64, pcm.NumChannels)
+	numFrames := pcm.NumFrames()
+	if numFrames == 0 {
+		return means
+	}
+	for i, s := range pcm.Samples {
+		means[i%pcm.NumChannels] += s
+	}
+	for ch := range means {
+		means[ch] /= float64(numFrames)
+	}
+	return means
+}
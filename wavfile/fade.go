@@ -0,0 +1,42 @@
+package wavfile
+
+import "fmt"
+
+// FadeFile applies a linear fade-in over the fadeInFrames following
+// startFrame and a fade-out over the fadeOutFrames ending at endFrame,
+// rewriting the WAV file. Frames outside the region are left untouched.
+func FadeFile(filename string, startFrame, endFrame, fadeInFrames, fadeOutFrames int) error {
+	if fadeInFrames < 0 || fadeOutFrames < 0 {
+		return fmt.Errorf("fade lengths must be positive")
+	}
+
+	pcm, err := ReadPCM(filename)
+	if err != nil {
+		return err
+	}
+
+	numFrames := pcm.NumFrames()
+	if startFrame < 0 || endFrame >= numFrames || startFrame >= endFrame {
+		return fmt.Errorf("invalid region %d-%d for %d frames", startFrame, endFrame, numFrames)
+	}
+	regionFrames := endFrame - startFrame + 1
+	if fadeInFrames+fadeOutFrames > regionFrames {
+		return fmt.Errorf("fades are longer than the %d frame region", regionFrames)
+	}
+
+	for i := 0; i < fadeInFrames; i++ {
+		scaleFrame(pcm, startFrame+i, float64(i)/float64(fadeInFrames))
+	}
+	for i := 0; i < fadeOutFrames; i++ {
+		scaleFrame(pcm, endFrame-i, float64(i)/float64(fadeOutFrames))
+	}
+
+	return WritePCM(filename, pcm)
+}
+
+// scaleFrame multiplies every channel of a frame by gain
+func scaleFrame(pcm *PCM, frame int, gain float64) {
+	for ch := 0; ch < pcm.NumChannels; ch++ {
+		pcm.Samples[frame*pcm.NumChannels+ch] *= gain
+	}
+}
@@ -0,0 +1,89 @@
+package wavfile
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"sort"
+)
+
+// silenceThreshold is the level below which leading and trailing samples
+// are ignored when fingerprinting
+const silenceThreshold = 1.0 / 256
+
+// Fingerprint returns a hash of the file's normalized audio. Files that
+// differ only in gain, bit depth, or padding silence hash the same.
+func Fingerprint(filename string) (string, error) {
+	pcm, err := ReadPCM(filename)
+	if err != nil {
+		return "", err
+	}
+
+	// Mix down to mono
+	frames := pcm.NumFrames()
+	mono := make([]float64, frames)
+	for i := range frames {
+		sum := 0.0
+		for c := range pcm.NumChannels {
+			sum += pcm.Samples[i*pcm.NumChannels+c]
+		}
+		mono[i] = sum / float64(pcm.NumChannels)
+	}
+
+	// Peak-normalize so gain differences don't matter
+	peak := 0.0
+	for _, s := range mono {
+		peak = math.Max(peak, math.Abs(s))
+	}
+	if peak > 0 {
+		for i := range mono {
+			mono[i] /= peak
+		}
+	}
+
+	// Strip leading and trailing silence
+	start, end := 0, len(mono)
+	for start < end && math.Abs(mono[start]) < silenceThreshold {
+		start++
+	}
+	for end > start && math.Abs(mono[end-1]) < silenceThreshold {
+		end--
+	}
+
+	// Quantize to 8 bits so dithering and rounding noise don't matter
+	hash := sha256.New()
+	binary.Write(hash, binary.LittleEndian, pcm.SampleRate)
+	quantized := make([]byte, end-start)
+	for i, s := range mono[start:end] {
+		quantized[i] = byte(int8(math.Round(s * 127)))
+	}
+	hash.Write(quantized)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// FindDuplicates fingerprints the given files and returns groups of files
+// with identical content, each sorted by name. Files that can't be read are
+// skipped.
+func FindDuplicates(filenames []string) [][]string {
+	byHash := map[string][]string{}
+	for _, filename := range filenames {
+		hash, err := Fingerprint(filename)
+		if err != nil {
+			continue
+		}
+		byHash[hash] = append(byHash[hash], filename)
+	}
+
+	var groups [][]string
+	for _, group := range byHash {
+		if len(group) > 1 {
+			sort.Strings(group)
+			groups = append(groups, group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+
+	return groups
+}
@@ -0,0 +1,111 @@
+package wavfile
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// gmDrum is a General MIDI drum note and the file name keywords that
+// suggest it. Keywords of four or more letters match anywhere in the name;
+// shorter ones must be a whole word, so "oh" doesn't match "john".
+type gmDrum struct {
+	note     int
+	keywords []string
+}
+
+// gmDrums is checked in order, so specific drums come before the generic
+// ones they contain (open hat before hat, cowbell before ride bell)
+var gmDrums = []gmDrum{
+	{56, []string{"cowbell", "cow", "cb"}},
+	{46, []string{"openhat", "openhh", "hatopen", "hhopen", "ohh", "oh", "ohat"}},
+	{44, []string{"pedalhat", "pedalhh", "foothat", "phh"}},
+	{42, []string{"closedhat", "closedhh", "hihat", "hat", "chh", "hh"}},
+	{53, []string{"ridebell", "bell"}},
+	{49, []string{"crash"}},
+	{51, []string{"ride", "rd"}},
+	{52, []string{"china"}},
+	{55, []string{"splash"}},
+	{36, []string{"kick", "bassdrum", "bd", "kik", "kck"}},
+	{37, []string{"sidestick", "rimshot", "rim", "rs"}},
+	{39, []string{"clap", "clp", "cp"}},
+	{38, []string{"snare", "sd", "snr", "sn"}},
+	{41, []string{"floortom", "ft"}},
+	{45, []string{"lowtom", "lt"}},
+	{50, []string{"hightom", "hitom", "ht"}},
+	{47, []string{"midtom", "mt", "tom"}},
+	{54, []string{"tambourine", "tamb"}},
+	{70, []string{"shaker", "maraca", "shk"}},
+	{69, []string{"cabasa"}},
+	{63, []string{"conga"}},
+	{60, []string{"bongo"}},
+	{65, []string{"timbale"}},
+	{67, []string{"agogo"}},
+	{75, []string{"clave"}},
+	{76, []string{"woodblock", "block"}},
+	{58, []string{"vibraslap"}},
+	{71, []string{"whistle"}},
+}
+
+// GMDrumNote guesses the General MIDI drum note of a sample from its file
+// name, returning false when nothing matches
+func GMDrumNote(filename string) (int, bool) {
+	base := strings.ToLower(strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)))
+	words := strings.FieldsFunc(base, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	squashed := strings.Join(words, "")
+
+	for _, drum := range gmDrums {
+		for _, keyword := range drum.keywords {
+			if len(keyword) >= 4 && strings.Contains(squashed, keyword) {
+				return drum.note, true
+			}
+			for _, word := range words {
+				if word == keyword {
+					return drum.note, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// AssignGMDrumNotes gives every sample whose name matches a drum of the
+// General MIDI drum map that drum's note, keeping each note for the first
+// sample that matches it. Other samples that would share a channel and note
+// with an assigned one move to free notes above the highest in use, up to
+// 127; any left over keep their note. It returns the number of samples
+// assigned a GM note and the number that found no free note.
+func AssignGMDrumNotes(files []WavFile) (int, int) {
+	type slot struct{ channel, note int }
+	taken := map[slot]bool{}
+	assigned := make([]bool, len(files))
+	count := 0
+
+	for i := range files {
+		note, ok := GMDrumNote(files[i].Name)
+		if !ok || taken[slot{files[i].MidiChannel, note}] {
+			continue
+		}
+		files[i].MidiNote = note
+		taken[slot{files[i].MidiChannel, note}] = true
+		assigned[i] = true
+		count++
+	}
+
+	next := FindMaxMidiNote(files)
+	unplaced := 0
+	for i := range files {
+		if assigned[i] || !taken[slot{files[i].MidiChannel, files[i].MidiNote}] {
+			continue
+		}
+		if next >= 127 {
+			unplaced++
+			continue
+		}
+		next++
+		files[i].MidiNote = next
+	}
+	return count, unplaced
+}
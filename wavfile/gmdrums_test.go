@@ -0,0 +1,54 @@
+package wavfile
+
+import "testing"
+
+func TestGMDrumNote(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     int
+		wantOK   bool
+	}{
+		{"Kick 01.wav", 36, true},
+		{"drums/808_BD.wav", 36, true},
+		{"snare_tight.wav", 38, true},
+		{"open-hat.wav", 46, true},
+		{"OH 2.wav", 46, true},
+		{"closed hh.wav", 42, true},
+		{"hihat.wav", 42, true},
+		{"cowbell.wav", 56, true},
+		{"ride bell.wav", 53, true},
+		{"ride.wav", 51, true},
+		{"clap.aif", 39, true},
+		{"low tom.wav", 45, true},
+		{"tom.wav", 47, true},
+		{"john.wav", 0, false},
+		{"pad.wav", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := GMDrumNote(tt.filename)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("GMDrumNote(%q) = %d, %v, want %d, %v", tt.filename, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestAssignGMDrumNotes(t *testing.T) {
+	files := []WavFile{
+		{Name: "kick.wav", MidiChannel: 9, MidiNote: 60},
+		{Name: "kick2.wav", MidiChannel: 9, MidiNote: 61},
+		{Name: "pad.wav", MidiChannel: 9, MidiNote: 38},
+		{Name: "snare.wav", MidiChannel: 9, MidiNote: 62},
+	}
+
+	count, unplaced := AssignGMDrumNotes(files)
+	if count != 2 || unplaced != 0 {
+		t.Errorf("AssignGMDrumNotes = %d, %d, want 2, 0", count, unplaced)
+	}
+	want := []int{36, 61, 62, 38}
+	for i, file := range files {
+		if file.MidiNote != want[i] {
+			t.Errorf("%s note = %d, want %d", file.Name, file.MidiNote, want[i])
+		}
+	}
+}
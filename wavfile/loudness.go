@@ -0,0 +1,47 @@
+package wavfile
+
+import "math"
+
+// silenceDB is reported for digital silence instead of negative infinity
+const silenceDB = -120.0
+
+// Loudness summarizes the level of a file for gain staging
+type Loudness struct {
+	PeakDB float64 // Sample peak in dBFS
+	RMSDB  float64 // RMS level over the whole file in dBFS
+}
+
+// MeasureLoudness reads a WAV file and measures its peak and RMS level
+func MeasureLoudness(filename string) (Loudness, error) {
+	pcm, err := ReadPCM(filename)
+	if err != nil {
+		return Loudness{}, err
+	}
+
+	peak := 0.0
+	sumSquares := 0.0
+	for _, s := range pcm.Samples {
+		peak = math.Max(peak, math.Abs(s))
+		sumSquares += s * s
+	}
+
+	rms := 0.0
+	if len(pcm.Samples) > 0 {
+		rms = math.Sqrt(sumSquares / float64(len(pcm.Samples)))
+	}
+
+	return Loudness{PeakDB: AmplitudeToDB(peak), RMSDB: AmplitudeToDB(rms)}, nil
+}
+
+// AmplitudeToDB converts a linear amplitude to decibels
+func AmplitudeToDB(amplitude float64) float64 {
+	if amplitude <= 0 {
+		return silenceDB
+	}
+	return math.Max(20*math.Log10(amplitude), silenceDB)
+}
+
+// DBToAmplitude converts decibels to a linear amplitude
+func DBToAmplitude(db float64) float64 {
+	return math.Pow(10, db/20)
+}
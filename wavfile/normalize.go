@@ -0,0 +1,38 @@
+package wavfile
+
+import (
+	"fmt"
+	"math"
+)
+
+// NormalizeFile scales a WAV file so its sample peak sits at targetDB dBFS,
+// returning the gain applied in dB
+func NormalizeFile(filename string, targetDB float64) (float64, error) {
+	if targetDB > 0 {
+		return 0, fmt.Errorf("target must be at or below 0 dBFS")
+	}
+
+	pcm, err := ReadPCM(filename)
+	if err != nil {
+		return 0, err
+	}
+
+	peak := 0.0
+	for _, s := range pcm.Samples {
+		peak = math.Max(peak, math.Abs(s))
+	}
+	if peak == 0 {
+		return 0, fmt.Errorf("file is silent")
+	}
+
+	gainDB := targetDB - AmplitudeToDB(peak)
+	gain := DBToAmplitude(gainDB)
+	for i := range pcm.Samples {
+		pcm.Samples[i] *= gain
+	}
+
+	if err := WritePCM(filename, pcm); err != nil {
+		return 0, err
+	}
+	return gainDB, nil
+}
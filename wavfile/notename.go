@@ -0,0 +1,68 @@
+package wavfile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// noteNames are the pitch classes of an octave, spelled with sharps
+var noteNames = []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// lowestOctave is the octave of MIDI note 0, so middle C (60) is C3 as in
+// most DAWs and drum machines
+const lowestOctave = -2
+
+// NoteName formats a MIDI note as a name such as C1 or F#3
+func NoteName(note int) string {
+	if note < 0 || note > 127 {
+		return strconv.Itoa(note)
+	}
+	return fmt.Sprintf("%s%d", noteNames[note%12], note/12+lowestOctave)
+}
+
+// ParseNote reads a MIDI note given either as a number (36) or as a name
+// with an optional sharp or flat (C1, F#3, Bb-1)
+func ParseNote(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if note, err := strconv.Atoi(s); err == nil {
+		if note < 0 || note > 127 {
+			return 0, fmt.Errorf("note %d is outside 0-127", note)
+		}
+		return note, nil
+	}
+
+	if s == "" {
+		return 0, fmt.Errorf("empty note")
+	}
+	pitchClass := -1
+	for i, name := range noteNames {
+		if len(name) == 1 && strings.EqualFold(s[:1], name) {
+			pitchClass = i
+			break
+		}
+	}
+	if pitchClass < 0 {
+		return 0, fmt.Errorf("invalid note name %q", s)
+	}
+
+	rest := s[1:]
+	if strings.HasPrefix(rest, "#") {
+		pitchClass++
+		rest = rest[1:]
+	} else if strings.HasPrefix(rest, "b") {
+		pitchClass--
+		rest = rest[1:]
+	}
+
+	octave, err := strconv.Atoi(rest)
+	if err != nil {
+		return 0, fmt.Errorf("invalid octave in note name %q", s)
+	}
+
+	note := (octave-lowestOctave)*12 + pitchClass
+	if note < 0 || note > 127 {
+		return 0, fmt.Errorf("note %s is outside C-2 to G8", s)
+	}
+	return note, nil
+}
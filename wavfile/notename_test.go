@@ -0,0 +1,69 @@
+package wavfile
+
+import "testing"
+
+func TestNoteName(t *testing.T) {
+	tests := []struct {
+		note int
+		want string
+	}{
+		{0, "C-2"},
+		{36, "C1"},
+		{60, "C3"},
+		{66, "F#3"},
+		{127, "G8"},
+		{-1, "-1"},
+		{128, "128"},
+	}
+
+	for _, tt := range tests {
+		if got := NoteName(tt.note); got != tt.want {
+			t.Errorf("NoteName(%d) = %q, want %q", tt.note, got, tt.want)
+		}
+	}
+}
+
+func TestParseNote(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"36", 36, false},
+		{" 60 ", 60, false},
+		{"C1", 36, false},
+		{"c3", 60, false},
+		{"F#3", 66, false},
+		{"Bb-1", 22, false},
+		{"C-2", 0, false},
+		{"G8", 127, false},
+		{"Cb-2", 0, true},
+		{"G#8", 0, true},
+		{"128", 0, true},
+		{"-1", 0, true},
+		{"", 0, true},
+		{"H2", 0, true},
+		{"C", 0, true},
+		{"C#x", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseNote(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseNote(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseNote(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNoteNameRoundTrip(t *testing.T) {
+	for note := range 128 {
+		got, err := ParseNote(NoteName(note))
+		if err != nil || got != note {
+			t.Errorf("ParseNote(NoteName(%d)) = %d, %v", note, got, err)
+		}
+	}
+}
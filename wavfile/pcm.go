@@ -0,0 +1,338 @@
+package wavfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// WAV audio format codes
+const (
+	wavFormatPCM        = 1      // Integer samples
+	wavFormatFloat      = 3      // IEEE float samples
+	wavFormatExtensible = 0xFFFE // Format code is in the sub-format GUID
+)
+
+// PCM holds a WAV file's audio as interleaved samples in the range [-1, 1]
+type PCM struct {
+	AudioFormat   uint16
+	NumChannels   int
+	SampleRate    uint32
+	BitsPerSample int
+	Samples       []float64    // Interleaved samples, NumChannels per frame
+	Sampler       *SamplerInfo // smpl chunk, nil when the file has none
+	Cues          []CuePoint   // cue chunk with its labels
+	extra         []rawChunk   // Other chunks, such as LIST INFO and bext, written back as they are
+}
+
+// NumFrames returns the number of frames in the PCM data
+func (p *PCM) NumFrames() int {
+	if p.NumChannels == 0 {
+		return 0
+	}
+	return len(p.Samples) / p.NumChannels
+}
+
+// ReadPCM reads and decodes every sample of a WAV file
+func ReadPCM(filename string) (*PCM, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header, dataSize, err := readWavHeader(file)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, dataSize)
+	n, err := io.ReadFull(file, data)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("error reading samples: %w", err)
+	}
+	data = data[:n]
+
+	samples, err := decodeSamples(data, header.AudioFormat, int(header.BitsPerSample))
+	if err != nil {
+		return nil, err
+	}
+
+	// Malformed smpl or cue chunks only lose the loops or cues
+	sampler, _ := ReadSampler(filename)
+	cues, _ := ReadCues(filename)
+	extra, err := readExtraChunks(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PCM{
+		AudioFormat:   header.AudioFormat,
+		NumChannels:   int(header.NumChannels),
+		SampleRate:    header.SampleRate,
+		BitsPerSample: int(header.BitsPerSample),
+		Samples:       samples,
+		Sampler:       sampler,
+		Cues:          cues,
+		extra:         extra,
+	}, nil
+}
+
+// region returns the frames from startFrame up to endFrame as PCM of the
+// same format, keeping the loops and cues that lie inside them and the
+// other chunks, with a bext time reference moved to the first frame
+func (p *PCM) region(startFrame, endFrame int) *PCM {
+	region := *p
+	region.Samples = p.Samples[startFrame*p.NumChannels : endFrame*p.NumChannels]
+	region.Sampler = regionSampler(p.Sampler, startFrame, endFrame)
+	region.Cues = regionCues(p.Cues, startFrame, endFrame)
+	region.extra = nil
+	for _, chunk := range p.extra {
+		if chunk.id == "bext" {
+			chunk.body = shiftBext(chunk.body, startFrame)
+		}
+		region.extra = append(region.extra, chunk)
+	}
+	return &region
+}
+
+// readWavHeader reads the chunks of a WAV file up to its data chunk,
+// leaving file positioned at the first sample, and returns the format and
+// the size of the data in bytes. Extensible formats are resolved to the
+// plain format code of their sub-format.
+func readWavHeader(file io.ReadSeeker) (wavHeader, uint32, error) {
+	// Read RIFF header
+	var chunkID [4]byte
+	var chunkSize uint32
+	var format [4]byte
+
+	binary.Read(file, binary.LittleEndian, &chunkID)
+	binary.Read(file, binary.LittleEndian, &chunkSize)
+	binary.Read(file, binary.LittleEndian, &format)
+
+	if string(chunkID[:]) != "RIFF" || string(format[:]) != "WAVE" {
+		return wavHeader{}, 0, fmt.Errorf("not a valid WAV file")
+	}
+
+	var header wavHeader
+	var dataSize uint32
+	foundFmt := false
+	foundData := false
+
+	for !foundData {
+		var subchunkID [4]byte
+		var subchunkSize uint32
+
+		if err := binary.Read(file, binary.LittleEndian, &subchunkID); err != nil {
+			return wavHeader{}, 0, fmt.Errorf("error reading chunk ID: %w", err)
+		}
+		if err := binary.Read(file, binary.LittleEndian, &subchunkSize); err != nil {
+			return wavHeader{}, 0, fmt.Errorf("error reading chunk size: %w", err)
+		}
+
+		switch string(subchunkID[:]) {
+		case "fmt ":
+			binary.Read(file, binary.LittleEndian, &header.AudioFormat)
+			binary.Read(file, binary.LittleEndian, &header.NumChannels)
+			binary.Read(file, binary.LittleEndian, &header.SampleRate)
+			binary.Read(file, binary.LittleEndian, &header.ByteRate)
+			binary.Read(file, binary.LittleEndian, &header.BlockAlign)
+			binary.Read(file, binary.LittleEndian, &header.BitsPerSample)
+			extra := int64(subchunkSize) - 16
+			if header.AudioFormat == wavFormatExtensible && extra >= 24 {
+				// cbSize, valid bits and channel mask precede the sub-format
+				// GUID, whose first two bytes are the format code
+				file.Seek(8, io.SeekCurrent)
+				binary.Read(file, binary.LittleEndian, &header.AudioFormat)
+				extra -= 10
+			}
+			if extra > 0 {
+				file.Seek(extra, io.SeekCurrent)
+			}
+			foundFmt = true
+		case "data":
+			dataSize = subchunkSize
+			foundData = true
+		default:
+			// Chunks are padded to an even size
+			file.Seek(int64(subchunkSize)+int64(subchunkSize&1), io.SeekCurrent)
+		}
+	}
+
+	if !foundFmt {
+		return wavHeader{}, 0, fmt.Errorf("fmt chunk not found")
+	}
+
+	return header, dataSize, nil
+}
+
+// decodeSamples converts little-endian PCM or IEEE float bytes to floats
+func decodeSamples(data []byte, audioFormat uint16, bitsPerSample int) ([]float64, error) {
+	bytesPerSample := bitsPerSample / 8
+	if bytesPerSample == 0 {
+		return nil, fmt.Errorf("unsupported bit depth: %d", bitsPerSample)
+	}
+	numSamples := len(data) / bytesPerSample
+	samples := make([]float64, numSamples)
+
+	if audioFormat == wavFormatFloat {
+		switch bitsPerSample {
+		case 32:
+			for i := range numSamples {
+				samples[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:])))
+			}
+		case 64:
+			for i := range numSamples {
+				samples[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:]))
+			}
+		default:
+			return nil, fmt.Errorf("unsupported float bit depth: %d", bitsPerSample)
+		}
+		return samples, nil
+	} else if audioFormat != wavFormatPCM {
+		return nil, fmt.Errorf("unsupported audio format: %d", audioFormat)
+	}
+
+	switch bitsPerSample {
+	case 8:
+		for i := range numSamples {
+			samples[i] = (float64(data[i]) - 128.0) / 128.0
+		}
+	case 16:
+		for i := range numSamples {
+			samples[i] = float64(int16(binary.LittleEndian.Uint16(data[i*2:]))) / 32768.0
+		}
+	case 24:
+		for i := range numSamples {
+			b := data[i*3:]
+			sample := int32(b[0]) | int32(b[1])<<8 | int32(b[2])<<16
+			// Sign extend from 24-bit to 32-bit
+			if sample&0x800000 != 0 {
+				sample |= ^0xFFFFFF
+			}
+			samples[i] = float64(sample) / 8388608.0
+		}
+	case 32:
+		for i := range numSamples {
+			samples[i] = float64(int32(binary.LittleEndian.Uint32(data[i*4:]))) / 2147483648.0
+		}
+	default:
+		return nil, fmt.Errorf("unsupported bit depth: %d", bitsPerSample)
+	}
+
+	return samples, nil
+}
+
+// encodeSamples converts floats to little-endian PCM bytes, clipping to
+// [-1, 1], or to IEEE float bytes, which keep any headroom above full scale
+func encodeSamples(samples []float64, audioFormat uint16, bitsPerSample int) ([]byte, error) {
+	bytesPerSample := bitsPerSample / 8
+	data := make([]byte, len(samples)*bytesPerSample)
+
+	if audioFormat == wavFormatFloat {
+		for i, sample := range samples {
+			switch bitsPerSample {
+			case 32:
+				binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(float32(sample)))
+			case 64:
+				binary.LittleEndian.PutUint64(data[i*8:], math.Float64bits(sample))
+			default:
+				return nil, fmt.Errorf("unsupported float bit depth: %d", bitsPerSample)
+			}
+		}
+		return data, nil
+	}
+
+	for i, sample := range samples {
+		sample = math.Max(-1, math.Min(1, sample))
+		switch bitsPerSample {
+		case 8:
+			data[i] = uint8(math.Round(math.Min(sample*128.0+128.0, 255)))
+		case 16:
+			binary.LittleEndian.PutUint16(data[i*2:], uint16(int16(math.Round(math.Min(sample*32768.0, 32767)))))
+		case 24:
+			v := int32(math.Round(math.Min(sample*8388608.0, 8388607)))
+			data[i*3] = byte(v)
+			data[i*3+1] = byte(v >> 8)
+			data[i*3+2] = byte(v >> 16)
+		case 32:
+			v := int32(math.Round(math.Min(sample*2147483648.0, 2147483647)))
+			binary.LittleEndian.PutUint32(data[i*4:], uint32(v))
+		default:
+			return nil, fmt.Errorf("unsupported bit depth: %d", bitsPerSample)
+		}
+	}
+
+	return data, nil
+}
+
+// WritePCM writes PCM data to a WAV file, replacing it atomically. The
+// smpl, cue and other chunks the PCM was read with follow the data.
+func WritePCM(filename string, pcm *PCM) error {
+	audioFormat := pcm.AudioFormat
+	if audioFormat == 0 {
+		audioFormat = wavFormatPCM
+	}
+	data, err := encodeSamples(pcm.Samples, audioFormat, pcm.BitsPerSample)
+	if err != nil {
+		return err
+	}
+
+	blockAlign := uint16(pcm.NumChannels * pcm.BitsPerSample / 8)
+	byteRate := pcm.SampleRate * uint32(blockAlign)
+
+	var trailing []byte
+	if len(data)%2 != 0 {
+		trailing = append(trailing, 0)
+	}
+	if pcm.Sampler != nil {
+		trailing = append(trailing, smplChunk(pcm.Sampler, pcm.SampleRate)...)
+	}
+	trailing = append(trailing, cueChunks(pcm.Cues)...)
+	for _, chunk := range pcm.extra {
+		trailing = append(trailing, riffChunk(chunk.id, chunk.body)...)
+	}
+
+	// RIFF header, fmt chunk and the data chunk's header
+	header := []byte("RIFF")
+	header = binary.LittleEndian.AppendUint32(header, uint32(36+len(data)+len(trailing)))
+	header = append(header, "WAVEfmt "...)
+	header = binary.LittleEndian.AppendUint32(header, 16)
+	header = binary.LittleEndian.AppendUint16(header, audioFormat)
+	header = binary.LittleEndian.AppendUint16(header, uint16(pcm.NumChannels))
+	header = binary.LittleEndian.AppendUint32(header, pcm.SampleRate)
+	header = binary.LittleEndian.AppendUint32(header, byteRate)
+	header = binary.LittleEndian.AppendUint16(header, blockAlign)
+	header = binary.LittleEndian.AppendUint16(header, uint16(pcm.BitsPerSample))
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(data)))
+
+	// Write to temporary file
+	tempFilename := filename + ".tmp"
+	outFile, err := os.Create(tempFilename)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	for _, part := range [][]byte{header, data, trailing} {
+		if _, err := outFile.Write(part); err != nil {
+			outFile.Close()
+			os.Remove(tempFilename)
+			return fmt.Errorf("failed to write temp file: %w", err)
+		}
+	}
+	// A full disk can show up only on close
+	if err := outFile.Close(); err != nil {
+		os.Remove(tempFilename)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	// Replace original file with temp file
+	if err := os.Rename(tempFilename, filename); err != nil {
+		os.Remove(tempFilename)
+		return fmt.Errorf("failed to replace original file: %w", err)
+	}
+
+	return nil
+}
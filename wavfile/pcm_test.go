@@ -0,0 +1,162 @@
+package wavfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestWAV writes pcm to a new file in the test's temporary directory
+func writeTestWAV(t *testing.T, name string, pcm *PCM) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := WritePCM(filename, pcm); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// ramp returns numFrames frames of a rising ramp on each channel
+func ramp(numFrames int, numChannels int) []float64 {
+	samples := make([]float64, numFrames*numChannels)
+	for i := range samples {
+		samples[i] = float64(i%256)/256 - 0.5
+	}
+	return samples
+}
+
+func TestPCMRoundTrip(t *testing.T) {
+	tests := []struct {
+		name          string
+		audioFormat   uint16
+		bitsPerSample int
+		numChannels   int
+		tolerance     float64
+	}{
+		{"8-bit", wavFormatPCM, 8, 1, 1.0 / 128},
+		{"16-bit", wavFormatPCM, 16, 2, 1.0 / 32768},
+		{"24-bit", wavFormatPCM, 24, 2, 1.0 / 8388608},
+		{"32-bit", wavFormatPCM, 32, 1, 1.0 / 2147483648},
+		{"32-bit float", wavFormatFloat, 32, 2, 1e-7},
+		{"64-bit float", wavFormatFloat, 64, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples := ramp(101, tt.numChannels)
+			filename := writeTestWAV(t, "test.wav", &PCM{
+				AudioFormat:   tt.audioFormat,
+				NumChannels:   tt.numChannels,
+				SampleRate:    48000,
+				BitsPerSample: tt.bitsPerSample,
+				Samples:       samples,
+			})
+
+			pcm, err := ReadPCM(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pcm.AudioFormat != tt.audioFormat || pcm.NumChannels != tt.numChannels ||
+				pcm.SampleRate != 48000 || pcm.BitsPerSample != tt.bitsPerSample {
+				t.Errorf("format = %d %dch %dHz %d-bit", pcm.AudioFormat, pcm.NumChannels, pcm.SampleRate, pcm.BitsPerSample)
+			}
+			if pcm.NumFrames() != 101 {
+				t.Fatalf("NumFrames = %d, want 101", pcm.NumFrames())
+			}
+			for i, sample := range pcm.Samples {
+				if math.Abs(sample-samples[i]) > tt.tolerance {
+					t.Fatalf("sample %d = %g, want %g", i, sample, samples[i])
+				}
+			}
+
+			metadata, err := ReadMetadata(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if metadata.NumFrames != 101 || metadata.NumChannels != tt.numChannels {
+				t.Errorf("metadata has %d frames of %d channels, want 101 of %d", metadata.NumFrames, metadata.NumChannels, tt.numChannels)
+			}
+		})
+	}
+}
+
+func TestWriteRegionKeepsChunks(t *testing.T) {
+	filename := writeTestWAV(t, "source.wav", &PCM{
+		NumChannels:   1,
+		SampleRate:    44100,
+		BitsPerSample: 16,
+		Samples:       ramp(100, 1),
+		Sampler:       &SamplerInfo{UnityNote: 48, Loops: []SampleLoop{{Start: 10, End: 19}, {Start: 50, End: 59}}},
+		Cues:          []CuePoint{{Frame: 5, Label: "before"}, {Frame: 30, Label: "inside"}},
+	})
+
+	bext := make([]byte, bextMinSize)
+	copy(bext[bextOriginatorOffset:], "smplr")
+	binary.LittleEndian.PutUint64(bext[bextTimeReferenceOffset:], 1000)
+	info := riffList("INFO", riffChunk("INAM", []byte("Test\x00")))
+	if err := AppendChunks(filename, append(riffChunk("bext", bext), info...)); err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(filepath.Dir(filename), "region.wav")
+	if err := WriteRegion(filename, target, 20, 60, false); err != nil {
+		t.Fatal(err)
+	}
+
+	pcm, err := ReadPCM(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pcm.NumFrames() != 40 {
+		t.Errorf("NumFrames = %d, want 40", pcm.NumFrames())
+	}
+	if pcm.Sampler == nil || pcm.Sampler.UnityNote != 48 || len(pcm.Sampler.Loops) != 1 || pcm.Sampler.Loops[0] != (SampleLoop{Start: 30, End: 39}) {
+		t.Errorf("Sampler = %+v, want unity note 48 and the loop at 30-39", pcm.Sampler)
+	}
+	if len(pcm.Cues) != 1 || pcm.Cues[0] != (CuePoint{Frame: 10, Label: "inside"}) {
+		t.Errorf("Cues = %+v, want the cue at 10", pcm.Cues)
+	}
+
+	broadcast, err := ReadBroadcastInfo(target)
+	if err != nil || broadcast == nil {
+		t.Fatalf("ReadBroadcastInfo = %v, %v", broadcast, err)
+	}
+	if broadcast.Originator != "smplr" || broadcast.TimeReference != 1020 {
+		t.Errorf("bext = %+v, want originator smplr and time reference 1020", broadcast)
+	}
+
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, info) {
+		t.Error("LIST INFO chunk was dropped")
+	}
+
+	if err := WriteRegion(filename, target, 0, 10, false); err == nil {
+		t.Error("WriteRegion overwrote an existing file")
+	}
+}
+
+func TestScanChunksTruncated(t *testing.T) {
+	// A chunk claiming more bytes than the file holds
+	data := []byte("RIFF\x00\x00\x00\x00WAVE")
+	data = append(data, "LIST"...)
+	data = binary.LittleEndian.AppendUint32(data, 0xFFFFFFF0)
+	data = append(data, "INFOabcd"...)
+	filename := filepath.Join(t.TempDir(), "truncated.wav")
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var bodies [][]byte
+	if err := scanChunks(filename, func(id string, body []byte) { bodies = append(bodies, body) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 1 || string(bodies[0]) != "INFOabcd" {
+		t.Errorf("scanChunks read %q, want the 8 bytes left", bodies)
+	}
+}
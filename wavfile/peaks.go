@@ -0,0 +1,137 @@
+package wavfile
+
+import "math"
+
+// pyramidBinFrames is the number of frames summarized by one bin of the
+// finest pyramid level
+const pyramidBinFrames = 32
+
+// PeakLevel holds one resolution of a peak pyramid
+type PeakLevel struct {
+	Peaks       []float32 // Peak amplitude of each bin
+	MeanSquares []float32 // Mean square of each bin, so bins can be merged into RMS
+}
+
+// PeakPyramid summarizes a channel's audio at successively halved
+// resolutions, like a mip-map, so any window of the file can be drawn at
+// any width without re-reading it
+type PeakPyramid struct {
+	NumFrames int
+	Levels    []PeakLevel // Level i has bins of pyramidBinFrames<<i frames
+}
+
+// peakBuilder accumulates one channel's samples into the finest pyramid
+// level as they are read, so the samples themselves needn't be kept
+type peakBuilder struct {
+	base       PeakLevel
+	numFrames  int
+	binFrames  int
+	peak       float64
+	sumSquares float64
+}
+
+// add folds the next sample of the channel into the current bin
+func (b *peakBuilder) add(sample float64) {
+	b.peak = math.Max(b.peak, math.Abs(sample))
+	b.sumSquares += sample * sample
+	b.binFrames++
+	b.numFrames++
+	if b.binFrames == pyramidBinFrames {
+		b.flush()
+	}
+}
+
+// flush closes the current bin, which may be short at the end of the file
+func (b *peakBuilder) flush() {
+	if b.binFrames == 0 {
+		return
+	}
+	b.base.Peaks = append(b.base.Peaks, float32(b.peak))
+	b.base.MeanSquares = append(b.base.MeanSquares, float32(b.sumSquares/float64(b.binFrames)))
+	b.peak, b.sumSquares, b.binFrames = 0, 0, 0
+}
+
+// pyramid builds the coarser levels over the bins closed so far. Adding
+// more samples afterwards only appends to the base level, so a pyramid
+// taken mid-read stays valid.
+func (b *peakBuilder) pyramid() *PeakPyramid {
+	return buildPyramid(b.numFrames-b.binFrames, b.base)
+}
+
+// MixPeakPyramids combines channel pyramids into one holding the loudest
+// channel's peak and the average power across channels
+func MixPeakPyramids(channels []*PeakPyramid) *PeakPyramid {
+	first := channels[0].Levels[0]
+	base := PeakLevel{
+		Peaks:       make([]float32, len(first.Peaks)),
+		MeanSquares: make([]float32, len(first.Peaks)),
+	}
+	for bin := range base.Peaks {
+		for _, channel := range channels {
+			base.Peaks[bin] = max(base.Peaks[bin], channel.Levels[0].Peaks[bin])
+			base.MeanSquares[bin] += channel.Levels[0].MeanSquares[bin] / float32(len(channels))
+		}
+	}
+	return buildPyramid(channels[0].NumFrames, base)
+}
+
+// buildPyramid halves the base level until a single bin is left
+func buildPyramid(numFrames int, base PeakLevel) *PeakPyramid {
+	pyramid := &PeakPyramid{NumFrames: numFrames, Levels: []PeakLevel{base}}
+	for level := base; len(level.Peaks) > 1; {
+		numBins := (len(level.Peaks) + 1) / 2
+		next := PeakLevel{
+			Peaks:       make([]float32, numBins),
+			MeanSquares: make([]float32, numBins),
+		}
+		for bin := range numBins {
+			a, b := bin*2, min(bin*2+1, len(level.Peaks)-1)
+			next.Peaks[bin] = max(level.Peaks[a], level.Peaks[b])
+			next.MeanSquares[bin] = (level.MeanSquares[a] + level.MeanSquares[b]) / 2
+		}
+		pyramid.Levels = append(pyramid.Levels, next)
+		level = next
+	}
+	return pyramid
+}
+
+// Window returns the peak and RMS amplitude of numBins equal slices of the
+// numFrames frames from startFrame, read from the coarsest level that still
+// resolves each slice
+func (p *PeakPyramid) Window(startFrame, numFrames, numBins int) ([]float64, []float64) {
+	peaks := make([]float64, numBins)
+	rms := make([]float64, numBins)
+	if p == nil || len(p.Levels) == 0 || numFrames <= 0 || numBins <= 0 {
+		return peaks, rms
+	}
+
+	framesPerBin := float64(numFrames) / float64(numBins)
+	levelIndex, binFrames := 0, pyramidBinFrames
+	for levelIndex+1 < len(p.Levels) && float64(binFrames*2) <= framesPerBin {
+		levelIndex++
+		binFrames *= 2
+	}
+	level := p.Levels[levelIndex]
+	levelFrames := float64(binFrames)
+
+	for i := range numBins {
+		from := float64(startFrame) + float64(i)*framesPerBin
+		to := from + framesPerBin
+		first := int(from / levelFrames)
+		last := max(int(math.Ceil(to/levelFrames)), first+1)
+		first = max(0, first)
+		last = min(last, len(level.Peaks))
+
+		peak, meanSquare := float32(0), float32(0)
+		for bin := first; bin < last; bin++ {
+			peak = max(peak, level.Peaks[bin])
+			meanSquare += level.MeanSquares[bin]
+		}
+		if last > first {
+			meanSquare /= float32(last - first)
+		}
+		peaks[i] = float64(peak)
+		rms[i] = math.Sqrt(float64(meanSquare))
+	}
+	return peaks, rms
+}
@@ -0,0 +1,90 @@
+package wavfile
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// testPyramid builds a pyramid over samples the way StreamMetadata does
+func testPyramid(samples []float64) *PeakPyramid {
+	var builder peakBuilder
+	for _, sample := range samples {
+		builder.add(sample)
+	}
+	builder.flush()
+	return buildPyramid(builder.numFrames, builder.base)
+}
+
+func TestPeakPyramidLevels(t *testing.T) {
+	pyramid := testPyramid(make([]float64, 5*pyramidBinFrames+1))
+	var bins []int
+	for _, level := range pyramid.Levels {
+		bins = append(bins, len(level.Peaks))
+	}
+	if want := []int{6, 3, 2, 1}; !slices.Equal(bins, want) {
+		t.Errorf("levels have %v bins, want %v", bins, want)
+	}
+}
+
+func TestPeakPyramidWindow(t *testing.T) {
+	// Silence with a single loud burst in the last quarter
+	samples := make([]float64, 4096)
+	for i := 3072; i < 3072+64; i++ {
+		samples[i] = -0.8
+	}
+	pyramid := testPyramid(samples)
+
+	tests := []struct {
+		name       string
+		startFrame int
+		numFrames  int
+		numBins    int
+		wantPeaks  []float64
+	}{
+		{"whole file", 0, 4096, 4, []float64{0, 0, 0, 0.8}},
+		{"zoomed in on the burst", 3040, 128, 4, []float64{0, 0.8, 0.8, 0}},
+		{"coarse level", 0, 4096, 1, []float64{0.8}},
+		{"past the end", 8192, 1024, 2, []float64{0, 0}},
+		{"no frames", 0, 0, 3, []float64{0, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peaks, rms := pyramid.Window(tt.startFrame, tt.numFrames, tt.numBins)
+			if len(peaks) != tt.numBins || len(rms) != tt.numBins {
+				t.Fatalf("Window returned %d peaks and %d rms, want %d", len(peaks), len(rms), tt.numBins)
+			}
+			for i := range peaks {
+				if math.Abs(peaks[i]-tt.wantPeaks[i]) > 1e-6 {
+					t.Errorf("peaks = %v, want %v", peaks, tt.wantPeaks)
+					break
+				}
+				if rms[i] > peaks[i]+1e-6 {
+					t.Errorf("bin %d rms %g is above its peak %g", i, rms[i], peaks[i])
+				}
+			}
+		})
+	}
+
+	var empty *PeakPyramid
+	if peaks, _ := empty.Window(0, 100, 2); len(peaks) != 2 || peaks[0] != 0 {
+		t.Errorf("nil pyramid Window = %v, want two zero bins", peaks)
+	}
+}
+
+func TestMixPeakPyramids(t *testing.T) {
+	left := testPyramid([]float64{0.5, -0.5})
+	right := testPyramid([]float64{0.1, 0.1})
+	mix := MixPeakPyramids([]*PeakPyramid{left, right})
+
+	if mix.NumFrames != 2 {
+		t.Errorf("NumFrames = %d, want 2", mix.NumFrames)
+	}
+	if peak := mix.Levels[0].Peaks[0]; peak != 0.5 {
+		t.Errorf("peak = %g, want the louder channel's 0.5", peak)
+	}
+	if meanSquare := mix.Levels[0].MeanSquares[0]; math.Abs(float64(meanSquare)-0.13) > 1e-6 {
+		t.Errorf("mean square = %g, want the average 0.13", meanSquare)
+	}
+}
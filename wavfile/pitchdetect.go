@@ -0,0 +1,114 @@
+package wavfile
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// pitchWindowFrames is how much audio the detector analyzes
+	pitchWindowFrames = 8192
+	// Lowest and highest fundamentals the detector looks for
+	minPitchHz = 40.0
+	maxPitchHz = 2000.0
+	// yinThreshold is the normalized difference below which a lag counts
+	// as a period; lower is stricter
+	yinThreshold = 0.15
+)
+
+// DetectPitch estimates the fundamental frequency of the audio between
+// startFrame and endFrame of a WAV file with the YIN algorithm. It skips
+// the first tenth of the region, where the attack transient sits, and
+// analyzes a mono mix of the rest.
+func DetectPitch(filename string, startFrame, endFrame int) (float64, error) {
+	pcm, err := ReadPCM(filename)
+	if err != nil {
+		return 0, err
+	}
+
+	endFrame = min(endFrame, pcm.NumFrames())
+	if startFrame < 0 || startFrame >= endFrame {
+		return 0, fmt.Errorf("invalid region %d-%d", startFrame, endFrame)
+	}
+	start := startFrame + (endFrame-startFrame)/10
+	end := min(endFrame, start+pitchWindowFrames)
+
+	mono := make([]float64, end-start)
+	for i := range mono {
+		for ch := 0; ch < pcm.NumChannels; ch++ {
+			mono[i] += pcm.Samples[(start+i)*pcm.NumChannels+ch]
+		}
+	}
+
+	rate := float64(pcm.SampleRate)
+	minLag := int(rate / maxPitchHz)
+	maxLag := int(rate / minPitchHz)
+	if len(mono) < 2*maxLag {
+		maxLag = len(mono) / 2
+	}
+	if maxLag <= minLag+1 {
+		return 0, fmt.Errorf("region is too short to detect a pitch")
+	}
+	window := len(mono) - maxLag
+
+	// Cumulative mean normalized difference for every lag
+	diff := make([]float64, maxLag+1)
+	diff[0] = 1
+	sum := 0.0
+	for lag := 1; lag <= maxLag; lag++ {
+		d := 0.0
+		for i := 0; i < window; i++ {
+			delta := mono[i] - mono[i+lag]
+			d += delta * delta
+		}
+		sum += d
+		if sum == 0 {
+			diff[lag] = 1
+		} else {
+			diff[lag] = d * float64(lag) / sum
+		}
+	}
+
+	// The first dip below the threshold is the period; follow it to its bottom
+	lag := 0
+	for l := minLag; l < maxLag; l++ {
+		if diff[l] < yinThreshold {
+			for l+1 < maxLag && diff[l+1] < diff[l] {
+				l++
+			}
+			lag = l
+			break
+		}
+	}
+	if lag == 0 {
+		return 0, fmt.Errorf("no clear pitch")
+	}
+
+	// Refine the period between samples with a parabola through the dip
+	period := float64(lag)
+	if lag > 1 && lag < maxLag {
+		a, b, c := diff[lag-1], diff[lag], diff[lag+1]
+		if denom := a - 2*b + c; denom != 0 {
+			period += (a - c) / (2 * denom)
+		}
+	}
+	return rate / period, nil
+}
+
+// FrequencyToNote returns the MIDI note nearest a frequency and how many
+// cents the frequency is above (positive) or below (negative) it
+func FrequencyToNote(hz float64) (int, float64) {
+	exact := 69 + 12*math.Log2(hz/440)
+	note := int(math.Round(exact))
+	return note, (exact - float64(note)) * 100
+}
+
+// TuneToC returns the pitch shift, in semitones and cents, that moves a
+// sample sounding at hz to the nearest C
+func TuneToC(hz float64) (semitones int, cents int) {
+	exact := 69 + 12*math.Log2(hz/440)
+	shift := math.Round(exact/12)*12 - exact
+	total := int(math.Round(shift * 100))
+	semitones = int(math.Round(float64(total) / 100))
+	return semitones, total - semitones*100
+}
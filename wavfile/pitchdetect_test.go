@@ -0,0 +1,100 @@
+package wavfile
+
+import (
+	"math"
+	"testing"
+)
+
+// sine returns numFrames frames of a sine wave at hz on numChannels channels
+func sine(hz float64, sampleRate uint32, numFrames int, numChannels int) []float64 {
+	samples := make([]float64, numFrames*numChannels)
+	for i := range samples {
+		frame := i / numChannels
+		samples[i] = 0.5 * math.Sin(2*math.Pi*hz*float64(frame)/float64(sampleRate))
+	}
+	return samples
+}
+
+func TestDetectPitch(t *testing.T) {
+	tests := []struct {
+		name        string
+		hz          float64
+		numChannels int
+		numFrames   int
+		startFrame  int
+		endFrame    int
+		wantErr     bool
+	}{
+		{"A4", 440, 1, 44100, 0, 44100, false},
+		{"low E", 82.41, 1, 44100, 0, 44100, false},
+		{"stereo C5", 523.25, 2, 44100, 0, 44100, false},
+		{"end past the file", 261.63, 1, 22050, 1000, 100000, false},
+		{"region too short", 440, 1, 44100, 0, 40, true},
+		{"empty region", 440, 1, 44100, 500, 500, true},
+		{"negative start", 440, 1, 44100, -1, 100, true},
+		{"silence", 0, 1, 44100, 0, 44100, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := writeTestWAV(t, "tone.wav", &PCM{
+				AudioFormat:   wavFormatFloat,
+				NumChannels:   tt.numChannels,
+				SampleRate:    44100,
+				BitsPerSample: 32,
+				Samples:       sine(tt.hz, 44100, tt.numFrames, tt.numChannels),
+			})
+
+			got, err := DetectPitch(filename, tt.startFrame, tt.endFrame)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DetectPitch error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// Within 5 cents
+			if !tt.wantErr && math.Abs(1200*math.Log2(got/tt.hz)) > 5 {
+				t.Errorf("DetectPitch = %g Hz, want %g Hz", got, tt.hz)
+			}
+		})
+	}
+}
+
+func TestFrequencyToNote(t *testing.T) {
+	tests := []struct {
+		hz        float64
+		wantNote  int
+		wantCents float64
+	}{
+		{440, 69, 0},
+		{261.6256, 60, 0},
+		{446.16, 69, 24},
+		{430, 69, -39.8},
+	}
+
+	for _, tt := range tests {
+		note, cents := FrequencyToNote(tt.hz)
+		if note != tt.wantNote || math.Abs(cents-tt.wantCents) > 0.5 {
+			t.Errorf("FrequencyToNote(%g) = %d, %.1f, want %d, %.1f", tt.hz, note, cents, tt.wantNote, tt.wantCents)
+		}
+	}
+}
+
+func TestTuneToC(t *testing.T) {
+	tests := []struct {
+		hz            float64
+		wantSemitones int
+		wantCents     int
+	}{
+		{261.6256, 0, 0},
+		{440, 3, 0},
+		{392, 5, 0},
+		{349.23, -5, 0},
+		{446.16, 3, -24},
+		{97.999, 5, 0},
+	}
+
+	for _, tt := range tests {
+		semitones, cents := TuneToC(tt.hz)
+		if semitones != tt.wantSemitones || cents != tt.wantCents {
+			t.Errorf("TuneToC(%g) = %d, %d, want %d, %d", tt.hz, semitones, cents, tt.wantSemitones, tt.wantCents)
+		}
+	}
+}
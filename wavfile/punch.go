@@ -0,0 +1,54 @@
+package wavfile
+
+import "fmt"
+
+// PunchIn writes the audio of take into filename from startFrame, mixed
+// with what's there or replacing it, stopping at endFrame so nothing after
+// the region changes. The take must be at filename's sample rate; a mono
+// take plays on every channel and a wider one is averaged down to mono.
+func PunchIn(filename, take string, startFrame, endFrame int, mix bool) error {
+	pcm, err := ReadPCM(filename)
+	if err != nil {
+		return err
+	}
+	takePCM, err := ReadPCM(take)
+	if err != nil {
+		return fmt.Errorf("failed to read take: %w", err)
+	}
+	if takePCM.SampleRate != pcm.SampleRate {
+		return fmt.Errorf("take is %d Hz but %s is %d Hz", takePCM.SampleRate, filename, pcm.SampleRate)
+	}
+
+	numFrames := pcm.NumFrames()
+	if startFrame < 0 || endFrame >= numFrames || startFrame > endFrame {
+		return fmt.Errorf("invalid region %d-%d for %d frames", startFrame, endFrame, numFrames)
+	}
+
+	frames := min(takePCM.NumFrames(), endFrame-startFrame+1)
+	for i := range frames {
+		for ch := range pcm.NumChannels {
+			sample := takeSample(takePCM, i, ch, pcm.NumChannels)
+			index := (startFrame+i)*pcm.NumChannels + ch
+			if mix {
+				sample += pcm.Samples[index]
+			}
+			pcm.Samples[index] = max(-1, min(1, sample))
+		}
+	}
+
+	return WritePCM(filename, pcm)
+}
+
+// takeSample returns a take's sample for one channel of a file with
+// numChannels channels
+func takeSample(take *PCM, frame, channel, numChannels int) float64 {
+	samples := take.Samples[frame*take.NumChannels : (frame+1)*take.NumChannels]
+	if numChannels == 1 && len(samples) > 1 {
+		sum := 0.0
+		for _, sample := range samples {
+			sum += sample
+		}
+		return sum / float64(len(samples))
+	}
+	return samples[min(channel, len(samples)-1)]
+}
@@ -0,0 +1,35 @@
+package wavfile
+
+import "slices"
+
+// ReverseFile rewrites a WAV file with its frames in reverse order. Loops
+// and cues are mirrored to keep marking the same audio.
+func ReverseFile(filename string) error {
+	pcm, err := ReadPCM(filename)
+	if err != nil {
+		return err
+	}
+
+	numFrames := pcm.NumFrames()
+	for i, j := 0, numFrames-1; i < j; i, j = i+1, j-1 {
+		for ch := 0; ch < pcm.NumChannels; ch++ {
+			a := i*pcm.NumChannels + ch
+			b := j*pcm.NumChannels + ch
+			pcm.Samples[a], pcm.Samples[b] = pcm.Samples[b], pcm.Samples[a]
+		}
+	}
+
+	if pcm.Sampler != nil {
+		for i, loop := range pcm.Sampler.Loops {
+			pcm.Sampler.Loops[i].Start = numFrames - 1 - loop.End
+			pcm.Sampler.Loops[i].End = numFrames - 1 - loop.Start
+		}
+	}
+
+	for i := range pcm.Cues {
+		pcm.Cues[i].Frame = numFrames - 1 - pcm.Cues[i].Frame
+	}
+	slices.Reverse(pcm.Cues)
+
+	return WritePCM(filename, pcm)
+}
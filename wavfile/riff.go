@@ -0,0 +1,180 @@
+package wavfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// scanChunks calls fn with the ID and body of every chunk of a WAV file
+// except data, whose body is skipped unread
+func scanChunks(filename string, fn func(id string, body []byte)) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	var riff [12]byte
+	if _, err := io.ReadFull(file, riff[:]); err != nil || string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return fmt.Errorf("not a valid WAV file")
+	}
+
+	for {
+		var chunkID [4]byte
+		var chunkSize uint32
+		if err := binary.Read(file, binary.LittleEndian, &chunkID); err != nil {
+			return nil
+		}
+		if err := binary.Read(file, binary.LittleEndian, &chunkSize); err != nil {
+			return nil
+		}
+		// Chunks are padded to an even size
+		padded := int64(chunkSize) + int64(chunkSize&1)
+		if string(chunkID[:]) == "data" {
+			if _, err := file.Seek(padded, io.SeekCurrent); err != nil {
+				return nil
+			}
+			continue
+		}
+
+		// A corrupt header can claim more than the file holds
+		offset, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil
+		}
+		body := make([]byte, max(min(padded, info.Size()-offset), 0))
+		n, _ := io.ReadFull(file, body)
+		fn(string(chunkID[:]), body[:min(n, int(chunkSize))])
+		if n < len(body) {
+			return nil
+		}
+	}
+}
+
+// rewriteChunks replaces a WAV file with its chunks that keep accepts,
+// followed by extra, which must be complete chunks. The file is replaced
+// atomically.
+func rewriteChunks(filename string, keep func(id string, body []byte) bool, extra ...[]byte) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return fmt.Errorf("not a valid WAV file")
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	out.Write([]byte{0, 0, 0, 0}) // Size, filled in below
+	out.WriteString("WAVE")
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		end := min(offset+8+size, len(data))
+		if keep(id, data[offset+8:end]) {
+			out.Write(riffChunk(id, data[offset+8:end]))
+		}
+		offset = end + size&1
+	}
+	for _, chunk := range extra {
+		out.Write(chunk)
+	}
+
+	result := out.Bytes()
+	binary.LittleEndian.PutUint32(result[4:], uint32(len(result)-8))
+
+	tempFilename := filename + ".tmp"
+	if err := os.WriteFile(tempFilename, result, 0644); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempFilename, filename); err != nil {
+		os.Remove(tempFilename)
+		return fmt.Errorf("failed to replace original file: %w", err)
+	}
+	return nil
+}
+
+// MetadataChunks returns the chunks of a WAV file other than fmt, fact and
+// data, encoded to follow the audio of the frames from startFrame up to
+// endFrame once it's cut out. smpl loops and cue points outside those
+// frames are dropped and the rest shifted to match, and a bext time
+// reference moves to the new first frame; anything else, such as LIST
+// INFO, is copied as it is.
+func MetadataChunks(filename string, startFrame, endFrame int) ([]byte, error) {
+	cues, err := ReadCues(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []byte
+	err = scanChunks(filename, func(id string, body []byte) {
+		switch {
+		case id == "fmt " || id == "fact":
+			// Written along with the new audio
+		case isCueChunk(id, body):
+			// Written from the shifted cue points below
+		case id == "smpl":
+			info, err := parseSampler(body)
+			if err != nil {
+				return
+			}
+			smpl := smplChunk(regionSampler(info, startFrame, endFrame), 0)
+			// Keep the manufacturer, period, tuning and SMPTE fields as they were
+			copy(smpl[8:8+28], body)
+			chunks = append(chunks, smpl...)
+		case id == "bext":
+			chunks = append(chunks, riffChunk(id, shiftBext(body, startFrame))...)
+		default:
+			chunks = append(chunks, riffChunk(id, body)...)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(chunks, cueChunks(regionCues(cues, startFrame, endFrame))...), nil
+}
+
+// AppendChunks adds complete chunks to the end of a WAV file, replacing it
+// atomically
+func AppendChunks(filename string, chunks []byte) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	return rewriteChunks(filename, func(string, []byte) bool { return true }, chunks)
+}
+
+// rawChunk is a chunk of a WAV file kept as it was read
+type rawChunk struct {
+	id   string
+	body []byte
+}
+
+// readExtraChunks returns the chunks of a WAV file that PCM has no fields
+// for, such as LIST INFO and bext
+func readExtraChunks(filename string) ([]rawChunk, error) {
+	var chunks []rawChunk
+	err := scanChunks(filename, func(id string, body []byte) {
+		if id != "fmt " && id != "fact" && id != "smpl" && !isCueChunk(id, body) {
+			chunks = append(chunks, rawChunk{id, body})
+		}
+	})
+	return chunks, err
+}
+
+// shiftBext returns a copy of a bext chunk body whose time reference
+// points frames further into the recording, for audio cut out from there
+func shiftBext(body []byte, frames int) []byte {
+	bext := append([]byte(nil), body...)
+	if len(bext) >= bextMinSize {
+		timeReference := binary.LittleEndian.Uint64(bext[bextTimeReferenceOffset:])
+		binary.LittleEndian.PutUint64(bext[bextTimeReferenceOffset:], timeReference+uint64(frames))
+	}
+	return bext
+}
@@ -0,0 +1,230 @@
+package wavfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// SessionFileName is the session file kept in the working directory
+const SessionFileName = ".smplr.json"
+
+// sessionVersion is bumped when the session format changes incompatibly
+const sessionVersion = 1
+
+// Session is the saved list order and per-sample settings of a directory
+type Session struct {
+	Version    int             `json:"version"`
+	Recursive  bool            `json:"recursive,omitempty"` // samples are loaded from subdirectories too
+	Samples    []SessionSample `json:"samples"`
+	CCMappings []CCMapping     `json:"ccMappings,omitempty"`
+}
+
+// SessionSample holds the saved settings of one sample
+type SessionSample struct {
+	Name           string   `json:"name"`
+	Slot           int      `json:"slot,omitempty"`
+	MidiChannel    int      `json:"midiChannel"`
+	MidiNote       int      `json:"midiNote"`
+	Pitch          int      `json:"pitch"`
+	FineTune       int      `json:"fineTune,omitempty"`
+	Chromatic      bool     `json:"chromatic,omitempty"`
+	StartFrame     int      `json:"startFrame"`
+	EndFrame       int      `json:"endFrame"`
+	ChainNext      string   `json:"chainNext,omitempty"`
+	ChainNextSlot  int      `json:"chainNextSlot,omitempty"`
+	ChainCrossfade int      `json:"chainCrossfade,omitempty"`
+	StereoWidth    int      `json:"stereoWidth"`
+	GainDB         float64  `json:"gainDb"`
+	Pan            int      `json:"pan,omitempty"`
+	AttackMs       int      `json:"attackMs,omitempty"`
+	DecayMs        int      `json:"decayMs,omitempty"`
+	SustainLevel   int      `json:"sustainLevel"`
+	ReleaseMs      int      `json:"releaseMs,omitempty"`
+	Loop           bool     `json:"loop,omitempty"`
+	Reverse        bool     `json:"reverse,omitempty"`
+	ChannelPair    int      `json:"channelPair,omitempty"`
+	PlayMode       PlayMode `json:"playMode,omitempty"`
+	ChokeGroup     int      `json:"chokeGroup,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	Favorite       bool     `json:"favorite,omitempty"`
+}
+
+// UnmarshalJSON fills in defaults for settings missing from older session files
+func (s *SessionSample) UnmarshalJSON(data []byte) error {
+	type plain SessionSample
+	sample := plain{StereoWidth: 100, SustainLevel: 100}
+	if err := json.Unmarshal(data, &sample); err != nil {
+		return err
+	}
+	*s = SessionSample(sample)
+	return nil
+}
+
+// NewSessionSample returns the settings of file to save
+func NewSessionSample(file WavFile) SessionSample {
+	return SessionSample{
+		Name:           file.Name,
+		Slot:           file.Slot,
+		MidiChannel:    file.MidiChannel,
+		MidiNote:       file.MidiNote,
+		Pitch:          file.Pitch,
+		FineTune:       file.FineTune,
+		Chromatic:      file.Chromatic,
+		StartFrame:     file.StartFrame,
+		EndFrame:       file.EndFrame,
+		ChainNext:      file.ChainNext,
+		ChainNextSlot:  file.ChainNextSlot,
+		ChainCrossfade: file.ChainCrossfade,
+		StereoWidth:    file.StereoWidth,
+		GainDB:         file.GainDB,
+		Pan:            file.Pan,
+		AttackMs:       file.AttackMs,
+		DecayMs:        file.DecayMs,
+		SustainLevel:   file.SustainLevel,
+		ReleaseMs:      file.ReleaseMs,
+		Loop:           file.Loop,
+		Reverse:        file.Reverse,
+		ChannelPair:    file.ChannelPair,
+		PlayMode:       file.PlayMode,
+		ChokeGroup:     file.ChokeGroup,
+		Tags:           file.Tags,
+		Favorite:       file.Favorite,
+	}
+}
+
+// Restore sets the saved settings on file, except its slot, which must
+// stay unique in the list it is in
+func (s SessionSample) Restore(file *WavFile) {
+	file.MidiChannel = s.MidiChannel
+	file.MidiNote = s.MidiNote
+	file.Pitch = s.Pitch
+	file.FineTune = s.FineTune
+	file.Chromatic = s.Chromatic
+	file.StartFrame = s.StartFrame
+	file.EndFrame = s.EndFrame
+	file.ChainNext = s.ChainNext
+	file.ChainNextSlot = s.ChainNextSlot
+	file.ChainCrossfade = s.ChainCrossfade
+	file.StereoWidth = s.StereoWidth
+	file.GainDB = s.GainDB
+	file.Pan = s.Pan
+	file.AttackMs = s.AttackMs
+	file.DecayMs = s.DecayMs
+	file.SustainLevel = s.SustainLevel
+	file.ReleaseMs = s.ReleaseMs
+	file.Loop = s.Loop
+	file.Reverse = s.Reverse
+	file.ChannelPair = s.ChannelPair
+	file.PlayMode = s.PlayMode
+	file.ChokeGroup = s.ChokeGroup
+	file.Tags = s.Tags
+	file.Favorite = s.Favorite
+}
+
+// Sample returns the saved settings of the named sample
+func (s *Session) Sample(name string) (SessionSample, bool) {
+	for _, sample := range s.Samples {
+		if sample.Name == name {
+			return sample, true
+		}
+	}
+	return SessionSample{}, false
+}
+
+// LoadSession reads a session file
+func LoadSession(filename string) (*Session, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("invalid session file: %w", err)
+	}
+	if session.Version > sessionVersion {
+		return nil, fmt.Errorf("session file version %d is newer than supported version %d", session.Version, sessionVersion)
+	}
+	return &session, nil
+}
+
+// EncodeSession returns the session file contents for the order and
+// settings of files, the CC routing table and whether subdirectories are
+// scanned
+func EncodeSession(files []WavFile, ccMappings []CCMapping, recursive bool) ([]byte, error) {
+	session := Session{Version: sessionVersion, Recursive: recursive, CCMappings: ccMappings}
+	for _, file := range files {
+		session.Samples = append(session.Samples, NewSessionSample(file))
+	}
+
+	return json.MarshalIndent(session, "", "  ")
+}
+
+// WriteSession replaces a session file atomically with data from
+// EncodeSession
+func WriteSession(filename string, data []byte) error {
+	tempFilename := filename + ".tmp"
+	if err := os.WriteFile(tempFilename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	if err := os.Rename(tempFilename, filename); err != nil {
+		os.Remove(tempFilename)
+		return fmt.Errorf("failed to replace session: %w", err)
+	}
+	return nil
+}
+
+// Save writes the session to a file, replacing it atomically
+func (s *Session) Save(filename string) error {
+	s.Version = sessionVersion
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteSession(filename, data)
+}
+
+// Apply restores saved settings onto files and puts them in the saved
+// order. A file saved more than once is a duplicated slot and gets an
+// entry for each. Files missing from the session keep their settings and
+// go after the saved ones on the next free notes; saved samples that no
+// longer exist are dropped.
+func (s *Session) Apply(files []WavFile) []WavFile {
+	byName := map[string]int{}
+	for i, file := range files {
+		byName[file.Name] = i
+	}
+
+	used := make([]bool, len(files))
+	ordered := make([]WavFile, 0, len(files))
+	for _, saved := range s.Samples {
+		i, ok := byName[saved.Name]
+		if !ok {
+			continue
+		}
+		used[i] = true
+
+		file := files[i]
+		saved.Restore(&file)
+		// Sessions from before slots were saved have them all at 0
+		file.Slot = saved.Slot
+		if FindSlot(ordered, file.Name, file.Slot) >= 0 {
+			file.Slot = NextSlot(ordered, file.Name)
+		}
+		ordered = append(ordered, file)
+	}
+
+	// New files go after the saved ones without clashing with their notes
+	note := FindMaxMidiNote(ordered)
+	for i, file := range files {
+		if used[i] {
+			continue
+		}
+		note++
+		file.MidiNote = note
+		ordered = append(ordered, file)
+	}
+
+	return ordered
+}
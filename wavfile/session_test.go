@@ -0,0 +1,175 @@
+package wavfile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionRoundTrip(t *testing.T) {
+	files := []WavFile{
+		{Name: "kick.wav", MidiChannel: 9, MidiNote: 36, StereoWidth: 100, SustainLevel: 100, GainDB: -3, Tags: []string{"kick"}},
+		{Name: "snare.wav", MidiChannel: 9, MidiNote: 38, StereoWidth: 50, SustainLevel: 80, Loop: true, ChainNext: "kick.wav", ChainNextSlot: 1},
+		{Name: "kick.wav", Slot: 1, MidiChannel: 9, MidiNote: 40, StereoWidth: 100, SustainLevel: 100, Reverse: true},
+	}
+	mappings := []CCMapping{{Sample: "kick.wav", Slot: 1}}
+
+	data, err := EncodeSession(files, mappings, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		t.Fatal(err)
+	}
+	if !session.Recursive {
+		t.Error("Recursive was not saved")
+	}
+	if len(session.CCMappings) != 1 || session.CCMappings[0].Slot != 1 {
+		t.Errorf("CCMappings = %+v, want the slot 1 mapping", session.CCMappings)
+	}
+
+	// Loaded files come in directory order with default settings
+	loaded := []WavFile{
+		{Name: "kick.wav", StereoWidth: 100, SustainLevel: 100},
+		{Name: "snare.wav", StereoWidth: 100, SustainLevel: 100},
+	}
+	got := session.Apply(loaded)
+	if len(got) != len(files) {
+		t.Fatalf("Apply returned %d files, want %d", len(got), len(files))
+	}
+	for i := range files {
+		want := NewSessionSample(files[i])
+		if have := NewSessionSample(got[i]); !sameSample(have, want) {
+			t.Errorf("file %d = %+v, want %+v", i, have, want)
+		}
+	}
+}
+
+func sameSample(a, b SessionSample) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
+
+func TestSessionApply(t *testing.T) {
+	tests := []struct {
+		name      string
+		saved     []SessionSample
+		files     []string
+		wantNames []string
+		wantSlots []int
+		wantNotes []int
+	}{
+		{
+			name:      "saved order wins",
+			saved:     []SessionSample{{Name: "b.wav", MidiNote: 40}, {Name: "a.wav", MidiNote: 41}},
+			files:     []string{"a.wav", "b.wav"},
+			wantNames: []string{"b.wav", "a.wav"},
+			wantSlots: []int{0, 0},
+			wantNotes: []int{40, 41},
+		},
+		{
+			name:      "new files go after on free notes",
+			saved:     []SessionSample{{Name: "a.wav", MidiNote: 50}},
+			files:     []string{"c.wav", "a.wav", "d.wav"},
+			wantNames: []string{"a.wav", "c.wav", "d.wav"},
+			wantSlots: []int{0, 0, 0},
+			wantNotes: []int{50, 51, 52},
+		},
+		{
+			name:      "missing samples are dropped",
+			saved:     []SessionSample{{Name: "gone.wav", MidiNote: 36}, {Name: "a.wav", MidiNote: 37}},
+			files:     []string{"a.wav"},
+			wantNames: []string{"a.wav"},
+			wantSlots: []int{0},
+			wantNotes: []int{37},
+		},
+		{
+			name:      "duplicated slots keep their numbers",
+			saved:     []SessionSample{{Name: "a.wav", Slot: 2, MidiNote: 36}, {Name: "a.wav", MidiNote: 37}},
+			files:     []string{"a.wav"},
+			wantNames: []string{"a.wav", "a.wav"},
+			wantSlots: []int{2, 0},
+			wantNotes: []int{36, 37},
+		},
+		{
+			name:      "sessions without slots get distinct ones",
+			saved:     []SessionSample{{Name: "a.wav", MidiNote: 36}, {Name: "a.wav", MidiNote: 37}, {Name: "a.wav", MidiNote: 38}},
+			files:     []string{"a.wav"},
+			wantNames: []string{"a.wav", "a.wav", "a.wav"},
+			wantSlots: []int{0, 1, 2},
+			wantNotes: []int{36, 37, 38},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var files []WavFile
+			for _, name := range tt.files {
+				files = append(files, WavFile{Name: name})
+			}
+			session := Session{Samples: tt.saved}
+			got := session.Apply(files)
+			if len(got) != len(tt.wantNames) {
+				t.Fatalf("Apply returned %d files, want %d", len(got), len(tt.wantNames))
+			}
+			for i, file := range got {
+				if file.Name != tt.wantNames[i] || file.Slot != tt.wantSlots[i] || file.MidiNote != tt.wantNotes[i] {
+					t.Errorf("file %d = %s slot %d note %d, want %s slot %d note %d", i,
+						file.Name, file.Slot, file.MidiNote, tt.wantNames[i], tt.wantSlots[i], tt.wantNotes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadSession(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+		check   func(*testing.T, *Session)
+	}{
+		{
+			name: "older sessions get default width and sustain",
+			data: `{"version": 1, "samples": [{"name": "a.wav", "midiNote": 36}]}`,
+			check: func(t *testing.T, s *Session) {
+				sample, ok := s.Sample("a.wav")
+				if !ok {
+					t.Fatal("a.wav not found")
+				}
+				if sample.StereoWidth != 100 || sample.SustainLevel != 100 {
+					t.Errorf("StereoWidth %d SustainLevel %d, want 100 and 100", sample.StereoWidth, sample.SustainLevel)
+				}
+			},
+		},
+		{
+			name:    "newer version is refused",
+			data:    `{"version": 99, "samples": []}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			data:    `{"version": `,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), SessionFileName)
+			if err := os.WriteFile(filename, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			session, err := LoadSession(filename)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadSession error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, session)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package wavfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SoundFont generator operators used by the exporter
+const (
+	sf2GenPan                = 17
+	sf2GenAttackVolEnv       = 34
+	sf2GenDecayVolEnv        = 36
+	sf2GenSustainVolEnv      = 37
+	sf2GenReleaseVolEnv      = 38
+	sf2GenInstrument         = 41
+	sf2GenKeyRange           = 43
+	sf2GenInitialAttenuation = 48
+	sf2GenCoarseTune         = 51
+	sf2GenFineTune           = 52
+	sf2GenSampleID           = 53
+	sf2GenSampleModes        = 54
+	sf2GenExclusiveClass     = 57
+	sf2GenOverridingRootKey  = 58
+)
+
+// SoundFont sample types
+const (
+	sf2MonoSample  = 1
+	sf2RightSample = 2
+	sf2LeftSample  = 4
+)
+
+// sf2SamplePadding is the silence the spec requires after every sample
+const sf2SamplePadding = 46
+
+// sf2Generator is one generator of a zone
+type sf2Generator struct {
+	oper   uint16
+	amount uint16
+}
+
+// sf2Sample is one sample header and its 16-bit data
+type sf2Sample struct {
+	name       string
+	data       []int16
+	sampleRate uint32
+	rootKey    int
+	link       int // index of the other half of a stereo pair
+	sampleType uint16
+}
+
+// sf2Zone is an instrument zone: a sample and how it plays
+type sf2Zone struct {
+	generators []sf2Generator
+}
+
+// sf2Instrument is an instrument and its zones
+type sf2Instrument struct {
+	name    string
+	channel int
+	zones   []sf2Zone
+}
+
+// WriteSF2 writes the samples as a SoundFont 2 bank. SoundFonts have no MIDI
+// channels, so each channel in use becomes a preset of bank 0 whose program
+// number is the channel minus one. Every sample keeps only the region
+// between its markers, and stereo samples become linked left and right
+// samples. Samples that failed to load are left out.
+func WriteSF2(filename string, name string, files []WavFile) error {
+	var samples []sf2Sample
+	instruments := map[int]*sf2Instrument{}
+
+	for _, file := range files {
+		if file.Metadata == nil || file.Corrupted {
+			continue
+		}
+		pcm, err := ReadPCM(file.SourceFileName())
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+
+		instrument, ok := instruments[file.MidiChannel]
+		if !ok {
+			instrument = &sf2Instrument{
+				name:    fmt.Sprintf("Channel %d", file.MidiChannel),
+				channel: file.MidiChannel,
+			}
+			instruments[file.MidiChannel] = instrument
+		}
+
+		channels := sf2Channels(pcm, file.StartFrame, file.EndFrame, file.ChannelPair)
+		sampleName := strings.TrimSuffix(filepath.Base(file.Name), filepath.Ext(file.Name))
+		if len(channels) == 1 {
+			samples = append(samples, sf2Sample{
+				name: sampleName, data: channels[0], sampleRate: pcm.SampleRate,
+				rootKey: file.MidiNote, sampleType: sf2MonoSample,
+			})
+			instrument.zones = append(instrument.zones, sf2ZoneFor(file, len(samples)-1, 0))
+			continue
+		}
+
+		left := len(samples)
+		samples = append(samples,
+			sf2Sample{
+				name: sampleName + "L", data: channels[0], sampleRate: pcm.SampleRate,
+				rootKey: file.MidiNote, link: left + 1, sampleType: sf2LeftSample,
+			},
+			sf2Sample{
+				name: sampleName + "R", data: channels[1], sampleRate: pcm.SampleRate,
+				rootKey: file.MidiNote, link: left, sampleType: sf2RightSample,
+			})
+		instrument.zones = append(instrument.zones,
+			sf2ZoneFor(file, left, -500),
+			sf2ZoneFor(file, left+1, 500))
+	}
+
+	if len(samples) == 0 {
+		return fmt.Errorf("no loaded samples to export")
+	}
+
+	// Presets in channel order
+	var ordered []*sf2Instrument
+	for _, instrument := range instruments {
+		ordered = append(ordered, instrument)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].channel < ordered[j].channel })
+
+	data := riffChunk("RIFF", append([]byte("sfbk"), bytes.Join([][]byte{
+		sf2Info(name),
+		sf2SampleData(samples),
+		sf2PresetData(ordered, samples),
+	}, nil)...))
+
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
+
+// sf2Channels returns the 16-bit data of the region between the markers,
+// one slice per channel. Files of more than two channels become the stereo
+// pair they play, as the engine does: channelPair 0 mixes odd-numbered
+// channels left and even-numbered ones right.
+func sf2Channels(pcm *PCM, startFrame int, endFrame int, channelPair int) [][]int16 {
+	frames := pcm.NumFrames()
+	if endFrame <= startFrame || endFrame > frames {
+		endFrame = frames
+	}
+	startFrame = max(0, min(startFrame, endFrame))
+
+	count := min(pcm.NumChannels, 2)
+	channels := make([][]int16, count)
+	for c := range channels {
+		// Source channels summed into this one
+		sources := []int{c}
+		if pcm.NumChannels > 2 && channelPair > 0 {
+			sources = []int{min((channelPair-1)*2+c, pcm.NumChannels-1)}
+		} else if pcm.NumChannels > 2 {
+			sources = nil
+			for source := c; source < pcm.NumChannels; source += 2 {
+				sources = append(sources, source)
+			}
+		}
+
+		channels[c] = make([]int16, 0, endFrame-startFrame)
+		for frame := startFrame; frame < endFrame; frame++ {
+			sum := 0.0
+			for _, source := range sources {
+				sum += pcm.Samples[frame*pcm.NumChannels+source]
+			}
+			sample := math.Max(-1, math.Min(1, sum/float64(len(sources))))
+			channels[c] = append(channels[c], int16(math.Round(math.Min(sample*32768, 32767))))
+		}
+	}
+	return channels
+}
+
+// sf2ZoneFor builds the zone playing sample index for a file, panned for
+// one half of a stereo pair
+func sf2ZoneFor(file WavFile, sample int, pan int) sf2Zone {
+	// keyRange has to come first and sampleID last
+	lowKey, highKey := file.MidiNote, file.MidiNote
+	if file.Chromatic {
+		lowKey, highKey = 0, 127
+	}
+	generators := []sf2Generator{{sf2GenKeyRange, uint16(lowKey) | uint16(highKey)<<8}}
+
+	generators = append(generators, sf2Generator{sf2GenOverridingRootKey, uint16(file.MidiNote)})
+	if file.Pitch != 0 {
+		generators = append(generators, sf2Generator{sf2GenCoarseTune, uint16(int16(file.Pitch))})
+	}
+	if file.FineTune != 0 {
+		generators = append(generators, sf2Generator{sf2GenFineTune, uint16(int16(file.FineTune))})
+	}
+	// Pan is in tenths of a percent; the halves of a stereo pair move together
+	if pan = max(-500, min(500, pan+file.Pan*5)); pan != 0 {
+		generators = append(generators, sf2Generator{sf2GenPan, uint16(int16(pan))})
+	}
+	if file.GainDB < 0 {
+		// Attenuation is in centibels
+		generators = append(generators, sf2Generator{sf2GenInitialAttenuation, uint16(math.Round(-file.GainDB * 10))})
+	}
+	if file.HasEnvelope() {
+		generators = append(generators,
+			sf2Generator{sf2GenAttackVolEnv, uint16(sf2Timecents(file.AttackMs))},
+			sf2Generator{sf2GenDecayVolEnv, uint16(sf2Timecents(file.DecayMs))},
+			sf2Generator{sf2GenSustainVolEnv, uint16(sf2SustainCentibels(file.SustainLevel))},
+			sf2Generator{sf2GenReleaseVolEnv, uint16(sf2Timecents(file.ReleaseMs))},
+		)
+	}
+	if file.Loop {
+		generators = append(generators, sf2Generator{sf2GenSampleModes, 1})
+	}
+	if file.ChokeGroup > 0 {
+		generators = append(generators, sf2Generator{sf2GenExclusiveClass, uint16(file.ChokeGroup)})
+	}
+
+	generators = append(generators, sf2Generator{sf2GenSampleID, uint16(sample)})
+	return sf2Zone{generators: generators}
+}
+
+// sf2Timecents converts milliseconds to timecents, the unit of envelope times
+func sf2Timecents(ms int) int16 {
+	if ms <= 0 {
+		return -12000 // the shortest time a SoundFont can express
+	}
+	return int16(math.Round(1200 * math.Log2(float64(ms)/1000)))
+}
+
+// sf2SustainCentibels converts a sustain level in percent to the
+// attenuation SoundFonts expect, in centibels
+func sf2SustainCentibels(percent int) int16 {
+	if percent <= 0 {
+		return 1440 // silence
+	}
+	return int16(math.Round(-200 * math.Log10(float64(percent)/100)))
+}
+
+// sf2Info builds the INFO list
+func sf2Info(name string) []byte {
+	var version bytes.Buffer
+	binary.Write(&version, binary.LittleEndian, [2]uint16{2, 1})
+
+	return riffList("INFO",
+		riffChunk("ifil", version.Bytes()),
+		riffChunk("isng", sf2String("EMU8000")),
+		riffChunk("INAM", sf2String(name)),
+		riffChunk("ISFT", sf2String("smplr")),
+	)
+}
+
+// sf2SampleData builds the sdta list, laying the samples out one after
+// another with the required padding
+func sf2SampleData(samples []sf2Sample) []byte {
+	var smpl bytes.Buffer
+	padding := make([]int16, sf2SamplePadding)
+	for _, sample := range samples {
+		binary.Write(&smpl, binary.LittleEndian, sample.data)
+		binary.Write(&smpl, binary.LittleEndian, padding)
+	}
+	return riffList("sdta", riffChunk("smpl", smpl.Bytes()))
+}
+
+// sf2PresetData builds the pdta list: one preset and instrument per channel
+func sf2PresetData(instruments []*sf2Instrument, samples []sf2Sample) []byte {
+	var phdr, pbag, pgen, inst, ibag, igen, shdr bytes.Buffer
+	le := binary.LittleEndian
+
+	for i, instrument := range instruments {
+		// Presets have one zone pointing at their instrument
+		phdr.Write(sf2Name(instrument.name))
+		binary.Write(&phdr, le, [3]uint16{uint16(instrument.channel - 1), 0, uint16(i)})
+		binary.Write(&phdr, le, [3]uint32{})
+		binary.Write(&pbag, le, [2]uint16{uint16(i), 0})
+		binary.Write(&pgen, le, [2]uint16{sf2GenInstrument, uint16(i)})
+
+		inst.Write(sf2Name(instrument.name))
+		binary.Write(&inst, le, uint16(ibag.Len()/4))
+		for _, zone := range instrument.zones {
+			binary.Write(&ibag, le, [2]uint16{uint16(igen.Len() / 4), 0})
+			for _, generator := range zone.generators {
+				binary.Write(&igen, le, [2]uint16{generator.oper, generator.amount})
+			}
+		}
+	}
+
+	// Terminal records
+	phdr.Write(sf2Name("EOP"))
+	binary.Write(&phdr, le, [3]uint16{0, 0, uint16(len(instruments))})
+	binary.Write(&phdr, le, [3]uint32{})
+	binary.Write(&pbag, le, [2]uint16{uint16(len(instruments)), 0})
+	binary.Write(&pgen, le, [2]uint16{})
+	inst.Write(sf2Name("EOI"))
+	binary.Write(&inst, le, uint16(ibag.Len()/4))
+	binary.Write(&ibag, le, [2]uint16{uint16(igen.Len() / 4), 0})
+	binary.Write(&igen, le, [2]uint16{})
+
+	var start uint32
+	for _, sample := range samples {
+		end := start + uint32(len(sample.data))
+		shdr.Write(sf2Name(sample.name))
+		// Loops cover the whole sample, which is already cut to the markers
+		binary.Write(&shdr, le, [5]uint32{start, end, start, end, sample.sampleRate})
+		binary.Write(&shdr, le, [2]uint8{uint8(sample.rootKey), 0})
+		binary.Write(&shdr, le, [2]uint16{uint16(sample.link), sample.sampleType})
+		start = end + sf2SamplePadding
+	}
+	shdr.Write(sf2Name("EOS"))
+	shdr.Write(make([]byte, 26))
+
+	emptyModulators := make([]byte, 10)
+	return riffList("pdta",
+		riffChunk("phdr", phdr.Bytes()),
+		riffChunk("pbag", pbag.Bytes()),
+		riffChunk("pmod", emptyModulators),
+		riffChunk("pgen", pgen.Bytes()),
+		riffChunk("inst", inst.Bytes()),
+		riffChunk("ibag", ibag.Bytes()),
+		riffChunk("imod", emptyModulators),
+		riffChunk("igen", igen.Bytes()),
+		riffChunk("shdr", shdr.Bytes()),
+	)
+}
+
+// sf2Name pads or truncates a name to the 20 bytes of a SoundFont record
+func sf2Name(name string) []byte {
+	field := make([]byte, 20)
+	copy(field[:19], name)
+	return field
+}
+
+// sf2String is a zero-terminated INFO string padded to an even length
+func sf2String(value string) []byte {
+	data := append([]byte(value), 0)
+	if len(data)%2 != 0 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+// riffChunk wraps data in a RIFF chunk, padding it to an even length
+func riffChunk(id string, data []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(id)
+	binary.Write(&b, binary.LittleEndian, uint32(len(data)))
+	b.Write(data)
+	if len(data)%2 != 0 {
+		b.WriteByte(0)
+	}
+	return b.Bytes()
+}
+
+// riffList wraps chunks in a LIST chunk of the given type
+func riffList(listType string, chunks ...[]byte) []byte {
+	return riffChunk("LIST", append([]byte(listType), bytes.Join(chunks, nil)...))
+}
@@ -0,0 +1,81 @@
+package wavfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SFZ renders the samples as an SFZ instrument with one region per loaded
+// sample. Sample paths are relative to the current directory, so the file
+// belongs next to the kit. Samples that failed to load are left out.
+func SFZ(files []WavFile) string {
+	var b strings.Builder
+	b.WriteString("// Exported from smplr\n")
+
+	for _, file := range files {
+		if file.Metadata == nil || file.Corrupted {
+			continue
+		}
+
+		b.WriteString("\n<region>\n")
+		// sample takes the rest of the line, so it gets a line of its own
+		fmt.Fprintf(&b, "sample=%s\n", filepath.ToSlash(file.SourceFileName()))
+		fmt.Fprintf(&b, "lochan=%d hichan=%d\n", file.MidiChannel, file.MidiChannel)
+		if file.Chromatic {
+			fmt.Fprintf(&b, "lokey=0 hikey=127 pitch_keycenter=%d\n", file.MidiNote)
+		} else {
+			fmt.Fprintf(&b, "key=%d\n", file.MidiNote)
+		}
+		if file.Pitch != 0 {
+			fmt.Fprintf(&b, "transpose=%d\n", file.Pitch)
+		}
+		if file.FineTune != 0 {
+			fmt.Fprintf(&b, "tune=%d\n", file.FineTune)
+		}
+
+		// SFZ end is the last frame played, smplr's the first one that isn't
+		if file.EndFrame > file.StartFrame {
+			fmt.Fprintf(&b, "offset=%d end=%d\n", file.StartFrame, file.EndFrame-1)
+		}
+		switch {
+		case file.Loop:
+			fmt.Fprintf(&b, "loop_mode=loop_continuous loop_start=%d loop_end=%d\n", file.StartFrame, max(file.EndFrame-1, file.StartFrame))
+		case file.PlayMode == PlayModeGate:
+			b.WriteString("loop_mode=no_loop\n")
+		default:
+			b.WriteString("loop_mode=one_shot\n")
+		}
+
+		if file.Reverse {
+			b.WriteString("direction=reverse\n")
+		}
+		if file.GainDB != 0 {
+			fmt.Fprintf(&b, "volume=%.1f\n", file.GainDB)
+		}
+		if file.StereoWidth != 100 {
+			fmt.Fprintf(&b, "width=%d\n", file.StereoWidth)
+		}
+		if file.Pan != 0 {
+			fmt.Fprintf(&b, "pan=%d\n", file.Pan)
+		}
+		if file.HasEnvelope() {
+			fmt.Fprintf(&b, "ampeg_attack=%.3f ampeg_decay=%.3f ampeg_sustain=%d ampeg_release=%.3f\n",
+				float64(file.AttackMs)/1000, float64(file.DecayMs)/1000, file.SustainLevel, float64(file.ReleaseMs)/1000)
+		}
+		if file.ChokeGroup > 0 {
+			fmt.Fprintf(&b, "group=%d off_by=%d\n", file.ChokeGroup, file.ChokeGroup)
+		}
+	}
+
+	return b.String()
+}
+
+// WriteSFZ writes the samples as an SFZ instrument to filename
+func WriteSFZ(filename string, files []WavFile) error {
+	if err := os.WriteFile(filename, []byte(SFZ(files)), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package wavfile
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultSilenceDB is the level below which audio counts as silence
+const DefaultSilenceDB = -50.0
+
+// FindSound returns the first and last frames of a WAV file where any
+// channel reaches thresholdDB, so leading and trailing silence can be cut
+func FindSound(filename string, thresholdDB float64) (startFrame, endFrame int, err error) {
+	pcm, err := ReadPCM(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	threshold := DBToAmplitude(thresholdDB)
+	loud := func(frame int) bool {
+		for ch := 0; ch < pcm.NumChannels; ch++ {
+			if math.Abs(pcm.Samples[frame*pcm.NumChannels+ch]) >= threshold {
+				return true
+			}
+		}
+		return false
+	}
+
+	numFrames := pcm.NumFrames()
+	startFrame = 0
+	for startFrame < numFrames && !loud(startFrame) {
+		startFrame++
+	}
+	if startFrame == numFrames {
+		return 0, 0, fmt.Errorf("no audio above %.0f dBFS", thresholdDB)
+	}
+	endFrame = numFrames - 1
+	for endFrame > startFrame && !loud(endFrame) {
+		endFrame--
+	}
+	return startFrame, endFrame, nil
+}
@@ -0,0 +1,157 @@
+package wavfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SliceFile cuts a WAV file at the given frames and writes each non-empty
+// piece next to the target name as <base>_slice<n>.wav, returning the new
+// filenames in order. Existing files are never overwritten.
+func SliceFile(filename string, target string, cuts []int) ([]string, error) {
+	pcm, err := ReadPCM(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	numFrames := pcm.NumFrames()
+	bounds := []int{0}
+	sorted := append([]int(nil), cuts...)
+	sort.Ints(sorted)
+	for _, cut := range sorted {
+		if cut > bounds[len(bounds)-1] && cut < numFrames {
+			bounds = append(bounds, cut)
+		}
+	}
+	bounds = append(bounds, numFrames)
+	if len(bounds) < 3 {
+		return nil, fmt.Errorf("no cut points inside the file")
+	}
+
+	base := strings.TrimSuffix(target, filepath.Ext(target))
+	var names []string
+	for i := 0; i+1 < len(bounds); i++ {
+		name := fmt.Sprintf("%s_slice%d.wav", base, i+1)
+		if _, err := os.Stat(name); err == nil {
+			return nil, fmt.Errorf("%s already exists", name)
+		}
+		names = append(names, name)
+	}
+
+	for i, name := range names {
+		if err := WritePCM(name, pcm.region(bounds[i], bounds[i+1])); err != nil {
+			return names[:i], err
+		}
+	}
+	return names, nil
+}
+
+// sliceSuffix matches the suffix SliceFile gives its pieces
+var sliceSuffix = regexp.MustCompile(`_slice(\d+)$`)
+
+// SliceBase returns the name, without extension, of the file a slice was
+// cut from, or of the file itself when it isn't a slice
+func SliceBase(filename string) string {
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	return sliceSuffix.ReplaceAllString(base, "")
+}
+
+// HasSlices reports whether any file in the list was cut from the file
+// named base
+func HasSlices(files []WavFile, base string) bool {
+	return len(slicesOf(files, base)) > 0
+}
+
+// slicesOf returns the indexes of the slices cut from the file named base,
+// in slice order
+func slicesOf(files []WavFile, base string) []int {
+	type slice struct{ index, number int }
+	var slices []slice
+	for i := range files {
+		name := strings.TrimSuffix(files[i].Name, filepath.Ext(files[i].Name))
+		match := sliceSuffix.FindStringSubmatch(name)
+		if match == nil || strings.TrimSuffix(name, match[0]) != base {
+			continue
+		}
+		number, _ := strconv.Atoi(match[1])
+		slices = append(slices, slice{i, number})
+	}
+	sort.Slice(slices, func(a, b int) bool { return slices[a].number < slices[b].number })
+
+	indexes := make([]int, len(slices))
+	for i, s := range slices {
+		indexes[i] = s.index
+	}
+	return indexes
+}
+
+// ChopSlices maps the slices cut from the file named base, in slice order,
+// to consecutive notes from root on one channel, so a chopped loop plays
+// up the keyboard. Slices past note 127 keep their mapping. Other samples
+// on those notes move to free notes above the highest in use, up to 127;
+// any left over keep their note. It returns the number of slices mapped
+// and the number of slices and samples that found no note.
+func ChopSlices(files []WavFile, base string, channel int, root int) (int, int) {
+	slices := slicesOf(files, base)
+	mapped := min(len(slices), max(128-root, 0))
+	unplaced := len(slices) - mapped
+
+	chopped := make([]bool, len(files))
+	taken := map[int]bool{}
+	for n, index := range slices {
+		chopped[index] = true
+		if n >= mapped {
+			continue
+		}
+		files[index].MidiChannel = channel
+		files[index].MidiNote = root + n
+		taken[root+n] = true
+	}
+
+	next := FindMaxMidiNote(files)
+	for i := range files {
+		if chopped[i] || files[i].MidiChannel != channel || !taken[files[i].MidiNote] {
+			continue
+		}
+		if next >= 127 {
+			unplaced++
+			continue
+		}
+		next++
+		files[i].MidiNote = next
+	}
+	return mapped, unplaced
+}
+
+// WriteRegion writes the frames from startFrame up to endFrame of a WAV
+// file to a new WAV file in the same format, refusing to overwrite one.
+// With loop set, the smpl chunk loops the whole region; otherwise it keeps
+// the file's own loops that lie inside the region.
+func WriteRegion(filename string, target string, startFrame, endFrame int, loop bool) error {
+	if _, err := os.Stat(target); err == nil {
+		return fmt.Errorf("%s already exists", target)
+	}
+
+	pcm, err := ReadPCM(filename)
+	if err != nil {
+		return err
+	}
+	endFrame = min(endFrame, pcm.NumFrames())
+	if startFrame < 0 || startFrame >= endFrame {
+		return fmt.Errorf("invalid region %d-%d", startFrame, endFrame)
+	}
+
+	region := pcm.region(startFrame, endFrame)
+	if loop {
+		if region.Sampler == nil {
+			region.Sampler = &SamplerInfo{UnityNote: 60}
+		}
+		region.Sampler.Loops = []SampleLoop{{Start: 0, End: endFrame - startFrame - 1}}
+	}
+	return WritePCM(target, region)
+}
@@ -0,0 +1,161 @@
+package wavfile
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSliceFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		cuts       []int
+		wantFrames []int
+		wantErr    bool
+	}{
+		{"one cut", []int{40}, []int{40, 60}, false},
+		{"unsorted cuts", []int{70, 20, 50}, []int{20, 30, 20, 30}, false},
+		{"duplicate and outside cuts are ignored", []int{0, 30, 30, 100, 250}, []int{30, 70}, false},
+		{"no cut inside", []int{0, 100}, nil, true},
+		{"no cuts", nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := writeTestWAV(t, "loop.wav", &PCM{
+				NumChannels:   2,
+				SampleRate:    44100,
+				BitsPerSample: 16,
+				Samples:       ramp(100, 2),
+				Cues:          []CuePoint{{Frame: 45, Label: "hit"}},
+			})
+
+			names, err := SliceFile(filename, filename, tt.cuts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SliceFile error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(names) != len(tt.wantFrames) {
+				t.Fatalf("SliceFile wrote %v, want %d slices", names, len(tt.wantFrames))
+			}
+
+			start := 0
+			for i, name := range names {
+				if want := filepath.Join(filepath.Dir(filename), fmt.Sprintf("loop_slice%d.wav", i+1)); name != want {
+					t.Errorf("slice %d is %s, want %s", i, name, want)
+				}
+				pcm, err := ReadPCM(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if pcm.NumFrames() != tt.wantFrames[i] {
+					t.Errorf("slice %d has %d frames, want %d", i, pcm.NumFrames(), tt.wantFrames[i])
+				}
+				if 45 >= start && 45 < start+tt.wantFrames[i] {
+					if len(pcm.Cues) != 1 || pcm.Cues[0].Frame != 45-start {
+						t.Errorf("slice %d cues = %+v, want the cue at %d", i, pcm.Cues, 45-start)
+					}
+				} else if len(pcm.Cues) != 0 {
+					t.Errorf("slice %d cues = %+v, want none", i, pcm.Cues)
+				}
+				start += tt.wantFrames[i]
+			}
+
+			if len(names) > 0 {
+				if _, err := SliceFile(filename, filename, tt.cuts); err == nil {
+					t.Error("SliceFile overwrote existing slices")
+				}
+			}
+		})
+	}
+}
+
+func TestSliceBase(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"loop_slice3.wav", "loop"},
+		{"loops/break_slice12.wav", "loops/break"},
+		{"loop.wav", "loop"},
+		{"loop_slice.wav", "loop_slice"},
+		{"loop_slice2_slice1.wav", "loop_slice2"},
+	}
+
+	for _, tt := range tests {
+		if got := SliceBase(tt.filename); got != tt.want {
+			t.Errorf("SliceBase(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestChopSlices(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        []WavFile
+		root         int
+		wantNotes    []int
+		wantChannels []int
+		wantMapped   int
+		wantUnplaced int
+	}{
+		{
+			name: "slices go up from root in slice order",
+			files: []WavFile{
+				{Name: "loop_slice2.wav", MidiChannel: 1, MidiNote: 10},
+				{Name: "loop.wav", MidiChannel: 1, MidiNote: 11},
+				{Name: "loop_slice10.wav", MidiChannel: 1, MidiNote: 12},
+				{Name: "loop_slice1.wav", MidiChannel: 1, MidiNote: 13},
+				{Name: "other_slice1.wav", MidiChannel: 1, MidiNote: 14},
+			},
+			root:         60,
+			wantNotes:    []int{61, 11, 62, 60, 14},
+			wantChannels: []int{2, 1, 2, 2, 1},
+			wantMapped:   3,
+		},
+		{
+			name: "samples in the way move up",
+			files: []WavFile{
+				{Name: "kick.wav", MidiChannel: 2, MidiNote: 36},
+				{Name: "loop_slice1.wav", MidiChannel: 1, MidiNote: 40},
+				{Name: "snare.wav", MidiChannel: 1, MidiNote: 36},
+			},
+			root:         36,
+			wantNotes:    []int{37, 36, 36},
+			wantChannels: []int{2, 2, 1},
+			wantMapped:   1,
+		},
+		{
+			name: "slices past 127 keep their mapping",
+			files: []WavFile{
+				{Name: "loop_slice1.wav", MidiChannel: 1, MidiNote: 1},
+				{Name: "loop_slice2.wav", MidiChannel: 1, MidiNote: 2},
+			},
+			root:         127,
+			wantNotes:    []int{127, 2},
+			wantChannels: []int{2, 1},
+			wantMapped:   1,
+			wantUnplaced: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !HasSlices(tt.files, "loop") {
+				t.Error("HasSlices = false")
+			}
+			mapped, unplaced := ChopSlices(tt.files, "loop", 2, tt.root)
+			if mapped != tt.wantMapped || unplaced != tt.wantUnplaced {
+				t.Errorf("ChopSlices = %d, %d, want %d, %d", mapped, unplaced, tt.wantMapped, tt.wantUnplaced)
+			}
+			var notes, channels []int
+			for _, file := range tt.files {
+				notes = append(notes, file.MidiNote)
+				channels = append(channels, file.MidiChannel)
+			}
+			if !slices.Equal(notes, tt.wantNotes) || !slices.Equal(channels, tt.wantChannels) {
+				t.Errorf("notes %v on channels %v, want %v on %v", notes, channels, tt.wantNotes, tt.wantChannels)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package wavfile
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// smplHeaderSize and smplLoopSize are the byte sizes of the smpl chunk's
+// fixed fields and of each loop record
+const (
+	smplHeaderSize = 36
+	smplLoopSize   = 24
+)
+
+// SampleLoop is a loop from a smpl chunk, in frames. End is the last frame
+// of the loop, as hardware samplers write it.
+type SampleLoop struct {
+	Start int
+	End   int
+	Type  uint32 // 0 = forward, 1 = alternating, 2 = backward
+}
+
+// SamplerInfo holds the smpl chunk fields smplr round-trips
+type SamplerInfo struct {
+	UnityNote     int    // MIDI note that plays the sample at its recorded pitch
+	PitchFraction uint32 // Fraction of a semitone above UnityNote, in 1/2^32
+	Loops         []SampleLoop
+}
+
+// ReadSampler returns the smpl chunk of a WAV file, or nil when it has none
+func ReadSampler(filename string) (*SamplerInfo, error) {
+	var smpl []byte
+	err := scanChunks(filename, func(id string, body []byte) {
+		if id == "smpl" && smpl == nil {
+			smpl = body
+		}
+	})
+	if err != nil || smpl == nil {
+		return nil, err
+	}
+	return parseSampler(smpl)
+}
+
+// parseSampler decodes the body of a smpl chunk
+func parseSampler(data []byte) (*SamplerInfo, error) {
+	if len(data) < smplHeaderSize {
+		return nil, fmt.Errorf("smpl chunk too short")
+	}
+	info := &SamplerInfo{
+		UnityNote:     int(binary.LittleEndian.Uint32(data[12:])),
+		PitchFraction: binary.LittleEndian.Uint32(data[16:]),
+	}
+	numLoops := int(binary.LittleEndian.Uint32(data[28:]))
+	for i := range numLoops {
+		offset := smplHeaderSize + i*smplLoopSize
+		if offset+smplLoopSize > len(data) {
+			break
+		}
+		loop := data[offset:]
+		info.Loops = append(info.Loops, SampleLoop{
+			Type:  binary.LittleEndian.Uint32(loop[4:]),
+			Start: int(binary.LittleEndian.Uint32(loop[8:])),
+			End:   int(binary.LittleEndian.Uint32(loop[12:])),
+		})
+	}
+	return info, nil
+}
+
+// smplChunk encodes a smpl chunk, header included, for a file at sampleRate
+func smplChunk(info *SamplerInfo, sampleRate uint32) []byte {
+	data := make([]byte, smplHeaderSize+len(info.Loops)*smplLoopSize)
+	if sampleRate > 0 {
+		// Sample period in nanoseconds
+		binary.LittleEndian.PutUint32(data[8:], uint32(1e9/float64(sampleRate)))
+	}
+	binary.LittleEndian.PutUint32(data[12:], uint32(info.UnityNote))
+	binary.LittleEndian.PutUint32(data[16:], info.PitchFraction)
+	binary.LittleEndian.PutUint32(data[28:], uint32(len(info.Loops)))
+	for i, loop := range info.Loops {
+		record := data[smplHeaderSize+i*smplLoopSize:]
+		binary.LittleEndian.PutUint32(record[0:], uint32(i))
+		binary.LittleEndian.PutUint32(record[4:], loop.Type)
+		binary.LittleEndian.PutUint32(record[8:], uint32(loop.Start))
+		binary.LittleEndian.PutUint32(record[12:], uint32(loop.End))
+	}
+	return riffChunk("smpl", data)
+}
+
+// regionSampler returns the sampler info for the frames from startFrame up
+// to endFrame, keeping the loops that lie inside them, shifted to match
+func regionSampler(info *SamplerInfo, startFrame, endFrame int) *SamplerInfo {
+	if info == nil {
+		return nil
+	}
+	region := &SamplerInfo{UnityNote: info.UnityNote, PitchFraction: info.PitchFraction}
+	for _, loop := range info.Loops {
+		if loop.Start >= startFrame && loop.End < endFrame {
+			region.Loops = append(region.Loops, SampleLoop{
+				Start: loop.Start - startFrame,
+				End:   loop.End - startFrame,
+				Type:  loop.Type,
+			})
+		}
+	}
+	return region
+}
@@ -0,0 +1,70 @@
+package wavfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSampler(t *testing.T) {
+	info := &SamplerInfo{
+		UnityNote:     62,
+		PitchFraction: 1 << 31,
+		Loops:         []SampleLoop{{Start: 100, End: 199, Type: 0}, {Start: 300, End: 399, Type: 1}},
+	}
+	encoded := smplChunk(info, 44100)[8:]
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    *SamplerInfo
+		wantErr bool
+	}{
+		{"round trip", encoded, info, false},
+		{"loop cut short", encoded[:len(encoded)-1], &SamplerInfo{UnityNote: 62, PitchFraction: 1 << 31, Loops: info.Loops[:1]}, false},
+		{"no loops", encoded[:smplHeaderSize], &SamplerInfo{UnityNote: 62, PitchFraction: 1 << 31}, false},
+		{"header cut short", encoded[:smplHeaderSize-1], nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSampler(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSampler error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSampler = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegionSampler(t *testing.T) {
+	info := &SamplerInfo{
+		UnityNote: 60,
+		Loops:     []SampleLoop{{Start: 0, End: 9}, {Start: 10, End: 19, Type: 2}, {Start: 15, End: 25}},
+	}
+	want := &SamplerInfo{UnityNote: 60, Loops: []SampleLoop{{Start: 0, End: 9, Type: 2}}}
+	if got := regionSampler(info, 10, 20); !reflect.DeepEqual(got, want) {
+		t.Errorf("regionSampler = %+v, want %+v", got, want)
+	}
+	if got := regionSampler(nil, 0, 10); got != nil {
+		t.Errorf("regionSampler(nil) = %+v, want nil", got)
+	}
+}
+
+func TestReadSampler(t *testing.T) {
+	info := &SamplerInfo{UnityNote: 36, Loops: []SampleLoop{{Start: 2, End: 8}}}
+	filename := writeTestWAV(t, "looped.wav", &PCM{NumChannels: 1, SampleRate: 44100, BitsPerSample: 16, Samples: ramp(10, 1), Sampler: info})
+	got, err := ReadSampler(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, info) {
+		t.Errorf("ReadSampler = %+v, want %+v", got, info)
+	}
+
+	plain := writeTestWAV(t, "plain.wav", &PCM{NumChannels: 1, SampleRate: 44100, BitsPerSample: 16, Samples: ramp(10, 1)})
+	if got, err := ReadSampler(plain); got != nil || err != nil {
+		t.Errorf("ReadSampler without smpl = %+v, %v, want nil, nil", got, err)
+	}
+}
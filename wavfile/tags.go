@@ -0,0 +1,39 @@
+package wavfile
+
+import (
+	"slices"
+	"strings"
+)
+
+// ParseTags splits text into tags at spaces and commas, lowercased, with
+// any leading # dropped and duplicates removed, in the order typed
+func ParseTags(text string) []string {
+	var tags []string
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' }) {
+		tag := strings.ToLower(strings.TrimLeft(field, "#"))
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// HasTagPrefix reports whether one of the sample's tags starts with
+// prefix, so a tag filter narrows as it is typed
+func (w *WavFile) HasTagPrefix(prefix string) bool {
+	prefix = strings.ToLower(prefix)
+	for _, tag := range w.Tags {
+		if strings.HasPrefix(tag, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// TagLabel returns the sample's tags as #kick #808, "" for none
+func (w *WavFile) TagLabel() string {
+	if len(w.Tags) == 0 {
+		return ""
+	}
+	return "#" + strings.Join(w.Tags, " #")
+}
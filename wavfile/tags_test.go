@@ -0,0 +1,55 @@
+package wavfile
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"kick", []string{"kick"}},
+		{"#Kick #808", []string{"kick", "808"}},
+		{"kick, snare,fx", []string{"kick", "snare", "fx"}},
+		{"kick KICK #kick", []string{"kick"}},
+		{"  ,, # ##  ", nil},
+		{"##lofi dusty", []string{"lofi", "dusty"}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseTags(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("ParseTags(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTagHelpers(t *testing.T) {
+	file := WavFile{Tags: []string{"kick", "808"}}
+	tests := []struct {
+		prefix string
+		want   bool
+	}{
+		{"", true},
+		{"ki", true},
+		{"KICK", true},
+		{"80", true},
+		{"snare", false},
+		{"kicks", false},
+	}
+
+	for _, tt := range tests {
+		if got := file.HasTagPrefix(tt.prefix); got != tt.want {
+			t.Errorf("HasTagPrefix(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+
+	if got := file.TagLabel(); got != "#kick #808" {
+		t.Errorf("TagLabel = %q, want %q", got, "#kick #808")
+	}
+	if got := (&WavFile{}).TagLabel(); got != "" {
+		t.Errorf("TagLabel without tags = %q, want empty", got)
+	}
+}
@@ -0,0 +1,94 @@
+package wavfile
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// MoveToTrash moves a file, and any pitched versions older versions of
+// smplr rendered from it, to the system trash so a deletion can be undone
+func MoveToTrash(filename string) error {
+	pitched, err := filepath.Glob(pitchedPattern(filename))
+	if err != nil {
+		return fmt.Errorf("failed to find pitched files: %w", err)
+	}
+
+	for _, name := range append([]string{filename}, pitched...) {
+		if err := trashFile(name); err != nil {
+			return fmt.Errorf("failed to move %s to the trash: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// trashFile moves one file to the trash: ~/.Trash on macOS and the
+// freedesktop.org trash elsewhere, which also records where it came from
+func trashFile(filename string) error {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "darwin" {
+		return moveFile(path, freeName(filepath.Join(home, ".Trash"), filepath.Base(path)))
+	}
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	filesDir := filepath.Join(dataHome, "Trash", "files")
+	infoDir := filepath.Join(dataHome, "Trash", "info")
+	for _, dir := range []string{filesDir, infoDir} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+
+	trashed := freeName(filesDir, filepath.Base(path))
+	info := filepath.Join(infoDir, filepath.Base(trashed)+".trashinfo")
+	content := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n", (&url.URL{Path: path}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+	if err := os.WriteFile(info, []byte(content), 0600); err != nil {
+		return err
+	}
+	if err := moveFile(path, trashed); err != nil {
+		os.Remove(info)
+		return err
+	}
+	return nil
+}
+
+// freeName returns a path for name in dir that isn't taken, numbering it
+// "name 2.wav", "name 3.wav", ... as Finder does
+func freeName(dir, name string) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	path := filepath.Join(dir, name)
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s %d%s", base, n, ext))
+	}
+}
+
+// moveFile renames src to dst, copying when they are on different volumes
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyFile(src, dst); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
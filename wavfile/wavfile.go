@@ -1,9 +1,9 @@
 package wavfile
 
 import (
-	"encoding/binary"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,23 +11,53 @@ import (
 
 type PlaybackStartedMsg struct {
 	Filename string
+	PlayerId int
 }
 
 type PlaybackFinishedMsg struct {
 	Filename string
+	PlayerId int
 }
 
 // WaveformData contains pre-calculated waveform visualization data
 type WaveformData struct {
-	Peaks []float64 // Peak amplitude for each display segment
+	Mix      *PeakPyramid   // Loudest channel's peak and power across channels
+	Channels []*PeakPyramid // Each channel's own pyramid for multichannel files, nil for mono
 }
 
 // Metadata contains information about a WAV file
 type Metadata struct {
-	SampleRate   uint32
-	NumFrames    int
-	Duration     float64
-	WaveformData WaveformData
+	SampleRate    uint32
+	NumChannels   int
+	BitsPerSample int
+	Float         bool // Samples are IEEE float rather than integer
+	NumFrames     int
+	Duration      float64
+	Loops         []SampleLoop   // Loops from the smpl chunk
+	Cues          []CuePoint     // Named markers from the cue chunk, in frame order
+	Broadcast     *BroadcastInfo // Broadcast WAV bext chunk, nil when there's none
+	WaveformData  WaveformData
+}
+
+// PlayMode decides how a sample reacts to note-off
+type PlayMode int
+
+const (
+	// PlayModeOneShot ignores note-offs that arrive right after the note-on,
+	// so short pad hits play the whole sample
+	PlayModeOneShot PlayMode = iota
+	// PlayModeGate stops playback on every note-off
+	PlayModeGate
+)
+
+// String returns the display name of the play mode
+func (p PlayMode) String() string {
+	switch p {
+	case PlayModeGate:
+		return "gate"
+	default:
+		return "one-shot"
+	}
 }
 
 // WavFile represents a WAV file with its MIDI mapping and playback state
@@ -37,12 +67,34 @@ type WavFile struct {
 	Corrupted       bool // True if file is unreadable or corrupted
 	MidiChannel     int
 	MidiNote        int
-	Pitch           int    // Pitch shift in semitones (-12 to 12)
-	PitchedFileName string // Path to offline-rendered pitched file, empty if pitch is 0
+	Pitch           int     // Pitch shift in semitones (-12 to 12), applied in real time
+	FineTune        int     // Pitch shift in cents (-50 to 50) on top of Pitch
+	DetectedHz      float64 // Fundamental found by the tuner, 0 until detected
+	Chromatic       bool    // Keyboard mode: every note on MidiChannel plays, transposed from MidiNote as root
+	DecodedFileName string  // Path to decoded WAV for non-WAV sources, empty for WAV files
+	ChainNext       string  // Name of the sample that plays gaplessly after this one, empty for no chain
+	ChainNextSlot   int     // Which duplicated slot of ChainNext
+	ChainCrossfade  int     // Crossfade into ChainNext in milliseconds
+	StereoWidth     int     // Stereo width in percent, 0 = mono sum, 100 = original
+	GainDB          float64 // Playback gain in dB, 0 = unity
+	Pan             int     // Stereo position in percent, -100 = left, 0 = center, 100 = right
+	AttackMs        int     // Envelope attack time
+	DecayMs         int     // Envelope decay time
+	SustainLevel    int     // Envelope sustain level in percent, 100 = no decay
+	ReleaseMs       int     // Envelope release time after note-off
+	Loop            bool    // Repeat the region until the note is released or stopped
+	Reverse         bool    // Play the region backwards without touching the file
+	ChannelPair     int     // Stereo pair played from files of more than two channels, 0 = mix of all, 1 = channels 1-2, ...
+	PlayMode        PlayMode
+	ChokeGroup      int      // Triggering stops other playing samples in the same group, 0 = none
+	Tags            []string // Lowercase labels such as kick or fx to filter the list by
+	Favorite        bool     // Marked with * to find again quickly
 	StartFrame      int
 	EndFrame        int
 	PlayerId        int
+	Slot            int // Tells duplicated slots of a file apart, 0 for the first; saved in the session
 	Metadata        *Metadata
+	PartialMetadata *Metadata // Waveform read so far while a long file is Loading
 	Name            string
 }
 
@@ -60,54 +112,167 @@ type wavHeader struct {
 	BitsPerSample uint16
 }
 
-// MetadataLoadedMsg is sent when a WAV file's metadata has been loaded
+// MetadataLoadedMsg is sent when a WAV file's metadata has been loaded.
+// Long files also send Partial messages while loading, whose Metadata
+// covers the whole file but only has peaks for the frames read so far.
 type MetadataLoadedMsg struct {
-	Filename string
-	Metadata *Metadata
-	Err      error
+	Filename        string
+	DecodedFilename string
+	Metadata        *Metadata
+	Partial         bool
+	Err             error
 }
 
-// isPitchedFile checks if a filename matches the pattern for auto-generated pitched files
-func isPitchedFile(filename string) bool {
-	return strings.Contains(filename, "_pitch_")
+// Decoder converts a non-WAV audio file into a WAV file at target
+type Decoder func(source string, target string) error
+
+// RecordingFormats lists the container formats recordings can be written in
+var RecordingFormats = []string{"wav", "aiff", "flac"}
+
+// decodedDir holds WAV copies of non-WAV sources
+const decodedDir = ".smplr/decoded"
+
+// IsRecordingFormat checks if format is one of RecordingFormats
+func IsRecordingFormat(format string) bool {
+	for _, f := range RecordingFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAudioFile checks if a filename has an extension smplr can load
+func IsAudioFile(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".wav", ".aif", ".aiff", ".flac", ".mp3", ".m4a", ".aac":
+		return true
+	}
+	return false
+}
+
+// NeedsDecode checks if a file must be decoded to WAV before use
+func NeedsDecode(filename string) bool {
+	return strings.ToLower(filepath.Ext(filename)) != ".wav"
+}
+
+// DecodedFilename returns the path of the decoded WAV copy of a non-WAV file
+func DecodedFilename(filename string) string {
+	return filepath.Join(decodedDir, flatName(filename)+".wav")
+}
+
+// flatNameEscaper escapes the path separator, and the escape character
+// itself so no two paths share an escaped name
+var flatNameEscaper = strings.NewReplacer("%", "%25", "/", "%2F")
+
+// flatName turns a path inside the working directory into a single file
+// name, so files of the same name in different subdirectories don't share
+// a decoded copy or backups. Names without a directory stay as they are.
+func flatName(filename string) string {
+	return flatNameEscaper.Replace(filepath.ToSlash(filepath.Clean(filename)))
 }
 
-// GeneratePitchedFilename creates a filename for a pitched version of the audio file
-func GeneratePitchedFilename(originalFilename string, pitch int) string {
-	if pitch == 0 {
-		return ""
+// SourceFileName returns the WAV file backing this entry, which is the
+// decoded copy for non-WAV sources
+func (w *WavFile) SourceFileName() string {
+	if w.DecodedFileName != "" {
+		return w.DecodedFileName
 	}
+	return w.Name
+}
 
-	ext := filepath.Ext(originalFilename)
-	nameWithoutExt := strings.TrimSuffix(originalFilename, ext)
-	cents := pitch * 100
+// LoadMetadata reads metadata for any supported audio file, decoding
+// non-WAV files with decode and compressed WAVs to PCM first. It returns
+// the decoded filename, which is empty for PCM WAV files. progress, when
+// not nil, gets partial metadata while a long file is read.
+func LoadMetadata(filename string, decode Decoder, progress func(*Metadata)) (*Metadata, string, error) {
+	if !NeedsDecode(filename) {
+		if !IsCompressedWAV(filename) {
+			metadata, err := CachedMetadata(filename, progress)
+			return metadata, "", err
+		}
+		decode = DecodeCompressedWAV
+	}
 
-	var sign string
-	if cents >= 0 {
-		sign = "+"
-	} else {
-		sign = ""
+	if decode == nil {
+		return nil, "", fmt.Errorf("no decoder for %s", filename)
+	}
+
+	decoded := DecodedFilename(filename)
+	if err := os.MkdirAll(decodedDir, 0755); err != nil {
+		return nil, "", fmt.Errorf("failed to create decode directory: %w", err)
 	}
 
-	return fmt.Sprintf("%s_pitch_%s%d%s", nameWithoutExt, sign, cents, ext)
+	// Reuse an existing decode unless the source is newer
+	srcInfo, err := os.Stat(filename)
+	if err != nil {
+		return nil, "", err
+	}
+	if dstInfo, err := os.Stat(decoded); err != nil || dstInfo.ModTime().Before(srcInfo.ModTime()) {
+		if err := decode(filename, decoded); err != nil {
+			return nil, "", fmt.Errorf("failed to decode %s: %w", filename, err)
+		}
+	}
+
+	metadata, err := CachedMetadata(decoded, progress)
+	return metadata, decoded, err
+}
+
+// IsSampleFile checks if a filename is an audio file smplr lists, which
+// excludes the pitched files older versions rendered
+func IsSampleFile(filename string) bool {
+	return IsAudioFile(filename) && !isPitchedFile(filepath.Base(filename))
+}
+
+// isPitchedFile checks if a filename matches the pattern of the pitched files
+// older versions rendered next to their originals
+func isPitchedFile(filename string) bool {
+	return strings.Contains(filename, "_pitch_")
 }
 
-// PitchedFileExists checks if a pitched file already exists on disk
-func PitchedFileExists(filename string) bool {
-	if filename == "" {
-		return false
+// ChannelPairs returns how many stereo pairs a multichannel sample can play,
+// 0 for mono and stereo files or before metadata has loaded
+func (w *WavFile) ChannelPairs() int {
+	if w.Metadata == nil || w.Metadata.NumChannels <= 2 {
+		return 0
 	}
-	_, err := os.Stat(filename)
-	return err == nil
+	return (w.Metadata.NumChannels + 1) / 2
 }
 
-// RemoveAllPitchedVersions removes all pitched versions of the given original file
-func RemoveAllPitchedVersions(originalFilename string) error {
+// ChannelPairLabel describes the channels the sample plays, "mix" when a
+// multichannel file is mixed down to stereo
+func (w *WavFile) ChannelPairLabel() string {
+	if w.ChannelPair == 0 || w.Metadata == nil {
+		return "mix"
+	}
+	first := w.ChannelPair*2 - 1
+	if first == w.Metadata.NumChannels {
+		return fmt.Sprintf("%d", first)
+	}
+	return fmt.Sprintf("%d-%d", first, first+1)
+}
+
+// HasEnvelope reports whether the sample shapes its amplitude at all
+func (w *WavFile) HasEnvelope() bool {
+	return w.AttackMs > 0 || w.DecayMs > 0 || w.SustainLevel < 100 || w.ReleaseMs > 0
+}
+
+// Cents returns the pitch shift in cents passed to the audio engine
+func (w *WavFile) Cents() float32 {
+	return float32(w.Pitch*100 + w.FineTune)
+}
+
+// pitchedPattern returns the glob matching every pitched version of a file
+func pitchedPattern(originalFilename string) string {
 	ext := filepath.Ext(originalFilename)
 	nameWithoutExt := strings.TrimSuffix(originalFilename, ext)
-	pattern := fmt.Sprintf("%s_pitch_*%s", nameWithoutExt, ext)
+	return fmt.Sprintf("%s_pitch_*%s", nameWithoutExt, ext)
+}
 
-	matches, err := filepath.Glob(pattern)
+// RemoveAllPitchedVersions removes all pitched versions of the given original
+// file left over from when pitch changes were rendered offline
+func RemoveAllPitchedVersions(originalFilename string) error {
+	matches, err := filepath.Glob(pitchedPattern(originalFilename))
 	if err != nil {
 		return fmt.Errorf("failed to find pitched files: %w", err)
 	}
@@ -121,48 +286,95 @@ func RemoveAllPitchedVersions(originalFilename string) error {
 	return nil
 }
 
-// LoadFiles loads all WAV files from the current directory
-// and assigns incremental MIDI note numbers starting from 1.
+// ListAudioFiles returns the names of the loadable audio files in dir,
+// excluding auto-generated pitched files
+func ListAudioFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && IsSampleFile(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// ListAudioFilesRecursive returns the paths, relative to dir, of the
+// loadable audio files in dir and its subdirectories, skipping hidden
+// directories such as .smplr
+func ListAudioFilesRecursive(dir string) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if IsSampleFile(entry.Name()) {
+			name, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			names = append(names, name)
+		}
+		return nil
+	})
+	return names, err
+}
+
+// LoadFiles loads all supported audio files (WAV, AIFF, FLAC, MP3 and AAC) from the current directory,
+// and its subdirectories when recursive is set, and assigns incremental MIDI note numbers starting from 1.
 // It returns WavFile structs without metadata immediately.
-// Metadata is loaded concurrently in background goroutines.
+// Metadata is loaded concurrently in background goroutines, decoding
+// non-WAV files with decode.
 // Excludes auto-generated pitched files (files with "_pitch_" in the name).
-func LoadFiles(metadataChan chan<- MetadataLoadedMsg) []WavFile {
-	entries, err := os.ReadDir(".")
+func LoadFiles(metadataChan chan<- MetadataLoadedMsg, decode Decoder, recursive bool) []WavFile {
+	list := ListAudioFiles
+	if recursive {
+		list = ListAudioFilesRecursive
+	}
+	names, err := list(".")
 	if err != nil {
 		return []WavFile{}
 	}
 
-	// Collect WAV file names and create WavFile structs without metadata
+	// Create WavFile structs without metadata
 	var wavFiles []WavFile
 	note := 1
-	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasSuffix(strings.ToLower(entry.Name()), ".wav") {
-			// Skip auto-generated pitched files
-			if isPitchedFile(entry.Name()) {
-				continue
-			}
-
-			wavFiles = append(wavFiles, WavFile{
-				Name:        entry.Name(),
-				MidiChannel: 1,
-				MidiNote:    note,
-				StartFrame:  0,
-				EndFrame:    0,
-				Metadata:    nil, // Will be loaded in background
-				Loading:     true,
-			})
-			note++
-		}
+	for _, name := range names {
+		wavFiles = append(wavFiles, WavFile{
+			Name:         name,
+			MidiChannel:  1,
+			MidiNote:     note,
+			StereoWidth:  100,
+			SustainLevel: 100,
+			StartFrame:   0,
+			EndFrame:     0,
+			Metadata:     nil, // Will be loaded in background
+			Loading:      true,
+		})
+		note++
 	}
 
 	// Start background goroutines to load metadata for each file
 	for _, file := range wavFiles {
 		go func(filename string) {
-			metadata, err := ReadMetadata(filename)
+			metadata, decoded, err := LoadMetadata(filename, decode, func(partial *Metadata) {
+				metadataChan <- MetadataLoadedMsg{Filename: filename, Metadata: partial, Partial: true}
+			})
 			metadataChan <- MetadataLoadedMsg{
-				Filename: filename,
-				Metadata: metadata,
-				Err:      err,
+				Filename:        filename,
+				DecodedFilename: decoded,
+				Metadata:        metadata,
+				Err:             err,
 			}
 		}(file.Name)
 	}
@@ -170,6 +382,73 @@ func LoadFiles(metadataChan chan<- MetadataLoadedMsg) []WavFile {
 	return wavFiles
 }
 
+// MaxChainCrossfade is the longest crossfade between chained samples, in
+// milliseconds
+const MaxChainCrossfade = 2000
+
+// ChainIndices returns the indices of the files played by a trigger on
+// files[start], following ChainNext links and stopping at cycles or
+// files that can't be played
+func ChainIndices(files []WavFile, start int) []int {
+	indices := []int{start}
+	visited := map[int]bool{start: true}
+
+	for i := start; files[i].ChainNext != ""; {
+		next := FindSlot(files, files[i].ChainNext, files[i].ChainNextSlot)
+		if next < 0 || visited[next] || files[next].Metadata == nil || files[next].Corrupted {
+			break
+		}
+		indices = append(indices, next)
+		visited[next] = true
+		i = next
+	}
+
+	return indices
+}
+
+// FindTrigger returns the index of the file a note plays and the transposition
+// in semitones, or -1 if nothing matches. An exact channel and note mapping
+// wins over a chromatic file on the same channel.
+func FindTrigger(files []WavFile, channel int, note int) (int, int) {
+	chromatic := -1
+	for i := range files {
+		if files[i].MidiChannel != channel {
+			continue
+		}
+		if files[i].MidiNote == note {
+			return i, 0
+		}
+		if files[i].Chromatic && chromatic < 0 {
+			chromatic = i
+		}
+	}
+	if chromatic < 0 {
+		return -1, 0
+	}
+	return chromatic, note - files[chromatic].MidiNote
+}
+
+// NextSlot returns a slot number no entry of the named file uses yet
+func NextSlot(files []WavFile, name string) int {
+	slot := 0
+	for _, file := range files {
+		if file.Name == name && file.Slot >= slot {
+			slot = file.Slot + 1
+		}
+	}
+	return slot
+}
+
+// FindSlot returns the index of the given slot of the named file, or -1
+func FindSlot(files []WavFile, name string, slot int) int {
+	for i, file := range files {
+		if file.Name == name && file.Slot == slot {
+			return i
+		}
+	}
+	return -1
+}
+
 // FindMaxMidiNote returns the largest MIDI note value in a slice of WavFiles
 func FindMaxMidiNote(files []WavFile) int {
 	maxNote := 0
@@ -222,186 +501,107 @@ func (w *WavFile) MoveMarker(activeMarker string, direction int, stepSize int) {
 	}
 }
 
-// ReadMetadata reads a WAV file and returns its metadata
+// metadataBlockFrames is the number of frames ReadMetadata decodes at a time
+const metadataBlockFrames = 8192
+
+// metadataProgressBlocks is the number of blocks read between partial
+// metadata updates, so only long files send any
+const metadataProgressBlocks = 64
+
+// ReadMetadata reads a WAV file and returns its metadata. The data chunk is
+// streamed in blocks into the peak pyramids, so memory stays proportional
+// to the pyramids rather than the recording.
 func ReadMetadata(filename string) (*Metadata, error) {
+	return StreamMetadata(filename, nil)
+}
+
+// StreamMetadata reads a WAV file's metadata like ReadMetadata, calling
+// progress every metadataProgressBlocks blocks with metadata for the whole
+// file whose peaks stop at the frames read so far
+func StreamMetadata(filename string, progress func(*Metadata)) (*Metadata, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	// Read RIFF header
-	var chunkID [4]byte
-	var chunkSize uint32
-	var format [4]byte
-
-	binary.Read(file, binary.LittleEndian, &chunkID)
-	binary.Read(file, binary.LittleEndian, &chunkSize)
-	binary.Read(file, binary.LittleEndian, &format)
-
-	if string(chunkID[:]) != "RIFF" || string(format[:]) != "WAVE" {
-		return nil, fmt.Errorf("not a valid WAV file")
+	header, dataSize, err := readWavHeader(file)
+	if err != nil {
+		return nil, err
 	}
 
-	var header wavHeader
-	var dataSize uint32
-	foundFmt := false
-	foundData := false
-
-	// Read all chunks
-	for !foundData {
-		var subchunkID [4]byte
-		var subchunkSize uint32
-
-		if err := binary.Read(file, binary.LittleEndian, &subchunkID); err != nil {
-			return nil, fmt.Errorf("error reading chunk ID: %w", err)
-		}
-		if err := binary.Read(file, binary.LittleEndian, &subchunkSize); err != nil {
-			return nil, fmt.Errorf("error reading chunk size: %w", err)
-		}
-
-		chunkName := string(subchunkID[:])
-
-		switch chunkName {
-		case "fmt ":
-			binary.Read(file, binary.LittleEndian, &header.AudioFormat)
-			binary.Read(file, binary.LittleEndian, &header.NumChannels)
-			binary.Read(file, binary.LittleEndian, &header.SampleRate)
-			binary.Read(file, binary.LittleEndian, &header.ByteRate)
-			binary.Read(file, binary.LittleEndian, &header.BlockAlign)
-			binary.Read(file, binary.LittleEndian, &header.BitsPerSample)
-			// Skip any extra bytes in fmt chunk
-			if subchunkSize > 16 {
-				file.Seek(int64(subchunkSize-16), io.SeekCurrent)
-			}
-			foundFmt = true
-		case "data":
-			dataSize = subchunkSize
-			foundData = true
-		default:
-			// Skip unknown chunk
-			file.Seek(int64(subchunkSize), io.SeekCurrent)
-		}
+	numChannels := int(header.NumChannels)
+	frameBytes := numChannels * int(header.BitsPerSample) / 8
+	if frameBytes == 0 {
+		return nil, fmt.Errorf("unsupported format: %d channels at %d bits", numChannels, header.BitsPerSample)
 	}
 
-	if !foundFmt {
-		return nil, fmt.Errorf("fmt chunk not found")
+	metadata := func(numFrames int, waveformData WaveformData) *Metadata {
+		return &Metadata{
+			SampleRate:    header.SampleRate,
+			NumChannels:   numChannels,
+			BitsPerSample: int(header.BitsPerSample),
+			Float:         header.AudioFormat == wavFormatFloat,
+			NumFrames:     numFrames,
+			Duration:      float64(numFrames) / float64(header.SampleRate),
+			WaveformData:  waveformData,
+		}
 	}
 
-	// Read samples
-	numSamples := int(dataSize) / int(header.BlockAlign)
-	samples := make([]float64, numSamples)
-
-	switch header.BitsPerSample {
-	case 16:
-		for i := range numSamples {
-			var sample int16
-			if err := binary.Read(file, binary.LittleEndian, &sample); err != nil {
-				if err == io.EOF {
-					break
-				}
-				return nil, err
-			}
-			samples[i] = float64(sample) / 32768.0
-
-			// Skip other channels if stereo
-			if header.NumChannels > 1 {
-				file.Seek(int64((header.NumChannels-1)*2), io.SeekCurrent)
-			}
+	// Pre-calculate peak pyramids for visualization at any zoom
+	builders := make([]peakBuilder, numChannels)
+	block := make([]byte, metadataBlockFrames*frameBytes)
+	numFrames := 0
+	for blocks, remaining := 1, int64(dataSize); remaining >= int64(frameBytes); blocks++ {
+		chunk := block[:min(int64(len(block)), remaining)]
+		n, err := io.ReadFull(file, chunk)
+		// Only whole frames count; a truncated file ends at its last one
+		n -= n % frameBytes
+		samples, decodeErr := decodeSamples(chunk[:n], header.AudioFormat, int(header.BitsPerSample))
+		if decodeErr != nil {
+			return nil, decodeErr
 		}
-	case 8:
-		for i := range numSamples {
-			var sample uint8
-			if err := binary.Read(file, binary.LittleEndian, &sample); err != nil {
-				if err == io.EOF {
-					break
-				}
-				return nil, err
-			}
-			samples[i] = (float64(sample) - 128.0) / 128.0
-
-			if header.NumChannels > 1 {
-				file.Seek(int64(header.NumChannels-1), io.SeekCurrent)
-			}
+		for i, sample := range samples {
+			builders[i%numChannels].add(sample)
 		}
-	case 24:
-		for i := range numSamples {
-			var bytes [3]byte
-			if err := binary.Read(file, binary.LittleEndian, &bytes); err != nil {
-				if err == io.EOF {
-					break
-				}
-				return nil, err
-			}
-			// Convert 24-bit little-endian to int32
-			sample := int32(bytes[0]) | int32(bytes[1])<<8 | int32(bytes[2])<<16
-			// Sign extend from 24-bit to 32-bit
-			if sample&0x800000 != 0 {
-				sample |= ^0xFFFFFF
-			}
-			samples[i] = float64(sample) / 8388608.0
+		numFrames += n / frameBytes
+		remaining -= int64(len(chunk))
 
-			// Skip other channels if stereo
-			if header.NumChannels > 1 {
-				file.Seek(int64((header.NumChannels-1)*3), io.SeekCurrent)
-			}
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading samples: %w", err)
 		}
-	default:
-		return nil, fmt.Errorf("unsupported bit depth: %d", header.BitsPerSample)
-	}
-
-	duration := float64(len(samples)) / float64(header.SampleRate)
 
-	// Pre-calculate waveform data for visualization
-	// Use a reasonable number of segments for display (e.g., 2000 segments = 1000 char width * 2)
-	maxSegments := 2000
-	waveformData := calculateWaveformData(samples, maxSegments)
-
-	return &Metadata{
-		SampleRate:   header.SampleRate,
-		NumFrames:    len(samples),
-		Duration:     duration,
-		WaveformData: waveformData,
-	}, nil
-}
-
-// calculateWaveformData pre-calculates peak values for waveform display
-func calculateWaveformData(samples []float64, numSegments int) WaveformData {
-	if len(samples) == 0 {
-		return WaveformData{Peaks: []float64{}}
+		if progress != nil && blocks%metadataProgressBlocks == 0 && remaining >= int64(frameBytes) {
+			progress(metadata(int(dataSize)/frameBytes, waveformDataFrom(builders)))
+		}
 	}
 
-	// Don't create more segments than samples
-	if numSegments > len(samples) {
-		numSegments = len(samples)
+	for i := range builders {
+		builders[i].flush()
 	}
-
-	peaks := make([]float64, numSegments)
-	samplesPerSegment := len(samples) / numSegments
-	if samplesPerSegment < 1 {
-		samplesPerSegment = 1
+	result := metadata(numFrames, waveformDataFrom(builders))
+	// Malformed smpl, cue or bext chunks only lose what they hold
+	if sampler, _ := ReadSampler(filename); sampler != nil {
+		result.Loops = sampler.Loops
 	}
+	result.Cues, _ = ReadCues(filename)
+	result.Broadcast, _ = ReadBroadcastInfo(filename)
+	return result, nil
+}
 
-	for i := 0; i < numSegments; i++ {
-		start := i * samplesPerSegment
-		end := start + samplesPerSegment
-		if end > len(samples) {
-			end = len(samples)
-		}
-
-		// Find max absolute value in this segment
-		maxAbs := 0.0
-		for j := start; j < end; j++ {
-			abs := samples[j]
-			if abs < 0 {
-				abs = -abs
-			}
-			if abs > maxAbs {
-				maxAbs = abs
-			}
+// waveformDataFrom builds the channel and mix pyramids from everything
+// the builders have been given so far. Mono files have only the mix.
+func waveformDataFrom(builders []peakBuilder) WaveformData {
+	var waveformData WaveformData
+	if len(builders) > 1 {
+		for i := range builders {
+			waveformData.Channels = append(waveformData.Channels, builders[i].pyramid())
 		}
-		peaks[i] = maxAbs
+		waveformData.Mix = MixPeakPyramids(waveformData.Channels)
+	} else {
+		waveformData.Mix = builders[0].pyramid()
 	}
-
-	return WaveformData{Peaks: peaks}
+	return waveformData
 }
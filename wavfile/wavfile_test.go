@@ -0,0 +1,195 @@
+package wavfile
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestChainIndices(t *testing.T) {
+	loaded := &Metadata{}
+	tests := []struct {
+		name  string
+		files []WavFile
+		start int
+		want  []int
+	}{
+		{
+			name:  "no chain",
+			files: []WavFile{{Name: "a.wav", Metadata: loaded}},
+			want:  []int{0},
+		},
+		{
+			name: "follows links",
+			files: []WavFile{
+				{Name: "a.wav", ChainNext: "c.wav", Metadata: loaded},
+				{Name: "b.wav", Metadata: loaded},
+				{Name: "c.wav", ChainNext: "b.wav", Metadata: loaded},
+			},
+			want: []int{0, 2, 1},
+		},
+		{
+			name: "stops at cycles",
+			files: []WavFile{
+				{Name: "a.wav", ChainNext: "b.wav", Metadata: loaded},
+				{Name: "b.wav", ChainNext: "a.wav", Metadata: loaded},
+			},
+			start: 1,
+			want:  []int{1, 0},
+		},
+		{
+			name: "stops at files that can't play",
+			files: []WavFile{
+				{Name: "a.wav", ChainNext: "b.wav", Metadata: loaded},
+				{Name: "b.wav", ChainNext: "c.wav", Metadata: loaded},
+				{Name: "c.wav", Metadata: loaded, Corrupted: true},
+			},
+			want: []int{0, 1},
+		},
+		{
+			name: "stops at files still loading",
+			files: []WavFile{
+				{Name: "a.wav", ChainNext: "b.wav", Metadata: loaded},
+				{Name: "b.wav"},
+			},
+			want: []int{0},
+		},
+		{
+			name: "stops at missing files",
+			files: []WavFile{
+				{Name: "a.wav", ChainNext: "gone.wav", Metadata: loaded},
+			},
+			want: []int{0},
+		},
+		{
+			name: "links to a duplicated slot",
+			files: []WavFile{
+				{Name: "a.wav", ChainNext: "b.wav", ChainNextSlot: 1, Metadata: loaded},
+				{Name: "b.wav", Metadata: loaded},
+				{Name: "b.wav", Slot: 1, ChainNext: "b.wav", Metadata: loaded},
+			},
+			want: []int{0, 2, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChainIndices(tt.files, tt.start); !slices.Equal(got, tt.want) {
+				t.Errorf("ChainIndices = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindTrigger(t *testing.T) {
+	files := []WavFile{
+		{Name: "kick.wav", MidiChannel: 9, MidiNote: 36},
+		{Name: "keys.wav", MidiChannel: 1, MidiNote: 60, Chromatic: true},
+		{Name: "stab.wav", MidiChannel: 1, MidiNote: 64},
+		{Name: "pad.wav", MidiChannel: 1, MidiNote: 48, Chromatic: true},
+	}
+	tests := []struct {
+		name      string
+		channel   int
+		note      int
+		wantIndex int
+		wantShift int
+	}{
+		{"exact note", 9, 36, 0, 0},
+		{"nothing on the channel", 2, 36, -1, 0},
+		{"no chromatic file on the channel", 9, 37, -1, 0},
+		{"exact note beats chromatic", 1, 64, 2, 0},
+		{"first chromatic file transposes up", 1, 67, 1, 7},
+		{"first chromatic file transposes down", 1, 55, 1, -5},
+		{"chromatic root", 1, 60, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, shift := FindTrigger(files, tt.channel, tt.note)
+			if index != tt.wantIndex || shift != tt.wantShift {
+				t.Errorf("FindTrigger(%d, %d) = %d, %d, want %d, %d", tt.channel, tt.note, index, shift, tt.wantIndex, tt.wantShift)
+			}
+		})
+	}
+}
+
+func TestSlots(t *testing.T) {
+	files := []WavFile{
+		{Name: "a.wav"},
+		{Name: "b.wav"},
+		{Name: "a.wav", Slot: 2},
+	}
+	tests := []struct {
+		name     string
+		slot     int
+		wantFind int
+		wantNext int
+	}{
+		{"a.wav", 0, 0, 3},
+		{"a.wav", 2, 2, 3},
+		{"a.wav", 1, -1, 3},
+		{"b.wav", 0, 1, 1},
+		{"c.wav", 0, -1, 0},
+	}
+
+	for _, tt := range tests {
+		if got := FindSlot(files, tt.name, tt.slot); got != tt.wantFind {
+			t.Errorf("FindSlot(%s, %d) = %d, want %d", tt.name, tt.slot, got, tt.wantFind)
+		}
+		if got := NextSlot(files, tt.name); got != tt.wantNext {
+			t.Errorf("NextSlot(%s) = %d, want %d", tt.name, got, tt.wantNext)
+		}
+	}
+}
+
+func TestFlatName(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"kick.wav", "kick.wav"},
+		{"drums/kick.wav", "drums%2Fkick.wav"},
+		{"drums/../kick.wav", "kick.wav"},
+		{"100%/kick.wav", "100%25%2Fkick.wav"},
+	}
+
+	for _, tt := range tests {
+		if got := flatName(tt.filename); got != tt.want {
+			t.Errorf("flatName(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+
+	// Escaping the escape character keeps these apart
+	if flatName("a/b.wav") == flatName("a%2Fb.wav") {
+		t.Error("flatName gives a/b.wav and a%2Fb.wav the same name")
+	}
+}
+
+func TestCCMappings(t *testing.T) {
+	mappings := []CCMapping{
+		{Channel: 1, Controller: 7, Param: CCParamMasterVolume},
+		{Channel: 1, Controller: 20, Param: CCParamGain, Sample: "a.wav"},
+		{Channel: 1, Controller: 21, Param: CCParamPitch, Sample: "a.wav", Slot: 1},
+	}
+
+	mappings = SetCCMapping(mappings, CCMapping{Channel: 1, Controller: 20, Param: CCParamStart, Sample: "b.wav"})
+	if len(mappings) != 3 || mappings[1].Sample != "b.wav" {
+		t.Errorf("SetCCMapping didn't replace the mapping of controller 20: %+v", mappings)
+	}
+	if i := FindCCMapping(mappings, 2, 20); i != -1 {
+		t.Errorf("FindCCMapping on another channel = %d, want -1", i)
+	}
+
+	mappings = RemoveCCMappings(mappings, "a.wav", 1)
+	want := []CCMapping{
+		{Channel: 1, Controller: 7, Param: CCParamMasterVolume},
+		{Channel: 1, Controller: 20, Param: CCParamStart, Sample: "b.wav"},
+	}
+	if !slices.Equal(mappings, want) {
+		t.Errorf("RemoveCCMappings = %+v, want %+v", mappings, want)
+	}
+
+	if !want[1].Targets(&WavFile{Name: "b.wav"}) || want[1].Targets(&WavFile{Name: "b.wav", Slot: 1}) {
+		t.Error("Targets doesn't tell slots apart")
+	}
+}